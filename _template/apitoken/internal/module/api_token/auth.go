@@ -0,0 +1,17 @@
+package api_token
+
+import (
+	"strings"
+	"github.com/gin-gonic/gin"
+)
+
+
+//Authorization: Bearer のAPIトークン (JWTの場合は false)
+func BearerToken(c *gin.Context) (string, bool) {
+	bearer := c.Request.Header.Get("Authorization")
+	if !strings.HasPrefix(bearer, "Bearer ") {
+		return "", false
+	}
+	token := strings.TrimSpace(bearer[7:])
+	return token, strings.HasPrefix(token, TOKEN_PREFIX)
+}
@@ -0,0 +1,106 @@
+package api_token
+
+import (
+	"github.com/gin-gonic/gin"
+	"masmaint/internal/module"
+	"masmaint/internal/core/jwt"
+	"masmaint/internal/core/errs"
+	"masmaint/internal/module/audit"
+	"masmaint/internal/module/permission"
+)
+
+type controller struct {
+	service Service
+}
+
+func NewController(service Service) *controller {
+	return &controller{service}
+}
+
+
+//GET /api_tokens
+func (ctr *controller) GetPage(c *gin.Context) {
+	c.HTML(200, "api_token.html", gin.H{
+		"tables": permission.Tables(),
+		"admin": permission.Can(c, TABLE_NAME, permission.Read),
+	})
+}
+
+
+//GET /api/api_tokens
+func (ctr *controller) Get(c *gin.Context) {
+	req, err := requester(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	ret, err := ctr.service.Get(req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(200, ret)
+}
+
+
+//POST /api/api_tokens (トークンは応答でのみ返す)
+func (ctr *controller) Post(c *gin.Context) {
+	req, err := requester(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	var body PostBody
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.Error(module.NewBindError(err, &body))
+		return
+	}
+
+	ret, err := ctr.service.Create(req, body)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(200, ret)
+}
+
+
+//DELETE /api/api_tokens/:api_token_id (失効)
+func (ctr *controller) Delete(c *gin.Context) {
+	req, err := requester(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	var key Key
+	if err := c.ShouldBindUri(&key); err != nil {
+		c.Error(module.NewBindError(err, &key))
+		return
+	}
+
+	if err := ctr.service.Revoke(req, key); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(200, gin.H{})
+}
+
+
+//APIトークンでのトークンの操作は不可
+func requester(c *gin.Context) (Requester, error) {
+	pl := jwt.GetPayload(c)
+	if pl.Scopes != nil {
+		return Requester{}, errs.NewForbiddenError()
+	}
+	return Requester{
+		Actor: audit.GetActor(c),
+		Role: pl.Role,
+		Admin: permission.Can(c, TABLE_NAME, permission.Read),
+	}, nil
+}
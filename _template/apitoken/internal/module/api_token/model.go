@@ -0,0 +1,30 @@
+package api_token
+
+//トークン自体は保存せず、SHA-256 のハッシュのみ保存する
+type ApiToken struct {
+	ApiTokenId int `db:"api_token_id" json:"api_token_id"`
+	TokenName string `db:"token_name" json:"token_name"`
+	TokenHash string `db:"token_hash" json:"-"`
+	//一覧で識別するための先頭部分
+	TokenPrefix string `db:"token_prefix" json:"token_prefix"`
+	//personal: ユーザ個人 (作成者のロール) / service: サービス用 (スコープのみ)
+	Kind string `db:"kind" json:"kind"`
+	AccountId int `db:"account_id" json:"account_id"`
+	AccountName string `db:"account_name" json:"account_name"`
+	//作成者を識別するキー (個人用トークンの一覧・失効の判定に使用)
+	AccountKey string `db:"account_key" json:"account_key"`
+	Role string `db:"role" json:"role"`
+	//"<table>:<action>" を空白区切り
+	Scopes string `db:"scopes" json:"scopes"`
+	ExpiresAt *string `db:"expires_at" json:"expires_at"`
+	LastUsedAt *string `db:"last_used_at" json:"last_used_at"`
+	RevokedAt *string `db:"revoked_at" json:"revoked_at"`
+	CreatedBy string `db:"created_by" json:"created_by"`
+	CreatedAt string `db:"created_at" json:"created_at"`
+}
+
+//作成時のみトークンを返す
+type Created struct {
+	ApiToken
+	Token string `json:"token"`
+}
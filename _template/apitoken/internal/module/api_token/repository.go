@@ -0,0 +1,204 @@
+package api_token
+
+import (
+	"database/sql"
+	"masmaint/internal/core/db"
+)
+
+
+type Repository interface {
+	Get() ([]ApiToken, error)
+	GetByAccount(accountKey string) ([]ApiToken, error)
+	GetOne(apiTokenId int, tx *sql.Tx) (ApiToken, error)
+	GetActive(tokenHash string, now string) (ApiToken, error)
+	Insert(t *ApiToken, tx *sql.Tx) (int, error)
+	Revoke(apiTokenId int, now string, tx *sql.Tx) error
+	Touch(apiTokenId int, now string) error
+}
+
+
+type repository struct {
+	db *db.DB
+}
+
+func NewRepository(db *db.DB) Repository {
+	return &repository{db}
+}
+
+
+const selectQuery =
+`SELECT
+	api_token_id
+	,token_name
+	,token_hash
+	,token_prefix
+	,kind
+	,account_id
+	,account_name
+	,account_key
+	,role
+	,scopes
+	,expires_at
+	,last_used_at
+	,revoked_at
+	,created_by
+	,created_at
+ FROM api_token`
+
+
+func (rep *repository) Get() ([]ApiToken, error) {
+	return rep.query(selectQuery + " ORDER BY api_token_id")
+}
+
+
+//個人用トークン
+func (rep *repository) GetByAccount(accountKey string) ([]ApiToken, error) {
+	return rep.query(
+		selectQuery + " WHERE kind = 'personal' AND account_key = ? ORDER BY api_token_id",
+		accountKey,
+	)
+}
+
+
+func (rep *repository) GetOne(apiTokenId int, tx *sql.Tx) (ApiToken, error) {
+	query := rep.db.Rebind(selectQuery + " WHERE api_token_id = ?")
+
+	var row *sql.Row
+	if tx != nil {
+		row = tx.QueryRow(query, apiTokenId)
+	} else {
+		row = rep.db.QueryRow(query, apiTokenId)
+	}
+	return scanOne(row)
+}
+
+
+//失効・期限切れでないトークン
+func (rep *repository) GetActive(tokenHash string, now string) (ApiToken, error) {
+	query := selectQuery +
+	` WHERE token_hash = ?
+	   AND revoked_at IS NULL
+	   AND (expires_at IS NULL OR expires_at > ?)`
+	return scanOne(rep.db.QueryRow(rep.db.Rebind(query), tokenHash, now))
+}
+
+
+//登録して api_token_id を返す (RDBMSに依存しないよう token_hash で取得し直す)
+func (rep *repository) Insert(t *ApiToken, tx *sql.Tx) (int, error) {
+	cmd :=
+	`INSERT INTO api_token (
+		token_name
+		,token_hash
+		,token_prefix
+		,kind
+		,account_id
+		,account_name
+		,account_key
+		,role
+		,scopes
+		,expires_at
+		,created_by
+		,created_at
+	 ) VALUES(?,?,?,?,?,?,?,?,?,?,?,?)`
+	binds := []interface{}{
+		t.TokenName,
+		t.TokenHash,
+		t.TokenPrefix,
+		t.Kind,
+		t.AccountId,
+		t.AccountName,
+		t.AccountKey,
+		t.Role,
+		t.Scopes,
+		t.ExpiresAt,
+		t.CreatedBy,
+		t.CreatedAt,
+	}
+	query := rep.db.Rebind("SELECT api_token_id FROM api_token WHERE token_hash = ?")
+
+	var apiTokenId int
+	var err error
+	if tx != nil {
+		if _, err = tx.Exec(rep.db.Rebind(cmd), binds...); err == nil {
+			err = tx.QueryRow(query, t.TokenHash).Scan(&apiTokenId)
+		}
+	} else {
+		if _, err = rep.db.Exec(rep.db.Rebind(cmd), binds...); err == nil {
+			err = rep.db.QueryRow(query, t.TokenHash).Scan(&apiTokenId)
+		}
+	}
+
+	return apiTokenId, err
+}
+
+
+func (rep *repository) Revoke(apiTokenId int, now string, tx *sql.Tx) error {
+	cmd := rep.db.Rebind("UPDATE api_token SET revoked_at = ? WHERE api_token_id = ? AND revoked_at IS NULL")
+
+	var err error
+	if tx != nil {
+		_, err = tx.Exec(cmd, now, apiTokenId)
+	} else {
+		_, err = rep.db.Exec(cmd, now, apiTokenId)
+	}
+
+	return err
+}
+
+
+//最終使用日時
+func (rep *repository) Touch(apiTokenId int, now string) error {
+	_, err := rep.db.Exec(rep.db.Rebind("UPDATE api_token SET last_used_at = ? WHERE api_token_id = ?"), now, apiTokenId)
+	return err
+}
+
+
+func (rep *repository) query(query string, args ...interface{}) ([]ApiToken, error) {
+	rows, err := rep.db.Query(rep.db.Rebind(query), args...)
+	if err != nil {
+		return []ApiToken{}, err
+	}
+	defer rows.Close()
+
+	ret := []ApiToken{}
+	for rows.Next() {
+		t, err := scan(rows)
+		if err != nil {
+			return []ApiToken{}, err
+		}
+		ret = append(ret, t)
+	}
+
+	return ret, nil
+}
+
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scan(s scanner) (ApiToken, error) {
+	var t ApiToken
+	err := s.Scan(
+		&t.ApiTokenId,
+		&t.TokenName,
+		&t.TokenHash,
+		&t.TokenPrefix,
+		&t.Kind,
+		&t.AccountId,
+		&t.AccountName,
+		&t.AccountKey,
+		&t.Role,
+		&t.Scopes,
+		&t.ExpiresAt,
+		&t.LastUsedAt,
+		&t.RevokedAt,
+		&t.CreatedBy,
+		&t.CreatedAt,
+	)
+	return t, err
+}
+
+func scanOne(row *sql.Row) (ApiToken, error) {
+	return scan(row)
+}
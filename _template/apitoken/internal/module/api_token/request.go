@@ -0,0 +1,13 @@
+package api_token
+
+type PostBody struct {
+	TokenName string `json:"token_name" binding:"required,max=255"`
+	Kind string `json:"kind" binding:"required,oneof=personal service"`
+	Scopes []string `json:"scopes" binding:"required,min=1,dive,required"`
+	//有効期限の日数 (0 は無期限)
+	ExpiresDays int `json:"expires_days" binding:"min=0,max=3650"`
+}
+
+type Key struct {
+	ApiTokenId int `uri:"api_token_id" binding:"required"`
+}
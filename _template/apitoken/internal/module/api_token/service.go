@@ -0,0 +1,250 @@
+package api_token
+
+import (
+	"time"
+	"strings"
+	"strconv"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+
+	"masmaint/internal/module"
+	"masmaint/internal/core/db"
+	"masmaint/internal/core/jwt"
+	"masmaint/internal/core/logger"
+	"masmaint/internal/core/utils"
+	"masmaint/internal/core/errs"
+	"masmaint/internal/module/audit"
+	"masmaint/internal/module/permission"
+)
+
+
+//監査ログのテーブル名 (トークンのハッシュは記録しない)
+const TABLE_NAME = "api_token"
+
+//JWTと区別するためのトークンの接頭辞
+const TOKEN_PREFIX = "mmt_"
+
+const (
+	KindPersonal = "personal"
+	KindService = "service"
+)
+
+//トークンを操作するユーザ
+type Requester struct {
+	Actor audit.Actor
+	Role string
+	//全てのトークンを管理できる (api_token の権限)
+	Admin bool
+}
+
+type Service interface {
+	Auth(token string, reload jwt.Reloader) (jwt.CustomClaims, error)
+	Get(req Requester) ([]ApiToken, error)
+	Create(req Requester, input PostBody) (Created, error)
+	Revoke(req Requester, key Key) error
+}
+
+type service struct {
+	db *db.DB
+	repository Repository
+}
+
+func NewService(db *db.DB, repository Repository) Service {
+	return &service{
+		db: db,
+		repository: repository,
+	}
+}
+
+
+/*
+ Authorization: Bearer のトークンを確認し、ログインユーザとして扱うクレームを返す
+ reload: 個人用トークンの作成者を確認 (users のユーザの削除・ロールの変更を反映、nil の場合は作成時のロール)
+*/
+func (srv *service) Auth(token string, reload jwt.Reloader) (jwt.CustomClaims, error) {
+	if !strings.HasPrefix(token, TOKEN_PREFIX) {
+		return jwt.CustomClaims{}, errs.NewUnauthorizedError()
+	}
+
+	now := utils.NowString()
+	t, err := srv.repository.GetActive(hashToken(token), now)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			logger.Error(err.Error())
+			return jwt.CustomClaims{}, errs.NewUnexpectedError(err.Error())
+		}
+		return jwt.CustomClaims{}, errs.NewUnauthorizedError()
+	}
+	if err := srv.repository.Touch(t.ApiTokenId, now); err != nil {
+		logger.Error(err.Error())
+	}
+
+	cc := jwt.CustomClaims{
+		AccountId: t.AccountId,
+		AccountName: t.AccountName,
+		AccountKey: t.AccountKey,
+		Role: t.Role,
+		Scopes: strings.Fields(t.Scopes),
+	}
+	if t.Kind == KindPersonal && reload != nil {
+		return reload(cc)
+	}
+	return cc, nil
+}
+
+
+//管理者は全て、それ以外は自身の個人用トークン
+func (srv *service) Get(req Requester) ([]ApiToken, error) {
+	var rows []ApiToken
+	var err error
+	if req.Admin {
+		rows, err = srv.repository.Get()
+	} else {
+		rows, err = srv.repository.GetByAccount(req.Actor.AccountKey)
+	}
+	if err != nil {
+		logger.Error(err.Error())
+		return []ApiToken{}, errs.NewUnexpectedError(err.Error())
+	}
+	return rows, nil
+}
+
+
+//個人用は作成者のロールとスコープの両方、サービス用 (管理者のみ作成可) はスコープのみで権限を判定
+func (srv *service) Create(req Requester, input PostBody) (Created, error) {
+	if !validScopes(input.Scopes) {
+		return Created{}, errs.NewBadRequestError("scopes")
+	}
+	if input.Kind == KindService && !req.Admin {
+		return Created{}, errs.NewForbiddenError()
+	}
+
+	token, err := newToken()
+	if err != nil {
+		logger.Error(err.Error())
+		return Created{}, errs.NewUnexpectedError(err.Error())
+	}
+
+	now := time.Now()
+	model := ApiToken{
+		TokenName: input.TokenName,
+		TokenHash: hashToken(token),
+		TokenPrefix: token[:len(TOKEN_PREFIX) + 6],
+		Kind: input.Kind,
+		AccountId: req.Actor.AccountId,
+		AccountName: req.Actor.AccountName,
+		AccountKey: req.Actor.AccountKey,
+		Role: req.Role,
+		Scopes: strings.Join(input.Scopes, " "),
+		CreatedBy: req.Actor.AccountName,
+		CreatedAt: now.Format("2006-01-02 15:04:05"),
+	}
+	if input.Kind == KindService {
+		model.AccountId = 0
+		model.AccountName = "service:" + input.TokenName
+		model.AccountKey = model.AccountName
+		model.Role = ""
+	}
+	if input.ExpiresDays > 0 {
+		expires := now.AddDate(0, 0, input.ExpiresDays).Format("2006-01-02 15:04:05")
+		model.ExpiresAt = &expires
+	}
+
+	var row ApiToken
+	err = module.RunInTx(srv.db, func(tx *sql.Tx) error {
+		apiTokenId, err := srv.repository.Insert(&model, tx)
+		if err != nil {
+			return err
+		}
+		row, err = srv.repository.GetOne(apiTokenId, tx)
+		if err != nil {
+			return err
+		}
+		return writeAudit(srv.db, tx, req.Actor, audit.OperationInsert, nil, &row)
+	})
+	if err != nil {
+		return Created{}, module.NewDBError(err)
+	}
+	return Created{ApiToken: row, Token: token}, nil
+}
+
+
+//管理者は全て、それ以外は自身の個人用トークンのみ失効できる
+func (srv *service) Revoke(req Requester, key Key) error {
+	err := module.RunInTx(srv.db, func(tx *sql.Tx) error {
+		before, err := srv.repository.GetOne(key.ApiTokenId, tx)
+		if err != nil {
+			return err
+		}
+		if !req.Admin && (before.Kind != KindPersonal || before.AccountKey != req.Actor.AccountKey) {
+			return errs.NewForbiddenError()
+		}
+		if err := srv.repository.Revoke(key.ApiTokenId, utils.NowString(), tx); err != nil {
+			return err
+		}
+		after, err := srv.repository.GetOne(key.ApiTokenId, tx)
+		if err != nil {
+			return err
+		}
+		return writeAudit(srv.db, tx, req.Actor, audit.OperationUpdate, &before, &after)
+	})
+	return module.NewDBError(err)
+}
+
+
+//"<table>:<action>" (table・action は * も可)
+func validScopes(scopes []string) bool {
+	tables := permission.Tables()
+	actions := []string{permission.Read, permission.Create, permission.Update, permission.Delete}
+	for _, s := range scopes {
+		t, a, ok := strings.Cut(s, ":")
+		if !ok || !(t == "*" || contains(tables, t)) || !(a == "*" || contains(actions, a)) {
+			return false
+		}
+	}
+	return true
+}
+
+
+func contains(ls []string, s string) bool {
+	for _, v := range ls {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+
+func newToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return TOKEN_PREFIX + base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+
+//トークンは十分な長さの乱数のため、ソルト無しの SHA-256 で保存する
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+
+func writeAudit(conn *db.DB, tx *sql.Tx, actor audit.Actor, operation string, before *ApiToken, after *ApiToken) error {
+	var apiTokenId int
+	var b, a interface{}
+	if before != nil {
+		apiTokenId = before.ApiTokenId
+		b = before
+	}
+	if after != nil {
+		apiTokenId = after.ApiTokenId
+		a = after
+	}
+	return audit.Write(conn, tx, actor, TABLE_NAME, strconv.Itoa(apiTokenId), operation, b, a)
+}
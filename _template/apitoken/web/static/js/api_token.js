@@ -0,0 +1,108 @@
+import { api } from '/js/api.js';
+
+
+document.addEventListener('DOMContentLoaded', () => {
+    document.getElementById('token-new').addEventListener('submit', (event) => {
+        event.preventDefault();
+        postToken();
+    });
+    getRows();
+});
+
+
+const getRows = async () => {
+    try {
+        const rows = await api.get('api_tokens');
+        renderRows(rows);
+    } catch (e) {
+        renderMessage('APIトークンを取得できませんでした。', 'danger');
+    }
+}
+
+const postToken = async () => {
+    clearMessage();
+    const body = {
+        token_name: document.getElementById('token-new-name').value.trim(),
+        kind: document.getElementById('token-new-kind').value,
+        scopes: document.getElementById('token-new-scopes').value.split(/\s+/).filter(s => s !== ''),
+        expires_days: Number(document.getElementById('token-new-expires').value),
+    };
+    try {
+        const created = await api.post('api_tokens', body);
+        document.getElementById('token-new').reset();
+        document.getElementById('token-value').textContent = created.token;
+        document.getElementById('token-created').classList.remove('d-none');
+        getRows();
+    } catch (e) {
+        renderMessage(errorMessage(e, '作成'), 'danger');
+    }
+}
+
+const revokeToken = async (row) => {
+    if (!window.confirm(`${row.token_name} を失効します。よろしいですか？`)) {
+        return;
+    }
+    clearMessage();
+    try {
+        await api.delete(`api_tokens/${row.api_token_id}`);
+        renderMessage(`${row.token_name} を失効しました。`, 'success');
+        getRows();
+    } catch (e) {
+        renderMessage(errorMessage(e, '失効'), 'danger');
+    }
+}
+
+const errorMessage = (e, action) => {
+    if (e.status === 403) {
+        return `${action}の権限がありません。`;
+    }
+    if (e.details && e.details.field) {
+        return `${e.details.field} が不正です。`;
+    }
+    return `${action}に失敗しました。`;
+}
+
+const renderRows = (rows) => {
+    const tbody = document.getElementById('records');
+    tbody.replaceChildren();
+    for (const row of rows) {
+        const tr = tbody.insertRow();
+        if (row.revoked_at !== null) {
+            tr.className = 'text-muted';
+        }
+        tr.insertCell().textContent = row.api_token_id;
+        tr.insertCell().textContent = row.token_name;
+        tr.insertCell().textContent = `${row.token_prefix}…`;
+        tr.insertCell().textContent = row.kind;
+        tr.insertCell().textContent = row.account_name;
+        tr.insertCell().textContent = row.role;
+        tr.insertCell().textContent = row.scopes;
+        tr.insertCell().textContent = row.expires_at ?? '無期限';
+        tr.insertCell().textContent = row.last_used_at ?? '';
+        tr.insertCell().textContent = `${row.created_at} ${row.created_by}`;
+        tr.insertCell().appendChild(createRevokeCell(row));
+    }
+}
+
+const createRevokeCell = (row) => {
+    if (row.revoked_at !== null) {
+        return document.createTextNode(row.revoked_at);
+    }
+    const button = document.createElement('button');
+    button.type = 'button';
+    button.className = 'btn btn-outline-danger btn-sm py-0';
+    button.textContent = '失効';
+    button.addEventListener('click', () => revokeToken(row));
+    return button;
+}
+
+const renderMessage = (msg, type) => {
+    const div = document.createElement('div');
+    div.className = `alert alert-${type} alert-custom my-1`;
+    div.textContent = msg;
+    document.getElementById('message').replaceChildren(div);
+}
+
+const clearMessage = () => {
+    document.getElementById('message').replaceChildren();
+}
@@ -1,9 +1,24 @@
 package main
  
 import (
+	"log"
+
+	"masmaint/config"
+	"masmaint/internal/core/db"
+	"masmaint/internal/core/logger"
 	"masmaint/internal/server"
 )
  
 func main() {
-	server.Run()
+	config.Load()
+	logger.Init()
+
+	cf := config.GetConfig()
+	conn, err := db.Open(cf.DBDriver, cf.DataSourceName())
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer conn.Close()
+
+	server.Run(server.NewApp(conn))
 }
\ No newline at end of file
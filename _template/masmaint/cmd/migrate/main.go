@@ -0,0 +1,213 @@
+package main
+
+import (
+	"os"
+	"fmt"
+	"flag"
+	"sort"
+	"regexp"
+	"path/filepath"
+
+	"masmaint/config"
+	"masmaint/internal/core/db"
+)
+
+/*
+ scripts/migrations のマイグレーションを適用する (適用済みのバージョンは schema_migrations に記録)
+ ENV=local go run ./cmd/migrate [-dir scripts/migrations] [up | down [-n 1] | status]
+ up:     未適用のマイグレーションを古い順にすべて適用 (省略時)
+ down:   適用済みのマイグレーションを新しい順に n 件戻す
+ status: マイグレーションの適用状況を表示
+*/
+func main() {
+	dir := flag.String("dir", "scripts/migrations", "マイグレーションのディレクトリ")
+	n := flag.Int("n", 1, "down で戻す件数")
+	flag.Parse()
+
+	command := flag.Arg(0)
+	if command == "" {
+		command = "up"
+	}
+
+	config.Load()
+	cf := config.GetConfig()
+	conn, err := db.Open(cf.DBDriver, cf.DataSourceName())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(1)
+	}
+	defer conn.Close()
+
+	if err := run(conn, command, *dir, *n); err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(1)
+	}
+}
+
+func run(conn *db.DB, command string, dir string, n int) error {
+	if err := createSchemaMigrations(conn); err != nil {
+		return err
+	}
+	migrations, err := readMigrations(dir)
+	if err != nil {
+		return err
+	}
+	applied, err := appliedVersions(conn)
+	if err != nil {
+		return err
+	}
+
+	switch command {
+	case "up":
+		count := 0
+		for _, m := range migrations {
+			if applied[m.version] {
+				continue
+			}
+			if err := apply(conn, m, m.up, true); err != nil {
+				return err
+			}
+			fmt.Printf("適用しました: %s\n", m.name)
+			count++
+		}
+		if count == 0 {
+			fmt.Println("未適用のマイグレーションはありません。")
+		}
+	case "down":
+		for i := len(migrations) - 1; i >= 0 && n > 0; i-- {
+			m := migrations[i]
+			if !applied[m.version] {
+				continue
+			}
+			if m.down == "" {
+				return fmt.Errorf("%s.down.sql がありません。", m.name)
+			}
+			if err := apply(conn, m, m.down, false); err != nil {
+				return err
+			}
+			fmt.Printf("戻しました: %s\n", m.name)
+			n--
+		}
+	case "status":
+		for _, m := range migrations {
+			status := "未適用"
+			if applied[m.version] {
+				status = "適用済"
+			}
+			fmt.Printf("%s  %s\n", status, m.name)
+		}
+	default:
+		return fmt.Errorf("コマンド '%s' は指定できません。(up / down / status)", command)
+	}
+	return nil
+}
+
+type migration struct {
+	version string
+	// <バージョン>_<名前>
+	name string
+	// up.sql・down.sql のパス
+	up string
+	down string
+}
+
+var reMigrationFile = regexp.MustCompile(`^((\d+)_\w+)\.(up|down)\.sql$`)
+
+// バージョン順のマイグレーション
+func readMigrations(dir string) ([]migration, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	ms := map[string]*migration{}
+	for _, e := range entries {
+		r := reMigrationFile.FindStringSubmatch(e.Name())
+		if e.IsDir() || r == nil {
+			continue
+		}
+		m, ok := ms[r[2]]
+		if !ok {
+			m = &migration{version: r[2], name: r[1]}
+			ms[r[2]] = m
+		}
+		if r[3] == "up" {
+			m.up = filepath.Join(dir, e.Name())
+		} else {
+			m.down = filepath.Join(dir, e.Name())
+		}
+	}
+
+	ret := []migration{}
+	for _, m := range ms {
+		if m.up == "" {
+			return nil, fmt.Errorf("%s.up.sql がありません。", m.name)
+		}
+		ret = append(ret, *m)
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].version < ret[j].version })
+	return ret, nil
+}
+
+//create-table.sql で作成していないデータベースのために作成
+func createSchemaMigrations(conn *db.DB) error {
+	ddl := "CREATE TABLE IF NOT EXISTS schema_migrations (version TEXT PRIMARY KEY, applied_at TEXT NOT NULL DEFAULT CURRENT_TIMESTAMP)"
+	switch conn.Driver {
+	case "postgres":
+		ddl = "CREATE TABLE IF NOT EXISTS schema_migrations (version VARCHAR(64) PRIMARY KEY, applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP)"
+	case "mysql":
+		ddl = "CREATE TABLE IF NOT EXISTS schema_migrations (version VARCHAR(64) PRIMARY KEY, applied_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP)"
+	}
+	_, err := conn.Exec(ddl)
+	return err
+}
+
+func appliedVersions(conn *db.DB) (map[string]bool, error) {
+	rows, err := conn.Query("SELECT version FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ret := map[string]bool{}
+	for rows.Next() {
+		var v string
+		if err := rows.Scan(&v); err != nil {
+			return nil, err
+		}
+		ret[v] = true
+	}
+	return ret, rows.Err()
+}
+
+/*
+ SQLファイルの文と schema_migrations の記録を1トランザクションで実行する
+ (MySQL はDDLで暗黙にコミットされるため、途中で失敗した場合は手動で戻すこと)
+*/
+func apply(conn *db.DB, m migration, path string, up bool) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	tx, err := conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, stmt := range db.SplitStatements(string(b)) {
+		if _, err := tx.Exec(stmt); err != nil {
+			return fmt.Errorf("%s: %s\n%s", filepath.Base(path), err.Error(), stmt)
+		}
+	}
+	query := "DELETE FROM schema_migrations WHERE version = ?"
+	if up {
+		query = "INSERT INTO schema_migrations (version) VALUES (?)"
+	}
+	if _, err := tx.Exec(conn.Rebind(query), m.version); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -4,6 +4,7 @@ import (
 	"os"
 	"log"
 	"fmt"
+	"strconv"
 
 	"github.com/joho/godotenv"
 )
@@ -31,15 +32,34 @@ type Config struct {
 
 	AuthUser string
 	AuthPass string
+	AuthRole string
+	AuthHeader string
+
+	OidcIssuer string
+	OidcClientId string
+	OidcClientSecret string
+	OidcRedirectUrl string
+	OidcScopes string
+	OidcGroupsClaim string
+	OidcRoleMapping string
+	OidcDefaultRole string
 
 	JwtSecretKey string
+	//本番 (HTTPS) では true
+	CookieSecure bool
+	//操作が無い場合にセッションが切れるまでの分数
+	SessionIdleMinutes int
 	LogLevel string
 }
 
 var cf Config
 
 
-func init() {
+/*
+ 設定ファイル (config/env/<ENV>.env) と環境変数から設定を読み込む (main の最初に呼ぶ)
+ テストでは呼ばず、必要な設定は GetConfig のフィールドに直接設定する
+*/
+func Load() {
 	err := godotenv.Load(fmt.Sprintf("config/env/%s.env", os.Getenv("ENV")))
 
 	if err != nil {
@@ -67,12 +87,52 @@ func init() {
 
 	cf.AuthUser = os.Getenv("AUTH_USER")
 	cf.AuthPass = os.Getenv("AUTH_PASSWORD")
+	cf.AuthRole = os.Getenv("AUTH_ROLE")
+	if cf.AuthRole == "" {
+		cf.AuthRole = "admin"
+	}
+	cf.AuthHeader = os.Getenv("AUTH_HEADER")
+	if cf.AuthHeader == "" {
+		cf.AuthHeader = "X-Forwarded-User"
+	}
+
+	cf.OidcIssuer = os.Getenv("OIDC_ISSUER")
+	cf.OidcClientId = os.Getenv("OIDC_CLIENT_ID")
+	cf.OidcClientSecret = os.Getenv("OIDC_CLIENT_SECRET")
+	cf.OidcRedirectUrl = os.Getenv("OIDC_REDIRECT_URL")
+	cf.OidcScopes = os.Getenv("OIDC_SCOPES")
+	cf.OidcGroupsClaim = os.Getenv("OIDC_GROUPS_CLAIM")
+	cf.OidcRoleMapping = os.Getenv("OIDC_ROLE_MAPPING")
+	cf.OidcDefaultRole = os.Getenv("OIDC_DEFAULT_ROLE")
 
 	cf.JwtSecretKey = os.Getenv("JWT_SECRET_KEY")
+	cf.CookieSecure = os.Getenv("COOKIE_SECURE") == "true"
+	cf.SessionIdleMinutes, err = strconv.Atoi(os.Getenv("SESSION_IDLE_MINUTES"))
+	if err != nil || cf.SessionIdleMinutes <= 0 {
+		cf.SessionIdleMinutes = 120
+	}
 	cf.LogLevel = os.Getenv("LOG_LEVEL")
 }
 
 
 func GetConfig() *Config{
 	return &cf
+}
+
+
+//DB_DRIVER に合わせた接続文字列
+func (cf *Config) DataSourceName() string {
+	switch cf.DBDriver {
+	case "mysql":
+		return fmt.Sprintf(
+			"%s:%s@tcp(%s:%s)/%s", 
+			cf.DBUser, cf.DBPass, cf.DBHost, cf.DBPort, cf.DBName,
+		)
+	case "postgres":
+		return fmt.Sprintf(
+			"host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
+			cf.DBHost, cf.DBPort, cf.DBUser, cf.DBPass, cf.DBName,
+		)
+	}
+	return cf.DBName
 }
\ No newline at end of file
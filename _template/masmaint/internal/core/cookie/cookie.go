@@ -0,0 +1,34 @@
+package cookie
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"masmaint/config"
+)
+
+
+/*
+ Cookie の発行 (未指定の場合 Path: /、Domain: APP_HOST、SameSite: Strict、Secure: COOKIE_SECURE)
+ maxAge が負の場合は削除
+*/
+func Set(c *gin.Context, ck *http.Cookie) {
+	cf := config.GetConfig()
+	if ck.Path == "" {
+		ck.Path = "/"
+	}
+	if ck.Domain == "" {
+		ck.Domain = cf.AppHost
+	}
+	if ck.SameSite == 0 {
+		ck.SameSite = http.SameSiteStrictMode
+	}
+	ck.Secure = cf.CookieSecure
+	http.SetCookie(c.Writer, ck)
+}
+
+
+func Remove(c *gin.Context, name string) {
+	Set(c, &http.Cookie{Name: name, MaxAge: -1, HttpOnly: true})
+}
@@ -0,0 +1,49 @@
+package csrf
+
+import (
+	"net/http"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+
+	"github.com/gin-gonic/gin"
+
+	"masmaint/internal/core/cookie"
+)
+
+
+/*
+ 二重送信 (double submit) のCSRF対策
+ Cookie のトークンを画面のJavaScriptが X-CSRF-Token ヘッダで送信し、両者が一致することを確認する
+*/
+
+const COOKIE_KEY_CSRF string = "csrf_token"
+const HEADER_CSRF string = "X-CSRF-Token"
+
+
+//Cookie が無い場合は発行 (JavaScriptから読むため HttpOnly にしない)
+func Ensure(c *gin.Context) error {
+	if token, err := c.Cookie(COOKIE_KEY_CSRF); err == nil && token != "" {
+		return nil
+	}
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return err
+	}
+	cookie.Set(c, &http.Cookie{
+		Name: COOKIE_KEY_CSRF,
+		Value: base64.RawURLEncoding.EncodeToString(b),
+	})
+	return nil
+}
+
+
+//ヘッダと Cookie のトークンが一致するか
+func Valid(c *gin.Context) bool {
+	token, err := c.Cookie(COOKIE_KEY_CSRF)
+	header := c.GetHeader(HEADER_CSRF)
+	if err != nil || token == "" || header == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(token), []byte(header)) == 1
+}
@@ -0,0 +1,178 @@
+package csvutil
+
+import (
+	"io"
+	"fmt"
+	"bytes"
+	"strings"
+	"strconv"
+	"reflect"
+	"encoding/csv"
+
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/transform"
+)
+
+
+const (
+	EncodingUTF8 = "utf8"
+	EncodingUTF8BOM = "utf8bom"
+	EncodingShiftJIS = "sjis"
+)
+
+const bom = "\xEF\xBB\xBF"
+
+type ColumnType int
+
+const (
+	String ColumnType = iota
+	Int
+	Float
+)
+
+type Column struct {
+	Name string
+	Type ColumnType
+}
+
+//行単位のエラー (Line はヘッダ行を1行目とした行番号)
+type RowError struct {
+	Line int `json:"line"`
+	Field string `json:"field"`
+	Message string `json:"message"`
+}
+
+
+//Content-Type の charset
+func Charset(encoding string) string {
+	if encoding == EncodingShiftJIS {
+		return "Shift_JIS"
+	}
+	return "UTF-8"
+}
+
+
+func Write(records [][]string, encoding string) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	var w io.Writer = buf
+
+	switch encoding {
+	case EncodingUTF8BOM:
+		buf.WriteString(bom)
+	case EncodingShiftJIS:
+		w = transform.NewWriter(buf, japanese.ShiftJIS.NewEncoder())
+	}
+
+	cw := csv.NewWriter(w)
+	cw.UseCRLF = true
+	if err := cw.WriteAll(records); err != nil {
+		return nil, err
+	}
+	if tw, ok := w.(*transform.Writer); ok {
+		if err := tw.Close(); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+
+//UTF-8 の BOM は自動で取り除く
+func Read(r io.Reader, encoding string) ([][]string, error) {
+	if encoding == EncodingShiftJIS {
+		r = transform.NewReader(r, japanese.ShiftJIS.NewDecoder())
+	}
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	cr := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(b, []byte(bom))))
+	cr.FieldsPerRecord = -1
+	return cr.ReadAll()
+}
+
+
+//モデルのフィールド値 -> CSVの値 (nil は空文字)
+func Format(value interface{}) string {
+	v := reflect.ValueOf(value)
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.Int, reflect.Int64, reflect.Int32:
+		return strconv.FormatInt(v.Int(), 10)
+	case reflect.Float64, reflect.Float32:
+		return strconv.FormatFloat(v.Float(), 'f', -1, 64)
+	default:
+		return fmt.Sprint(v.Interface())
+	}
+}
+
+
+/*
+ CSVレコード -> カラム名をキーとしたマップ
+ 空文字は null、数値カラムは数値に変換する
+ (JSONに変換し、リクエストと同じ規則で検証するため)
+*/
+func ToMap(columns []Column, header []string, record []string) (map[string]interface{}, *RowError) {
+	ret := map[string]interface{}{}
+	for i, name := range header {
+		if i >= len(record) {
+			break
+		}
+		col, ok := findColumn(columns, name)
+		if !ok {
+			continue
+		}
+
+		value := strings.TrimSpace(record[i])
+		if value == "" {
+			ret[col.Name] = nil
+			continue
+		}
+
+		switch col.Type {
+		case Int:
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, &RowError{Field: col.Name, Message: "整数ではありません。"}
+			}
+			ret[col.Name] = n
+		case Float:
+			f, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return nil, &RowError{Field: col.Name, Message: "数値ではありません。"}
+			}
+			ret[col.Name] = f
+		default:
+			ret[col.Name] = record[i]
+		}
+	}
+	return ret, nil
+}
+
+
+//主キーの値を連結した文字列 (行の突き合わせ用)
+func JoinKey(values ...interface{}) string {
+	ls := make([]string, len(values))
+	for i, v := range values {
+		ls[i] = Format(v)
+	}
+	return strings.Join(ls, "\x00")
+}
+
+
+func findColumn(columns []Column, name string) (Column, bool) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for _, c := range columns {
+		if c.Name == name {
+			return c, true
+		}
+	}
+	return Column{}, false
+}
@@ -1,7 +1,6 @@
 package db
 
 import (
-	"log"
 	"fmt"
 	"reflect"
 	"strings"	
@@ -11,56 +10,69 @@ import (
 	_ "github.com/go-sql-driver/mysql"
 	_ "github.com/lib/pq"
 
-	"masmaint/config"
 	"masmaint/internal/core/utils"
 )
 
 
-var db *sql.DB
-var driver string
-
-func init() {
-	var err error
-
-	cf := config.GetConfig()
-	driver = cf.DBDriver
-	var dsn string
-
-	if driver == "sqlite3" {
-		dsn = cf.DBName
-	} else if driver == "mysql" {
-		dsn = fmt.Sprintf(
-			"%s:%s@tcp(%s:%s)/%s", 
-			cf.DBUser, cf.DBPass, cf.DBHost, cf.DBPort, cf.DBName,
-		)
-	} else if driver == "postgres" {
-		dsn = fmt.Sprintf(
-			"host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
-			cf.DBHost, cf.DBPort, cf.DBUser, cf.DBPass, cf.DBName,
-		)
-	} else {
-		log.Panic("Error: must specify a valid DB_DRIVER: 'postgres', 'mysql', or 'sqlite3'.")
+//DB接続 (プレースホルダの形式を決めるため、Open したドライバを持つ)
+type DB struct {
+	*sql.DB
+	Driver string
+}
+
+/*
+ DBに接続する (接続は main で開き、リポジトリ・サービスに渡す)
+ driverName: 'postgres'・'mysql'・'sqlite3'
+*/
+func Open(driverName string, dsn string) (*DB, error) {
+	switch driverName {
+	case "postgres", "mysql", "sqlite3":
+	default:
+		return nil, fmt.Errorf("must specify a valid DB_DRIVER: 'postgres', 'mysql', or 'sqlite3'. (%s)", driverName)
 	}
 
-	db, err = sql.Open(driver, dsn)
+	conn, err := sql.Open(driverName, dsn)
 	if err != nil {
-		log.Panic(err)
+		return nil, err
 	}
+	return &DB{conn, driverName}, nil
 }
 
-func GetDB() *sql.DB {
-	return db
-}
-
-func getBindVar (seq int) string {
-	if driver == "postgres" {
+func (db *DB) getBindVar (seq int) string {
+	if db.Driver == "postgres" {
 		return fmt.Sprintf("$%d", seq)
 	} else {
 		return "?"
 	}
 }
 
-func BuildWhereClause(filter interface{}) (string, []interface{}) {
+//BuildWhereClause の結果に条件を追加 (例: 論理削除されていない行)
+func AndWhere(where string, condition string) string {
+	if where == "" {
+		return " WHERE " + condition
+	}
+	return where + " AND " + condition
+}
+
+//? のプレースホルダをドライバに合わせて変換 (postgres: $1, $2, ...)
+func (db *DB) Rebind(query string) string {
+	if db.Driver != "postgres" {
+		return query
+	}
+	seq := 0
+	var b strings.Builder
+	for _, r := range query {
+		if r == '?' {
+			seq++
+			b.WriteString(db.getBindVar(seq))
+		} else {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+func (db *DB) BuildWhereClause(filter interface{}) (string, []interface{}) {
 	var conditions []string
 	var binds []interface{}
 
@@ -82,8 +94,9 @@ func BuildWhereClause(filter interface{}) (string, []interface{}) {
 			continue
 		}
 
-		conditions = append(conditions, fmt.Sprintf("%s = %s", columnName, getBindVar(seq)))
+		conditions = append(conditions, fmt.Sprintf("%s = %s", columnName, db.getBindVar(seq)))
 		binds = append(binds, fieldValue)
+		seq++
 	}
 
 	whereClause := ""
@@ -92,4 +105,44 @@ func BuildWhereClause(filter interface{}) (string, []interface{}) {
 	}
 
 	return whereClause, binds
-}
\ No newline at end of file
+}
+
+
+//SQLを ; で文に分ける (文字列・識別子の引用符と -- のコメントの中は除く)
+func SplitStatements(src string) []string {
+	ret := []string{}
+	var b strings.Builder
+	var quote rune
+	comment := false
+	rs := []rune(src)
+	for i := 0; i < len(rs); i++ {
+		r := rs[i]
+		switch {
+		case comment:
+			if r == '\n' {
+				comment = false
+			}
+			continue
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '-' && i + 1 < len(rs) && rs[i+1] == '-':
+			comment = true
+			continue
+		case r == '\'' || r == '"' || r == '`':
+			quote = r
+		case r == ';':
+			if s := strings.TrimSpace(b.String()); s != "" {
+				ret = append(ret, s)
+			}
+			b.Reset()
+			continue
+		}
+		b.WriteRune(r)
+	}
+	if s := strings.TrimSpace(b.String()); s != "" {
+		ret = append(ret, s)
+	}
+	return ret
+}
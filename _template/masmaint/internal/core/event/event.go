@@ -0,0 +1,105 @@
+package event
+
+import (
+	"io"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"masmaint/internal/core/logger"
+)
+
+
+/*
+ テーブルの変更を画面へ配信する (Server-Sent Events)
+ 購読者はプロセス内で管理するため、複数台で動かす場合は同じサーバの変更のみ配信される
+*/
+
+//変更の操作は監査ログと同じ (INSERT・UPDATE・DELETE・RESTORE)、編集中の通知は EDITING
+const OperationEditing = "EDITING"
+
+//画面ごとに発行するID (自身の変更を受け流すため)
+const HEADER_CLIENT_ID string = "X-Client-Id"
+
+//接続を維持するための送信間隔
+const KEEPALIVE time.Duration = 30 * time.Second
+
+//受信が追いつかない購読者には送信しない
+const BUFFER_SIZE int = 64
+
+
+type Event struct {
+	Operation string `json:"operation"`
+	//変更後の行 (削除の場合は削除前の行)、編集中の通知は Editing
+	Data interface{} `json:"data"`
+	AccountName string `json:"account_name"`
+	ClientId string `json:"client_id"`
+}
+
+//編集中の行 (主キーのパス)
+type Editing struct {
+	Key string `json:"key" binding:"required"`
+}
+
+
+var mu sync.Mutex
+var subscribers = map[string]map[chan Event]struct{}{}
+
+
+//table の変更を購読 (戻り値の関数で解除)
+func Subscribe(table string) (<-chan Event, func()) {
+	ch := make(chan Event, BUFFER_SIZE)
+
+	mu.Lock()
+	if subscribers[table] == nil {
+		subscribers[table] = map[chan Event]struct{}{}
+	}
+	subscribers[table][ch] = struct{}{}
+	mu.Unlock()
+
+	return ch, func() {
+		mu.Lock()
+		delete(subscribers[table], ch)
+		mu.Unlock()
+	}
+}
+
+
+func Publish(table string, e Event) {
+	mu.Lock()
+	defer mu.Unlock()
+	for ch := range subscribers[table] {
+		select {
+		case ch <- e:
+		default:
+			logger.Warning("event: subscriber is too slow, dropped " + table + " " + e.Operation)
+		}
+	}
+}
+
+
+//GET /api/<table>/_events (クライアントが切断するまで変更を送信)
+func Stream(c *gin.Context, table string) {
+	ch, unsubscribe := Subscribe(table)
+	defer unsubscribe()
+
+	ticker := time.NewTicker(KEEPALIVE)
+	defer ticker.Stop()
+
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+	c.SSEvent("ready", "")
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case e := <-ch:
+			c.SSEvent("change", e)
+			return true
+		case <-ticker.C:
+			c.SSEvent("ping", "")
+			return true
+		case <-c.Request.Context().Done():
+			return false
+		}
+	})
+}
@@ -1,9 +1,5 @@
 package jwt
 
-import (
-	"time"
-)
-
 const COOKIE_KEY_JWT string = "access_token"
 const CONTEXT_KEY_PAYLOAD string = "payload"
-const JWT_EXPIRES time.Duration = 30 * 24 * 60 * 60
\ No newline at end of file
+//有効期限は config の SESSION_IDLE_MINUTES (Expires 参照)
\ No newline at end of file
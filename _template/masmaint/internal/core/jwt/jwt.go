@@ -5,28 +5,39 @@ import (
 	"encoding/json"
 	"errors"
 	"strings"
+	"net/http"
 
 	"github.com/gin-gonic/gin"
 	jwtpackage "github.com/golang-jwt/jwt/v4"
 
 	"masmaint/config"
+	"masmaint/internal/core/cookie"
 )
 
 
+//セッションの有効期限 (操作が無い時間、操作のたびに延長する)
+func Expires() time.Duration {
+	return time.Duration(config.GetConfig().SessionIdleMinutes) * time.Minute
+}
+
+
 func SetTokenToCookie (c *gin.Context, pl Payload) error {
 	jwtStr, err := EncodeJwt(pl)
 	if err != nil {
 		return err
 	}
-	cf := config.GetConfig()
-	c.SetCookie(COOKIE_KEY_JWT, jwtStr, int(JWT_EXPIRES), "/", cf.AppHost, false, true)
+	cookie.Set(c, &http.Cookie{
+		Name: COOKIE_KEY_JWT,
+		Value: jwtStr,
+		MaxAge: int(Expires().Seconds()),
+		HttpOnly: true,
+	})
 	return nil
 }
 
 
 func RemoveTokenFromCookie (c *gin.Context) {
-	cf := config.GetConfig()
-	c.SetCookie(COOKIE_KEY_JWT, "", 0, "/", cf.AppHost, false, true)
+	cookie.Remove(c, COOKIE_KEY_JWT)
 }
 
 
@@ -39,6 +50,12 @@ func GetPayload(c *gin.Context) Payload {
 }
 
 
+//JWT以外の認証 (basic / header / none) でもログインユーザとして参照させる
+func SetPayload(c *gin.Context, pl Payload) {
+	c.Set(CONTEXT_KEY_PAYLOAD, pl)
+}
+
+
 func EncodeJwt (pl Payload) (string, error) {
 	return encodeJwt(pl)
 }
@@ -50,8 +67,17 @@ func ExpireJwt (pl Payload) Payload {
 }  
 
 
-func Auth (c *gin.Context) error {
-	tokenStr, err := getJwtToken(c)
+//ログインユーザを読み込み直す (users のユーザの削除・ロールの変更をセッションに反映する)
+type Reloader func(cc CustomClaims) (CustomClaims, error)
+
+
+/*
+ JWTを確認し、ペイロードを設定する
+ reload: リクエストごとにログインユーザを確認 (nil の場合はJWTのクレームのまま)
+ ログインユーザが存在しない場合は Cookie のセッションを破棄し、ロールが変わった場合は再発行する
+*/
+func Auth (c *gin.Context, reload Reloader) error {
+	tokenStr, fromCookie, err := getJwtToken(c)
 	if err != nil {
 		return err
 	}
@@ -60,12 +86,42 @@ func Auth (c *gin.Context) error {
 	if err != nil {
 		return err
 	}
+
+	if reload != nil {
+		cc, err := reload(pl.CustomClaims)
+		if err != nil {
+			if fromCookie {
+				RemoveTokenFromCookie(c)
+			}
+			return err
+		}
+		if cc.Role != pl.Role {
+			pl.CustomClaims = cc
+			SetPayload(c, pl)
+			if fromCookie {
+				return SetTokenToCookie(c, NewPayload(cc))
+			}
+			return nil
+		}
+	}
 	
-	c.Set(CONTEXT_KEY_PAYLOAD, pl)
+	SetPayload(c, pl)
+	if fromCookie {
+		return renew(c, pl)
+	}
 	return nil
 }
 
 
+//有効期限の残りが半分を切ったCookieのセッションを延長 (スライディング)
+func renew(c *gin.Context, pl Payload) error {
+	if time.Until(time.Unix(pl.ExpiresAt, 0)) > Expires() / 2 {
+		return nil
+	}
+	return SetTokenToCookie(c, NewPayload(pl.CustomClaims))
+}
+
+
 func encodeJwt (pl Payload) (string, error) {
 	cf := config.GetConfig()
 	token := jwtpackage.NewWithClaims(jwtpackage.SigningMethodHS256, pl)
@@ -89,20 +145,20 @@ func decodeJwt (encoded string) (Payload, error) {
 }
 
 
-func getJwtToken (c *gin.Context) (string, error) {
-	token, err := c.Cookie(COOKIE_KEY_JWT)
-	if err == nil {
-		return token, nil
+//トークンと Cookie から取得したか
+//Authorization: Bearer がある場合は Cookie より優先する (APIのCSRFの確認を省略するため、不正なトークンを Cookie で認証させない)
+func getJwtToken (c *gin.Context) (string, bool, error) {
+	bearer := c.Request.Header.Get("Authorization")
+	if strings.HasPrefix(bearer, "Bearer ") {
+		return strings.TrimSpace(bearer[7:]), false, nil
 	}
 
-	bearer := c.Request.Header.Get("Authorization")
-	if bearer != "" {
-		if strings.Index(bearer, "Bearer ") != 0 {
-			return strings.TrimSpace(bearer[7:]), nil
-		}
+	token, err := c.Cookie(COOKIE_KEY_JWT)
+	if err == nil {
+		return token, true, nil
 	}
 
-	return "", errors.New("Token not found")
+	return "", false, errors.New("Token not found")
 }
 
 
@@ -14,17 +14,33 @@ type Payload struct {
 
 type CustomClaims struct {
 	AccountId int
+	//表示名
 	AccountName string
+	//アカウントを識別するキー (oidc は issuer と sub、空の場合は AccountName)
+	AccountKey string `json:",omitempty"`
+	//権限のロール (masmaint/internal/module/permission 参照)
+	Role string
+	//APIトークンのスコープ ("<table>:<action>"、nil の場合は制限なし)
+	Scopes []string `json:",omitempty"`
 	/* 独自のフィールドを追加可能 */
 }
 
 
+//アカウントを識別するキー (APIトークンの作成者・監査ログに記録)
+func (cc CustomClaims) Key() string {
+	if cc.AccountKey != "" {
+		return cc.AccountKey
+	}
+	return cc.AccountName
+}
+
+
 func NewPayload(claims CustomClaims) Payload {
 	var pl Payload
 
 	pl.CustomClaims = claims
 	pl.IssuedAt =  time.Now().Unix()
-	pl.ExpiresAt = time.Now().Add(time.Second * JWT_EXPIRES).Unix()
+	pl.ExpiresAt = time.Now().Add(Expires()).Unix()
 
 	return pl
 }
\ No newline at end of file
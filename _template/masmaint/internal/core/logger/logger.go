@@ -23,16 +23,35 @@ const LOGFILE = "app.log"
 
 var file *os.File
 
-var logD *log.Logger
-var logI *log.Logger
-var logW *log.Logger
-var logE *log.Logger
-var logF *log.Logger
+//Init を呼ぶまでは標準出力のみ (テストでは Init を呼ばない)
+var logD = log.New(os.Stdout, "[DEBUG]", log.LstdFlags)
+var logI = log.New(os.Stdout, "[INFO]", log.LstdFlags)
+var logW = log.New(os.Stdout, "[WARNING]", log.LstdFlags)
+var logE = log.New(os.Stdout, "[ERROR]", log.LstdFlags)
+var logF = log.New(os.Stdout, "[FATAL]", log.LstdFlags)
 
-var logLevel int
+var logLevel = LOG_LEVEL_INFO
 
 
-func init() {
+//ログファイルを開き、LOG_LEVEL を反映する (main で config.Load の後に呼ぶ)
+func Init() {
+	if err := openLogFile(); err != nil {
+		log.Panic(err)
+	}
+	out := io.MultiWriter(os.Stdout, file)
+
+	logLevel = getLogLevel()
+
+	logI.SetOutput(out)
+	logW.SetOutput(out)
+	logE.SetOutput(out)
+	logF.SetOutput(out)
+
+	gin.DefaultWriter = out
+}
+
+
+func openLogFile() error {
 	_, err := os.Stat(LOGFOLDER)
 	if err != nil {
 		os.Mkdir(LOGFOLDER, 0755)
@@ -45,44 +64,7 @@ func init() {
 	} else {
 		file, err = os.OpenFile(fp, os.O_APPEND|os.O_WRONLY, 0644)
 	}
-
-	if err != nil {
-		log.Panic(err)
-	}
-
-	logLevel = getLogLevel()
-
-	logD = log.New(
-		os.Stdout,
-		"[DEBUG]",
-		log.LstdFlags,
-	)
-
-	logI = log.New(
-		io.MultiWriter(os.Stdout, file),
-		"[INFO]",
-		log.LstdFlags,
-	)
-
-	logW = log.New(
-		io.MultiWriter(os.Stdout, file),
-		"[WARNING]",
-		log.LstdFlags,
-	)
-
-	logE = log.New(
-		io.MultiWriter(os.Stdout, file),
-		"[ERROR]",
-		log.LstdFlags,
-	)
-
-	logF = log.New(
-		io.MultiWriter(os.Stdout, file),
-		"[FATAL]",
-		log.LstdFlags,
-	)
-
-	gin.DefaultWriter = io.MultiWriter(os.Stdout, file)
+	return err
 }
 
 
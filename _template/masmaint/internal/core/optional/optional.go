@@ -0,0 +1,73 @@
+package optional
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+
+/*
+ JSONでの 未指定 / null / 値あり を区別して受け取るための型
+   未指定: Set=false
+   null  : Set=true, Null=true
+   値あり: Set=true, Null=false
+*/
+type Value[T any] struct {
+	Set bool
+	Null bool
+	Value T
+}
+
+
+func (v *Value[T]) UnmarshalJSON(data []byte) error {
+	v.Set = true
+	if bytes.Equal(bytes.TrimSpace(data), []byte("null")) {
+		v.Null = true
+		return nil
+	}
+	return json.Unmarshal(data, &v.Value)
+}
+
+
+func (v Value[T]) MarshalJSON() ([]byte, error) {
+	if !v.Set || v.Null {
+		return []byte("null"), nil
+	}
+	return json.Marshal(v.Value)
+}
+
+
+//null指定の場合は nil を返す
+func (v Value[T]) Ptr() *T {
+	if v.Null {
+		return nil
+	}
+	ret := v.Value
+	return &ret
+}
+
+
+/*
+ オブジェクトをフィールド単位でデコードする
+ (型エラー時に UnmarshalTypeError.Field へJSONのフィールド名を設定するため)
+*/
+func DecodeFields(data []byte, fields map[string]json.Unmarshaler) error {
+	raw := map[string]json.RawMessage{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	for name, value := range raw {
+		field, ok := fields[name]
+		if !ok {
+			continue
+		}
+		if err := field.UnmarshalJSON(value); err != nil {
+			if typeErr, ok := err.(*json.UnmarshalTypeError); ok {
+				typeErr.Field = name
+			}
+			return err
+		}
+	}
+	return nil
+}
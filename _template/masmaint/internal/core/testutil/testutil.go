@@ -0,0 +1,312 @@
+package testutil
+
+import (
+	"os"
+	"fmt"
+	"time"
+	"bytes"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"path/filepath"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"github.com/lib/pq"
+	"github.com/go-sql-driver/mysql"
+	_ "github.com/mattn/go-sqlite3"
+
+	"masmaint/internal/core/db"
+	"masmaint/internal/core/utils"
+)
+
+
+/*
+ 生成したテストの共通処理
+ サービスのテストはリポジトリをモックにし、トランザクションと監査ログの記録のみ SQLite のメモリDBで実行する
+*/
+
+const auditLogDdl =
+`CREATE TABLE audit_log (
+	audit_log_id INTEGER PRIMARY KEY AUTOINCREMENT,
+	table_name TEXT NOT NULL,
+	record_key TEXT NOT NULL,
+	operation TEXT NOT NULL,
+	before_data TEXT,
+	after_data TEXT,
+	account_id INTEGER NOT NULL,
+	account_name TEXT NOT NULL,
+	account_key TEXT NOT NULL DEFAULT '',
+	created_at TEXT NOT NULL
+)`
+
+//監査ログのテーブルのみ作成したメモリDB (テストの終了時に閉じる)
+func OpenAuditDB(t *testing.T) *db.DB {
+	t.Helper()
+	conn := openMemoryDB(t)
+	if _, err := conn.Exec(auditLogDdl); err != nil {
+		t.Fatal(err)
+	}
+	return conn
+}
+
+//監査ログの件数
+func CountAuditLog(t *testing.T, conn *db.DB) int {
+	t.Helper()
+	var n int
+	if err := conn.QueryRow("SELECT COUNT(*) FROM audit_log").Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+/*
+ リポジトリの結合テストのDB (scripts/create-table.sql のテーブルを作成し、テストの終了時に破棄する)
+ driver: 'postgres'・'mysql'・'sqlite3'
+ SQLite はメモリDB、PostgreSQL・MySQL は TEST_DB_DSN のDBに一時のスキーマ (MySQL はデータベース) を作成する
+ (TEST_DB_DSN が無い場合はスキップ、外部キー制約はテーブル単位で確認するため外す)
+*/
+func OpenSchemaDB(t *testing.T, driver string) *db.DB {
+	t.Helper()
+	dsn := os.Getenv("TEST_DB_DSN")
+	name := fmt.Sprintf("masmaint_test_%d", time.Now().UnixNano())
+
+	switch driver {
+	case "postgres", "mysql":
+		if dsn == "" {
+			t.Skip("TEST_DB_DSN が未設定のためスキップ")
+		}
+		admin, err := sql.Open(driver, dsn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if driver == "postgres" {
+			execAll(t, admin, "CREATE SCHEMA " + name)
+			t.Cleanup(func() {
+				admin.Exec("DROP SCHEMA " + name + " CASCADE")
+				admin.Close()
+			})
+			dsn = withSearchPath(t, dsn, name)
+		} else {
+			execAll(t, admin, "CREATE DATABASE " + name)
+			t.Cleanup(func() {
+				admin.Exec("DROP DATABASE " + name)
+				admin.Close()
+			})
+			cfg, err := mysql.ParseDSN(dsn)
+			if err != nil {
+				t.Fatal(err)
+			}
+			cfg.DBName = name
+			dsn = cfg.FormatDSN()
+		}
+	default:
+		dsn = ":memory:"
+	}
+
+	//db.Open の接続はドライバを持つ (プレースホルダの形式をドライバに合わせる)
+	conn, err := db.Open(driver, dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if driver == "sqlite3" {
+		conn.SetMaxOpenConns(1)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	b, err := os.ReadFile(filepath.Join(projectRoot(t), "scripts", "create-table.sql"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	execAll(t, conn.DB, db.SplitStatements(string(b))...)
+	dropForeignKeys(t, conn, name)
+	return conn
+}
+
+//ポインタの値 (NULL許容のフィールドの値に使用)
+func Ptr[T any](v T) *T {
+	return &v
+}
+
+//got と want の指定したフィールドが一致するか (ポインタは参照先の値を比較)
+func AssertFields(t *testing.T, got interface{}, want interface{}, fields ...string) {
+	t.Helper()
+	gv, wv := reflect.ValueOf(got), reflect.ValueOf(want)
+	for _, f := range fields {
+		g, w := gv.FieldByName(f).Interface(), wv.FieldByName(f).Interface()
+		if !reflect.DeepEqual(g, w) {
+			t.Errorf("%s: got %v, want %v", f, deref(g), deref(w))
+		}
+	}
+}
+
+/*
+ 一意制約違反のDBエラー (driver: 'postgres'・'mysql'・'sqlite3' のドライバが返す形式)
+ SQLite は実際に違反させたエラー
+*/
+func UniqueViolation(t *testing.T, driver string, table string, column string) error {
+	t.Helper()
+	switch driver {
+	case "postgres":
+		return &pq.Error{
+			Code: "23505",
+			Message: fmt.Sprintf("duplicate key value violates unique constraint \"%s_%s_key\"", table, column),
+			Detail: fmt.Sprintf("Key (%s)=(1) already exists.", column),
+		}
+	case "mysql":
+		return &mysql.MySQLError{
+			Number: 1062,
+			Message: fmt.Sprintf("Duplicate entry '1' for key '%s.%s'", table, column),
+		}
+	}
+
+	conn := openMemoryDB(t)
+	ddl := fmt.Sprintf("CREATE TABLE \"%s\" (\"%s\" TEXT UNIQUE)", table, column)
+	if _, err := conn.Exec(ddl); err != nil {
+		t.Fatal(err)
+	}
+	insert := fmt.Sprintf("INSERT INTO \"%s\" VALUES ('1')", table)
+	if _, err := conn.Exec(insert); err != nil {
+		t.Fatal(err)
+	}
+	_, err := conn.Exec(insert)
+	if err == nil {
+		t.Fatal("unique violation: expected error")
+	}
+	return err
+}
+
+/*
+ filter の値を設定した (ゼロ値でない) フィールドが row と一致するか
+ リポジトリのモックで使用 (db.BuildWhereClause と同じ条件)
+*/
+func Match(row interface{}, filter interface{}) bool {
+	rv, fv := reflect.ValueOf(row), reflect.ValueOf(filter)
+	for i := 0; i < fv.NumField(); i++ {
+		v := fv.Field(i).Interface()
+		if utils.IsZero(v) {
+			continue
+		}
+		if !reflect.DeepEqual(rv.Field(i).Interface(), v) {
+			return false
+		}
+	}
+	return true
+}
+
+//JSONのリクエストを実行し、ステータスとレスポンスのJSONを返す (body が nil の場合は本文なし)
+func ServeJSON(t *testing.T, handler http.Handler, method string, path string, body interface{}) (int, map[string]interface{}) {
+	t.Helper()
+	var buf bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&buf).Encode(body); err != nil {
+			t.Fatal(err)
+		}
+	}
+	req := httptest.NewRequest(method, path, &buf)
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	ret := map[string]interface{}{}
+	if err := json.Unmarshal(w.Body.Bytes(), &ret); err != nil {
+		t.Fatalf("%s %s: %s", method, path, w.Body.String())
+	}
+	return w.Code, ret
+}
+
+//エラーのJSONの details の項目 (例: field・column)
+func Detail(res map[string]interface{}, name string) interface{} {
+	details, _ := res["details"].(map[string]interface{})
+	return details[name]
+}
+
+func deref(v interface{}) interface{} {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Ptr && !rv.IsNil() {
+		return rv.Elem().Interface()
+	}
+	return v
+}
+
+func execAll(t *testing.T, conn *sql.DB, stmts ...string) {
+	t.Helper()
+	for _, stmt := range stmts {
+		if _, err := conn.Exec(stmt); err != nil {
+			t.Fatalf("%s\n%s", err.Error(), stmt)
+		}
+	}
+}
+
+//接続時の search_path を指定 (URL形式・key=value形式)
+func withSearchPath(t *testing.T, dsn string, schema string) string {
+	if !strings.HasPrefix(dsn, "postgres://") && !strings.HasPrefix(dsn, "postgresql://") {
+		return dsn + " search_path=" + schema
+	}
+	u, err := url.Parse(dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := u.Query()
+	q.Set("search_path", schema)
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+func dropForeignKeys(t *testing.T, conn *db.DB, schema string) {
+	if conn.Driver == "sqlite3" {
+		return
+	}
+	rows, err := conn.Query(
+		conn.Rebind("SELECT table_name, constraint_name FROM information_schema.table_constraints WHERE constraint_type = 'FOREIGN KEY' AND table_schema = ?"),
+		schema,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stmts := []string{}
+	for rows.Next() {
+		var table, constraint string
+		if err := rows.Scan(&table, &constraint); err != nil {
+			t.Fatal(err)
+		}
+		if conn.Driver == "postgres" {
+			stmts = append(stmts, fmt.Sprintf("ALTER TABLE \"%s\" DROP CONSTRAINT \"%s\"", table, constraint))
+		} else {
+			stmts = append(stmts, fmt.Sprintf("ALTER TABLE `%s` DROP FOREIGN KEY `%s`", table, constraint))
+		}
+	}
+	rows.Close()
+	execAll(t, conn.DB, stmts...)
+}
+
+//go.mod のあるディレクトリ (テストはパッケージのディレクトリで実行される)
+func projectRoot(t *testing.T) string {
+	dir, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for {
+		if _, err := os.Stat(filepath.Join(dir, "go.mod")); err == nil {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			t.Fatal("go.mod が見つかりません。")
+		}
+		dir = parent
+	}
+}
+
+func openMemoryDB(t *testing.T) *db.DB {
+	conn, err := db.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	//メモリDBは接続ごとに別のDBとなるため1接続にする
+	conn.SetMaxOpenConns(1)
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
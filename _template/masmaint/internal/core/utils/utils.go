@@ -135,4 +135,10 @@ func IsZero(value interface{}) bool {
 		v = v.Elem()
 	}
 	return v.IsZero()
-}
\ No newline at end of file
+}
+
+
+//現在日時 (YYYY-MM-DD HH:MM:SS)
+func NowString() string {
+	return time.Now().Format("2006-01-02 15:04:05")
+}
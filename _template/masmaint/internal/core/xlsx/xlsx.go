@@ -0,0 +1,275 @@
+package xlsx
+
+import (
+	"io"
+	"fmt"
+	"time"
+	"bytes"
+	"strings"
+	"strconv"
+	"reflect"
+	"archive/zip"
+	"encoding/xml"
+)
+
+
+/*
+ 標準ライブラリのみで1シートのXLSX(Office Open XML)を出力する
+ ヘッダ行の固定・列幅・セルの型(数値/日付/日時/文字列)に対応
+*/
+
+const ContentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+
+type CellType int
+
+const (
+	String CellType = iota
+	Number
+	Date
+	DateTime
+)
+
+type Column struct {
+	Label string
+	Type CellType
+	Width float64
+}
+
+//styles.xml の cellXfs のインデックス
+const (
+	styleDefault = 0
+	styleDate = 1
+	styleDateTime = 2
+	styleHeader = 3
+)
+
+var dateLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04",
+	"2006-01-02",
+	"2006/01/02 15:04:05",
+	"2006/01/02",
+}
+
+//Excelのシリアル値の基準日 (1900年うるう年問題を含む)
+var excelEpoch = time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+
+
+func Write(w io.Writer, sheetName string, columns []Column, rows [][]interface{}) error {
+	zw := zip.NewWriter(w)
+
+	files := []struct {
+		name string
+		body string
+	}{
+		{"[Content_Types].xml", contentTypesXml},
+		{"_rels/.rels", relsXml},
+		{"xl/workbook.xml", fmt.Sprintf(workbookXml, escape(sanitizeSheetName(sheetName)))},
+		{"xl/_rels/workbook.xml.rels", workbookRelsXml},
+		{"xl/styles.xml", stylesXml},
+		{"xl/worksheets/sheet1.xml", sheetXml(columns, rows)},
+	}
+
+	//更新日時を設定しない場合は 1980年 となる
+	now := time.Now()
+	for _, f := range files {
+		fw, err := zw.CreateHeader(&zip.FileHeader{Name: f.name, Method: zip.Deflate, Modified: now})
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(fw, f.body); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+
+func sheetXml(columns []Column, rows [][]interface{}) string {
+	var b strings.Builder
+	b.WriteString(xml.Header)
+	b.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">`)
+
+	//ヘッダ行を固定
+	b.WriteString(`<sheetViews><sheetView workbookViewId="0">`)
+	b.WriteString(`<pane ySplit="1" topLeftCell="A2" activePane="bottomLeft" state="frozen"/>`)
+	b.WriteString(`</sheetView></sheetViews>`)
+
+	b.WriteString(`<cols>`)
+	for i, c := range columns {
+		fmt.Fprintf(&b, `<col min="%d" max="%d" width="%s" customWidth="1"/>`, i+1, i+1, formatFloat(c.Width))
+	}
+	b.WriteString(`</cols>`)
+
+	b.WriteString(`<sheetData>`)
+	b.WriteString(`<row r="1">`)
+	for i, c := range columns {
+		writeStringCell(&b, cellRef(i, 1), c.Label, styleHeader)
+	}
+	b.WriteString(`</row>`)
+
+	for r, row := range rows {
+		fmt.Fprintf(&b, `<row r="%d">`, r+2)
+		for i, value := range row {
+			if i >= len(columns) {
+				break
+			}
+			writeCell(&b, cellRef(i, r+2), columns[i].Type, value)
+		}
+		b.WriteString(`</row>`)
+	}
+	b.WriteString(`</sheetData>`)
+
+	if len(columns) > 0 {
+		fmt.Fprintf(&b, `<autoFilter ref="A1:%s"/>`, cellRef(len(columns)-1, len(rows)+1))
+	}
+	b.WriteString(`</worksheet>`)
+	return b.String()
+}
+
+
+func writeCell(b *strings.Builder, ref string, typ CellType, value interface{}) {
+	v := reflect.ValueOf(value)
+	if !v.IsValid() {
+		return
+	}
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.Int, reflect.Int64, reflect.Int32:
+		fmt.Fprintf(b, `<c r="%s"><v>%d</v></c>`, ref, v.Int())
+		return
+	case reflect.Float64, reflect.Float32:
+		fmt.Fprintf(b, `<c r="%s"><v>%s</v></c>`, ref, formatFloat(v.Float()))
+		return
+	}
+
+	s := fmt.Sprint(v.Interface())
+	switch typ {
+	case Number:
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			fmt.Fprintf(b, `<c r="%s"><v>%s</v></c>`, ref, formatFloat(f))
+			return
+		}
+	case Date, DateTime:
+		if t, ok := parseTime(s); ok {
+			style := styleDateTime
+			if typ == Date {
+				style = styleDate
+			}
+			fmt.Fprintf(b, `<c r="%s" s="%d"><v>%s</v></c>`, ref, style, formatFloat(toSerial(t)))
+			return
+		}
+	}
+	writeStringCell(b, ref, s, styleDefault)
+}
+
+
+func writeStringCell(b *strings.Builder, ref string, s string, style int) {
+	fmt.Fprintf(b, `<c r="%s" t="inlineStr"`, ref)
+	if style != styleDefault {
+		fmt.Fprintf(b, ` s="%d"`, style)
+	}
+	fmt.Fprintf(b, `><is><t xml:space="preserve">%s</t></is></c>`, escape(s))
+}
+
+
+func parseTime(s string) (time.Time, bool) {
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+//日時 -> シリアル値 (タイムゾーンは変換せず、表記どおりの日時とする)
+func toSerial(t time.Time) float64 {
+	wall := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+	return wall.Sub(excelEpoch).Hours() / 24
+}
+
+
+//0, 1 -> A1 / 26, 2 -> AA2
+func cellRef(col int, row int) string {
+	name := ""
+	for n := col + 1; n > 0; n = (n - 1) / 26 {
+		name = string(rune('A' + (n - 1) % 26)) + name
+	}
+	return fmt.Sprintf("%s%d", name, row)
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+func escape(s string) string {
+	var buf bytes.Buffer
+	xml.EscapeText(&buf, []byte(s))
+	return buf.String()
+}
+
+//シート名は31文字以内、: \ / ? * [ ] は使用不可
+func sanitizeSheetName(name string) string {
+	name = strings.NewReplacer(":", "_", "\\", "_", "/", "_", "?", "_", "*", "_", "[", "_", "]", "_").Replace(name)
+	if r := []rune(name); len(r) > 31 {
+		name = string(r[:31])
+	}
+	if name == "" {
+		name = "Sheet1"
+	}
+	return name
+}
+
+
+const contentTypesXml = xml.Header +
+`<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+`<Default Extension="xml" ContentType="application/xml"/>` +
+`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>` +
+`</Types>`
+
+const relsXml = xml.Header +
+`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+`</Relationships>`
+
+const workbookXml = xml.Header +
+`<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+`<sheets><sheet name="%s" sheetId="1" r:id="rId1"/></sheets>` +
+`</workbook>`
+
+const workbookRelsXml = xml.Header +
+`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+`<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>` +
+`</Relationships>`
+
+const stylesXml = xml.Header +
+`<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+`<numFmts count="2">` +
+`<numFmt numFmtId="164" formatCode="yyyy-mm-dd"/>` +
+`<numFmt numFmtId="165" formatCode="yyyy-mm-dd hh:mm:ss"/>` +
+`</numFmts>` +
+`<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>` +
+`<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>` +
+`<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>` +
+`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>` +
+`<cellXfs count="4">` +
+`<xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>` +
+`<xf numFmtId="164" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>` +
+`<xf numFmtId="165" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>` +
+`<xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/>` +
+`</cellXfs>` +
+`<cellStyles count="1"><cellStyle name="Normal" xfId="0" builtinId="0"/></cellStyles>` +
+`</styleSheet>`
@@ -2,23 +2,49 @@ package middleware
 
 import (
 	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 
-	"masmaint/config"
-	"masmaint/internal/core/jwt"
+	"masmaint/internal/core/csrf"
 	"masmaint/internal/core/errs"
+	"masmaint/internal/module/permission"
 )
 
 
-func BasicAuth() gin.HandlerFunc {
+/* 認証 (Auth・ApiAuth) は auth.go */
+
+
+//Bootstrap は cdn.jsdelivr.net から読み込む (インラインのスクリプト・スタイル属性は使用しない)
+const CONTENT_SECURITY_POLICY string = "default-src 'self'; " +
+	"script-src 'self' https://cdn.jsdelivr.net; " +
+	"style-src 'self' https://cdn.jsdelivr.net; " +
+	"font-src 'self' https://cdn.jsdelivr.net; " +
+	"img-src 'self' data:; " +
+	"connect-src 'self'; " +
+	"object-src 'none'; " +
+	"base-uri 'self'; " +
+	"form-action 'self'; " +
+	"frame-ancestors 'none'"
+
+//全てのレスポンスに Content-Security-Policy 等のヘッダを付与
+func SecurityHeaders() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		cf := config.GetConfig()
+		h := c.Writer.Header()
+		h.Set("Content-Security-Policy", CONTENT_SECURITY_POLICY)
+		h.Set("X-Content-Type-Options", "nosniff")
+		h.Set("X-Frame-Options", "DENY")
+		h.Set("Referrer-Policy", "same-origin")
+		c.Next()
+	}
+}
+
 
-		user, pass, ok := c.Request.BasicAuth()
-		if !ok || user != cf.BasicAuthUser || pass != cf.BasicAuthPass {
-			c.Header("WWW-Authenticate", "Basic realm=Authorization Required")
-			c.AbortWithStatus(http.StatusUnauthorized)
+//画面の表示時にCSRFトークンのCookieを発行
+func CsrfCookie() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := csrf.Ensure(c); err != nil {
+			c.AbortWithStatus(http.StatusInternalServerError)
 			return
 		}
 		c.Next()
@@ -26,10 +52,22 @@ func BasicAuth() gin.HandlerFunc {
 }
 
 
-func JwtAuth() gin.HandlerFunc {
+//POST/PUT/PATCH/DELETE は X-CSRF-Token ヘッダと Cookie のトークンが一致すること
+//Authorization: Bearer (APIトークン・JWT) はブラウザが自動で送信しないため対象外
+//(Bearer で認証する方式 (auth.go の acceptsBearer) のみ、Bearer は Cookie より優先し不正な場合は 401 とする)
+func ApiCsrf() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		if err := jwt.Auth(c); err != nil {
-			c.Redirect(http.StatusSeeOther, "/login")
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			c.Next()
+			return
+		}
+		if acceptsBearer && strings.HasPrefix(c.GetHeader("Authorization"), "Bearer ") {
+			c.Next()
+			return
+		}
+		if !csrf.Valid(c) {
+			c.Error(errs.NewForbiddenError())
 			c.Abort()
 			return
 		}
@@ -38,10 +76,11 @@ func JwtAuth() gin.HandlerFunc {
 }
 
 
-func ApiJwtAuth() gin.HandlerFunc {
+//ロールにテーブルの操作が許可されていない場合は 403
+func Permission(table string, action string) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		if err := jwt.Auth(c); err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		if !permission.Can(c, table, action) {
+			c.String(http.StatusForbidden, "権限がありません。")
 			c.Abort()
 			return
 		}
@@ -50,6 +89,36 @@ func ApiJwtAuth() gin.HandlerFunc {
 }
 
 
+func ApiPermission(table string, action string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !permission.Can(c, table, action) {
+			c.Error(errs.NewForbiddenError())
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+
+//テーブルの操作ごとの権限チェック (APIのルートに指定する)
+type TablePermission struct {
+	Read gin.HandlerFunc
+	Create gin.HandlerFunc
+	Update gin.HandlerFunc
+	Delete gin.HandlerFunc
+}
+
+func ApiTablePermission(table string) TablePermission {
+	return TablePermission{
+		Read: ApiPermission(table, permission.Read),
+		Create: ApiPermission(table, permission.Create),
+		Update: ApiPermission(table, permission.Update),
+		Delete: ApiPermission(table, permission.Delete),
+	}
+}
+
+
 func ApiResponse() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		c.Next()
@@ -0,0 +1,90 @@
+package audit
+
+import (
+	"strings"
+	"database/sql"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+
+	"masmaint/internal/core/db"
+	"masmaint/internal/core/jwt"
+	"masmaint/internal/core/event"
+	"masmaint/internal/core/utils"
+	"masmaint/internal/core/csvutil"
+)
+
+
+const (
+	OperationInsert = "INSERT"
+	OperationUpdate = "UPDATE"
+	OperationDelete = "DELETE"
+	//論理削除からの復元
+	OperationRestore = "RESTORE"
+)
+
+//変更を行ったユーザ
+type Actor struct {
+	AccountId int
+	AccountName string
+	//アカウントを識別するキー (空の場合は AccountName)
+	AccountKey string
+	//変更を行った画面 (変更の配信で自身の変更を区別する)
+	ClientId string
+}
+
+func GetActor(c *gin.Context) Actor {
+	pl := jwt.GetPayload(c)
+	return Actor{AccountId: pl.AccountId, AccountName: pl.AccountName, AccountKey: pl.Key(), ClientId: c.GetHeader(event.HEADER_CLIENT_ID)}
+}
+
+
+//主キーの値を / で連結 (監査ログの検索用)
+func Key(values ...interface{}) string {
+	ls := make([]string, len(values))
+	for i, v := range values {
+		ls[i] = csvutil.Format(v)
+	}
+	return strings.Join(ls, "/")
+}
+
+
+/*
+ 変更前後のスナップショットを監査ログに記録する
+ 変更と同じトランザクションで実行すること (登録時の before, 削除時の after は nil)
+ conn: トランザクションを開始した接続 (プレースホルダの形式に使用)
+*/
+func Write(conn *db.DB, tx *sql.Tx, actor Actor, tableName string, key string, operation string, before interface{}, after interface{}) error {
+	al := AuditLog{
+		TableName: tableName,
+		RecordKey: key,
+		Operation: operation,
+		AccountId: actor.AccountId,
+		AccountName: actor.AccountName,
+		AccountKey: actor.AccountKey,
+		CreatedAt: utils.NowString(),
+	}
+	if al.AccountKey == "" {
+		al.AccountKey = actor.AccountName
+	}
+
+	var err error
+	if al.BeforeData, err = toJson(before); err != nil {
+		return err
+	}
+	if al.AfterData, err = toJson(after); err != nil {
+		return err
+	}
+	return NewRepository(conn).Insert(&al, tx)
+}
+
+func toJson(v interface{}) (*string, error) {
+	if v == nil {
+		return nil, nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	s := string(b)
+	return &s, nil
+}
@@ -0,0 +1,38 @@
+package audit
+
+import (
+	"github.com/gin-gonic/gin"
+	"masmaint/internal/module"
+)
+
+type controller struct {
+	service Service
+}
+
+func NewController(service Service) *controller {
+	return &controller{service}
+}
+
+
+//GET /audit
+func (ctr *controller) GetPage(c *gin.Context) {
+	c.HTML(200, "audit.html", gin.H{})
+}
+
+
+//GET /api/audit?table_name=&record_key=&account_name=&from=&to=
+func (ctr *controller) Get(c *gin.Context) {
+	var q SearchQuery
+	if err := c.ShouldBindQuery(&q); err != nil {
+		c.Error(module.NewBindError(err, &q))
+		return
+	}
+
+	ret, err := ctr.service.Search(q)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(200, ret)
+}
@@ -0,0 +1,15 @@
+package audit
+
+
+type AuditLog struct {
+	Id int `db:"audit_log_id" json:"audit_log_id"`
+	TableName string `db:"table_name" json:"table_name"`
+	RecordKey string `db:"record_key" json:"record_key"`
+	Operation string `db:"operation" json:"operation"`
+	BeforeData *string `db:"before_data" json:"before_data"`
+	AfterData *string `db:"after_data" json:"after_data"`
+	AccountId int `db:"account_id" json:"account_id"`
+	AccountName string `db:"account_name" json:"account_name"`
+	AccountKey string `db:"account_key" json:"account_key"`
+	CreatedAt string `db:"created_at" json:"created_at"`
+}
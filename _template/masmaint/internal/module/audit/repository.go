@@ -0,0 +1,140 @@
+package audit
+
+import (
+	"time"
+	"database/sql"
+	"masmaint/internal/core/db"
+)
+
+
+type Repository interface {
+	Search(q SearchQuery, limit int) ([]AuditLog, error)
+	Insert(al *AuditLog, tx *sql.Tx) error
+}
+
+
+type repository struct {
+	db *db.DB
+}
+
+func NewRepository(db *db.DB) Repository {
+	return &repository{db}
+}
+
+
+func (rep *repository) Search(q SearchQuery, limit int) ([]AuditLog, error) {
+	query := 
+	`SELECT
+		audit_log_id
+		,table_name
+		,record_key
+		,operation
+		,before_data
+		,after_data
+		,account_id
+		,account_name
+		,account_key
+		,created_at
+	 FROM audit_log
+	 WHERE 1 = 1`
+	binds := []interface{}{}
+
+	if q.TableName != "" {
+		query += " AND table_name = ?"
+		binds = append(binds, q.TableName)
+	}
+	if q.RecordKey != "" {
+		query += " AND record_key LIKE ?"
+		binds = append(binds, "%" + q.RecordKey + "%")
+	}
+	if q.AccountName != "" {
+		query += " AND account_name LIKE ?"
+		binds = append(binds, "%" + q.AccountName + "%")
+	}
+	if q.From != "" {
+		query += " AND created_at >= ?"
+		binds = append(binds, q.From)
+	}
+	if q.To != "" {
+		query += " AND created_at < ?"
+		binds = append(binds, nextDay(q.To))
+	}
+	query += " ORDER BY audit_log_id DESC LIMIT ?"
+	binds = append(binds, limit)
+
+	rows, err := rep.db.Query(rep.db.Rebind(query), binds...)
+	if err != nil {
+		return []AuditLog{}, err
+	}
+	defer rows.Close()
+
+	ret := []AuditLog{}
+	for rows.Next() {
+		al := AuditLog{}
+		err = rows.Scan(
+			&al.Id,
+			&al.TableName,
+			&al.RecordKey,
+			&al.Operation,
+			&al.BeforeData,
+			&al.AfterData,
+			&al.AccountId,
+			&al.AccountName,
+			&al.AccountKey,
+			&al.CreatedAt,
+		)
+		if err != nil {
+			return []AuditLog{}, err
+		}
+		ret = append(ret, al)
+	}
+
+	return ret, nil
+}
+
+
+func (rep *repository) Insert(al *AuditLog, tx *sql.Tx) error {
+	cmd := 
+	`INSERT INTO audit_log (
+		table_name
+		,record_key
+		,operation
+		,before_data
+		,after_data
+		,account_id
+		,account_name
+		,account_key
+		,created_at
+	 ) VALUES(?,?,?,?,?,?,?,?,?)`
+
+	binds := []interface{}{
+		al.TableName,
+		al.RecordKey,
+		al.Operation,
+		al.BeforeData,
+		al.AfterData,
+		al.AccountId,
+		al.AccountName,
+		al.AccountKey,
+		al.CreatedAt,
+	}
+
+	var err error
+	if tx != nil {
+		_, err = tx.Exec(rep.db.Rebind(cmd), binds...)
+	} else {
+		_, err = rep.db.Exec(rep.db.Rebind(cmd), binds...)
+	}
+
+	return err
+}
+
+
+//YYYY-MM-DD の翌日 (期間の終了日を含めるため)
+func nextDay(date string) string {
+	t, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return date
+	}
+	return t.AddDate(0, 0, 1).Format("2006-01-02")
+}
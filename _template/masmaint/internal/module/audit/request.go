@@ -0,0 +1,11 @@
+package audit
+
+
+//GET /api/audit の検索条件 (日付は YYYY-MM-DD)
+type SearchQuery struct {
+	TableName string `form:"table_name" json:"table_name"`
+	RecordKey string `form:"record_key" json:"record_key"`
+	AccountName string `form:"account_name" json:"account_name"`
+	From string `form:"from" json:"from" binding:"omitempty,datetime=2006-01-02"`
+	To string `form:"to" json:"to" binding:"omitempty,datetime=2006-01-02"`
+}
@@ -0,0 +1,35 @@
+package audit
+
+import (
+	"masmaint/internal/core/logger"
+	"masmaint/internal/core/errs"
+)
+
+
+//1回の検索で返す最大件数
+const SEARCH_LIMIT = 1000
+
+type Service interface {
+	Search(q SearchQuery) ([]AuditLog, error)
+}
+
+type service struct {
+	repository Repository
+}
+
+func NewService(repository Repository) Service {
+	return &service{
+		repository: repository,
+	}
+}
+
+
+func (srv *service) Search(q SearchQuery) ([]AuditLog, error) {
+	rows, err := srv.repository.Search(q, SEARCH_LIMIT)
+	if err != nil {
+		logger.Error(err.Error())
+		return []AuditLog{}, errs.NewUnexpectedError(err.Error())
+	}
+	return rows, nil
+}
+
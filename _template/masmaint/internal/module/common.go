@@ -4,22 +4,90 @@ import (
 	"reflect"
     "regexp"
     "strings"
+	"sync"
+	"time"
+	"database/sql"
 	"encoding/json"
+	"github.com/gin-gonic/gin/binding"
 	"github.com/lib/pq"
     "github.com/go-sql-driver/mysql"
     "github.com/mattn/go-sqlite3"
 
+	"masmaint/internal/core/db"
 	"masmaint/internal/core/errs"
+	"masmaint/internal/core/logger"
 )
 
 
+//トランザクション内で fn を実行 (fn がエラーを返した場合はロールバック)
+func RunInTx(conn *db.DB, fn func(tx *sql.Tx) error) error {
+	tx, err := conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer takeAfterCommit(tx)
+
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	for _, f := range takeAfterCommit(tx) {
+		f()
+	}
+	return nil
+}
+
+
+var afterCommitMu sync.Mutex
+var afterCommit = map[*sql.Tx][]func(){}
+
+//RunInTx のトランザクションがコミットされた後に fn を実行 (ロールバックの場合は実行しない)
+func AfterCommit(tx *sql.Tx, fn func()) {
+	afterCommitMu.Lock()
+	defer afterCommitMu.Unlock()
+	afterCommit[tx] = append(afterCommit[tx], fn)
+}
+
+func takeAfterCommit(tx *sql.Tx) []func() {
+	afterCommitMu.Lock()
+	defer afterCommitMu.Unlock()
+	fns := afterCommit[tx]
+	delete(afterCommit, tx)
+	return fns
+}
+
+//DB操作のエラーを変換 (errs のエラーはそのまま返す)
+func NewDBError(err error) error {
+    if err == nil {
+        return nil
+    }
+
+	switch err.(type) {
+	case errs.BadRequestError, errs.UnauthorizedError, errs.ForbiddenError, errs.NotFoundError, errs.ConflictError:
+		return err
+	}
+	if err == sql.ErrNoRows {
+		return errs.NewNotFoundError()
+	}
+	if column, ok := GetConflictColumn(err); ok {
+		return errs.NewConflictError(column)
+	}
+	logger.Error(err.Error())
+	return errs.NewUnexpectedError(err.Error())
+}
+
+
 func GetConflictColumn(err error) (string, bool) {
     if err == nil {
         return "", false
     }
 
 	if pgErr, ok := err.(*pq.Error); ok && pgErr.Code == "23505" {
-        re := regexp.MustCompile(`Key \((\w+)\)=`)
+        //複合キーの場合は先頭のカラム (例: Key (shop_code, product_code)=(...))
+        re := regexp.MustCompile(`Key \((\w+)[,)]`)
 		match := re.FindStringSubmatch(pgErr.Detail)
 		if len(match) > 1 {
 			return match[1], true
@@ -61,6 +129,63 @@ func NewBindError(err error, dataStruct interface{}) error {
 }
 
 
+//マップをリクエスト構造体に変換し、リクエストと同じ規則で検証する (CSV取込等)
+func BindMap(values map[string]interface{}, dataStruct interface{}) error {
+	b, err := json.Marshal(values)
+	if err != nil {
+		return errs.NewBadRequestError("")
+	}
+	if err := json.Unmarshal(b, dataStruct); err != nil {
+		return NewBindError(err, dataStruct)
+	}
+	clearNullFields(dataStruct, values)
+	if err := binding.Validator.ValidateStruct(dataStruct); err != nil {
+		return NewBindError(err, dataStruct)
+	}
+	return nil
+}
+
+//nil の項目をゼロ値にする (json.Unmarshal は非ポインタ型への null を無視するため)
+func clearNullFields(dataStruct interface{}, values map[string]interface{}) {
+    val := reflect.ValueOf(dataStruct).Elem()
+    typ := val.Type()
+
+    for i := 0; i < typ.NumField(); i++ {
+        tag := strings.Split(typ.Field(i).Tag.Get("json"), ",")[0]
+        if v, ok := values[tag]; ok && v == nil {
+            val.Field(i).Set(reflect.Zero(typ.Field(i).Type))
+        }
+    }
+}
+
+
+/*
+ 時点の指定 (YYYY-MM-DD / YYYY-MM-DD HH:MM / YYYY-MM-DD HH:MM:SS) を
+ その時点を含む上限の日時に変換する (この日時より前が対象、日付のみはその日の終わりまで)
+*/
+func ParseAsOf(at string) (string, error) {
+	if at == "" {
+		return "", nil
+	}
+	at = strings.Replace(at, "T", " ", 1)
+
+	layouts := []struct {
+		layout string
+		add time.Duration
+	}{
+		{"2006-01-02", 24 * time.Hour},
+		{"2006-01-02 15:04", time.Minute},
+		{"2006-01-02 15:04:05", time.Second},
+	}
+	for _, l := range layouts {
+		if t, err := time.Parse(l.layout, at); err == nil {
+			return t.Add(l.add).Format("2006-01-02 15:04:05"), nil
+		}
+	}
+	return "", errs.NewBadRequestError("at")
+}
+
+
 func getFieldJsonTag(dataStruct interface{}, fieldName string) string {
     val := reflect.TypeOf(dataStruct).Elem()
 
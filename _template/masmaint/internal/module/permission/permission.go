@@ -0,0 +1,127 @@
+package permission
+
+import (
+	"sort"
+	"strings"
+	"github.com/gin-gonic/gin"
+
+	"masmaint/internal/core/jwt"
+)
+
+
+//テーブルに対する操作
+const (
+	Read = "read"
+	Create = "create"
+	Update = "update"
+	Delete = "delete"
+)
+
+//ロール (JWTの Role)
+const (
+	RoleAdmin = "admin"
+	RoleEditor = "editor"
+	RoleViewer = "viewer"
+)
+
+//テーブルに対する権限 (画面の表示制御に使用)
+type Permission struct {
+	Read bool
+	Create bool
+	Update bool
+	Delete bool
+}
+
+
+//ロールにテーブルの操作が許可されているか (matrix に無いロール・テーブルは不許可)
+func Allowed(role string, table string, action string) bool {
+	for _, a := range matrix[role][table] {
+		if a == action {
+			return true
+		}
+	}
+	return false
+}
+
+
+//matrix に定義されたロール
+func Roles() []string {
+	ret := []string{}
+	for role := range matrix {
+		ret = append(ret, role)
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+
+func IsRole(role string) bool {
+	_, found := matrix[role]
+	return found
+}
+
+
+//matrix に定義されたテーブル
+func Tables() []string {
+	set := map[string]bool{}
+	for _, tables := range matrix {
+		for table := range tables {
+			set[table] = true
+		}
+	}
+	ret := []string{}
+	for table := range set {
+		ret = append(ret, table)
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+
+//スコープ ("<table>:<action>"、* は全て) にテーブルの操作が含まれるか
+func InScopes(scopes []string, table string, action string) bool {
+	for _, s := range scopes {
+		t, a, _ := strings.Cut(s, ":")
+		if (t == "*" || t == table) && (a == "*" || a == action) {
+			return true
+		}
+	}
+	return false
+}
+
+
+func Of(role string, table string) Permission {
+	return Permission{
+		Read: Allowed(role, table, Read),
+		Create: Allowed(role, table, Create),
+		Update: Allowed(role, table, Update),
+		Delete: Allowed(role, table, Delete),
+	}
+}
+
+
+//ログインユーザのロールにテーブルの操作が許可されているか
+//APIトークンはスコープ内に限る (ロールの無いサービス用トークンはスコープのみで判定)
+func Can(c *gin.Context, table string, action string) bool {
+	pl := jwt.GetPayload(c)
+	if pl.Scopes != nil {
+		if !InScopes(pl.Scopes, table, action) {
+			return false
+		}
+		if pl.Role == "" {
+			return true
+		}
+	}
+	return Allowed(pl.Role, table, action)
+}
+
+
+//ログインユーザのテーブルに対する権限
+func Get(c *gin.Context, table string) Permission {
+	return Permission{
+		Read: Can(c, table, Read),
+		Create: Can(c, table, Create),
+		Update: Can(c, table, Update),
+		Delete: Can(c, table, Delete),
+	}
+}
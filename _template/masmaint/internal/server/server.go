@@ -4,16 +4,20 @@ import (
 	"github.com/gin-gonic/gin"
 
 	"masmaint/config"
+	"masmaint/internal/middleware"
 )
 
-func Run() {
+func Run(app *App) {
 	cf := config.GetConfig()
-	r := router()
+	r := router(app)
 	r.Run(":" + cf.AppPort)
 }
 
-func router() *gin.Engine {
+func router(app *App) *gin.Engine {
 	r := gin.Default()
+	//主キーに / 等を含む場合でもパスパラメータとして扱えるよう、エンコード済みのパスでルーティング
+	r.UseRawPath = true
+	r.Use(middleware.SecurityHeaders())
 	
 	//TEMPLATE
 	r.LoadHTMLGlob("web/template/*.html")
@@ -25,8 +29,8 @@ func router() *gin.Engine {
 	r.StaticFile("/favicon.ico", "web/static/favicon.ico")
 	r.StaticFile("/manifest.json", "web/static/manifest.json")
 
-	SetWebRouter(r.Group("/"))
-	SetApiRouter(r.Group("/api"))
+	SetWebRouter(r.Group("/"), app)
+	SetApiRouter(r.Group("/api"), app)
 
 	return r
 }
\ No newline at end of file
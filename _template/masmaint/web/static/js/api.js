@@ -1,5 +1,14 @@
 const BASE_URL = '/api';
 
+//画面ごとのID (変更の配信で自身の変更を区別する)
+const CLIENT_ID = `${Date.now().toString(36)}-${Math.random().toString(36).slice(2)}`;
+
+//二重送信のCSRFトークン (Cookie の csrf_token を X-CSRF-Token ヘッダで送信)
+const getCsrfToken = () => {
+    const cookie = document.cookie.split('; ').find(c => c.startsWith('csrf_token='));
+    return cookie ? decodeURIComponent(cookie.slice('csrf_token='.length)) : '';
+}
+
 
 class HttpError extends Error{
     status;
@@ -28,10 +37,17 @@ class Api {
                 method: method,
                 headers: {
                     'Content-Type': 'application/json',
+                    'X-Client-Id': CLIENT_ID,
                 },
             };
+            if (method !== 'GET') {
+                header.headers['X-CSRF-Token'] = getCsrfToken();
+            }
     
-            if (body) {
+            if (body instanceof FormData) {
+                delete header.headers['Content-Type'];
+                header.body = body;
+            } else if (body) {
                 header.body = JSON.stringify(body);
             }
             const response = await fetch(`${this.#url}/${endpoint}`, header);
@@ -71,11 +87,19 @@ class Api {
     put = async (endpoint, body) => {
         return this.apiFetch(endpoint, 'PUT', body);
     };
+
+    patch = async (endpoint, body) => {
+        return this.apiFetch(endpoint, 'PATCH', body);
+    };
     
     delete = async (endpoint, body = null) => {
         return this.apiFetch(endpoint, 'DELETE', body);
     };
 
+    upload = async (endpoint, formData) => {
+        return this.apiFetch(endpoint, 'POST', formData);
+    };
+
     handleHttpError = (error) => {
         console.error(error);
         throw error;
@@ -97,4 +121,4 @@ api.handleHttpError = (error) => {
     throw error;
 }
 
-export { HttpError, Api, BASE_URL, api };
\ No newline at end of file
+export { HttpError, Api, BASE_URL, CLIENT_ID, api };
\ No newline at end of file
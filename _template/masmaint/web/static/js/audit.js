@@ -0,0 +1,101 @@
+import { api } from '/js/api.js';
+
+
+document.addEventListener('DOMContentLoaded', () => {
+    document.getElementById('audit-search').addEventListener('submit', (event) => {
+        event.preventDefault();
+        getRows();
+    });
+    getRows();
+});
+
+
+/* 検索条件 -> クエリ文字列 (未入力の項目は除外) */
+const getQuery = () => {
+    const params = new URLSearchParams();
+    for (const name of ['table_name', 'record_key', 'account_name', 'from', 'to']) {
+        const value = document.getElementById(`audit-${name}`).value.trim();
+        if (value !== '') {
+            params.append(name, value);
+        }
+    }
+    return params.toString();
+}
+
+const getRows = async () => {
+    clearMessage();
+    try {
+        const data = await api.get(`audit?${getQuery()}`);
+        renderRows(data);
+    } catch (e) {
+        renderMessage(e.details && e.details.field
+            ? `${e.details.field} が不正です。`
+            : '監査ログを取得できませんでした。', 'danger');
+    }
+}
+
+const renderRows = (rows) => {
+    const tbody = document.getElementById('records');
+    tbody.replaceChildren();
+    for (const row of rows) {
+        const tr = tbody.insertRow();
+        tr.insertCell().textContent = formatDateTime(row.created_at);
+        tr.insertCell().textContent = row.operation;
+        tr.insertCell().textContent = row.table_name;
+        tr.insertCell().textContent = row.record_key;
+        tr.insertCell().textContent = row.account_name;
+        tr.insertCell().appendChild(createChanges(row));
+    }
+    if (rows.length === 0) {
+        renderMessage('該当する監査ログはありません。', 'secondary');
+    }
+}
+
+/* 更新は変更されたカラムのみ、登録・削除は全カラムを表示 */
+const createChanges = (row) => {
+    const before = parse(row.before_data);
+    const after = parse(row.after_data);
+    const columns = Object.keys(after || before || {});
+
+    const ul = document.createElement('ul');
+    ul.className = 'list-unstyled mb-0';
+    for (const c of columns) {
+        let text;
+        if (before && after) {
+            if (format(before[c]) === format(after[c])) {
+                continue;
+            }
+            text = `${c}: ${format(before[c])} → ${format(after[c])}`;
+        } else {
+            text = `${c}: ${format((after || before)[c])}`;
+        }
+        const li = document.createElement('li');
+        li.textContent = text;
+        ul.appendChild(li);
+    }
+    return ul;
+}
+
+const parse = (data) => {
+    return (data == null) ? null : JSON.parse(data);
+}
+
+const format = (value) => {
+    return (value == null) ? '' : String(value);
+}
+
+/* 2006-01-02T15:04:05Z / 2006-01-02 15:04:05 -> 2006-01-02 15:04:05 */
+const formatDateTime = (value) => {
+    return format(value).replace('T', ' ').replace(/(Z|[+-]\d{2}:\d{2})$/, '');
+}
+
+const renderMessage = (msg, type) => {
+    const div = document.createElement('div');
+    div.className = `alert alert-${type} alert-custom my-1`;
+    div.textContent = msg;
+    document.getElementById('message').replaceChildren(div);
+}
+
+const clearMessage = () => {
+    document.getElementById('message').replaceChildren();
+}
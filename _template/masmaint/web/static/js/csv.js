@@ -0,0 +1,159 @@
+import { api } from '/js/api.js';
+
+/* CSV取込モーダルのセットアップ (差分確認 -> 反映) */
+export const setupCsvImport = (tableName, onApplied) => {
+    const fileInput = document.getElementById('csv-file');
+    const applyButton = document.getElementById('csv-apply');
+
+    const upload = async (dryRun) => {
+        const file = fileInput.files[0];
+        if (file === undefined) {
+            renderError('ファイルが選択されていません。');
+            return null;
+        }
+
+        const formData = new FormData();
+        formData.append('file', file);
+        formData.append('encoding', document.getElementById('csv-encoding').value);
+        formData.append('delete_missing', document.getElementById('csv-delete-missing').checked);
+        formData.append('dry_run', dryRun);
+
+        try {
+            return await api.upload(`${tableName}/_import.csv`, formData);
+        } catch (e) {
+            renderError(e.details && e.details.column
+                ? `${e.details.column} が重複しています。`
+                : 'CSVを取り込めませんでした。');
+            return null;
+        }
+    }
+
+    document.getElementById('csv-dry-run').addEventListener('click', async () => {
+        applyButton.disabled = true;
+        const diff = await upload(true);
+        if (diff) {
+            renderDiff(diff);
+            applyButton.disabled = diff.errors.length > 0 || countChanges(diff) === 0;
+        }
+    });
+
+    applyButton.addEventListener('click', async () => {
+        applyButton.disabled = true;
+        const diff = await upload(false);
+        if (diff) {
+            renderDiff(diff);
+            if (diff.applied) {
+                onApplied();
+            }
+        }
+    });
+
+    fileInput.addEventListener('change', () => {
+        applyButton.disabled = true;
+        clear();
+    });
+}
+
+const countChanges = (diff) => {
+    return diff.inserts.length + diff.updates.length + diff.deletes.length;
+}
+
+const clear = () => {
+    document.getElementById('csv-diff').replaceChildren();
+}
+
+const renderError = (msg) => {
+    clear();
+    document.getElementById('csv-diff').appendChild(createAlert(msg, 'danger'));
+}
+
+const createAlert = (msg, type) => {
+    const div = document.createElement('div');
+    div.className = `alert alert-${type} alert-custom my-1`;
+    div.textContent = msg;
+    return div;
+}
+
+const renderDiff = (diff) => {
+    clear();
+    const area = document.getElementById('csv-diff');
+
+    if (diff.applied) {
+        area.appendChild(createAlert('反映しました。', 'success'));
+    }
+    area.appendChild(createAlert(
+        `登録 ${diff.inserts.length}件 / 更新 ${diff.updates.length}件 / 削除 ${diff.deletes.length}件 / エラー ${diff.errors.length}件`,
+        diff.errors.length > 0 ? 'danger' : 'secondary'
+    ));
+
+    if (diff.errors.length > 0) {
+        area.appendChild(createSection('エラー', diff.errors));
+    }
+    if (diff.inserts.length > 0) {
+        area.appendChild(createSection('登録', diff.inserts));
+    }
+    if (diff.updates.length > 0) {
+        area.appendChild(createUpdateSection(diff.updates));
+    }
+    if (diff.deletes.length > 0) {
+        area.appendChild(createSection('削除', diff.deletes));
+    }
+}
+
+const createSection = (title, rows) => {
+    const section = document.createElement('div');
+    const h = document.createElement('h5');
+    h.className = 'h6 mt-3';
+    h.textContent = title;
+    section.appendChild(h);
+
+    const columns = Object.keys(rows[0]);
+    section.appendChild(createTable(columns, rows.map(row => columns.map(c => row[c]))));
+    return section;
+}
+
+/* 更新は変更されたカラムのみ 変更前 → 変更後 で表示 */
+const createUpdateSection = (updates) => {
+    const section = document.createElement('div');
+    const h = document.createElement('h5');
+    h.className = 'h6 mt-3';
+    h.textContent = '更新';
+    section.appendChild(h);
+
+    const columns = Object.keys(updates[0].after);
+    const rows = updates.map(u => columns.map(c => {
+        const before = u.before[c];
+        const after = u.after[c];
+        return (before === after) ? format(after) : `${format(before)} → ${format(after)}`;
+    }));
+    section.appendChild(createTable(columns, rows));
+    return section;
+}
+
+const createTable = (columns, rows) => {
+    const wrapper = document.createElement('div');
+    wrapper.className = 'table-responsive';
+    const table = document.createElement('table');
+    table.className = 'table table-bordered table-sm';
+
+    const thead = table.createTHead().insertRow();
+    for (const c of columns) {
+        const th = document.createElement('th');
+        th.textContent = c;
+        thead.appendChild(th);
+    }
+
+    const tbody = table.createTBody();
+    for (const row of rows) {
+        const tr = tbody.insertRow();
+        for (const value of row) {
+            tr.insertCell().textContent = format(value);
+        }
+    }
+    wrapper.appendChild(table);
+    return wrapper;
+}
+
+const format = (value) => {
+    return (value == null) ? '' : String(value);
+}
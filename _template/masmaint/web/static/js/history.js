@@ -0,0 +1,146 @@
+import { api } from '/js/api.js';
+import { can } from './permission.js';
+
+const HISTORY_COLUMNS = ['history_id', 'history_operation', 'history_changed_at'];
+const OPERATION_LABELS = { INSERT: '登録', UPDATE: '更新', DELETE: '削除', RESTORE: '復元' };
+
+let tableName = '';
+let keyPath = '';
+let onRestored = () => {};
+
+/* 履歴ドロワーのセットアップ */
+export const setupHistory = (name, callback) => {
+    tableName = name;
+    onRestored = callback;
+
+    document.getElementById('history-search').addEventListener('click', () => {
+        getHistory();
+    });
+    document.getElementById('history-clear').addEventListener('click', () => {
+        document.getElementById('history-at').value = '';
+        getHistory();
+    });
+}
+
+/* 行の履歴ボタン (<td></td>) を作成 */
+export const createHistoryCell = (path) => {
+    const td = document.createElement('td');
+    const button = document.createElement('button');
+    button.type = 'button';
+    button.className = 'btn btn-outline-secondary btn-sm py-0';
+    button.textContent = '履歴';
+    button.addEventListener('click', () => openHistory(path));
+    td.appendChild(button);
+    return td;
+}
+
+const openHistory = (path) => {
+    keyPath = path;
+    document.getElementById('history-title').textContent =
+        `履歴 ${path.split('/').map(v => decodeURIComponent(v)).join(' / ')}`;
+    document.getElementById('history-at').value = '';
+    bootstrap.Offcanvas.getOrCreateInstance(document.getElementById('history-drawer')).show();
+    getHistory();
+}
+
+const getHistory = async () => {
+    clearMessage();
+    const at = document.getElementById('history-at').value;
+    const query = (at === '') ? '' : `?at=${encodeURIComponent(at)}`;
+    try {
+        const rows = await api.get(`${tableName}/${keyPath}/_history${query}`);
+        renderHistory(rows, at !== '');
+    } catch (e) {
+        document.getElementById('history-list').replaceChildren();
+        renderMessage('履歴を取得できませんでした。', 'danger');
+    }
+}
+
+const restore = async (historyId) => {
+    if (!window.confirm('この版に戻します。よろしいですか？')) {
+        return;
+    }
+    clearMessage();
+    try {
+        await api.post(`${tableName}/${keyPath}/_history/${historyId}/restore`, {});
+        document.getElementById('history-at').value = '';
+        await getHistory();
+        renderMessage('復元しました。', 'success');
+        onRestored();
+    } catch (e) {
+        renderMessage(e.details && e.details.field
+            ? `${e.details.field} が不正なため復元できません。`
+            : '復元できませんでした。', 'danger');
+    }
+}
+
+/* 新しい順に表示し、1つ前の版から変更されたカラムを強調 */
+const renderHistory = (rows, isAt) => {
+    const list = document.getElementById('history-list');
+    list.replaceChildren();
+    if (rows.length === 0) {
+        renderMessage(isAt ? '指定した時点の履歴はありません。' : '履歴はありません。', 'secondary');
+        return;
+    }
+    rows.forEach((row, i) => {
+        list.appendChild(createVersion(row, isAt ? null : rows[i + 1]));
+    });
+}
+
+const createVersion = (row, previous) => {
+    const card = document.createElement('div');
+    card.className = 'card mb-2';
+
+    const header = document.createElement('div');
+    header.className = 'card-header d-flex justify-content-between align-items-center py-1';
+    const title = document.createElement('span');
+    title.textContent = `${formatDateTime(row.history_changed_at)} ${OPERATION_LABELS[row.history_operation] || row.history_operation}`;
+    header.appendChild(title);
+
+    if (row.history_operation !== 'DELETE' && can('update')) {
+        const button = document.createElement('button');
+        button.type = 'button';
+        button.className = 'btn btn-outline-primary btn-sm py-0';
+        button.textContent = 'この版に戻す';
+        button.addEventListener('click', () => restore(row.history_id));
+        header.appendChild(button);
+    }
+    card.appendChild(header);
+
+    const table = document.createElement('table');
+    table.className = 'table table-sm mb-0';
+    const tbody = table.createTBody();
+    for (const column of Object.keys(row)) {
+        if (HISTORY_COLUMNS.includes(column)) {
+            continue;
+        }
+        const tr = tbody.insertRow();
+        if (previous && format(previous[column]) !== format(row[column])) {
+            tr.className = 'table-warning';
+        }
+        tr.insertCell().textContent = column;
+        tr.insertCell().textContent = format(row[column]);
+    }
+    card.appendChild(table);
+    return card;
+}
+
+const format = (value) => {
+    return (value == null) ? '' : String(value);
+}
+
+/* 2006-01-02T15:04:05Z / 2006-01-02 15:04:05 -> 2006-01-02 15:04:05 */
+const formatDateTime = (value) => {
+    return format(value).replace('T', ' ').replace(/(Z|[+-]\d{2}:\d{2})$/, '');
+}
+
+const renderMessage = (msg, type) => {
+    const div = document.createElement('div');
+    div.className = `alert alert-${type} alert-custom my-1`;
+    div.textContent = msg;
+    document.getElementById('history-message').replaceChildren(div);
+}
+
+const clearMessage = () => {
+    document.getElementById('history-message').replaceChildren();
+}
@@ -0,0 +1,142 @@
+import { api, BASE_URL, CLIENT_ID } from '/js/api.js';
+import { applyPermission } from './permission.js';
+
+//編集中の表示を消すまでの時間・編集中を通知する間隔 (ミリ秒)
+const EDITING_EXPIRES = 30000;
+const EDITING_INTERVAL = 10000;
+
+//主キーのパス → (ユーザ名 → 表示を消すタイマー)
+const editing = new Map();
+
+/*
+ 他の画面の変更 (Server-Sent Events) を一覧に反映する
+ createTr: 行データから<tr>を作成 (data-key に主キーのパス)
+ onChange: 行の change イベントハンドラ
+*/
+export const setupLive = (table, { createTr, toKeyPath, onChange }) => {
+    const source = new EventSource(`${BASE_URL}/${table}/_events`);
+    source.addEventListener('change', (event) => {
+        const e = JSON.parse(event.data);
+        if (e.client_id === CLIENT_ID) return;
+
+        if (e.operation === 'EDITING') {
+            showEditing(e.data.key, e.account_name);
+        } else {
+            hideEditing(toKeyPath(e.data), e.account_name);
+            applyChange(e, toKeyPath(e.data), createTr, onChange);
+        }
+    });
+
+    notifyEditing(table);
+}
+
+const findTr = (key) => {
+    return Array.from(document.querySelectorAll('#records tr[data-key]')).find(tr => tr.dataset.key === key);
+}
+
+/* 画面で変更中 (未保存) の行 */
+const isDirty = (tr) => {
+    return tr.querySelector('input.changed') != null;
+}
+
+/* 変更中の行は上書きせず、競合として表示する */
+const applyChange = (e, key, createTr, onChange) => {
+    const tr = findTr(key);
+
+    if (e.operation === 'DELETE') {
+        if (tr == null) return;
+        if (isDirty(tr)) {
+            markConflict(tr, `${e.account_name} が削除しました。`);
+        } else {
+            tr.remove();
+        }
+        return;
+    }
+
+    //INSERT・UPDATE・RESTORE
+    if (tr != null && isDirty(tr)) {
+        markConflict(tr, `${e.account_name} が更新しました。`);
+        return;
+    }
+    const newTr = createTr(e.data);
+    newTr.addEventListener('change', onChange);
+    if (tr != null) {
+        newTr.querySelector('input[name=del]').checked = tr.querySelector('input[name=del]').checked;
+        tr.replaceWith(newTr);
+    } else {
+        document.getElementById('records').insertBefore(newTr, document.getElementById('new'));
+    }
+    renderEditing(newTr);
+    applyPermission();
+}
+
+const markConflict = (tr, msg) => {
+    tr.classList.add('conflict');
+    const badge = getBadge(tr, 'row-conflict', 'text-bg-warning');
+    badge.textContent = '競合';
+    badge.title = `${msg}保存すると上書きします。リロードで最新の値を表示します。`;
+}
+
+/* 行の先頭 (削除チェックボックス) のセルに表示するバッジ */
+const getBadge = (tr, name, color) => {
+    let badge = tr.querySelector(`.${name}`);
+    if (badge == null) {
+        badge = document.createElement('span');
+        badge.className = `badge ${color} ${name} ms-1`;
+        tr.firstElementChild.appendChild(badge);
+    }
+    return badge;
+}
+
+
+const showEditing = (key, accountName) => {
+    if (!editing.has(key)) {
+        editing.set(key, new Map());
+    }
+    const users = editing.get(key);
+    clearTimeout(users.get(accountName));
+    users.set(accountName, setTimeout(() => hideEditing(key, accountName), EDITING_EXPIRES));
+
+    const tr = findTr(key);
+    if (tr != null) renderEditing(tr);
+}
+
+const hideEditing = (key, accountName) => {
+    const users = editing.get(key);
+    if (users == null || !users.has(accountName)) return;
+    clearTimeout(users.get(accountName));
+    users.delete(accountName);
+    if (users.size === 0) {
+        editing.delete(key);
+    }
+
+    const tr = findTr(key);
+    if (tr != null) renderEditing(tr);
+}
+
+const renderEditing = (tr) => {
+    const users = editing.get(tr.dataset.key);
+    if (users == null) {
+        tr.querySelector('.row-editing')?.remove();
+        return;
+    }
+    const names = Array.from(users.keys()).join(', ');
+    const badge = getBadge(tr, 'row-editing', 'text-bg-info');
+    badge.textContent = '編集中';
+    badge.title = `${names} が編集中です。`;
+}
+
+
+/* 入力中の行を他の画面へ通知 (行ごとに EDITING_INTERVAL 間隔) */
+const notifyEditing = (table) => {
+    const sentAt = new Map();
+    document.getElementById('records').addEventListener('input', (event) => {
+        const tr = event.target.closest('tr[data-key]');
+        if (tr == null) return;
+
+        const now = Date.now();
+        if (now - (sentAt.get(tr.dataset.key) ?? 0) < EDITING_INTERVAL) return;
+        sentAt.set(tr.dataset.key, now);
+        api.post(`${table}/_editing`, { key: tr.dataset.key }).catch(() => {});
+    });
+}
@@ -1,7 +1,10 @@
 import { api } from '/js/api.js';
 
 window.addEventListener("DOMContentLoaded", function() {
-    document.getElementById("login").addEventListener("click", login);
+    document.getElementById("login-form").addEventListener("submit", (event) => {
+        event.preventDefault();
+        login();
+    });
 });
 
 
@@ -19,7 +22,7 @@ const login = async () => {
         await api.post('login', body);
         window.location.replace('/');
     } catch (e) {
-        document.getElementById("error").innerHTML = (e.status === 401)
+        document.getElementById("error").textContent = (e.status === 401)
         ? "ユーザ名またはパスワードが異なります。" 
         : "ログインに失敗しました。";
     }
@@ -0,0 +1,19 @@
+/* 画面のテーブルに対する権限 (<main data-can-create/update/delete>) */
+export const can = (action) => {
+    const main = document.querySelector('main');
+    return main.dataset[`can${action.charAt(0).toUpperCase()}${action.slice(1)}`] === 'true';
+}
+
+/* 権限の無い操作の入力欄を無効にする (<tbody id="records"> 内) */
+export const applyPermission = () => {
+    const tbody = document.getElementById('records');
+    if (!can('update')) {
+        tbody.querySelectorAll('tr:not(#new) input[type=text]').forEach(e => e.disabled = true);
+    }
+    if (!can('create')) {
+        tbody.querySelectorAll('tr#new input[type=text]').forEach(e => e.disabled = true);
+    }
+    if (!can('delete')) {
+        tbody.querySelectorAll('input[name=del]').forEach(e => e.disabled = true);
+    }
+}
@@ -44,4 +44,35 @@ export const parseBoolOrReturnOriginal = (value) => {
         return false;
     }
     return value;
-}
\ No newline at end of file
+}
+
+/* 一覧の行のセル (値は value に設定し、HTMLとして解釈させない) */
+export const createCheckboxCell = (name, value) => {
+    const td = document.createElement('td');
+    const input = document.createElement('input');
+    input.className = 'form-check-input';
+    input.type = 'checkbox';
+    input.name = name;
+    input.value = value;
+    td.appendChild(input);
+    return td;
+}
+
+/* backup: 変更検知用に元の値を hidden (name_bk) で直後に保持 */
+export const createInputCell = (name, value, { disabled = false, backup = false } = {}) => {
+    const td = document.createElement('td');
+    const input = document.createElement('input');
+    input.type = 'text';
+    input.name = name;
+    input.value = nullToEmpty(value);
+    input.disabled = disabled;
+    td.appendChild(input);
+    if (backup) {
+        const bk = document.createElement('input');
+        bk.type = 'hidden';
+        bk.name = `${name}_bk`;
+        bk.value = nullToEmpty(value);
+        td.appendChild(bk);
+    }
+    return td;
+}
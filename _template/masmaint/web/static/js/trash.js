@@ -0,0 +1,92 @@
+import { api } from '/js/api.js';
+import { can } from './permission.js';
+
+const main = document.getElementById('trash');
+const tableName = main.dataset.table;
+const keys = main.dataset.keys.split(',');
+
+
+document.addEventListener('DOMContentLoaded', () => {
+    document.getElementById('reload').addEventListener('click', () => {
+        clearMessage();
+        getRows();
+    });
+    getRows();
+});
+
+
+const getRows = async () => {
+    try {
+        const rows = await api.get(`${tableName}/_trash`);
+        renderRows(rows);
+    } catch (e) {
+        renderMessage('ゴミ箱を取得できませんでした。', 'danger');
+    }
+}
+
+/* 主キー -> パス (各値はURLエンコード) */
+const toKeyPath = (row) => {
+    return keys.map(k => encodeURIComponent(row[k])).join('/');
+}
+
+const restore = async (row, tr) => {
+    if (!window.confirm('この行を元に戻します。よろしいですか？')) {
+        return;
+    }
+    clearMessage();
+    try {
+        await api.post(`${tableName}/${toKeyPath(row)}/restore`, {});
+        tr.remove();
+        renderMessage('元に戻しました。', 'success');
+    } catch (e) {
+        renderMessage((e.status === 409)
+            ? '同じキーの行が既に存在するため元に戻せません。'
+            : '元に戻せませんでした。', 'danger');
+    }
+}
+
+const renderRows = (rows) => {
+    const head = document.getElementById('columns');
+    const tbody = document.getElementById('records');
+    head.replaceChildren();
+    tbody.replaceChildren();
+    if (rows.length === 0) {
+        renderMessage('ゴミ箱に行はありません。', 'secondary');
+        return;
+    }
+
+    const columns = Object.keys(rows[0]);
+    const canRestore = can('delete');
+    if (canRestore) {
+        head.appendChild(document.createElement('th')).textContent = '復元';
+    }
+    for (const column of columns) {
+        head.appendChild(document.createElement('th')).textContent = column;
+    }
+
+    for (const row of rows) {
+        const tr = tbody.insertRow();
+        if (canRestore) {
+            const button = document.createElement('button');
+            button.type = 'button';
+            button.className = 'btn btn-outline-primary btn-sm py-0';
+            button.textContent = '元に戻す';
+            button.addEventListener('click', () => restore(row, tr));
+            tr.insertCell().appendChild(button);
+        }
+        for (const column of columns) {
+            tr.insertCell().textContent = (row[column] == null) ? '' : String(row[column]);
+        }
+    }
+}
+
+const renderMessage = (msg, type) => {
+    const div = document.createElement('div');
+    div.className = `alert alert-${type} alert-custom my-1`;
+    div.textContent = msg;
+    document.getElementById('message').replaceChildren(div);
+}
+
+const clearMessage = () => {
+    document.getElementById('message').replaceChildren();
+}
@@ -0,0 +1,236 @@
+package oidc
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	jwtpackage "github.com/golang-jwt/jwt/v4"
+)
+
+
+/*
+ テスト用のIdP (discovery・authorize・token・jwks)
+ authorize はログイン画面を省略し、すぐに認可コードを返す
+*/
+
+const (
+	mockClientId = "masmaint"
+	mockClientSecret = "secret"
+	mockRedirectUrl = "http://localhost:3000/oidc/callback"
+)
+
+type mockIdP struct {
+	t *testing.T
+	server *httptest.Server
+
+	mu sync.Mutex
+	key *rsa.PrivateKey
+	kid string
+	//authorize で発行した認可コード
+	codes map[string]mockAuthRequest
+	//IDトークンに含めるクレーム (exp・nonce などを上書き可)
+	claims jwtpackage.MapClaims
+	//JWKSの取得回数
+	jwksRequests int
+}
+
+type mockAuthRequest struct {
+	nonce string
+	challenge string
+	redirectUri string
+}
+
+
+func newMockIdP(t *testing.T) *mockIdP {
+	idp := &mockIdP{
+		t: t,
+		codes: map[string]mockAuthRequest{},
+		claims: jwtpackage.MapClaims{
+			"sub": "u-001",
+			"preferred_username": "alice",
+			"groups": []string{"staff", "masmaint-editors"},
+		},
+	}
+	idp.rotateKey("key-1")
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", idp.discovery)
+	mux.HandleFunc("/authorize", idp.authorize)
+	mux.HandleFunc("/token", idp.token)
+	mux.HandleFunc("/jwks", idp.jwks)
+	idp.server = httptest.NewServer(mux)
+	t.Cleanup(idp.server.Close)
+	return idp
+}
+
+
+func (idp *mockIdP) issuer() string {
+	return idp.server.URL
+}
+
+
+func (idp *mockIdP) newClient(mapping []RoleMapping, defaultRole string) *Client {
+	return NewClient(Config{
+		Issuer: idp.issuer(),
+		ClientId: mockClientId,
+		ClientSecret: mockClientSecret,
+		RedirectUrl: mockRedirectUrl,
+		RoleMapping: mapping,
+		DefaultRole: defaultRole,
+	})
+}
+
+
+//署名鍵の更新 (以降のIDトークンは新しい鍵で署名、JWKSも新しい鍵のみ)
+func (idp *mockIdP) rotateKey(kid string) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		idp.t.Fatal(err)
+	}
+	idp.mu.Lock()
+	defer idp.mu.Unlock()
+	idp.key = key
+	idp.kid = kid
+}
+
+
+//IDトークンを発行 (override で標準のクレームを上書き、nil は削除)
+func (idp *mockIdP) idToken(nonce string, override jwtpackage.MapClaims) string {
+	idp.mu.Lock()
+	defer idp.mu.Unlock()
+
+	now := time.Now()
+	claims := jwtpackage.MapClaims{
+		"iss": idp.issuer(),
+		"aud": mockClientId,
+		"iat": now.Unix(),
+		"exp": now.Add(5 * time.Minute).Unix(),
+		"nonce": nonce,
+	}
+	for k, v := range idp.claims {
+		claims[k] = v
+	}
+	for k, v := range override {
+		if v == nil {
+			delete(claims, k)
+		} else {
+			claims[k] = v
+		}
+	}
+	token := jwtpackage.NewWithClaims(jwtpackage.SigningMethodRS256, claims)
+	token.Header["kid"] = idp.kid
+	signed, err := token.SignedString(idp.key)
+	if err != nil {
+		idp.t.Fatal(err)
+	}
+	return signed
+}
+
+
+func (idp *mockIdP) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJson(w, 200, map[string]string{
+		"issuer": idp.issuer(),
+		"authorization_endpoint": idp.issuer() + "/authorize",
+		"token_endpoint": idp.issuer() + "/token",
+		"jwks_uri": idp.issuer() + "/jwks",
+	})
+}
+
+
+func (idp *mockIdP) authorize(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if q.Get("response_type") != "code" || q.Get("client_id") != mockClientId ||
+		q.Get("code_challenge_method") != "S256" || q.Get("code_challenge") == "" {
+		http.Error(w, "invalid_request", http.StatusBadRequest)
+		return
+	}
+
+	code := mustRandom(idp.t)
+	idp.mu.Lock()
+	idp.codes[code] = mockAuthRequest{
+		nonce: q.Get("nonce"),
+		challenge: q.Get("code_challenge"),
+		redirectUri: q.Get("redirect_uri"),
+	}
+	idp.mu.Unlock()
+
+	redirect, _ := url.Parse(q.Get("redirect_uri"))
+	rq := redirect.Query()
+	rq.Set("code", code)
+	rq.Set("state", q.Get("state"))
+	redirect.RawQuery = rq.Encode()
+	http.Redirect(w, r, redirect.String(), http.StatusFound)
+}
+
+
+func (idp *mockIdP) token(w http.ResponseWriter, r *http.Request) {
+	id, secret, _ := r.BasicAuth()
+	if id != mockClientId || secret != mockClientSecret {
+		writeJson(w, 401, map[string]string{"error": "invalid_client"})
+		return
+	}
+	if r.PostFormValue("grant_type") != "authorization_code" {
+		writeJson(w, 400, map[string]string{"error": "unsupported_grant_type"})
+		return
+	}
+
+	//認可コードは一度のみ使用可
+	idp.mu.Lock()
+	req, ok := idp.codes[r.PostFormValue("code")]
+	delete(idp.codes, r.PostFormValue("code"))
+	idp.mu.Unlock()
+
+	if !ok || req.redirectUri != r.PostFormValue("redirect_uri") ||
+		CodeChallenge(r.PostFormValue("code_verifier")) != req.challenge {
+		writeJson(w, 400, map[string]string{"error": "invalid_grant"})
+		return
+	}
+	writeJson(w, 200, map[string]string{
+		"access_token": mustRandom(idp.t),
+		"token_type": "Bearer",
+		"id_token": idp.idToken(req.nonce, nil),
+	})
+}
+
+
+func (idp *mockIdP) jwks(w http.ResponseWriter, r *http.Request) {
+	idp.mu.Lock()
+	defer idp.mu.Unlock()
+	idp.jwksRequests++
+	pub := idp.key.PublicKey
+	writeJson(w, 200, map[string]interface{}{
+		"keys": []map[string]string{{
+			"kid": idp.kid,
+			"kty": "RSA",
+			"alg": "RS256",
+			"use": "sig",
+			"n": base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+			"e": base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}},
+	})
+}
+
+
+func writeJson(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+
+func mustRandom(t *testing.T) string {
+	s, err := RandomToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
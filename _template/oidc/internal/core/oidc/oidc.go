@@ -0,0 +1,343 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	jwtpackage "github.com/golang-jwt/jwt/v4"
+)
+
+
+/*
+ OpenID Connect (認可コードフロー + PKCE) のクライアント
+ config に依存しないため、テストではモックのIdPに接続する
+*/
+
+type Config struct {
+	Issuer string
+	ClientId string
+	ClientSecret string
+	RedirectUrl string
+	Scopes []string
+	//ロールの判定に使用するクレーム (既定: groups)
+	GroupsClaim string
+	//グループ → ロール (先に指定したものを優先)
+	RoleMapping []RoleMapping
+	//どのグループにも該当しない場合のロール (空の場合はログイン不可)
+	DefaultRole string
+	//未知の kid でJWKSを取り直す最短の間隔 (既定: 1分)
+	JwksRefreshInterval time.Duration
+}
+
+type Client struct {
+	cfg Config
+	http *http.Client
+
+	mu sync.Mutex
+	provider *provider
+	keys map[string]*rsa.PublicKey
+
+	//JWKSの取得は同時に1つのみ (mu は取得中に保持しない)
+	jwksMu sync.Mutex
+	jwksFetchedAt time.Time
+}
+
+///.well-known/openid-configuration
+type provider struct {
+	Issuer string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint string `json:"token_endpoint"`
+	JwksUri string `json:"jwks_uri"`
+}
+
+//IDトークンのクレーム
+type Claims map[string]interface{}
+
+var ErrInvalidToken = errors.New("invalid id token")
+
+
+func NewClient(cfg Config) *Client {
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = []string{"openid", "profile", "email"}
+	}
+	if cfg.GroupsClaim == "" {
+		cfg.GroupsClaim = "groups"
+	}
+	if cfg.JwksRefreshInterval == 0 {
+		cfg.JwksRefreshInterval = time.Minute
+	}
+	return &Client{
+		cfg: cfg,
+		http: &http.Client{Timeout: 10 * time.Second},
+		keys: map[string]*rsa.PublicKey{},
+	}
+}
+
+
+//認可リクエストのURL (state・nonce・PKCEの code_challenge を付与)
+func (cl *Client) AuthCodeURL(ctx context.Context, state, nonce, verifier string) (string, error) {
+	p, err := cl.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+	q := url.Values{}
+	q.Set("response_type", "code")
+	q.Set("client_id", cl.cfg.ClientId)
+	q.Set("redirect_uri", cl.cfg.RedirectUrl)
+	q.Set("scope", strings.Join(cl.cfg.Scopes, " "))
+	q.Set("state", state)
+	q.Set("nonce", nonce)
+	q.Set("code_challenge", CodeChallenge(verifier))
+	q.Set("code_challenge_method", "S256")
+
+	sep := "?"
+	if strings.Contains(p.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	return p.AuthorizationEndpoint + sep + q.Encode(), nil
+}
+
+
+//認可コードをトークンに交換し、検証済みのIDトークンのクレームを返す
+func (cl *Client) Exchange(ctx context.Context, code, verifier, nonce string) (Claims, error) {
+	p, err := cl.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", cl.cfg.RedirectUrl)
+	form.Set("code_verifier", verifier)
+	form.Set("client_id", cl.cfg.ClientId)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if cl.cfg.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(cl.cfg.ClientId), url.QueryEscape(cl.cfg.ClientSecret))
+	}
+
+	res, err := cl.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	var body struct {
+		IdToken string `json:"id_token"`
+		Error string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		return nil, err
+	}
+	if res.StatusCode != http.StatusOK || body.Error != "" {
+		return nil, fmt.Errorf("token request failed: %d %s %s", res.StatusCode, body.Error, body.ErrorDescription)
+	}
+	if body.IdToken == "" {
+		return nil, errors.New("token response has no id_token")
+	}
+	return cl.Verify(ctx, body.IdToken, nonce)
+}
+
+
+//IDトークンの署名 (JWKS)・iss・aud・exp・nonce を検証
+func (cl *Client) Verify(ctx context.Context, raw, nonce string) (Claims, error) {
+	p, err := cl.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	parser := jwtpackage.Parser{ValidMethods: []string{"RS256"}}
+	token, err := parser.Parse(raw, func(token *jwtpackage.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return cl.key(ctx, p, kid)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	mc := token.Claims.(jwtpackage.MapClaims)
+	if !mc.VerifyIssuer(p.Issuer, true) {
+		return nil, fmt.Errorf("%w: issuer mismatch", ErrInvalidToken)
+	}
+	if !mc.VerifyAudience(cl.cfg.ClientId, true) {
+		return nil, fmt.Errorf("%w: audience mismatch", ErrInvalidToken)
+	}
+	if _, ok := mc["exp"]; !ok {
+		return nil, fmt.Errorf("%w: exp is missing", ErrInvalidToken)
+	}
+	if n, _ := mc["nonce"].(string); n == "" || n != nonce {
+		return nil, fmt.Errorf("%w: nonce mismatch", ErrInvalidToken)
+	}
+	return Claims(mc), nil
+}
+
+
+//表示名 (preferred_username → email → sub)
+func (c Claims) Name() string {
+	for _, k := range []string{"preferred_username", "email", "sub"} {
+		if s, ok := c[k].(string); ok && s != "" {
+			return s
+		}
+	}
+	return ""
+}
+
+
+//アカウントを識別するキー (issuer で区別した sub、sub が無い場合は空)
+func (c Claims) Key() string {
+	iss, _ := c["iss"].(string)
+	sub, _ := c["sub"].(string)
+	if sub == "" {
+		return ""
+	}
+	return iss + "#" + sub
+}
+
+
+//ランダムな state・nonce・code_verifier
+func RandomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+
+//PKCE (S256)
+func CodeChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+
+func (cl *Client) discover(ctx context.Context) (*provider, error) {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+	if cl.provider != nil {
+		return cl.provider, nil
+	}
+
+	var p provider
+	wellKnown := strings.TrimSuffix(cl.cfg.Issuer, "/") + "/.well-known/openid-configuration"
+	if err := cl.getJson(ctx, wellKnown, &p); err != nil {
+		return nil, err
+	}
+	if p.Issuer != cl.cfg.Issuer {
+		return nil, fmt.Errorf("issuer mismatch: %s", p.Issuer)
+	}
+	cl.provider = &p
+	return cl.provider, nil
+}
+
+
+/*
+ kid の公開鍵 (見つからない場合は鍵の更新に備えてJWKSを取り直す)
+ 不明な kid のトークンでIdPへの取得を繰り返さないよう、取り直しは JwksRefreshInterval に1回まで
+*/
+func (cl *Client) key(ctx context.Context, p *provider, kid string) (*rsa.PublicKey, error) {
+	if k, ok := cl.cachedKey(kid); ok {
+		return k, nil
+	}
+
+	cl.jwksMu.Lock()
+	defer cl.jwksMu.Unlock()
+	//待っている間に他のリクエストが取得した場合
+	if k, ok := cl.cachedKey(kid); ok {
+		return k, nil
+	}
+	if !cl.jwksFetchedAt.IsZero() && time.Since(cl.jwksFetchedAt) < cl.cfg.JwksRefreshInterval {
+		return nil, fmt.Errorf("unknown key id: %s", kid)
+	}
+	cl.jwksFetchedAt = time.Now()
+
+	keys, err := cl.fetchKeys(ctx, p.JwksUri)
+	if err != nil {
+		return nil, err
+	}
+	cl.mu.Lock()
+	cl.keys = keys
+	cl.mu.Unlock()
+
+	if k, ok := keys[kid]; ok {
+		return k, nil
+	}
+	return nil, fmt.Errorf("unknown key id: %s", kid)
+}
+
+
+func (cl *Client) cachedKey(kid string) (*rsa.PublicKey, bool) {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+	k, ok := cl.keys[kid]
+	return k, ok
+}
+
+
+//JWKS の RSA の公開鍵 (kid → 鍵)
+func (cl *Client) fetchKeys(ctx context.Context, jwksUri string) (map[string]*rsa.PublicKey, error) {
+	var jwks struct {
+		Keys []struct {
+			Kid string `json:"kid"`
+			Kty string `json:"kty"`
+			N string `json:"n"`
+			E string `json:"e"`
+		} `json:"keys"`
+	}
+	if err := cl.getJson(ctx, jwksUri, &jwks); err != nil {
+		return nil, err
+	}
+	keys := map[string]*rsa.PublicKey{}
+	for _, k := range jwks.Keys {
+		if k.Kty != "RSA" {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			continue
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			continue
+		}
+		keys[k.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+	return keys, nil
+}
+
+
+func (cl *Client) getJson(ctx context.Context, url string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	res, err := cl.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %d", url, res.StatusCode)
+	}
+	return json.NewDecoder(res.Body).Decode(v)
+}
@@ -0,0 +1,254 @@
+package oidc
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	jwtpackage "github.com/golang-jwt/jwt/v4"
+)
+
+
+var editorMapping = []RoleMapping{
+	{Group: "masmaint-admins", Role: "admin"},
+	{Group: "masmaint-editors", Role: "editor"},
+}
+
+
+//認可リクエスト → IdPのリダイレクト先から認可コードと state を取得
+func authorize(t *testing.T, cl *Client, state, nonce, verifier string) (string, string) {
+	authUrl, err := cl.AuthCodeURL(context.Background(), state, nonce, verifier)
+	if err != nil {
+		t.Fatal(err)
+	}
+	noRedirect := &http.Client{
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	res, err := noRedirect.Get(authUrl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusFound {
+		t.Fatalf("authorize: status %d", res.StatusCode)
+	}
+	loc, err := url.Parse(res.Header.Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return loc.Query().Get("code"), loc.Query().Get("state")
+}
+
+
+func TestAuthorizationCodeFlow(t *testing.T) {
+	idp := newMockIdP(t)
+	cl := idp.newClient(editorMapping, "")
+
+	code, state := authorize(t, cl, "st", "nc", "verifier-0123456789")
+	if state != "st" {
+		t.Fatalf("state = %q", state)
+	}
+	claims, err := cl.Exchange(context.Background(), code, "verifier-0123456789", "nc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.Name() != "alice" {
+		t.Errorf("Name() = %q, want alice", claims.Name())
+	}
+	if claims.Key() != idp.issuer() + "#u-001" {
+		t.Errorf("Key() = %q, want <issuer>#u-001", claims.Key())
+	}
+	if role, ok := cl.Role(claims); !ok || role != "editor" {
+		t.Errorf("Role() = %q, %v, want editor", role, ok)
+	}
+
+	//認可コードの再利用は不可
+	if _, err := cl.Exchange(context.Background(), code, "verifier-0123456789", "nc"); err == nil {
+		t.Error("reused code: expected error")
+	}
+}
+
+
+func TestExchangeRequiresCodeVerifier(t *testing.T) {
+	idp := newMockIdP(t)
+	cl := idp.newClient(editorMapping, "")
+
+	code, _ := authorize(t, cl, "st", "nc", "verifier-0123456789")
+	if _, err := cl.Exchange(context.Background(), code, "another-verifier", "nc"); err == nil {
+		t.Error("expected error for wrong code_verifier")
+	}
+}
+
+
+func TestExchangeRejectsNonceMismatch(t *testing.T) {
+	idp := newMockIdP(t)
+	cl := idp.newClient(editorMapping, "")
+
+	code, _ := authorize(t, cl, "st", "nc", "verifier-0123456789")
+	_, err := cl.Exchange(context.Background(), code, "verifier-0123456789", "other")
+	if !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("err = %v, want ErrInvalidToken", err)
+	}
+}
+
+
+func TestVerify(t *testing.T) {
+	idp := newMockIdP(t)
+	cl := idp.newClient(editorMapping, "")
+	ctx := context.Background()
+
+	if _, err := cl.Verify(ctx, idp.idToken("nc", nil), "nc"); err != nil {
+		t.Fatalf("valid token: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		override jwtpackage.MapClaims
+	}{
+		{"expired", jwtpackage.MapClaims{"exp": time.Now().Add(-time.Minute).Unix()}},
+		{"no exp", jwtpackage.MapClaims{"exp": nil}},
+		{"other audience", jwtpackage.MapClaims{"aud": "other-client"}},
+		{"other issuer", jwtpackage.MapClaims{"iss": "https://evil.example.com"}},
+		{"no nonce", jwtpackage.MapClaims{"nonce": ""}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw := idp.idToken("nc", tt.override)
+			if _, err := cl.Verify(ctx, raw, "nc"); !errors.Is(err, ErrInvalidToken) {
+				t.Errorf("err = %v, want ErrInvalidToken", err)
+			}
+		})
+	}
+}
+
+
+func TestVerifyRejectsUnsignedAndForeignTokens(t *testing.T) {
+	idp := newMockIdP(t)
+	cl := idp.newClient(editorMapping, "")
+	ctx := context.Background()
+
+	//HS256 (クライアントシークレット等の共通鍵) は不可
+	hs := jwtpackage.NewWithClaims(jwtpackage.SigningMethodHS256, jwtpackage.MapClaims{
+		"iss": idp.issuer(), "aud": mockClientId, "exp": time.Now().Add(time.Minute).Unix(), "nonce": "nc",
+	})
+	hsRaw, _ := hs.SignedString([]byte(mockClientSecret))
+	if _, err := cl.Verify(ctx, hsRaw, "nc"); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("HS256: err = %v, want ErrInvalidToken", err)
+	}
+
+	//別のIdPの鍵で署名
+	other := newMockIdP(t)
+	other.kid = idp.kid
+	raw := other.idToken("nc", jwtpackage.MapClaims{"iss": idp.issuer()})
+	if _, err := cl.Verify(ctx, raw, "nc"); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("foreign key: err = %v, want ErrInvalidToken", err)
+	}
+}
+
+
+func TestVerifyAfterKeyRotation(t *testing.T) {
+	idp := newMockIdP(t)
+	cl := idp.newClient(editorMapping, "")
+	ctx := context.Background()
+
+	if _, err := cl.Verify(ctx, idp.idToken("nc", nil), "nc"); err != nil {
+		t.Fatal(err)
+	}
+	//取り直しの間隔を待たずに確認
+	cl.cfg.JwksRefreshInterval = 0
+	idp.rotateKey("key-2")
+	if _, err := cl.Verify(ctx, idp.idToken("nc", nil), "nc"); err != nil {
+		t.Fatalf("after rotation: %v", err)
+	}
+}
+
+
+func TestUnknownKidRefetchIsLimited(t *testing.T) {
+	idp := newMockIdP(t)
+	cl := idp.newClient(editorMapping, "")
+	ctx := context.Background()
+
+	if _, err := cl.Verify(ctx, idp.idToken("nc", nil), "nc"); err != nil {
+		t.Fatal(err)
+	}
+
+	//不明な kid のトークンを繰り返しても、間隔内はJWKSを取り直さない
+	other := newMockIdP(t)
+	other.kid = "unknown"
+	raw := other.idToken("nc", jwtpackage.MapClaims{"iss": idp.issuer()})
+	for i := 0; i < 3; i++ {
+		if _, err := cl.Verify(ctx, raw, "nc"); !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("unknown kid: err = %v, want ErrInvalidToken", err)
+		}
+	}
+	idp.mu.Lock()
+	defer idp.mu.Unlock()
+	if idp.jwksRequests != 1 {
+		t.Errorf("jwks requests = %d, want 1", idp.jwksRequests)
+	}
+}
+
+
+func TestClaimsKey(t *testing.T) {
+	c := Claims{"iss": "https://idp.example.com", "sub": "u-001", "preferred_username": "alice"}
+	if c.Key() != "https://idp.example.com#u-001" {
+		t.Errorf("Key() = %q", c.Key())
+	}
+	//表示名が同じでも別のアカウント
+	if (Claims{"iss": "https://idp.example.com", "sub": "u-002", "preferred_username": "alice"}).Key() == c.Key() {
+		t.Error("Key() should differ by sub")
+	}
+	if (Claims{"iss": "https://idp.example.com", "preferred_username": "alice"}).Key() != "" {
+		t.Error("Key() without sub should be empty")
+	}
+}
+
+
+func TestRole(t *testing.T) {
+	idp := newMockIdP(t)
+	tests := []struct {
+		name string
+		groups interface{}
+		defaultRole string
+		want string
+		ok bool
+	}{
+		{"first mapping wins", []interface{}{"masmaint-editors", "masmaint-admins"}, "", "admin", true},
+		{"single group string", "masmaint-editors", "", "editor", true},
+		{"no matching group", []interface{}{"staff"}, "", "", false},
+		{"default role", []interface{}{"staff"}, "viewer", "viewer", true},
+		{"no groups claim", nil, "", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cl := idp.newClient(editorMapping, tt.defaultRole)
+			claims := Claims{"sub": "u-001"}
+			if tt.groups != nil {
+				claims["groups"] = tt.groups
+			}
+			role, ok := cl.Role(claims)
+			if role != tt.want || ok != tt.ok {
+				t.Errorf("Role() = %q, %v, want %q, %v", role, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+
+func TestParseRoleMapping(t *testing.T) {
+	got, err := ParseRoleMapping(" masmaint-admins = admin, masmaint-editors=editor,")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0] != (RoleMapping{"masmaint-admins", "admin"}) || got[1] != (RoleMapping{"masmaint-editors", "editor"}) {
+		t.Errorf("got %v", got)
+	}
+	if _, err := ParseRoleMapping("admins"); err == nil {
+		t.Error("expected error for missing role")
+	}
+}
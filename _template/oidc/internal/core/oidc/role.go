@@ -0,0 +1,69 @@
+package oidc
+
+import (
+	"fmt"
+	"strings"
+)
+
+
+//IdPのグループ → アプリのロール
+type RoleMapping struct {
+	Group string
+	Role string
+}
+
+
+//"group1=admin,group2=editor" 形式
+func ParseRoleMapping(s string) ([]RoleMapping, error) {
+	ret := []RoleMapping{}
+	for _, kv := range strings.Split(s, ",") {
+		kv = strings.TrimSpace(kv)
+		if kv == "" {
+			continue
+		}
+		group, role, ok := strings.Cut(kv, "=")
+		group = strings.TrimSpace(group)
+		role = strings.TrimSpace(role)
+		if !ok || group == "" || role == "" {
+			return nil, fmt.Errorf("invalid role mapping: %s", kv)
+		}
+		ret = append(ret, RoleMapping{Group: group, Role: role})
+	}
+	return ret, nil
+}
+
+
+//クレームのグループに対応するロール (該当なしは DefaultRole、それも空の場合は false)
+func (cl *Client) Role(claims Claims) (string, bool) {
+	groups := claims.Groups(cl.cfg.GroupsClaim)
+	for _, m := range cl.cfg.RoleMapping {
+		for _, g := range groups {
+			if g == m.Group {
+				return m.Role, true
+			}
+		}
+	}
+	if cl.cfg.DefaultRole != "" {
+		return cl.cfg.DefaultRole, true
+	}
+	return "", false
+}
+
+
+//グループのクレーム (配列または文字列)
+func (c Claims) Groups(claim string) []string {
+	switch v := c[claim].(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		ret := []string{}
+		for _, g := range v {
+			if s, ok := g.(string); ok {
+				ret = append(ret, s)
+			}
+		}
+		return ret
+	default:
+		return []string{}
+	}
+}
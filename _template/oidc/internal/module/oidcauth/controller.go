@@ -0,0 +1,119 @@
+package oidcauth
+
+import (
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"masmaint/config"
+	"masmaint/internal/core/cookie"
+	"masmaint/internal/core/jwt"
+	"masmaint/internal/core/logger"
+	"masmaint/internal/core/oidc"
+)
+
+//認可リクエスト中の state・nonce・code_verifier を保持するCookie
+const COOKIE_KEY_FLOW string = "oidc_flow"
+const FLOW_EXPIRES int = 10 * 60
+
+type controller struct {
+	client *oidc.Client
+}
+
+func NewController() *controller {
+	cf := config.GetConfig()
+	mapping, err := oidc.ParseRoleMapping(cf.OidcRoleMapping)
+	if err != nil {
+		log.Panic(err)
+	}
+	client := oidc.NewClient(oidc.Config{
+		Issuer: cf.OidcIssuer,
+		ClientId: cf.OidcClientId,
+		ClientSecret: cf.OidcClientSecret,
+		RedirectUrl: cf.OidcRedirectUrl,
+		Scopes: strings.Fields(cf.OidcScopes),
+		GroupsClaim: cf.OidcGroupsClaim,
+		RoleMapping: mapping,
+		DefaultRole: cf.OidcDefaultRole,
+	})
+	return &controller{client}
+}
+
+
+//GET /oidc/login
+func (ctr *controller) Login(c *gin.Context) {
+	state, err1 := oidc.RandomToken()
+	nonce, err2 := oidc.RandomToken()
+	verifier, err3 := oidc.RandomToken()
+	if err1 != nil || err2 != nil || err3 != nil {
+		ctr.fail(c, "failed to generate random token")
+		return
+	}
+
+	authUrl, err := ctr.client.AuthCodeURL(c.Request.Context(), state, nonce, verifier)
+	if err != nil {
+		ctr.fail(c, err.Error())
+		return
+	}
+
+	//IdPからのリダイレクトで送信されるよう Lax
+	cookie.Set(c, &http.Cookie{
+		Name: COOKIE_KEY_FLOW,
+		Value: strings.Join([]string{state, nonce, verifier}, "."),
+		Path: "/oidc",
+		MaxAge: FLOW_EXPIRES,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	c.Redirect(http.StatusFound, authUrl)
+}
+
+
+//GET /oidc/callback
+func (ctr *controller) Callback(c *gin.Context) {
+	flow, _ := c.Cookie(COOKIE_KEY_FLOW)
+	cookie.Set(c, &http.Cookie{Name: COOKIE_KEY_FLOW, Path: "/oidc", MaxAge: -1, HttpOnly: true, SameSite: http.SameSiteLaxMode})
+
+	if e := c.Query("error"); e != "" {
+		ctr.fail(c, "authorization error: " + e)
+		return
+	}
+	v := strings.Split(flow, ".")
+	if len(v) != 3 || c.Query("state") == "" || c.Query("state") != v[0] {
+		ctr.fail(c, "state mismatch")
+		return
+	}
+
+	claims, err := ctr.client.Exchange(c.Request.Context(), c.Query("code"), v[2], v[1])
+	if err != nil {
+		ctr.fail(c, err.Error())
+		return
+	}
+	//preferred_username・email は変更・再利用され得るため、識別には sub を使用する (表示名のみ)
+	key := claims.Key()
+	if key == "" {
+		ctr.fail(c, "sub is missing")
+		return
+	}
+	role, ok := ctr.client.Role(claims)
+	if !ok {
+		ctr.fail(c, "no role for user: " + claims.Name())
+		return
+	}
+
+	cc := jwt.CustomClaims{ AccountName: claims.Name(), AccountKey: key, Role: role }
+	if err := jwt.SetTokenToCookie(c, jwt.NewPayload(cc)); err != nil {
+		ctr.fail(c, err.Error())
+		return
+	}
+	//IdPからのリダイレクトの続きでは SameSite=Strict の Cookie が送信されないため、画面から遷移する
+	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(`<!DOCTYPE html><meta http-equiv="refresh" content="0;url=/">`))
+}
+
+
+//失敗の詳細はログのみ (画面にはログイン失敗を表示)
+func (ctr *controller) fail(c *gin.Context, msg string) {
+	logger.Warning("oidc: " + msg)
+	c.Redirect(http.StatusSeeOther, "/login?error=1")
+}
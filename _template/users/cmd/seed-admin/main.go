@@ -0,0 +1,56 @@
+package main
+
+import (
+	"os"
+	"fmt"
+	"flag"
+	"bufio"
+	"strings"
+
+	"masmaint/config"
+	"masmaint/internal/core/db"
+	"masmaint/internal/core/logger"
+	"masmaint/internal/module/audit"
+	"masmaint/internal/module/permission"
+	"masmaint/internal/module/users"
+)
+
+/*
+ 最初の管理者ユーザを登録する
+ ENV=local go run ./cmd/seed-admin -name admin [-password ...]
+ (-password を省略した場合は標準入力から読み込む)
+*/
+func main() {
+	name := flag.String("name", "admin", "ユーザ名")
+	password := flag.String("password", "", "パスワード (8文字以上)")
+	flag.Parse()
+
+	if *password == "" {
+		fmt.Print("password: ")
+		line, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		*password = strings.TrimSpace(line)
+	}
+	if len(*password) < 8 {
+		fmt.Fprintln(os.Stderr, "パスワードは8文字以上で指定してください。")
+		os.Exit(1)
+	}
+
+	config.Load()
+	logger.Init()
+	cf := config.GetConfig()
+	conn, err := db.Open(cf.DBDriver, cf.DataSourceName())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(1)
+	}
+	defer conn.Close()
+
+	actor := audit.Actor{ AccountId: 0, AccountName: "seed-admin" }
+	input := users.PostBody{ UserName: *name, Password: *password, Role: permission.RoleAdmin }
+	u, err := users.NewService(conn, users.NewRepository(conn)).Create(actor, input)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(1)
+	}
+	fmt.Printf("user_id=%d user_name=%s role=%s を登録しました。\n", u.UserId, u.UserName, u.Role)
+}
@@ -0,0 +1,164 @@
+package users
+
+import (
+	"github.com/gin-gonic/gin"
+	"masmaint/internal/module"
+	"masmaint/internal/core/jwt"
+	"masmaint/internal/module/audit"
+	"masmaint/internal/module/permission"
+)
+
+type controller struct {
+	service Service
+}
+
+func NewController(service Service) *controller {
+	return &controller{service}
+}
+
+
+//GET /users
+func (ctr *controller) GetPage(c *gin.Context) {
+	c.HTML(200, "users.html", gin.H{"roles": permission.Roles(), "perm": permission.Get(c, TABLE_NAME)})
+}
+
+
+//GET /password
+func (ctr *controller) GetPasswordPage(c *gin.Context) {
+	c.HTML(200, "password.html", gin.H{})
+}
+
+
+//POST /api/login
+func (ctr *controller) Login(c *gin.Context) {
+	var req LoginBody
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(module.NewBindError(err, &req))
+		return
+	}
+
+	u, err := ctr.service.Login(req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	cc := jwt.CustomClaims{ AccountId: u.UserId, AccountName: u.UserName, Role: u.Role }
+	if err := jwt.SetTokenToCookie(c, jwt.NewPayload(cc)); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(200, gin.H{})
+}
+
+
+//GET /api/users
+func (ctr *controller) Get(c *gin.Context) {
+	ret, err := ctr.service.Get()
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(200, ret)
+}
+
+
+//POST /api/users
+func (ctr *controller) Post(c *gin.Context) {
+	var req PostBody
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(module.NewBindError(err, &req))
+		return
+	}
+
+	ret, err := ctr.service.Create(audit.GetActor(c), req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(200, ret)
+}
+
+
+//PUT /api/users/:user_id (ロールの変更)
+func (ctr *controller) Put(c *gin.Context) {
+	var key Key
+	if err := c.ShouldBindUri(&key); err != nil {
+		c.Error(module.NewBindError(err, &key))
+		return
+	}
+
+	var req PutBody
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(module.NewBindError(err, &req))
+		return
+	}
+
+	ret, err := ctr.service.UpdateRole(audit.GetActor(c), key, req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(200, ret)
+}
+
+
+//PUT /api/users/:user_id/password (パスワードの再設定)
+func (ctr *controller) PutPassword(c *gin.Context) {
+	var key Key
+	if err := c.ShouldBindUri(&key); err != nil {
+		c.Error(module.NewBindError(err, &key))
+		return
+	}
+
+	var req PasswordBody
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(module.NewBindError(err, &req))
+		return
+	}
+
+	if err := ctr.service.ResetPassword(audit.GetActor(c), key, req); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(200, gin.H{})
+}
+
+
+//DELETE /api/users/:user_id
+func (ctr *controller) Delete(c *gin.Context) {
+	var key Key
+	if err := c.ShouldBindUri(&key); err != nil {
+		c.Error(module.NewBindError(err, &key))
+		return
+	}
+
+	if err := ctr.service.Delete(audit.GetActor(c), key); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(200, gin.H{})
+}
+
+
+//PUT /api/password (ログインユーザ自身のパスワード変更)
+func (ctr *controller) ChangePassword(c *gin.Context) {
+	var req ChangePasswordBody
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(module.NewBindError(err, &req))
+		return
+	}
+
+	if err := ctr.service.ChangePassword(audit.GetActor(c), req); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(200, gin.H{})
+}
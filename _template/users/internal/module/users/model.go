@@ -0,0 +1,10 @@
+package users
+
+type User struct {
+	UserId int `db:"user_id" json:"user_id"`
+	UserName string `db:"user_name" json:"user_name"`
+	PasswordHash string `db:"password_hash" json:"-"`
+	Role string `db:"role" json:"role"`
+	CreatedAt string `db:"created_at" json:"created_at"`
+	UpdatedAt string `db:"updated_at" json:"updated_at"`
+}
@@ -0,0 +1,170 @@
+package users
+
+import (
+	"database/sql"
+	"masmaint/internal/core/db"
+)
+
+
+type Repository interface {
+	Get() ([]User, error)
+	GetOne(userId int, tx *sql.Tx) (User, error)
+	GetByName(userName string) (User, error)
+	Insert(u *User, tx *sql.Tx) (int, error)
+	Update(u *User, tx *sql.Tx) error
+	Delete(userId int, tx *sql.Tx) error
+}
+
+
+type repository struct {
+	db *db.DB
+}
+
+func NewRepository(db *db.DB) Repository {
+	return &repository{db}
+}
+
+
+const selectQuery =
+`SELECT
+	user_id
+	,user_name
+	,password_hash
+	,role
+	,created_at
+	,updated_at
+ FROM users`
+
+
+func (rep *repository) Get() ([]User, error) {
+	rows, err := rep.db.Query(selectQuery + " ORDER BY user_id")
+	if err != nil {
+		return []User{}, err
+	}
+	defer rows.Close()
+
+	ret := []User{}
+	for rows.Next() {
+		u := User{}
+		err = rows.Scan(
+			&u.UserId,
+			&u.UserName,
+			&u.PasswordHash,
+			&u.Role,
+			&u.CreatedAt,
+			&u.UpdatedAt,
+		)
+		if err != nil {
+			return []User{}, err
+		}
+		ret = append(ret, u)
+	}
+
+	return ret, nil
+}
+
+
+func (rep *repository) GetOne(userId int, tx *sql.Tx) (User, error) {
+	query := rep.db.Rebind(selectQuery + " WHERE user_id = ?")
+
+	var row *sql.Row
+	if tx != nil {
+		row = tx.QueryRow(query, userId)
+	} else {
+		row = rep.db.QueryRow(query, userId)
+	}
+	return scanOne(row)
+}
+
+
+func (rep *repository) GetByName(userName string) (User, error) {
+	return scanOne(rep.db.QueryRow(rep.db.Rebind(selectQuery + " WHERE user_name = ?"), userName))
+}
+
+
+//登録して user_id を返す (RDBMSに依存しないよう user_name で取得し直す)
+func (rep *repository) Insert(u *User, tx *sql.Tx) (int, error) {
+	cmd := 
+	`INSERT INTO users (
+		user_name
+		,password_hash
+		,role
+		,created_at
+		,updated_at
+	 ) VALUES(?,?,?,?,?)`
+	binds := []interface{}{
+		u.UserName,
+		u.PasswordHash,
+		u.Role,
+		u.CreatedAt,
+		u.UpdatedAt,
+	}
+	query := rep.db.Rebind("SELECT user_id FROM users WHERE user_name = ?")
+
+	var userId int
+	var err error
+	if tx != nil {
+		if _, err = tx.Exec(rep.db.Rebind(cmd), binds...); err == nil {
+			err = tx.QueryRow(query, u.UserName).Scan(&userId)
+		}
+	} else {
+		if _, err = rep.db.Exec(rep.db.Rebind(cmd), binds...); err == nil {
+			err = rep.db.QueryRow(query, u.UserName).Scan(&userId)
+		}
+	}
+
+	return userId, err
+}
+
+
+func (rep *repository) Update(u *User, tx *sql.Tx) error {
+	cmd := 
+	`UPDATE users
+	 SET password_hash = ?
+		,role = ?
+		,updated_at = ?
+	 WHERE user_id = ?`
+	binds := []interface{}{
+		u.PasswordHash,
+		u.Role,
+		u.UpdatedAt,
+		u.UserId,
+	}
+
+	var err error
+	if tx != nil {
+		_, err = tx.Exec(rep.db.Rebind(cmd), binds...)
+	} else {
+		_, err = rep.db.Exec(rep.db.Rebind(cmd), binds...)
+	}
+
+	return err
+}
+
+
+func (rep *repository) Delete(userId int, tx *sql.Tx) error {
+	cmd := rep.db.Rebind("DELETE FROM users WHERE user_id = ?")
+
+	var err error
+	if tx != nil {
+		_, err = tx.Exec(cmd, userId)
+	} else {
+		_, err = rep.db.Exec(cmd, userId)
+	}
+
+	return err
+}
+
+
+func scanOne(row *sql.Row) (User, error) {
+	var u User
+	err := row.Scan(
+		&u.UserId,
+		&u.UserName,
+		&u.PasswordHash,
+		&u.Role,
+		&u.CreatedAt,
+		&u.UpdatedAt,
+	)
+	return u, err
+}
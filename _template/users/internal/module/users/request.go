@@ -0,0 +1,32 @@
+package users
+
+type LoginBody struct {
+	Username string `json:"username" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+type PostBody struct {
+	UserName string `json:"user_name" binding:"required,max=255"`
+	Password string `json:"password" binding:"required,min=8,max=72"`
+	Role string `json:"role" binding:"required"`
+}
+
+//ロールの変更
+type PutBody struct {
+	Role string `json:"role" binding:"required"`
+}
+
+//管理者によるパスワードの再設定
+type PasswordBody struct {
+	Password string `json:"password" binding:"required,min=8,max=72"`
+}
+
+//ログインユーザ自身のパスワード変更
+type ChangePasswordBody struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	Password string `json:"password" binding:"required,min=8,max=72"`
+}
+
+type Key struct {
+	UserId int `uri:"user_id" binding:"required"`
+}
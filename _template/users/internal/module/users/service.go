@@ -0,0 +1,247 @@
+package users
+
+import (
+	"strconv"
+	"database/sql"
+	"golang.org/x/crypto/bcrypt"
+
+	"masmaint/internal/module"
+	"masmaint/internal/core/db"
+	"masmaint/internal/core/jwt"
+	"masmaint/internal/core/logger"
+	"masmaint/internal/core/utils"
+	"masmaint/internal/core/errs"
+	"masmaint/internal/module/audit"
+	"masmaint/internal/module/permission"
+)
+
+
+//監査ログのテーブル名 (パスワードのハッシュは記録しない)
+const TABLE_NAME = "users"
+
+type Service interface {
+	Login(input LoginBody) (User, error)
+	Reload(cc jwt.CustomClaims) (jwt.CustomClaims, error)
+	Get() ([]User, error)
+	Create(actor audit.Actor, input PostBody) (User, error)
+	UpdateRole(actor audit.Actor, key Key, input PutBody) (User, error)
+	ResetPassword(actor audit.Actor, key Key, input PasswordBody) error
+	ChangePassword(actor audit.Actor, input ChangePasswordBody) error
+	Delete(actor audit.Actor, key Key) error
+}
+
+type service struct {
+	db *db.DB
+	repository Repository
+}
+
+func NewService(db *db.DB, repository Repository) Service {
+	return &service{
+		db: db,
+		repository: repository,
+	}
+}
+
+
+//ユーザが存在しない場合も照合を行い、応答時間からユーザの有無を推測されないようにする
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("dummy-password"), bcrypt.DefaultCost)
+
+func (srv *service) Login(input LoginBody) (User, error) {
+	u, err := srv.repository.GetByName(input.Username)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			logger.Error(err.Error())
+			return User{}, errs.NewUnexpectedError(err.Error())
+		}
+		bcrypt.CompareHashAndPassword(dummyHash, []byte(input.Password))
+		return User{}, errs.NewUnauthorizedError()
+	}
+
+	if bcrypt.CompareHashAndPassword([]byte(u.PasswordHash), []byte(input.Password)) != nil {
+		return User{}, errs.NewUnauthorizedError()
+	}
+	return u, nil
+}
+
+
+//セッションのユーザを読み込み直す (削除されたユーザは認証しない、ロールは現在のロールとする)
+func (srv *service) Reload(cc jwt.CustomClaims) (jwt.CustomClaims, error) {
+	u, err := srv.repository.GetOne(cc.AccountId, nil)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			logger.Error(err.Error())
+			return cc, errs.NewUnexpectedError(err.Error())
+		}
+		return cc, errs.NewUnauthorizedError()
+	}
+	cc.Role = u.Role
+	return cc, nil
+}
+
+
+func (srv *service) Get() ([]User, error) {
+	rows, err := srv.repository.Get()
+	if err != nil {
+		logger.Error(err.Error())
+		return []User{}, errs.NewUnexpectedError(err.Error())
+	}
+	return rows, nil
+}
+
+
+func (srv *service) Create(actor audit.Actor, input PostBody) (User, error) {
+	if !permission.IsRole(input.Role) {
+		return User{}, errs.NewBadRequestError("role")
+	}
+	hash, err := hashPassword(input.Password)
+	if err != nil {
+		return User{}, err
+	}
+
+	now := utils.NowString()
+	model := User{
+		UserName: input.UserName,
+		PasswordHash: hash,
+		Role: input.Role,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	var row User
+	err = module.RunInTx(srv.db, func(tx *sql.Tx) error {
+		userId, err := srv.repository.Insert(&model, tx)
+		if err != nil {
+			return err
+		}
+		row, err = srv.repository.GetOne(userId, tx)
+		if err != nil {
+			return err
+		}
+		return writeAudit(srv.db, tx, actor, audit.OperationInsert, nil, &row)
+	})
+	if err != nil {
+		return User{}, module.NewDBError(err)
+	}
+	return row, nil
+}
+
+
+//自身のロールは変更不可 (管理者が不在になるのを防ぐ)
+func (srv *service) UpdateRole(actor audit.Actor, key Key, input PutBody) (User, error) {
+	if !permission.IsRole(input.Role) {
+		return User{}, errs.NewBadRequestError("role")
+	}
+	if key.UserId == actor.AccountId {
+		return User{}, errs.NewForbiddenError()
+	}
+
+	var row User
+	err := srv.update(actor, key.UserId, func(u *User) error {
+		u.Role = input.Role
+		return nil
+	}, &row)
+	if err != nil {
+		return User{}, err
+	}
+	return row, nil
+}
+
+
+func (srv *service) ResetPassword(actor audit.Actor, key Key, input PasswordBody) error {
+	hash, err := hashPassword(input.Password)
+	if err != nil {
+		return err
+	}
+	return srv.update(actor, key.UserId, func(u *User) error {
+		u.PasswordHash = hash
+		return nil
+	}, nil)
+}
+
+
+func (srv *service) ChangePassword(actor audit.Actor, input ChangePasswordBody) error {
+	hash, err := hashPassword(input.Password)
+	if err != nil {
+		return err
+	}
+	return srv.update(actor, actor.AccountId, func(u *User) error {
+		if bcrypt.CompareHashAndPassword([]byte(u.PasswordHash), []byte(input.CurrentPassword)) != nil {
+			return errs.NewBadRequestError("current_password")
+		}
+		u.PasswordHash = hash
+		return nil
+	}, nil)
+}
+
+
+//自身は削除不可
+func (srv *service) Delete(actor audit.Actor, key Key) error {
+	if key.UserId == actor.AccountId {
+		return errs.NewForbiddenError()
+	}
+
+	err := module.RunInTx(srv.db, func(tx *sql.Tx) error {
+		before, err := srv.repository.GetOne(key.UserId, tx)
+		if err != nil {
+			return err
+		}
+		if err := srv.repository.Delete(key.UserId, tx); err != nil {
+			return err
+		}
+		return writeAudit(srv.db, tx, actor, audit.OperationDelete, &before, nil)
+	})
+	return module.NewDBError(err)
+}
+
+
+//取得した行を fn で変更して更新し、監査ログを記録 (ret を指定した場合は更新後の行を設定)
+func (srv *service) update(actor audit.Actor, userId int, fn func(u *User) error, ret *User) error {
+	err := module.RunInTx(srv.db, func(tx *sql.Tx) error {
+		before, err := srv.repository.GetOne(userId, tx)
+		if err != nil {
+			return err
+		}
+		model := before
+		if err := fn(&model); err != nil {
+			return err
+		}
+		model.UpdatedAt = utils.NowString()
+		if err := srv.repository.Update(&model, tx); err != nil {
+			return err
+		}
+
+		row, err := srv.repository.GetOne(userId, tx)
+		if err != nil {
+			return err
+		}
+		if ret != nil {
+			*ret = row
+		}
+		return writeAudit(srv.db, tx, actor, audit.OperationUpdate, &before, &row)
+	})
+	return module.NewDBError(err)
+}
+
+
+func hashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", errs.NewBadRequestError("password")
+	}
+	return string(hash), nil
+}
+
+
+func writeAudit(conn *db.DB, tx *sql.Tx, actor audit.Actor, operation string, before *User, after *User) error {
+	var userId int
+	var b, a interface{}
+	if before != nil {
+		userId = before.UserId
+		b = before
+	}
+	if after != nil {
+		userId = after.UserId
+		a = after
+	}
+	return audit.Write(conn, tx, actor, TABLE_NAME, strconv.Itoa(userId), operation, b, a)
+}
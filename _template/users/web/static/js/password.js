@@ -0,0 +1,42 @@
+import { api } from '/js/api.js';
+
+
+document.addEventListener('DOMContentLoaded', () => {
+    document.getElementById('password-form').addEventListener('submit', (event) => {
+        event.preventDefault();
+        changePassword();
+    });
+});
+
+
+const changePassword = async () => {
+    const form = document.getElementById('password-form');
+    const password = document.getElementById('password').value;
+    if (password !== document.getElementById('password_confirm').value) {
+        renderMessage('新しいパスワードが一致しません。', 'danger');
+        return;
+    }
+
+    try {
+        await api.put('password', {
+            current_password: document.getElementById('current_password').value,
+            password: password,
+        });
+        form.reset();
+        renderMessage('パスワードを変更しました。', 'success');
+    } catch (e) {
+        const field = e.details && e.details.field;
+        renderMessage((field === 'current_password')
+            ? '現在のパスワードが異なります。'
+            : (field === 'password')
+            ? '新しいパスワードは8文字以上72文字以下で指定してください。'
+            : 'パスワードを変更できませんでした。', 'danger');
+    }
+}
+
+const renderMessage = (msg, type) => {
+    const div = document.createElement('div');
+    div.className = `alert alert-${type} alert-custom my-1`;
+    div.textContent = msg;
+    document.getElementById('message').replaceChildren(div);
+}
@@ -0,0 +1,153 @@
+import { api } from '/js/api.js';
+import { can } from './permission.js';
+
+
+document.addEventListener('DOMContentLoaded', () => {
+    const form = document.getElementById('user-new');
+    if (form) {
+        form.addEventListener('submit', (event) => {
+            event.preventDefault();
+            postUser();
+        });
+    }
+    getRows();
+});
+
+
+const getRows = async () => {
+    try {
+        const rows = await api.get('users');
+        renderRows(rows);
+    } catch (e) {
+        renderMessage('ユーザを取得できませんでした。', 'danger');
+    }
+}
+
+const postUser = async () => {
+    clearMessage();
+    const body = {
+        user_name: document.getElementById('user-new-name').value.trim(),
+        password: document.getElementById('user-new-password').value,
+        role: document.getElementById('user-new-role').value,
+    };
+    try {
+        await api.post('users', body);
+        document.getElementById('user-new').reset();
+        renderMessage('登録しました。', 'success');
+        getRows();
+    } catch (e) {
+        renderMessage(errorMessage(e, '登録'), 'danger');
+    }
+}
+
+const putRole = async (row, select) => {
+    clearMessage();
+    try {
+        await api.put(`users/${row.user_id}`, { role: select.value });
+        renderMessage(`${row.user_name} のロールを変更しました。`, 'success');
+        getRows();
+    } catch (e) {
+        select.value = row.role;
+        renderMessage(errorMessage(e, 'ロールの変更'), 'danger');
+    }
+}
+
+const putPassword = async (row, input) => {
+    clearMessage();
+    try {
+        await api.put(`users/${row.user_id}/password`, { password: input.value });
+        input.value = '';
+        renderMessage(`${row.user_name} のパスワードを再設定しました。`, 'success');
+    } catch (e) {
+        renderMessage(errorMessage(e, 'パスワードの再設定'), 'danger');
+    }
+}
+
+const deleteUser = async (row) => {
+    if (!window.confirm(`${row.user_name} を削除します。よろしいですか？`)) {
+        return;
+    }
+    clearMessage();
+    try {
+        await api.delete(`users/${row.user_id}`);
+        renderMessage(`${row.user_name} を削除しました。`, 'success');
+        getRows();
+    } catch (e) {
+        renderMessage(errorMessage(e, '削除'), 'danger');
+    }
+}
+
+const errorMessage = (e, action) => {
+    if (e.status === 403) {
+        return `${action}の権限がありません。（自身のロールの変更・削除はできません）`;
+    }
+    if (e.status === 409) {
+        return '同じユーザ名が既に登録されています。';
+    }
+    if (e.details && e.details.field) {
+        return `${e.details.field} が不正です。`;
+    }
+    return `${action}に失敗しました。`;
+}
+
+const renderRows = (rows) => {
+    const tbody = document.getElementById('records');
+    tbody.replaceChildren();
+    for (const row of rows) {
+        const tr = tbody.insertRow();
+        tr.insertCell().textContent = row.user_id;
+        tr.insertCell().textContent = row.user_name;
+        tr.insertCell().appendChild(createRoleSelect(row));
+        tr.insertCell().appendChild(createPasswordInput(row));
+        tr.insertCell().textContent = row.updated_at;
+        tr.insertCell().appendChild(createDeleteButton(row));
+    }
+}
+
+const createRoleSelect = (row) => {
+    const select = document.createElement('select');
+    select.className = 'form-select form-select-sm';
+    select.appendChild(document.getElementById('role-options').content.cloneNode(true));
+    select.value = row.role;
+    select.disabled = !can('update');
+    select.addEventListener('change', () => putRole(row, select));
+    return select;
+}
+
+const createPasswordInput = (row) => {
+    const group = document.createElement('div');
+    group.className = 'input-group input-group-sm';
+    const input = document.createElement('input');
+    input.type = 'password';
+    input.className = 'form-control';
+    input.autocomplete = 'new-password';
+    const button = document.createElement('button');
+    button.type = 'button';
+    button.className = 'btn btn-outline-secondary';
+    button.textContent = '再設定';
+    button.addEventListener('click', () => putPassword(row, input));
+    input.disabled = button.disabled = !can('update');
+    group.append(input, button);
+    return group;
+}
+
+const createDeleteButton = (row) => {
+    const button = document.createElement('button');
+    button.type = 'button';
+    button.className = 'btn btn-outline-danger btn-sm py-0';
+    button.textContent = '削除';
+    button.disabled = !can('delete');
+    button.addEventListener('click', () => deleteUser(row));
+    return button;
+}
+
+const renderMessage = (msg, type) => {
+    const div = document.createElement('div');
+    div.className = `alert alert-${type} alert-custom my-1`;
+    div.textContent = msg;
+    document.getElementById('message').replaceChildren(div);
+}
+
+const clearMessage = () => {
+    document.getElementById('message').replaceChildren();
+}
@@ -31,6 +31,9 @@ type Config struct {
 
 	JwtSecretKey string
 	LogLevel string
+
+	//データベースから生成する際に SQLite のファイルを開けるディレクトリ (空の場合はアップロードのみ)
+	SqliteDir string
 }
 
 var cf Config
@@ -64,6 +67,8 @@ func init() {
 
 	cf.JwtSecretKey = os.Getenv("JWT_SECRET_KEY")
 	cf.LogLevel = os.Getenv("LOG_LEVEL")
+
+	cf.SqliteDir = os.Getenv("SQLITE_DIR")
 }
 
 
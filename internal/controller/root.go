@@ -2,10 +2,20 @@ package controller
 
 import (
 	"io"
+	"fmt"
+	"errors"
+	"os"
 	"bytes"
+	"strings"
+	"path/filepath"
 	"github.com/gin-gonic/gin"
 
+	"masmaint-cg/config"
+	"masmaint-cg/internal/core/db"
+	"masmaint-cg/internal/core/logger"
 	"masmaint-cg/internal/module/generator"
+	"masmaint-cg/internal/module/introspect"
+	"masmaint-cg/internal/module/input"
 )
 
 type RootController struct {}
@@ -23,45 +33,228 @@ func (ctr *RootController) IndexPage(c *gin.Context) {
 
 //POST /generate
 func (ctr *RootController) PostGenerate(c *gin.Context) {
-	ddlFile, err := c.FormFile("ddl")
 	//lang := c.PostForm("lang")
 	rdbms := c.PostForm("rdbms")
 
+	option := generator.Option{
+		BodyKeyRoutes: c.PostForm("body_key_routes") == "true",
+		History: c.PostForm("history") == "true",
+		SoftDeleteColumn: c.PostForm("soft_delete_column"),
+		AuthMode: c.PostForm("auth_mode"),
+		ApiTokens: c.PostForm("api_tokens") == "true",
+		Merge: c.PostForm("merge") == "true",
+	}
+
+	var def input.Definition
+	var err error
+	switch c.PostForm("source") {
+	case "db":
+		def, err = ctr.readDefinitionFromDB(c, rdbms)
+	case "tabledef":
+		def, err = ctr.readDefinitionFromFile(c, "tabledef", rdbms)
+	default:
+		def, err = ctr.readDefinitionFromFile(c, "ddl", rdbms)
+	}
+	if err == nil {
+		option.Previous, err = ctr.readPrevious(c, rdbms)
+	}
 	if err != nil {
-		c.JSON(400, gin.H{"errors":[]string{"ファイルを取得できませんでした。"}})
+		var rowErrs input.RowErrors
+		if errors.As(err, &rowErrs) {
+			c.JSON(400, gin.H{"errors":rowErrs.Messages()})
+			return
+		}
+		c.JSON(400, gin.H{"errors":[]string{err.Error()}})
 		return
 	}
-	
-	file, err := ddlFile.Open()
+
+	gen, err := generator.NewGenerator(def, rdbms, option)
 	if err != nil {
-		c.JSON(500, gin.H{"errors":[]string{"ファイルを開けませんでした。"}})
+		c.JSON(400, gin.H{"errors":[]string{err.Error()}})
 		return
 	}
-	defer file.Close()
-	
-	buf := new(bytes.Buffer)
-	if _, err := io.Copy(buf, file); err != nil {
-		c.JSON(500, gin.H{"errors":[]string{"ファイル内容を読み込めませんでした。"}})
+	zip, err := gen.Generate()
+	if err != nil {
+		c.JSON(500, gin.H{"errors":[]string{"生成に失敗しました。"}})
 		return
 	}
-	
-	ddl := buf.String()
 
+	c.JSON(200, gin.H{"zip": zip, "notes": def.Notes, "merge": gen.MergeReport()})
+}
+
+
+/*
+ ファイルからテーブル定義を読み取る (形式は拡張子で判定)
+ ddl: DDL (.sql、拡張子が不明な場合を含む)・DBML (.dbml)・Prisma (.prisma)
+      DDLのRDBMS (ddl_rdbms) が rdbms と異なる場合は rdbms のテーブル定義に変換する
+ tabledef: テーブル定義書 (.csv / .xlsx)
+ previous: 前回の入力 (いずれの形式も可、拡張子が不明な場合はDDL)
+*/
+func (ctr *RootController) readDefinitionFromFile(c *gin.Context, name string, rdbms string) (input.Definition, error) {
+	fh, err := c.FormFile(name)
 	if err != nil {
-		c.JSON(400, gin.H{"errors": []string{err.Error()}})
-		return
+		return input.Definition{}, fmt.Errorf("ファイルを取得できませんでした。")
+	}
+	format := input.FormatOf(fh.Filename)
+	if name == "tabledef" && format != input.FormatCsv && format != input.FormatXlsx {
+		return input.Definition{}, fmt.Errorf("テーブル定義書は CSV または XLSX を指定してください。")
 	}
-	gen, err := generator.NewGenerator(ddl, rdbms)
+	if format == "" || (name == "ddl" && (format == input.FormatCsv || format == input.FormatXlsx)) {
+		format = input.FormatDdl
+	}
+
+	b, err := readFormFile(c, name)
 	if err != nil {
-		c.JSON(400, gin.H{"errors":[]string{err.Error()}})
-		return
+		return input.Definition{}, err
+	}
+	if from := c.PostForm("ddl_rdbms"); format == input.FormatDdl && from != "" && from != rdbms {
+		return input.Translate(b, from, rdbms)
 	}
+	return input.Read(format, b, rdbms)
+}
 
-	zip, err := gen.Generate()
+
+/*
+ 前回の生成の入力 (previous、指定がない場合は nil)
+ 前回生成したzip (.zip) の場合は scripts/create-table.sql・scripts/migrations とプロジェクトのファイル (マージに使用)、
+ それ以外は前回の入力ファイル
+*/
+func (ctr *RootController) readPrevious(c *gin.Context, rdbms string) (*generator.Previous, error) {
+	fh, err := c.FormFile("previous")
 	if err != nil {
-		c.JSON(500, gin.H{"errors":[]string{"生成に失敗しました。"}})
-		return
+		return nil, nil
+	}
+	if strings.ToLower(filepath.Ext(fh.Filename)) == ".zip" {
+		b, err := readFormFile(c, "previous")
+		if err != nil {
+			return nil, err
+		}
+		prev, err := generator.ReadPreviousZip(b)
+		if err != nil {
+			return nil, err
+		}
+		return &prev, nil
+	}
+	def, err := ctr.readDefinitionFromFile(c, "previous", rdbms)
+	if err != nil {
+		return nil, fmt.Errorf("前回の入力: %s", err.Error())
+	}
+	return &generator.Previous{Definition: &def}, nil
+}
+
+
+/*
+ データベースに接続してテーブル定義を読み取る
+ SQLite はファイルのアップロード (db_file) または config の SQLITE_DIR 配下のパス (db_name)
+*/
+func (ctr *RootController) readDefinitionFromDB(c *gin.Context, rdbms string) (input.Definition, error) {
+	settings := db.Settings{
+		Name: c.PostForm("db_name"),
+		Host: c.PostForm("db_host"),
+		Port: c.PostForm("db_port"),
+		User: c.PostForm("db_user"),
+		Pass: c.PostForm("db_password"),
+		SslMode: c.PostForm("db_sslmode"),
 	}
-	 
-	c.JSON(200, gin.H{"zip": zip})
-}
\ No newline at end of file
+	switch rdbms {
+	case "postgresql":
+		settings.Driver = "postgres"
+	case "mysql":
+		settings.Driver = "mysql"
+	case "sqlite3":
+		settings.Driver = "sqlite3"
+		settings.ReadOnly = true
+		if _, err := c.FormFile("db_file"); err == nil {
+			path, err := saveFormFile(c, "db_file")
+			if err != nil {
+				return input.Definition{}, err
+			}
+			defer os.Remove(path)
+			settings.Name = path
+		} else if settings.Name != "" {
+			path, err := sqlitePath(settings.Name)
+			if err != nil {
+				return input.Definition{}, err
+			}
+			settings.Name = path
+		}
+	default:
+		return input.Definition{}, fmt.Errorf("RDBMS '%s' は指定できません。", rdbms)
+	}
+	if settings.Name == "" {
+		return input.Definition{}, fmt.Errorf("データベース名（SQLiteはファイル）が指定されていません。")
+	}
+
+	conn, err := db.Open(settings)
+	if err != nil {
+		logger.Error(err.Error())
+		return input.Definition{}, fmt.Errorf("データベースに接続できませんでした。（%s）", err.Error())
+	}
+	defer conn.Close()
+
+	tables := []string{}
+	for _, t := range strings.Split(c.PostForm("db_tables"), ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			tables = append(tables, t)
+		}
+	}
+	result, err := introspect.Read(conn, rdbms, c.PostForm("db_schema"), tables)
+	if err != nil {
+		logger.Error(err.Error())
+		return input.Definition{}, fmt.Errorf("テーブル定義を読み取れませんでした。（%s）", err.Error())
+	}
+	return input.Definition{Tables: result.Tables, Comments: result.Comments}, nil
+}
+
+
+/*
+ サーバ上の SQLite ファイルのパス
+ config の SQLITE_DIR 配下の相対パスのみ (未設定の場合はアップロードのみ)
+*/
+func sqlitePath(name string) (string, error) {
+	dir := config.GetConfig().SqliteDir
+	if dir == "" {
+		return "", fmt.Errorf("SQLiteはファイルをアップロードしてください。")
+	}
+	if !filepath.IsLocal(name) {
+		return "", fmt.Errorf("SQLiteのファイル '%s' は指定できません。", name)
+	}
+	return filepath.Join(dir, name), nil
+}
+
+
+func readFormFile(c *gin.Context, name string) ([]byte, error) {
+	fh, err := c.FormFile(name)
+	if err != nil {
+		return nil, fmt.Errorf("ファイルを取得できませんでした。")
+	}
+	file, err := fh.Open()
+	if err != nil {
+		return nil, fmt.Errorf("ファイルを開けませんでした。")
+	}
+	defer file.Close()
+
+	buf := new(bytes.Buffer)
+	if _, err := io.Copy(buf, file); err != nil {
+		return nil, fmt.Errorf("ファイル内容を読み込めませんでした。")
+	}
+	return buf.Bytes(), nil
+}
+
+//一時ファイルに保存してパスを返す (呼び出し側で削除すること)
+func saveFormFile(c *gin.Context, name string) (string, error) {
+	b, err := readFormFile(c, name)
+	if err != nil {
+		return "", err
+	}
+	f, err := os.CreateTemp("", "masmaint-cg-*.db")
+	if err != nil {
+		return "", fmt.Errorf("ファイルを保存できませんでした。")
+	}
+	defer f.Close()
+	if _, err := f.Write(b); err != nil {
+		os.Remove(f.Name())
+		return "", fmt.Errorf("ファイルを保存できませんでした。")
+	}
+	return f.Name(), nil
+}
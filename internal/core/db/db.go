@@ -3,12 +3,17 @@ package db
 import (
 	"log"
 	"fmt"
+	"net"
+	"time"
+	"context"
 	"reflect"
+	"strconv"
 	"strings"	
+	"net/url"
 	"database/sql"
 	
 	_ "github.com/mattn/go-sqlite3"
-	_ "github.com/go-sql-driver/mysql"
+	"github.com/go-sql-driver/mysql"
 	_ "github.com/lib/pq"
 
 	"masmaint-cg/config"
@@ -24,21 +29,16 @@ func init() {
 
 	cf := config.GetConfig()
 	driver = cf.DBDriver
-	var dsn string
-
-	if driver == "sqlite3" {
-		dsn = cf.DBName
-	} else if driver == "mysql" {
-		dsn = fmt.Sprintf(
-			"%s:%s@tcp(%s:%s)/%s", 
-			cf.DBUser, cf.DBPass, cf.DBHost, cf.DBPort, cf.DBName,
-		)
-	} else if driver == "postgres" {
-		dsn = fmt.Sprintf(
-			"host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
-			cf.DBHost, cf.DBPort, cf.DBUser, cf.DBPass, cf.DBName,
-		)
-	} else {
+
+	dsn, err := Dsn(Settings{
+		Driver: cf.DBDriver,
+		Name: cf.DBName,
+		Host: cf.DBHost,
+		Port: cf.DBPort,
+		User: cf.DBUser,
+		Pass: cf.DBPass,
+	})
+	if err != nil {
 		log.Panic("Error: must specify a valid DB_DRIVER: 'postgres', 'mysql', or 'sqlite3'.")
 	}
 
@@ -48,6 +48,83 @@ func init() {
 	}
 }
 
+
+//接続設定
+type Settings struct {
+	//postgres / mysql / sqlite3
+	Driver string
+	//sqlite3 はファイルのパス
+	Name string
+	Host string
+	Port string
+	User string
+	Pass string
+	//PostgreSQL の sslmode (空の場合は disable)
+	SslMode string
+	//sqlite3 を読み取り専用で開く (ファイルが無い場合に作成しない)
+	ReadOnly bool
+}
+
+//接続の確認までの待ち時間
+const CONNECT_TIMEOUT time.Duration = 10 * time.Second
+
+
+func Dsn(s Settings) (string, error) {
+	if s.Driver == "sqlite3" {
+		if s.ReadOnly {
+			return fmt.Sprintf("file:%s?mode=ro", url.PathEscape(s.Name)), nil
+		}
+		return s.Name, nil
+	} else if s.Driver == "mysql" {
+		mc := mysql.NewConfig()
+		mc.User = s.User
+		mc.Passwd = s.Pass
+		mc.Net = "tcp"
+		mc.Addr = net.JoinHostPort(s.Host, s.Port)
+		mc.DBName = s.Name
+		mc.Timeout = CONNECT_TIMEOUT
+		return mc.FormatDSN(), nil
+	} else if s.Driver == "postgres" {
+		sslmode := s.SslMode
+		if sslmode == "" {
+			sslmode = "disable"
+		}
+		u := url.URL{
+			Scheme: "postgres",
+			User: url.UserPassword(s.User, s.Pass),
+			Host: net.JoinHostPort(s.Host, s.Port),
+			Path: "/" + s.Name,
+			RawQuery: url.Values{
+				"sslmode": {sslmode},
+				"connect_timeout": {strconv.Itoa(int(CONNECT_TIMEOUT.Seconds()))},
+			}.Encode(),
+		}
+		return u.String(), nil
+	}
+	return "", fmt.Errorf("unsupported driver: %s", s.Driver)
+}
+
+
+//設定のデータベースに接続 (接続を確認してから返す)
+func Open(s Settings) (*sql.DB, error) {
+	dsn, err := Dsn(s)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := sql.Open(s.Driver, dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), CONNECT_TIMEOUT)
+	defer cancel()
+	if err := conn.PingContext(ctx); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
 func GetDB() *sql.DB {
 	return db
 }
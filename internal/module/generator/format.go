@@ -6,21 +6,25 @@ const FORMAT_CONTROLLER =
 import (
 	"github.com/gin-gonic/gin"
 	"masmaint/internal/module"
+	"masmaint/internal/core/errs"
+	"masmaint/internal/core/csvutil"
+	"masmaint/internal/core/xlsx"
+	"masmaint/internal/module/audit"
+	"masmaint/internal/module/permission"
 )
 
 type controller struct {
 	service Service
 }
 
-func NewController() *controller {
-	service := NewService()
+func NewController(service Service) *controller {
 	return &controller{service}
 }
 
 
 //GET /%s
 func (ctr *controller) GetPage(c *gin.Context) {
-	c.HTML(200, "%s.html", gin.H{})
+	c.HTML(200, "%s.html", gin.H{"perm": permission.Get(c, "%s")})
 }
 
 
@@ -36,6 +40,24 @@ func (ctr *controller) Get(c *gin.Context) {
 }
 
 
+//GET /api/%s%s
+func (ctr *controller) GetOne(c *gin.Context) {
+	var key Key
+	if err := c.ShouldBindUri(&key); err != nil {
+		c.Error(module.NewBindError(err, &key))
+		return
+	}
+
+	ret, err := ctr.service.GetOne(key)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(200, ret)
+}
+
+
 //POST /api/%s
 func (ctr *controller) Post(c *gin.Context) {
 	var req PostBody
@@ -44,7 +66,7 @@ func (ctr *controller) Post(c *gin.Context) {
 		return
 	}
 
-	ret, err := ctr.service.Create(req)
+	ret, err := ctr.service.Create(audit.GetActor(c), req)
 	if err != nil {
 		c.Error(err)
 		return
@@ -54,15 +76,21 @@ func (ctr *controller) Post(c *gin.Context) {
 }
 
 
-//PUT /api/%s
+//PUT /api/%s%s
 func (ctr *controller) Put(c *gin.Context) {
+	var key Key
+	if err := c.ShouldBindUri(&key); err != nil {
+		c.Error(module.NewBindError(err, &key))
+		return
+	}
+
 	var req PutBody
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.Error(module.NewBindError(err, &req))
 		return
 	}
 
-	ret, err := ctr.service.Update(req)
+	ret, err := ctr.service.Update(audit.GetActor(c), key, req)
 	if err != nil {
 		c.Error(err)
 		return
@@ -72,15 +100,147 @@ func (ctr *controller) Put(c *gin.Context) {
 }
 
 
-//DELETE /api/%s
-func (ctr *controller) Delete(c *gin.Context) {
-	var req DeleteBody
+//PATCH /api/%s%s
+func (ctr *controller) Patch(c *gin.Context) {
+	var key Key
+	if err := c.ShouldBindUri(&key); err != nil {
+		c.Error(module.NewBindError(err, &key))
+		return
+	}
+
+	var req PatchBody
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.Error(module.NewBindError(err, &req))
 		return
 	}
 
-	if err := ctr.service.Delete(req); err != nil {
+	ret, err := ctr.service.Patch(audit.GetActor(c), key, req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(200, ret)
+}
+
+
+//DELETE /api/%s%s
+func (ctr *controller) Delete(c *gin.Context) {
+	var key Key
+	if err := c.ShouldBindUri(&key); err != nil {
+		c.Error(module.NewBindError(err, &key))
+		return
+	}
+
+	if err := ctr.service.Delete(audit.GetActor(c), key); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(200, gin.H{})
+}
+
+
+//GET /api/%s/_export.csv?encoding=utf8|utf8bom|sjis
+func (ctr *controller) ExportCsv(c *gin.Context) {
+	encoding := c.DefaultQuery("encoding", csvutil.EncodingUTF8)
+	ret, err := ctr.service.ExportCsv(encoding)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Header("Content-Disposition", "attachment; filename=\"%s.csv\"")
+	c.Data(200, "text/csv; charset=" + csvutil.Charset(encoding), ret)
+}
+
+
+//GET /api/%s/_export.xlsx
+func (ctr *controller) ExportXlsx(c *gin.Context) {
+	ret, err := ctr.service.ExportXlsx()
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Header("Content-Disposition", "attachment; filename=\"%s.xlsx\"")
+	c.Data(200, xlsx.ContentType, ret)
+}
+
+
+//POST /api/%s/_import.csv (multipart: file, encoding, dry_run, delete_missing)
+func (ctr *controller) ImportCsv(c *gin.Context) {
+	fh, err := c.FormFile("file")
+	if err != nil {
+		c.Error(errs.NewBadRequestError("file"))
+		return
+	}
+	file, err := fh.Open()
+	if err != nil {
+		c.Error(errs.NewBadRequestError("file"))
+		return
+	}
+	defer file.Close()
+
+	//CSVに無い行の削除は削除権限が必要
+	deleteMissing := c.PostForm("delete_missing") == "true"
+	if deleteMissing && !permission.Can(c, "%s", permission.Delete) {
+		c.Error(errs.NewForbiddenError())
+		return
+	}
+
+	ret, err := ctr.service.ImportCsv(
+		audit.GetActor(c),
+		file,
+		c.PostForm("encoding"),
+		c.PostForm("dry_run") != "false",
+		deleteMissing,
+	)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(200, ret)
+}%s%s%s`
+
+const FORMAT_CONTROLLER_BODY_KEY =
+`
+
+
+//PUT /api/%s (互換: 主キーをボディで指定)
+func (ctr *controller) PutByBody(c *gin.Context) {
+	var key Key
+	if err := c.ShouldBindBodyWithJSON(&key); err != nil {
+		c.Error(module.NewBindError(err, &key))
+		return
+	}
+
+	var req PutBody
+	if err := c.ShouldBindBodyWithJSON(&req); err != nil {
+		c.Error(module.NewBindError(err, &req))
+		return
+	}
+
+	ret, err := ctr.service.Update(audit.GetActor(c), key, req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(200, ret)
+}
+
+
+//DELETE /api/%s (互換: 主キーをボディで指定)
+func (ctr *controller) DeleteByBody(c *gin.Context) {
+	var key Key
+	if err := c.ShouldBindBodyWithJSON(&key); err != nil {
+		c.Error(module.NewBindError(err, &key))
+		return
+	}
+
+	if err := ctr.service.Delete(audit.GetActor(c), key); err != nil {
 		c.Error(err)
 		return
 	}
@@ -89,17 +249,123 @@ func (ctr *controller) Delete(c *gin.Context) {
 }`
 
 
+const FORMAT_CONTROLLER_HISTORY =
+`
+
+
+//GET /api/%s%s/_history?at=YYYY-MM-DD[ HH:MM:SS]
+func (ctr *controller) GetHistory(c *gin.Context) {
+	var key Key
+	if err := c.ShouldBindUri(&key); err != nil {
+		c.Error(module.NewBindError(err, &key))
+		return
+	}
+
+	ret, err := ctr.service.GetHistory(key, c.Query("at"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(200, ret)
+}
+
+
+//POST /api/%s%s/_history/:history_id/restore
+func (ctr *controller) Restore(c *gin.Context) {
+	var key Key
+	if err := c.ShouldBindUri(&key); err != nil {
+		c.Error(module.NewBindError(err, &key))
+		return
+	}
+
+	var param struct {
+		HistoryId int `+"`uri:\"history_id\" binding:\"required\"`"+`
+	}
+	if err := c.ShouldBindUri(&param); err != nil {
+		c.Error(errs.NewBadRequestError("history_id"))
+		return
+	}
+
+	ret, err := ctr.service.Restore(audit.GetActor(c), key, param.HistoryId)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(200, ret)
+}`
+
+
+const FORMAT_CONTROLLER_SOFT_DELETE =
+`
+
+
+//GET /%s/trash
+func (ctr *controller) GetTrashPage(c *gin.Context) {
+	c.HTML(200, "trash.html", gin.H{"table": "%s", "keys": "%s", "perm": permission.Get(c, "%s")})
+}
+
+
+//GET /api/%s/_trash
+func (ctr *controller) GetTrash(c *gin.Context) {
+	ret, err := ctr.service.GetTrash()
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(200, ret)
+}
+
+
+//POST /api/%s%s/restore
+func (ctr *controller) Undelete(c *gin.Context) {
+	var key Key
+	if err := c.ShouldBindUri(&key); err != nil {
+		c.Error(module.NewBindError(err, &key))
+		return
+	}
+
+	ret, err := ctr.service.Undelete(audit.GetActor(c), key)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(200, ret)
+}`
+
+
 const FORMAT_MODEL = `
 package %s
 
 type %s struct {
 %s
 }
+%s`
+
+const FORMAT_MODEL_HISTORY =
+`
+
+//変更履歴 (%s_history の1行)
+type %sHistory struct {
+	HistoryId int `+"`db:\"history_id\" json:\"history_id\"`"+`
+	HistoryOperation string `+"`db:\"history_operation\" json:\"history_operation\"`"+`
+	HistoryChangedAt string `+"`db:\"history_changed_at\" json:\"history_changed_at\"`"+`
+	%s
+}
 `
 
 const FORMAT_REQUEST = `
 package %s
 
+import (
+	"encoding/json"
+
+	"masmaint/internal/core/optional"
+)
+
 type PostBody struct {
 %s
 }
@@ -108,9 +374,24 @@ type PutBody struct {
 %s
 }
 
-type DeleteBody struct {
+//PATCH: JSONに含まれるフィールドのみ更新 (null指定でNULLに更新)
+type PatchBody struct {
 %s
 }
+
+type Key struct {
+%s
+}
+
+
+func (b *PatchBody) UnmarshalJSON(data []byte) error {
+	return optional.DecodeFields(data, map[string]json.Unmarshaler{%s
+	})
+}
+
+//変更内容をモデルに反映 (NOT NULLカラムへのnull指定はPutBodyの検証でエラーとなる)
+func (b PatchBody) applyTo(m *%s) {%s
+}
 `
 
 
@@ -127,11 +408,10 @@ import (
 
 
 type repository struct {
-	db *sql.DB
+	db *db.DB
 }
 
-func NewRepository() Repository {
-	db := db.GetDB()
+func NewRepository(db *db.DB) Repository {
 	return &repository{db}
 }
 
@@ -148,21 +428,147 @@ func NewRepository() Repository {
 %s
 
 
-%s`
+%s%s%s`
 
 const FORMAT_REPOSITORY_INTERFACE =
 `type Repository interface {
 	Get(%s *%s) ([]%s, error)
-	GetOne(%s *%s) (%s, error)
+	GetOne(%s *%s, tx *sql.Tx) (%s, error)
 	Insert(%s *%s, tx *sql.Tx) %s
 	Update(%s *%s, tx *sql.Tx) error
-	Delete(%s *%s, tx *sql.Tx) error
+	Delete(%s *%s, tx *sql.Tx) error%s%s
+}`
+
+const FORMAT_REPOSITORY_INTERFACE_HISTORY =
+`
+	InsertHistory(%s *%s, operation string, changedAt string, tx *sql.Tx) error
+	GetHistory(%s *%s, before string) ([]%sHistory, error)
+	GetHistoryOne(%s *%s, historyId int) (%sHistory, error)`
+
+const FORMAT_REPOSITORY_HISTORY =
+`
+
+
+func (rep *repository) InsertHistory(%s *%s, operation string, changedAt string, tx *sql.Tx) error {
+	cmd := %s
+	binds := []interface{}{
+		operation,
+		changedAt,%s
+	}
+
+	var err error
+	if tx != nil {
+		_, err = tx.Exec(rep.db.Rebind(cmd), binds...)
+	} else {
+		_, err = rep.db.Exec(rep.db.Rebind(cmd), binds...)
+	}
+
+	return err
+}
+
+
+//before を指定した場合はその日時より前の最新の1件
+func (rep *repository) GetHistory(%s *%s, before string) ([]%sHistory, error) {
+	query := %s
+	binds := []interface{}{%s}
+
+	if before != "" {
+		query += " AND history_changed_at < ? ORDER BY history_id DESC LIMIT 1"
+		binds = append(binds, before)
+	} else {
+		query += " ORDER BY history_id DESC"
+	}
+
+	rows, err := rep.db.Query(rep.db.Rebind(query), binds...)
+	if err != nil {
+		return []%sHistory{}, err
+	}
+	defer rows.Close()
+
+	ret := []%sHistory{}
+	for rows.Next() {
+		h := %sHistory{}
+		err = rows.Scan(%s)
+		if err != nil {
+			return []%sHistory{}, err
+		}
+		ret = append(ret, h)
+	}
+
+	return ret, nil
+}
+
+
+func (rep *repository) GetHistoryOne(%s *%s, historyId int) (%sHistory, error) {
+	var h %sHistory
+	query := %s + " AND history_id = ?"
+	binds := []interface{}{%s}
+
+	err := rep.db.QueryRow(rep.db.Rebind(query), binds...).Scan(%s)
+
+	return h, err
+}`
+
+const FORMAT_REPOSITORY_INTERFACE_SOFT_DELETE =
+`
+	GetDeleted(%s *%s) ([]%s, error)
+	Undelete(%s *%s, tx *sql.Tx) error`
+
+const FORMAT_REPOSITORY_SOFT_DELETE =
+`
+
+
+//論理削除した行 (ゴミ箱)
+func (rep *repository) GetDeleted(%s *%s) ([]%s, error) {
+	where, binds := rep.db.BuildWhereClause(%s)
+	query := %s + db.AndWhere(where, "%s")
+	rows, err := rep.db.Query(query, binds...)
+	if err != nil {
+		return []%s{}, err
+	}
+	defer rows.Close()
+
+	ret := []%s{}
+	for rows.Next() {
+		%s := %s{}
+		err = rows.Scan(%s)
+		if err != nil {
+			return []%s{}, err
+		}
+		ret = append(ret, %s)
+	}
+
+	return ret, nil
+}
+
+
+//論理削除の取り消し (対象の行が無い場合は sql.ErrNoRows)
+func (rep *repository) Undelete(%s *%s, tx *sql.Tx) error {
+	where, binds := rep.db.BuildWhereClause(%s)
+	cmd := "UPDATE %s SET %s = %s" + db.AndWhere(where, "%s")
+
+	var ret sql.Result
+	var err error
+	if tx != nil {
+		ret, err = tx.Exec(cmd, binds...)
+	} else {
+		ret, err = rep.db.Exec(cmd, binds...)
+	}
+	if err != nil {
+		return err
+	}
+
+	n, err := ret.RowsAffected()
+	if err == nil && n == 0 {
+		return sql.ErrNoRows
+	}
+	return err
 }`
 
 const FORMAT_REPOSITORY_GET =
 `func (rep *repository) Get(%s *%s) ([]%s, error) {
-	where, binds := db.BuildWhereClause(%s)
-	query := %s + where
+	where, binds := rep.db.BuildWhereClause(%s)
+	query := %s + %s
 	rows, err := rep.db.Query(query, binds...)
 	defer rows.Close()
 
@@ -184,12 +590,18 @@ const FORMAT_REPOSITORY_GET =
 }`
 
 const FORMAT_REPOSITORY_GETONE =
-`func (rep *repository) GetOne(%s *%s) (%s, error) {
+`func (rep *repository) GetOne(%s *%s, tx *sql.Tx) (%s, error) {
 	var ret %s
-	where, binds := db.BuildWhereClause(%s)
-	query := %s + where
+	where, binds := rep.db.BuildWhereClause(%s)
+	query := %s + %s
 
-	err := rep.db.QueryRow(query, binds...).Scan(%s)
+	var row *sql.Row
+	if tx != nil {
+		row = tx.QueryRow(query, binds...)
+	} else {
+		row = rep.db.QueryRow(query, binds...)
+	}
+	err := row.Scan(%s)
 
 	return ret, err
 }`
@@ -268,8 +680,8 @@ const FORMAT_REPOSITORY_UPDATE =
 
 const FORMAT_REPOSITORY_DELETE =
 `func (rep *repository) Delete(%s *%s, tx *sql.Tx) error {
-	where, binds := db.BuildWhereClause(%s)
-	cmd := "DELETE FROM %s " + where
+	where, binds := rep.db.BuildWhereClause(%s)
+	cmd := %s
 
 	var err error
 	if tx != nil {
@@ -285,188 +697,1825 @@ const FORMAT_SERVICE =
 `package %s
 
 import (
+	"io"
+	"bytes"
+	"reflect"
+	"database/sql"
+	"encoding/json"
+	"github.com/gin-gonic/gin/binding"
+
 	"masmaint/internal/module"
+	"masmaint/internal/core/db"
 	"masmaint/internal/core/logger"
 	"masmaint/internal/core/utils"
 	"masmaint/internal/core/errs"
+	"masmaint/internal/core/csvutil"
+	"masmaint/internal/core/xlsx"
+	"masmaint/internal/module/audit"
+)
+
+type Service interface {
+	Get() ([]%s, error)
+	GetOne(key Key) (%s, error)
+	Create(actor audit.Actor, input PostBody) (%s, error)
+	Update(actor audit.Actor, key Key, input PutBody) (%s, error)
+	Patch(actor audit.Actor, key Key, input PatchBody) (%s, error)
+	Delete(actor audit.Actor, key Key) error
+	ExportCsv(encoding string) ([]byte, error)
+	ImportCsv(actor audit.Actor, r io.Reader, encoding string, dryRun bool, deleteMissing bool) (CsvDiff, error)
+	ExportXlsx() ([]byte, error)%s%s
+}
+
+/*
+ 登録・更新・削除のトランザクション内で呼ばれる処理 (実装は hooks.go)
+ エラーを返すとトランザクションをロールバックする
+*/
+type Hooks interface {
+	BeforeCreate(tx *sql.Tx, actor audit.Actor, model *%s) error
+	AfterCreate(tx *sql.Tx, actor audit.Actor, row %s) error
+	BeforeUpdate(tx *sql.Tx, actor audit.Actor, before %s, model *%s) error
+	AfterUpdate(tx *sql.Tx, actor audit.Actor, before %s, row %s) error
+	BeforeDelete(tx *sql.Tx, actor audit.Actor, row %s) error
+	AfterDelete(tx *sql.Tx, actor audit.Actor, row %s) error
+}
+
+type service struct {
+	db *db.DB
+	repository Repository
+	hooks Hooks
+}
+
+func NewService(db *db.DB, repository Repository) Service {
+	return &service{
+		db: db,
+		repository: repository,
+		hooks: NewHooks(),
+	}
+}
+
+
+%s
+
+
+%s
+
+
+%s
+
+
+%s
+
+
+%s
+
+
+%s
+
+
+%s
+
+
+%s
+
+
+%s%s%s`
+
+const FORMAT_SERVICE_GET =
+`func (srv *service) Get() ([]%s, error) {
+	rows, err := srv.repository.Get(&%s{})
+	if err != nil {
+		logger.Error(err.Error())
+		return []%s{}, errs.NewUnexpectedError(err.Error())
+	}
+	return rows, nil
+}`
+
+const FORMAT_SERVICE_GETONE =
+`func (srv *service) GetOne(key Key) (%s, error) {
+	var model %s
+	utils.MapFields(&model, key)
+
+	row, err := srv.repository.GetOne(&model, nil)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return %s{}, errs.NewNotFoundError()
+		}
+		logger.Error(err.Error())
+		return %s{}, errs.NewUnexpectedError(err.Error())
+	}
+	return row, nil
+}`
+
+const FORMAT_SERVICE_CREATE =
+`func (srv *service) Create(actor audit.Actor, input PostBody) (%s, error) {
+	var model %s
+	utils.MapFields(&model, input)
+
+	var row %s
+	err := module.RunInTx(srv.db, func(tx *sql.Tx) error {
+		var err error
+		row, err = srv.insert(tx, actor, model)
+		return err
+	})
+	if err != nil {
+		return %s{}, module.NewDBError(err)
+	}
+	return row, nil
+}`
+
+const FORMAT_SERVICE_UPDATE =
+`func (srv *service) Update(actor audit.Actor, key Key, input PutBody) (%s, error) {
+	var model %s
+	utils.MapFields(&model, input)
+	utils.MapFields(&model, key)
+
+	var row %s
+	err := module.RunInTx(srv.db, func(tx *sql.Tx) error {
+		var err error
+		row, err = srv.update(tx, actor, model)
+		return err
+	})
+	if err != nil {
+		return %s{}, module.NewDBError(err)
+	}
+	return row, nil
+}`
+
+const FORMAT_SERVICE_PATCH =
+`func (srv *service) Patch(actor audit.Actor, key Key, input PatchBody) (%s, error) {
+	var km %s
+	utils.MapFields(&km, key)
+
+	var row %s
+	err := module.RunInTx(srv.db, func(tx *sql.Tx) error {
+		model, err := srv.repository.GetOne(&km, tx)
+		if err != nil {
+			return err
+		}
+		input.applyTo(&model)
+
+		//PUTと同じ規則で変更後の値を検証
+		var validated PutBody
+		utils.MapFields(&validated, model)
+		if err := binding.Validator.ValidateStruct(&validated); err != nil {
+			return module.NewBindError(err, &validated)
+		}
+
+		row, err = srv.update(tx, actor, model)
+		return err
+	})
+	if err != nil {
+		return %s{}, module.NewDBError(err)
+	}
+	return row, nil
+}`
+
+const FORMAT_SERVICE_DELETE =
+`func (srv *service) Delete(actor audit.Actor, key Key) error {
+	var model %s
+	utils.MapFields(&model, key)
+
+	err := module.RunInTx(srv.db, func(tx *sql.Tx) error {
+		return srv.delete(tx, actor, model)
+	})
+	if err != nil {
+		return module.NewDBError(err)
+	}
+	return nil
+}`
+
+const FORMAT_SERVICE_INSERT =
+`//登録して監査ログを記録 (登録後の行を返す)
+func (srv *service) insert(tx *sql.Tx, actor audit.Actor, model %s) (%s, error) {
+	if err := srv.hooks.BeforeCreate(tx, actor, &model); err != nil {
+		return %s{}, err
+	}
+	if err := srv.repository.Insert(&model, tx); err != nil {
+		return %s{}, err
+	}
+
+	km := keyModel(model)
+	row, err := srv.repository.GetOne(&km, tx)
+	if err != nil {
+		return %s{}, err
+	}
+	if err := srv.hooks.AfterCreate(tx, actor, row); err != nil {
+		return %s{}, err
+	}
+	return row, srv.writeChangeLog(tx, actor, audit.OperationInsert, nil, &row)
+}`
+
+const FORMAT_SERVICE_INSERT_AI =
+`//登録して監査ログを記録 (登録後の行を返す)
+func (srv *service) insert(tx *sql.Tx, actor audit.Actor, model %s) (%s, error) {
+	if err := srv.hooks.BeforeCreate(tx, actor, &model); err != nil {
+		return %s{}, err
+	}
+	%s, err := srv.repository.Insert(&model, tx)
+	if err != nil {
+		return %s{}, err
+	}
+
+	row, err := srv.repository.GetOne(&%s{ %s }, tx)
+	if err != nil {
+		return %s{}, err
+	}
+	if err := srv.hooks.AfterCreate(tx, actor, row); err != nil {
+		return %s{}, err
+	}
+	return row, srv.writeChangeLog(tx, actor, audit.OperationInsert, nil, &row)
+}`
+
+const FORMAT_SERVICE_UPDATE_TX =
+`//更新して監査ログを記録 (更新後の行を返す)
+func (srv *service) update(tx *sql.Tx, actor audit.Actor, model %s) (%s, error) {
+	km := keyModel(model)
+	before, err := srv.repository.GetOne(&km, tx)
+	if err != nil {
+		return %s{}, err
+	}
+
+	if err := srv.hooks.BeforeUpdate(tx, actor, before, &model); err != nil {
+		return %s{}, err
+	}
+	if err := srv.repository.Update(&model, tx); err != nil {
+		return %s{}, err
+	}
+
+	row, err := srv.repository.GetOne(&km, tx)
+	if err != nil {
+		return %s{}, err
+	}
+	if err := srv.hooks.AfterUpdate(tx, actor, before, row); err != nil {
+		return %s{}, err
+	}
+	return row, srv.writeChangeLog(tx, actor, audit.OperationUpdate, &before, &row)
+}`
+
+const FORMAT_SERVICE_DELETE_TX =
+`//削除して監査ログを記録
+func (srv *service) delete(tx *sql.Tx, actor audit.Actor, model %s) error {
+	km := keyModel(model)
+	before, err := srv.repository.GetOne(&km, tx)
+	if err != nil {
+		return err
+	}
+
+	if err := srv.hooks.BeforeDelete(tx, actor, before); err != nil {
+		return err
+	}
+	if err := srv.repository.Delete(&km, tx); err != nil {
+		return err
+	}
+	if err := srv.hooks.AfterDelete(tx, actor, before); err != nil {
+		return err
+	}
+	return srv.writeChangeLog(tx, actor, audit.OperationDelete, &before, nil)
+}`
+
+const FORMAT_SERVICE_CHANGELOG =
+`//変更の記録 (監査ログ) と画面への配信
+func (srv *service) writeChangeLog(tx *sql.Tx, actor audit.Actor, operation string, before *%s, after *%s) error {
+	if err := writeAudit(srv.db, tx, actor, operation, before, after); err != nil {
+		return err
+	}
+	publishChange(tx, actor, operation, before, after)
+	return nil
+}`
+
+const FORMAT_SERVICE_CHANGELOG_HISTORY =
+`//変更の記録 (監査ログ・履歴) と画面への配信
+func (srv *service) writeChangeLog(tx *sql.Tx, actor audit.Actor, operation string, before *%s, after *%s) error {
+	if err := writeAudit(srv.db, tx, actor, operation, before, after); err != nil {
+		return err
+	}
+	publishChange(tx, actor, operation, before, after)
+
+	//履歴は変更後の行 (削除の場合は削除前の行) を記録
+	m := after
+	if m == nil {
+		m = before
+	}
+	return srv.repository.InsertHistory(m, operation, utils.NowString(), tx)
+}`
+
+const FORMAT_SERVICE_INTERFACE_HISTORY =
+`
+	GetHistory(key Key, at string) ([]%sHistory, error)
+	Restore(actor audit.Actor, key Key, historyId int) (%s, error)`
+
+const FORMAT_SERVICE_HISTORY =
+`
+
+
+//行の変更履歴 (新しい順、at を指定した場合はその時点の版のみ)
+func (srv *service) GetHistory(key Key, at string) ([]%sHistory, error) {
+	before, err := module.ParseAsOf(at)
+	if err != nil {
+		return []%sHistory{}, err
+	}
+
+	var km %s
+	utils.MapFields(&km, key)
+
+	rows, err := srv.repository.GetHistory(&km, before)
+	if err != nil {
+		logger.Error(err.Error())
+		return []%sHistory{}, errs.NewUnexpectedError(err.Error())
+	}
+	return rows, nil
+}
+
+
+//履歴の版に戻す (通常の更新と同じ検証を行い、監査ログ・履歴も記録する)
+func (srv *service) Restore(actor audit.Actor, key Key, historyId int) (%s, error) {
+	var km %s
+	utils.MapFields(&km, key)
+
+	h, err := srv.repository.GetHistoryOne(&km, historyId)
+	if err != nil {
+		return %s{}, module.NewDBError(err)
+	}
+
+	var input PutBody
+	utils.MapFields(&input, h.%s)
+	if err := binding.Validator.ValidateStruct(&input); err != nil {
+		return %s{}, module.NewBindError(err, &input)
+	}
+	return srv.Update(actor, key, input)
+}`
+
+const FORMAT_SERVICE_INTERFACE_SOFT_DELETE =
+`
+	GetTrash() ([]%s, error)
+	Undelete(actor audit.Actor, key Key) (%s, error)`
+
+const FORMAT_SERVICE_SOFT_DELETE =
+`
+
+
+//論理削除した行 (ゴミ箱)
+func (srv *service) GetTrash() ([]%s, error) {
+	rows, err := srv.repository.GetDeleted(&%s{})
+	if err != nil {
+		logger.Error(err.Error())
+		return []%s{}, errs.NewUnexpectedError(err.Error())
+	}
+	return rows, nil
+}
+
+
+//論理削除を取り消して監査ログを記録 (復元後の行を返す)
+func (srv *service) Undelete(actor audit.Actor, key Key) (%s, error) {
+	var km %s
+	utils.MapFields(&km, key)
+
+	var row %s
+	err := module.RunInTx(srv.db, func(tx *sql.Tx) error {
+		if err := srv.repository.Undelete(&km, tx); err != nil {
+			return err
+		}
+
+		var err error
+		row, err = srv.repository.GetOne(&km, tx)
+		if err != nil {
+			return err
+		}
+		return srv.writeChangeLog(tx, actor, audit.OperationRestore, nil, &row)
+	})
+	if err != nil {
+		return %s{}, module.NewDBError(err)
+	}
+	return row, nil
+}`
+
+const FORMAT_SERVICE_CSV =
+`func (srv *service) ExportCsv(encoding string) ([]byte, error) {
+	rows, err := srv.Get()
+	if err != nil {
+		return nil, err
+	}
+
+	records := [][]string{csvHeader()}
+	for _, row := range rows {
+		records = append(records, toCsvRecord(row))
+	}
+
+	ret, err := csvutil.Write(records, encoding)
+	if err != nil {
+		return nil, errs.NewBadRequestError("encoding")
+	}
+	return ret, nil
+}
+
+
+/*
+ CSVの内容と現在のデータを主キーで突き合わせて差分を作成し、
+ dryRun=false かつエラーが無い場合は1トランザクションで反映する
+ (deleteMissing=true の場合はCSVに無い行を削除対象とする)
+*/
+func (srv *service) ImportCsv(actor audit.Actor, r io.Reader, encoding string, dryRun bool, deleteMissing bool) (CsvDiff, error) {
+	diff := newCsvDiff()
+
+	records, err := csvutil.Read(r, encoding)
+	if err != nil || len(records) == 0 {
+		return diff, errs.NewBadRequestError("file")
+	}
+
+	rows, err := srv.repository.Get(&%s{})
+	if err != nil {
+		logger.Error(err.Error())
+		return diff, errs.NewUnexpectedError(err.Error())
+	}
+	current := map[string]%s{}
+	for _, row := range rows {
+		current[csvKey(row)] = row
+	}
+
+	seen := map[string]bool{}
+	header := records[0]
+	for i, record := range records[1:] {
+		line := i + 2
+		values, rowErr := csvutil.ToMap(csvColumns, header, record)
+		if rowErr != nil {
+			rowErr.Line = line
+			diff.Errors = append(diff.Errors, *rowErr)
+			continue
+		}
+
+		var key Key
+		module.BindMap(values, &key)
+		var keyModel %s
+		utils.MapFields(&keyModel, key)
+		k := csvKey(keyModel)
+
+		if existing, found := current[k]; found && k != "" {
+			if seen[k] {
+				diff.Errors = append(diff.Errors, csvutil.RowError{Line: line, Message: "主キーが重複しています。"})
+				continue
+			}
+			seen[k] = true
+
+			//CSVに無いカラムは現在の値のまま (ポインタを共有しないようJSON経由でコピー)
+			var input PutBody
+			b, _ := json.Marshal(existing)
+			json.Unmarshal(b, &input)
+			if err := module.BindMap(values, &input); err != nil {
+				diff.Errors = append(diff.Errors, csvRowError(line, err))
+				continue
+			}
+
+			var before PutBody
+			utils.MapFields(&before, existing)
+			if !reflect.DeepEqual(before, input) {
+				after := existing
+				utils.MapFields(&after, input)
+				diff.Updates = append(diff.Updates, CsvUpdate{Before: existing, After: after})
+			}
+		} else {
+			var input PostBody
+			if err := module.BindMap(values, &input); err != nil {
+				diff.Errors = append(diff.Errors, csvRowError(line, err))
+				continue
+			}
+
+			var model %s
+			utils.MapFields(&model, input)
+			diff.Inserts = append(diff.Inserts, model)
+		}
+	}
+
+	if deleteMissing {
+		for _, row := range rows {
+			if !seen[csvKey(row)] {
+				diff.Deletes = append(diff.Deletes, row)
+			}
+		}
+	}
+
+	if dryRun || len(diff.Errors) > 0 {
+		return diff, nil
+	}
+
+	if err := srv.applyCsvDiff(actor, diff); err != nil {
+		return diff, module.NewDBError(err)
+	}
+	diff.Applied = true
+	return diff, nil
+}
+
+
+func (srv *service) applyCsvDiff(actor audit.Actor, diff CsvDiff) error {
+	return module.RunInTx(srv.db, func(tx *sql.Tx) error {
+		for _, m := range diff.Inserts {
+			if _, err := srv.insert(tx, actor, m); err != nil {
+				return err
+			}
+		}
+		for _, u := range diff.Updates {
+			if _, err := srv.update(tx, actor, u.After); err != nil {
+				return err
+			}
+		}
+		for _, m := range diff.Deletes {
+			if err := srv.delete(tx, actor, m); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}`
+
+const FORMAT_SERVICE_XLSX =
+`func (srv *service) ExportXlsx() ([]byte, error) {
+	rows, err := srv.Get()
+	if err != nil {
+		return nil, err
+	}
+
+	data := [][]interface{}{}
+	for _, row := range rows {
+		data = append(data, toXlsxRow(row))
+	}
+
+	var buf bytes.Buffer
+	if err := xlsx.Write(&buf, "%s", xlsxColumns, data); err != nil {
+		logger.Error(err.Error())
+		return nil, errs.NewUnexpectedError(err.Error())
+	}
+	return buf.Bytes(), nil
+}`
+
+const FORMAT_HOOKS =
+`package %s
+
+import (
+	"database/sql"
+
+	"masmaint/internal/module/audit"
+)
+
+/*
+ 登録・更新・削除の業務ルール (service がトランザクション内で呼ぶ)
+ このファイルは再生成で上書きされないため、自由に編集してよい
+ エラーを返すと変更を取り消す (errs のエラーはそのままのステータスで返す)
+   例: return errs.NewBadRequestError("code")
+*/
+type hooks struct {}
+
+func NewHooks() Hooks {
+	return &hooks{}
+}
+
+//登録の前 (model の値を変更できる)
+func (h *hooks) BeforeCreate(tx *sql.Tx, actor audit.Actor, model *%s) error {
+	return nil
+}
+
+//登録の後
+func (h *hooks) AfterCreate(tx *sql.Tx, actor audit.Actor, row %s) error {
+	return nil
+}
+
+//更新の前 (model の値を変更できる、before は更新前の行)
+func (h *hooks) BeforeUpdate(tx *sql.Tx, actor audit.Actor, before %s, model *%s) error {
+	return nil
+}
+
+//更新の後
+func (h *hooks) AfterUpdate(tx *sql.Tx, actor audit.Actor, before %s, row %s) error {
+	return nil
+}
+
+//削除の前
+func (h *hooks) BeforeDelete(tx *sql.Tx, actor audit.Actor, row %s) error {
+	return nil
+}
+
+//削除の後
+func (h *hooks) AfterDelete(tx *sql.Tx, actor audit.Actor, row %s) error {
+	return nil
+}
+`
+
+const FORMAT_MOCK_REPOSITORY =
+`package %s
+
+import (
+	"database/sql"
+
+	"masmaint/internal/core/testutil"
+	"masmaint/internal/module/audit"
+)
+
+
+//テスト用のリポジトリ (行をメモリに保持し、err を設定すると登録・更新・削除でそのエラーを返す)
+type mockRepository struct {
+	rows []%s%s
+	err error
+}
+
+func (rep *mockRepository) Get(p *%s) ([]%s, error) {
+	ret := []%s{}
+	for _, row := range rep.rows {
+		if testutil.Match(row, *p) {
+			ret = append(ret, row)
+		}
+	}
+	return ret, nil
+}
+
+func (rep *mockRepository) GetOne(p *%s, tx *sql.Tx) (%s, error) {
+	for _, row := range rep.rows {
+		if testutil.Match(row, *p) {
+			return row, nil
+		}
+	}
+	return %s{}, sql.ErrNoRows
+}
+
+%s
+
+func (rep *mockRepository) Update(p *%s, tx *sql.Tx) error {
+	if rep.err != nil {
+		return rep.err
+	}
+	for i, row := range rep.rows {
+		if testutil.Match(row, keyModel(*p)) {
+			rep.rows[i] = *p
+		}
+	}
+	return nil
+}
+
+//論理削除のテーブルも行を除く
+func (rep *mockRepository) Delete(p *%s, tx *sql.Tx) error {
+	if rep.err != nil {
+		return rep.err
+	}
+	ret := []%s{}
+	for _, row := range rep.rows {
+		if !testutil.Match(row, *p) {
+			ret = append(ret, row)
+		}
+	}
+	rep.rows = ret
+	return nil
+}%s%s
+
+
+//テスト用のフック (hooks.go の編集に影響されないよう、すべて err を返す)
+type mockHooks struct {
+	err error
+}
+
+func (h mockHooks) BeforeCreate(tx *sql.Tx, actor audit.Actor, model *%s) error {
+	return h.err
+}
+
+func (h mockHooks) AfterCreate(tx *sql.Tx, actor audit.Actor, row %s) error {
+	return h.err
+}
+
+func (h mockHooks) BeforeUpdate(tx *sql.Tx, actor audit.Actor, before %s, model *%s) error {
+	return h.err
+}
+
+func (h mockHooks) AfterUpdate(tx *sql.Tx, actor audit.Actor, before %s, row %s) error {
+	return h.err
+}
+
+func (h mockHooks) BeforeDelete(tx *sql.Tx, actor audit.Actor, row %s) error {
+	return h.err
+}
+
+func (h mockHooks) AfterDelete(tx *sql.Tx, actor audit.Actor, row %s) error {
+	return h.err
+}
+`
+
+const FORMAT_MOCK_REPOSITORY_INSERT =
+`func (rep *mockRepository) Insert(p *%s, tx *sql.Tx) error {
+	if rep.err != nil {
+		return rep.err
+	}
+	rep.rows = append(rep.rows, *p)
+	return nil
+}`
+
+const FORMAT_MOCK_REPOSITORY_INSERT_AI =
+`func (rep *mockRepository) Insert(p *%s, tx *sql.Tx) (int, error) {
+	if rep.err != nil {
+		return 0, rep.err
+	}
+	rep.nextId++
+	p.%s = rep.nextId
+	rep.rows = append(rep.rows, *p)
+	return p.%s, nil
+}`
+
+const FORMAT_MOCK_REPOSITORY_HISTORY =
+`
+
+func (rep *mockRepository) InsertHistory(p *%s, operation string, changedAt string, tx *sql.Tx) error {
+	return nil
+}
+
+func (rep *mockRepository) GetHistory(p *%s, before string) ([]%sHistory, error) {
+	return []%sHistory{}, nil
+}
+
+func (rep *mockRepository) GetHistoryOne(p *%s, historyId int) (%sHistory, error) {
+	return %sHistory{}, sql.ErrNoRows
+}`
+
+const FORMAT_MOCK_REPOSITORY_SOFT_DELETE =
+`
+
+func (rep *mockRepository) GetDeleted(p *%s) ([]%s, error) {
+	return []%s{}, nil
+}
+
+func (rep *mockRepository) Undelete(p *%s, tx *sql.Tx) error {
+	return rep.err
+}`
+
+const FORMAT_SERVICE_TEST =
+`package %s
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"masmaint/internal/core/errs"
+	"masmaint/internal/core/utils"
+	"masmaint/internal/core/testutil"
+	"masmaint/internal/module/audit"
+)
+
+
+/*
+ サービスのテスト (リポジトリ・フックはモック、監査ログのみメモリDBに記録)
+ このファイルは再生成で上書きされる
+*/
+
+var testActor = audit.Actor{AccountId: 1, AccountName: "test"}
+
+//一意制約違反で返すカラム (DBのエラーから取得した名前)
+const testConflictColumn = "%s"
+
+func newTestService(t *testing.T, rep *mockRepository) *service {
+	return &service{
+		db: testutil.OpenAuditDB(t),
+		repository: rep,
+		hooks: mockHooks{},
+	}
+}
+
+func testUniqueViolation(t *testing.T) error {
+	return testutil.UniqueViolation(t, "%s", "%s", "%s")
+}
+
+//登録の値 (NULL許容のカラムは省略)
+func testPostBody() PostBody {
+	return PostBody{%s}
+}
+
+
+func TestServiceCreate(t *testing.T) {
+	rep := &mockRepository{}
+	srv := newTestService(t, rep)
+
+	row, err := srv.Create(testActor, testPostBody())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rep.rows) != 1 || !reflect.DeepEqual(row, rep.rows[0]) {
+		t.Errorf("rows: %%+v", rep.rows)
+	}
+	var input PostBody
+	utils.MapFields(&input, row)
+	if !reflect.DeepEqual(input, testPostBody()) {
+		t.Errorf("got %%+v, want %%+v", input, testPostBody())
+	}
+	if n := testutil.CountAuditLog(t, srv.db); n != 1 {
+		t.Errorf("audit_log: got %%d, want 1", n)
+	}
+}
+
+func TestServiceCreateConflict(t *testing.T) {
+	rep := &mockRepository{err: testUniqueViolation(t)}
+	srv := newTestService(t, rep)
+
+	_, err := srv.Create(testActor, testPostBody())
+	if want := errs.NewConflictError(testConflictColumn); err != want {
+		t.Errorf("got %%#v, want %%#v", err, want)
+	}
+	if n := testutil.CountAuditLog(t, srv.db); n != 0 {
+		t.Errorf("audit_log: got %%d, want 0", n)
+	}
+}
+
+func TestServiceCreateHookError(t *testing.T) {
+	rep := &mockRepository{}
+	srv := newTestService(t, rep)
+	srv.hooks = mockHooks{err: errs.NewBadRequestError("")}
+
+	_, err := srv.Create(testActor, testPostBody())
+	if want := errs.NewBadRequestError(""); err != want {
+		t.Errorf("got %%#v, want %%#v", err, want)
+	}
+	if len(rep.rows) != 0 {
+		t.Errorf("rows: %%+v", rep.rows)
+	}
+}
+
+func TestServiceCreateUnexpectedError(t *testing.T) {
+	rep := &mockRepository{err: errors.New("connection refused")}
+	srv := newTestService(t, rep)
+
+	_, err := srv.Create(testActor, testPostBody())
+	if _, ok := err.(errs.UnexpectedError); !ok {
+		t.Errorf("got %%#v, want errs.UnexpectedError", err)
+	}
+}%s
+`
+
+const FORMAT_SERVICE_TEST_KEY =
+`
+
+
+//更新・削除の対象の行
+func testRow() %s {
+	return %s{%s}
+}
+
+func testKey() Key {
+	return Key{%s}
+}
+
+//存在しない行のキー
+func testMissingKey() Key {
+	return Key{%s}
+}
+
+//更新の値 (NULL許容のカラムは省略)
+func testPutBody() PutBody {
+	return PutBody{%s}
+}
+
+
+func TestServiceUpdate(t *testing.T) {
+	rep := &mockRepository{rows: []%s{testRow()}}
+	srv := newTestService(t, rep)
+
+	row, err := srv.Update(testActor, testKey(), testPutBody())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(keyModel(row), keyModel(testRow())) {
+		t.Errorf("key: got %%+v, want %%+v", keyModel(row), keyModel(testRow()))
+	}
+	var input PutBody
+	utils.MapFields(&input, row)
+	if !reflect.DeepEqual(input, testPutBody()) {
+		t.Errorf("got %%+v, want %%+v", input, testPutBody())
+	}
+	if n := testutil.CountAuditLog(t, srv.db); n != 1 {
+		t.Errorf("audit_log: got %%d, want 1", n)
+	}
+}
+
+func TestServiceUpdateNotFound(t *testing.T) {
+	rep := &mockRepository{rows: []%s{testRow()}}
+	srv := newTestService(t, rep)
+
+	_, err := srv.Update(testActor, testMissingKey(), testPutBody())
+	if want := errs.NewNotFoundError(); err != want {
+		t.Errorf("got %%#v, want %%#v", err, want)
+	}
+}
+
+func TestServiceUpdateConflict(t *testing.T) {
+	rep := &mockRepository{rows: []%s{testRow()}}
+	srv := newTestService(t, rep)
+	rep.err = testUniqueViolation(t)
+
+	_, err := srv.Update(testActor, testKey(), testPutBody())
+	if want := errs.NewConflictError(testConflictColumn); err != want {
+		t.Errorf("got %%#v, want %%#v", err, want)
+	}
+	if n := testutil.CountAuditLog(t, srv.db); n != 0 {
+		t.Errorf("audit_log: got %%d, want 0", n)
+	}
+}
+
+func TestServiceDelete(t *testing.T) {
+	rep := &mockRepository{rows: []%s{testRow()}}
+	srv := newTestService(t, rep)
+
+	if err := srv.Delete(testActor, testKey()); err != nil {
+		t.Fatal(err)
+	}
+	if len(rep.rows) != 0 {
+		t.Errorf("rows: %%+v", rep.rows)
+	}
+	if n := testutil.CountAuditLog(t, srv.db); n != 1 {
+		t.Errorf("audit_log: got %%d, want 1", n)
+	}
+}
+
+func TestServiceDeleteNotFound(t *testing.T) {
+	rep := &mockRepository{rows: []%s{testRow()}}
+	srv := newTestService(t, rep)
+
+	err := srv.Delete(testActor, testMissingKey())
+	if want := errs.NewNotFoundError(); err != want {
+		t.Errorf("got %%#v, want %%#v", err, want)
+	}
+	if len(rep.rows) != 1 {
+		t.Errorf("rows: %%+v", rep.rows)
+	}
+}`
+
+const FORMAT_CONTROLLER_TEST =
+`package %s
+
+import (
+	"errors"
+	"testing"
+	"github.com/gin-gonic/gin"
+
+	"masmaint/internal/core/testutil"
+	"masmaint/internal/middleware"
+)
+
+
+/*
+ コントローラのテスト (ApiResponse が返すステータスとエラーのJSON)
+ このファイルは再生成で上書きされる
+*/
+
+func newTestRouter(t *testing.T, rep *mockRepository) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.UseRawPath = true
+
+	ctr := NewController(newTestService(t, rep))
+	g := r.Group("/api/%s", middleware.ApiResponse())
+	g.POST("", ctr.Post)%s
+	return r
+}
+
+
+func TestControllerPost(t *testing.T) {
+	r := newTestRouter(t, &mockRepository{})
+
+	code, res := testutil.ServeJSON(t, r, "POST", "/api/%s", testPostBody())
+	if code != 200 {
+		t.Errorf("got %%d %%v", code, res)
+	}
+}%s
+
+func TestControllerPostConflict(t *testing.T) {
+	r := newTestRouter(t, &mockRepository{err: testUniqueViolation(t)})
+
+	code, res := testutil.ServeJSON(t, r, "POST", "/api/%s", testPostBody())
+	if code != 409 || testutil.Detail(res, "column") != testConflictColumn {
+		t.Errorf("got %%d %%v", code, res)
+	}
+}
+
+func TestControllerPostUnexpectedError(t *testing.T) {
+	r := newTestRouter(t, &mockRepository{err: errors.New("connection refused")})
+
+	code, res := testutil.ServeJSON(t, r, "POST", "/api/%s", testPostBody())
+	if code != 500 || res["error"] != "error: connection refused" {
+		t.Errorf("got %%d %%v", code, res)
+	}
+}%s
+`
+
+const FORMAT_CONTROLLER_TEST_BAD_REQUEST =
+`
+
+//必須のカラムが無い
+func TestControllerPostBadRequest(t *testing.T) {
+	r := newTestRouter(t, &mockRepository{})
+
+	code, res := testutil.ServeJSON(t, r, "POST", "/api/%s", map[string]interface{}{})
+	if code != 400 || testutil.Detail(res, "field") != "%s" {
+		t.Errorf("got %%d %%v", code, res)
+	}
+}`
+
+const FORMAT_CONTROLLER_TEST_KEY =
+`
+
+func TestControllerPut(t *testing.T) {
+	r := newTestRouter(t, &mockRepository{rows: []%s{testRow()}})
+
+	code, res := testutil.ServeJSON(t, r, "PUT", "/api/%s%s", testPutBody())
+	if code != 200 {
+		t.Errorf("got %%d %%v", code, res)
+	}
+}
+
+func TestControllerPutNotFound(t *testing.T) {
+	r := newTestRouter(t, &mockRepository{rows: []%s{testRow()}})
+
+	code, res := testutil.ServeJSON(t, r, "PUT", "/api/%s%s", testPutBody())
+	if _, ok := res["error"]; code != 404 || !ok {
+		t.Errorf("got %%d %%v", code, res)
+	}
+}
+
+func TestControllerDelete(t *testing.T) {
+	rep := &mockRepository{rows: []%s{testRow()}}
+	r := newTestRouter(t, rep)
+
+	code, res := testutil.ServeJSON(t, r, "DELETE", "/api/%s%s", nil)
+	if code != 200 || len(rep.rows) != 0 {
+		t.Errorf("got %%d %%v", code, res)
+	}
+}
+
+func TestControllerDeleteNotFound(t *testing.T) {
+	rep := &mockRepository{rows: []%s{testRow()}}
+	r := newTestRouter(t, rep)
+
+	code, res := testutil.ServeJSON(t, r, "DELETE", "/api/%s%s", nil)
+	if _, ok := res["error"]; code != 404 || !ok || len(rep.rows) != 1 {
+		t.Errorf("got %%d %%v", code, res)
+	}
+}`
+
+const FORMAT_REPOSITORY_TEST =
+`package %s
+
+import (%s
+	"testing"
+	"database/sql"
+
+	"masmaint/internal/module"
+	"masmaint/internal/core/db"
+	"masmaint/internal/core/testutil"%s
+)
+
+
+/*
+ リポジトリの結合テスト (生成したSQLをDBで実行)
+ SQLite はメモリDB、PostgreSQL・MySQL は TEST_DB_DSN を指定した場合に実行する
+ このファイルは再生成で上書きされる
+*/
+
+//結合テストの行 (NULL許容のカラムも値を設定)
+func testDbRow(n int) %s {
+	return %s{%s}
+}
+
+//登録後に比較するフィールド (日付などDBにより形式が変わるカラムは除く)
+var testDbFields = []string{%s}
+
+func openTestRepository(t *testing.T) (*db.DB, Repository) {
+	conn := testutil.OpenSchemaDB(t, "%s")
+	return conn, NewRepository(conn)
+}
+
+//サービスと同じくトランザクション内で登録
+func testDbInsert(conn *db.DB, rep Repository, row *%s) error {
+	return module.RunInTx(conn, func(tx *sql.Tx) error {%s
+	})
+}
+
+func testDbGetOne(t *testing.T, rep Repository, row %s) %s {
+	t.Helper()
+	km := keyModel(row)
+	ret, err := rep.GetOne(&km, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ret
+}
+
+
+func TestRepositoryInsert(t *testing.T) {
+	conn, rep := openTestRepository(t)
+
+	row := testDbRow(1)
+	if err := testDbInsert(conn, rep, &row); err != nil {
+		t.Fatal(err)
+	}%s
+	testutil.AssertFields(t, testDbGetOne(t, rep, row), row, testDbFields...)
+}%s
+
+func TestRepositoryGet(t *testing.T) {
+	conn, rep := openTestRepository(t)
+
+	rows := []%s{testDbRow(1), testDbRow(2)}
+	for i := range rows {
+		if err := testDbInsert(conn, rep, &rows[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	all, err := rep.Get(&%s{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 2 {
+		t.Errorf("got %%d rows, want 2", len(all))
+	}
+
+	//キーの条件 (プレースホルダ)
+	km := keyModel(rows[1])
+	ret, err := rep.Get(&km)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ret) != 1 {
+		t.Fatalf("got %%d rows, want 1", len(ret))
+	}
+	testutil.AssertFields(t, ret[0], rows[1], testDbFields...)
+}%s%s%s%s
+`
+
+const FORMAT_REPOSITORY_TEST_INSERT =
+`
+		return rep.Insert(row, tx)`
+
+const FORMAT_REPOSITORY_TEST_INSERT_AI =
+`
+		id, err := rep.Insert(row, tx)
+		row.%s = id
+		return err`
+
+const FORMAT_REPOSITORY_TEST_AI_CHECK =
+`
+	if row.%s == 0 {
+		t.Error("%s: the generated value was not returned")
+	}`
+
+const FORMAT_REPOSITORY_TEST_NULL =
+`
+
+//NULL許容のカラムにNULLを登録
+func TestRepositoryInsertNull(t *testing.T) {
+	conn, rep := openTestRepository(t)
+
+	row := testDbRow(1)%s
+	if err := testDbInsert(conn, rep, &row); err != nil {
+		t.Fatal(err)
+	}
+	testutil.AssertFields(t, testDbGetOne(t, rep, row), row, %s)
+}`
+
+const FORMAT_REPOSITORY_TEST_UPDATE =
+`
+
+//キーの行のみ更新 (NULL許容のカラムはNULLに更新)
+func TestRepositoryUpdate(t *testing.T) {
+	conn, rep := openTestRepository(t)
+
+	rows := []%s{testDbRow(1), testDbRow(2)}
+	for i := range rows {
+		if err := testDbInsert(conn, rep, &rows[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	upd := testDbRow(3)%s
+	err := module.RunInTx(conn, func(tx *sql.Tx) error {
+		return rep.Update(&upd, tx)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	testutil.AssertFields(t, testDbGetOne(t, rep, rows[0]), upd, %s)
+	testutil.AssertFields(t, testDbGetOne(t, rep, rows[1]), rows[1], testDbFields...)
+}`
+
+const FORMAT_REPOSITORY_TEST_DELETE =
+`
+
+//キーの行のみ削除
+func TestRepositoryDelete(t *testing.T) {
+	conn, rep := openTestRepository(t)
+
+	rows := []%s{testDbRow(1), testDbRow(2)}
+	for i := range rows {
+		if err := testDbInsert(conn, rep, &rows[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	km := keyModel(rows[0])
+	err := module.RunInTx(conn, func(tx *sql.Tx) error {
+		return rep.Delete(&km, tx)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rep.GetOne(&km, nil); err != sql.ErrNoRows {
+		t.Errorf("got %%v, want sql.ErrNoRows", err)
+	}
+	testDbGetOne(t, rep, rows[1])%s
+}`
+
+const FORMAT_REPOSITORY_TEST_SOFT_DELETE =
+`
+
+	//論理削除した行の復元
+	deleted, err := rep.GetDeleted(&%s{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deleted) != 1 {
+		t.Fatalf("trash: got %%d rows, want 1", len(deleted))
+	}
+	err = module.RunInTx(conn, func(tx *sql.Tx) error {
+		return rep.Undelete(&km, tx)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	testutil.AssertFields(t, testDbGetOne(t, rep, rows[0]), rows[0], testDbFields...)`
+
+const FORMAT_REPOSITORY_TEST_CONFLICT =
+`
+
+//一意制約違反は GetConflictColumn で判定できる (409 に変換)
+func TestRepositoryInsertConflict(t *testing.T) {
+	conn, rep := openTestRepository(t)
+
+	row := testDbRow(1)
+	if err := testDbInsert(conn, rep, &row); err != nil {
+		t.Fatal(err)
+	}
+	dup := testDbRow(1)
+	err := testDbInsert(conn, rep, &dup)
+	if _, ok := module.GetConflictColumn(err); !ok {
+		t.Errorf("got %v, want unique violation", err)
+	}
+}`
+
+const FORMAT_REPOSITORY_TEST_HISTORY =
+`
+
+func TestRepositoryHistory(t *testing.T) {
+	conn, rep := openTestRepository(t)
+
+	row := testDbRow(1)
+	if err := testDbInsert(conn, rep, &row); err != nil {
+		t.Fatal(err)
+	}
+	current := testDbGetOne(t, rep, row)
+	err := module.RunInTx(conn, func(tx *sql.Tx) error {
+		return rep.InsertHistory(&current, audit.OperationInsert, "2024-01-01 00:00:00", tx)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	km := keyModel(row)
+	hs, err := rep.GetHistory(&km, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hs) != 1 {
+		t.Fatalf("got %d rows, want 1", len(hs))
+	}
+	if _, err := rep.GetHistoryOne(&km, hs[0].HistoryId); err != nil {
+		t.Error(err)
+	}
+
+	//指定した日時より前の版
+	if hs, err := rep.GetHistory(&km, "2024-01-01 00:00:00"); err != nil || len(hs) != 0 {
+		t.Errorf("before: got %d rows, %v", len(hs), err)
+	}
+	if hs, err := rep.GetHistory(&km, "2024-01-02 00:00:00"); err != nil || len(hs) != 1 {
+		t.Errorf("before: got %d rows, %v", len(hs), err)
+	}
+}`
+
+const FORMAT_CSV =
+`package %s
+
+import (
+	"masmaint/internal/core/errs"
+	"masmaint/internal/core/csvutil"
+)
+
+
+var csvColumns = []csvutil.Column{
+%s
+}
+
+type CsvUpdate struct {
+	Before %s `+"`json:\"before\"`"+`
+	After %s `+"`json:\"after\"`"+`
+}
+
+//CSV取込の差分 (Applied=false の場合は未反映)
+type CsvDiff struct {
+	Inserts []%s `+"`json:\"inserts\"`"+`
+	Updates []CsvUpdate `+"`json:\"updates\"`"+`
+	Deletes []%s `+"`json:\"deletes\"`"+`
+	Errors []csvutil.RowError `+"`json:\"errors\"`"+`
+	Applied bool `+"`json:\"applied\"`"+`
+}
+
+func newCsvDiff() CsvDiff {
+	return CsvDiff{
+		Inserts: []%s{},
+		Updates: []CsvUpdate{},
+		Deletes: []%s{},
+		Errors: []csvutil.RowError{},
+	}
+}
+
+
+func csvHeader() []string {
+	ret := []string{}
+	for _, c := range csvColumns {
+		ret = append(ret, c.Name)
+	}
+	return ret
+}
+
+func toCsvRecord(m %s) []string {
+	return []string{
+%s
+	}
+}
+
+//主キーの値 (突き合わせ用、主キーが無いテーブルは空文字)
+func csvKey(m %s) string {
+	return %s
+}
+
+func csvRowError(line int, err error) csvutil.RowError {
+	ret := csvutil.RowError{Line: line, Message: "入力内容が不正です。"}
+	if e, ok := err.(errs.BadRequestError); ok {
+		ret.Field = e.Field
+	}
+	return ret
+}
+`
+
+const FORMAT_EVENT =
+`package %s
+
+import (
+	"database/sql"
+	"github.com/gin-gonic/gin"
+	"masmaint/internal/module"
+	"masmaint/internal/core/event"
+	"masmaint/internal/module/audit"
+)
+
+
+//変更を画面へ配信 (コミット後、削除の場合は削除前の行)
+func publishChange(tx *sql.Tx, actor audit.Actor, operation string, before *%s, after *%s) {
+	e := event.Event{Operation: operation, AccountName: actor.AccountName, ClientId: actor.ClientId}
+	if after != nil {
+		e.Data = *after
+	} else {
+		e.Data = *before
+	}
+	module.AfterCommit(tx, func() {
+		event.Publish("%s", e)
+	})
+}
+
+
+//GET /api/%s/_events (Server-Sent Events)
+func (ctr *controller) Events(c *gin.Context) {
+	event.Stream(c, "%s")
+}
+
+
+//POST /api/%s/_editing (編集中の行を他の画面へ通知)
+func (ctr *controller) Editing(c *gin.Context) {
+	var req event.Editing
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(module.NewBindError(err, &req))
+		return
+	}
+
+	actor := audit.GetActor(c)
+	event.Publish("%s", event.Event{
+		Operation: event.OperationEditing,
+		Data: req,
+		AccountName: actor.AccountName,
+		ClientId: actor.ClientId,
+	})
+	c.JSON(200, gin.H{})
+}`
+
+const FORMAT_AUDIT =
+`package %s
+
+import (
+	"database/sql"
+	"masmaint/internal/core/db"
+	"masmaint/internal/module/audit"
+)
+
+
+//主キーのみのモデル (主キーが無いテーブルは全カラム)
+func keyModel(m %s) %s {
+	return %s
+}
+
+//監査ログのキー (主キーの値を / で連結)
+func auditKey(m %s) string {
+	return %s
+}
+
+func writeAudit(conn *db.DB, tx *sql.Tx, actor audit.Actor, operation string, before *%s, after *%s) error {
+	var key string
+	var b, a interface{}
+	if before != nil {
+		key = auditKey(*before)
+		b = before
+	}
+	if after != nil {
+		key = auditKey(*after)
+		a = after
+	}
+	return audit.Write(conn, tx, actor, "%s", key, operation, b, a)
+}
+`
+
+const FORMAT_XLSX =
+`package %s
+
+import (
+	"masmaint/internal/core/xlsx"
+)
+
+
+var xlsxColumns = []xlsx.Column{
+%s
+}
+
+func toXlsxRow(m %s) []interface{} {
+	return []interface{}{
+%s
+	}
+}
+`
+
+const FORMAT_PERMISSION_MATRIX =
+`package permission
+
+/*
+ ロール -> テーブル -> 許可する操作 (カスタム推奨)
+ ロールはログイン時にJWTの Role に設定する (config の AUTH_ROLE)
+*/
+var matrix = map[string]map[string][]string{
+	RoleAdmin: {
+		"audit": {Read},
+%s
+	},
+	RoleEditor: {
+%s
+	},
+	RoleViewer: {
+%s
+	},
+}
+`
+
+const FORMAT_ROUTER =
+`package server
+
+import (
+%s	"github.com/gin-gonic/gin"
+%s	"masmaint/internal/middleware"
+	"masmaint/internal/module/audit"
+	"masmaint/internal/module/permission"
+
+%s
+)
+
+/*
+ Routing for "/" 
+*/
+%s
+
+
+%s`
+
+const FORMAT_APP =
+`package server
+
+import (
+	"masmaint/internal/core/db"
+	"masmaint/internal/module/audit"
+%s
 )
 
-type Service interface {
-	Get() ([]%s, error)
-	Create(input PostBody) (%s, error)
-	Update(input PutBody) (%s, error)
-	Delete(input DeleteBody) error
-}
-
-type service struct {
-	repository Repository
+/*
+ アプリケーションの依存関係 (main で開いたDB接続からリポジトリ・サービスを組み立てる)
+ コントローラは router で各サービスから作成する
+*/
+type App struct {
+	DB *db.DB
+	Audit audit.Service
+%s
 }
 
-func NewService() Service {
-	return &service{
-		repository: NewRepository(),
+func NewApp(conn *db.DB) *App {
+	return &App{
+		DB: conn,
+		Audit: audit.NewService(audit.NewRepository(conn)),
+%s
 	}
 }
+`
 
+const FORMAT_ROUTER_SETWEB =
+`func SetWebRouter(r *gin.RouterGroup, app *App) {
+	r.Use(middleware.CsrfCookie())
 
+	auditController := audit.NewController(app.Audit)
 %s
 
-
+%s	auth := r.Group("", middleware.Auth(%s))
+	{
+		auth.GET("/", func(c *gin.Context) { c.HTML(200, "index.html", gin.H{}) })
+		auth.GET("/audit", middleware.Permission("audit", permission.Read), auditController.GetPage)
 %s
+	}
+}`
 
+const FORMAT_ROUTER_WEB_LOGIN =
+`	r.GET("/login", func(c *gin.Context) { c.HTML(200, "login.html", gin.H{}) })
+	r.GET("/logout", func(c *gin.Context) {
+		jwt.RemoveTokenFromCookie(c)
+		c.Redirect(303, "/login")
+	})
 
-%s
+`
 
+const FORMAT_ROUTER_WEB_LOGIN_OIDC =
+`	r.GET("/login", func(c *gin.Context) { c.HTML(200, "login.html", gin.H{"error": c.Query("error") != ""}) })
+	r.GET("/logout", func(c *gin.Context) {
+		jwt.RemoveTokenFromCookie(c)
+		c.Redirect(303, "/login")
+	})
+	//OpenID Connect (認可コードフロー + PKCE)
+	r.GET("/oidc/login", oidcController.Login)
+	r.GET("/oidc/callback", oidcController.Callback)
 
-%s`
+`
 
-const FORMAT_SERVICE_GET =
-`func (srv *service) Get() ([]%s, error) {
-	rows, err := srv.repository.Get(&%s{})
-	if err != nil {
-		logger.Error(err.Error())
-		return []%s{}, errs.NewUnexpectedError(err.Error())
+const FORMAT_ROUTER_LOGIN =
+`	//カスタム推奨
+	r.POST("/login", func(c *gin.Context) { 
+		var body map[string]string
+		c.ShouldBindJSON(&body)
+		name := body["username"]
+		pass := body["password"]
+
+		cf := config.GetConfig()
+		//AUTH_USER・AUTH_PASSWORD 未設定ではログインさせない
+		if cf.AuthUser != "" && cf.AuthPass != "" &&
+			subtle.ConstantTimeCompare([]byte(name), []byte(cf.AuthUser)) == 1 &&
+			subtle.ConstantTimeCompare([]byte(pass), []byte(cf.AuthPass)) == 1 {
+			cc := jwt.CustomClaims{ AccountId: 1, AccountName: name, Role: cf.AuthRole}
+			jwt.SetTokenToCookie(c, jwt.NewPayload(cc))
+		} else {
+			c.JSON(401, gin.H{"error": "ユーザ名またはパスワードが異なります。"})
+		}
+	})`
+
+const FORMAT_ROUTER_LOGIN_USERS =
+`	//users テーブルのユーザでログイン
+	r.POST("/login", usersController.Login)`
+
+const FORMAT_ROUTER_LOGOUT =
+`	r.POST("/logout", func(c *gin.Context) {
+		jwt.RemoveTokenFromCookie(c)
+		c.JSON(200, gin.H{})
+	})`
+
+const FORMAT_ROUTER_SETAPI =
+`func SetApiRouter(r *gin.RouterGroup, app *App) {
+	r.Use(middleware.ApiResponse())
+	r.Use(middleware.ApiCsrf())
+
+	auditController := audit.NewController(app.Audit)
+%s
+
+%s	auth := r.Group("", middleware.ApiAuth(%s))
+	{
+		auth.GET("/audit", middleware.ApiPermission("audit", permission.Read), auditController.Get)
+
+%s
 	}
-	return rows, nil
 }`
 
-const FORMAT_SERVICE_CREATE =
-`func (srv *service) Create(input PostBody) (%s, error) {
-	var model %s
-	utils.MapFields(&model, input)
+const FORMAT_MIDDLEWARE_AUTH_JWT =
+`package middleware
 
-	err := srv.repository.Insert(&model, nil)
-	if err != nil {
-		if column, ok := module.GetConflictColumn(err); ok {
-			return %s{}, errs.NewConflictError(column)
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"masmaint/internal/core/jwt"%s
+)
+
+
+//Authorization: Bearer で認証する (Cookie より優先し、ApiCsrf の確認を省略する)
+const acceptsBearer = true
+
+
+//JWT (Cookie または Authorization: Bearer) で認証 未認証の場合はログイン画面へ
+func Auth(%s) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := jwt.Auth(c, %s); err != nil {
+			c.Redirect(http.StatusSeeOther, "/login")
+			c.Abort()
+			return
 		}
-		logger.Error(err.Error())
-		return %s{}, errs.NewUnexpectedError(err.Error())
+		c.Next()
 	}
+}
 
-	row, err := srv.repository.GetOne(&%s{ %s })
-	if err != nil {
-		logger.Error(err.Error())
-		return %s{}, errs.NewUnexpectedError(err.Error())
+
+func ApiAuth(%s) gin.HandlerFunc {
+	return func(c *gin.Context) {%s
+		if err := jwt.Auth(c, %s); err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			c.Abort()
+			return
+		}
+		c.Next()
 	}
-	return row, nil
 }`
 
-const FORMAT_SERVICE_CREATE_AI =
-`func (srv *service) Create(input PostBody) (%s, error) {
-	var model %s
-	utils.MapFields(&model, input)
+const FORMAT_MIDDLEWARE_AUTH_API_TOKEN =
+`
+		//Authorization: Bearer mmt_... はAPIトークンで認証
+		if token, ok := api_token.BearerToken(c); ok {
+			cc, err := tokens.Auth(token, %s)
+			if err != nil {
+				c.Error(err)
+				c.Abort()
+				return
+			}
+			jwt.SetPayload(c, jwt.NewPayload(cc))
+			c.Next()
+			return
+		}
+`
 
-	%s, err := srv.repository.Insert(&model, nil)
-	if err != nil {
-		if column, ok := module.GetConflictColumn(err); ok {
-			return %s{}, errs.NewConflictError(column)
+const FORMAT_MIDDLEWARE_AUTH_BASIC =
+`package middleware
+
+import (
+	"net/http"
+	"crypto/subtle"
+
+	"github.com/gin-gonic/gin"
+
+	"masmaint/config"
+	"masmaint/internal/core/jwt"
+)
+
+
+//Authorization: Bearer では認証しない (ApiCsrf の確認を省略しない)
+const acceptsBearer = false
+
+
+//Basic認証 (config の BASIC_AUTH_USER・BASIC_AUTH_PASSWORD、ロールは AUTH_ROLE)
+func Auth() gin.HandlerFunc {
+	return BasicAuth()
+}
+
+
+func ApiAuth() gin.HandlerFunc {
+	return BasicAuth()
+}
+
+
+func BasicAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		cf := config.GetConfig()
+
+		user, pass, ok := c.Request.BasicAuth()
+		//BASIC_AUTH_USER・BASIC_AUTH_PASSWORD 未設定では通さない
+		if !ok || cf.BasicAuthUser == "" || cf.BasicAuthPass == "" ||
+			!secureEqual(user, cf.BasicAuthUser) || !secureEqual(pass, cf.BasicAuthPass) {
+			c.Header("WWW-Authenticate", "Basic realm=Authorization Required")
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
 		}
-		logger.Error(err.Error())
-		return %s{}, errs.NewUnexpectedError(err.Error())
+		cc := jwt.CustomClaims{ AccountId: 1, AccountName: user, Role: cf.AuthRole }
+		jwt.SetPayload(c, jwt.NewPayload(cc))
+		c.Next()
 	}
+}
 
-	row, err := srv.repository.GetOne(&%s{ %s })
-	if err != nil {
-		logger.Error(err.Error())
-		return %s{}, errs.NewUnexpectedError(err.Error())
-	}
-	return row, nil
+
+//比較時間から一致した長さを推測させない
+func secureEqual(a string, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
 }`
 
-const FORMAT_SERVICE_UPDATE =
-`func (srv *service) Update(input PutBody) (%s, error) {
-	var model %s
-	utils.MapFields(&model, input)
+const FORMAT_MIDDLEWARE_AUTH_HEADER =
+`package middleware
 
-	err := srv.repository.Update(&model, nil)
-	if err != nil {
-		if column, ok := module.GetConflictColumn(err); ok {
-			return %s{}, errs.NewConflictError(column)
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"masmaint/config"
+	"masmaint/internal/core/jwt"
+	"masmaint/internal/core/errs"
+)
+
+
+//Authorization: Bearer では認証しない (ApiCsrf の確認を省略しない)
+const acceptsBearer = false
+
+
+//リバースプロキシ (SSO) が設定するヘッダ (config の AUTH_HEADER 既定: X-Forwarded-User) のユーザを信頼する
+//ヘッダは偽装できるため、プロキシを経由しないアクセスは遮断すること
+func Auth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !headerAuth(c) {
+			c.String(http.StatusUnauthorized, "認証されていません。")
+			c.Abort()
+			return
 		}
-		logger.Error(err.Error())
-		return %s{}, errs.NewUnexpectedError(err.Error())
+		c.Next()
 	}
+}
 
-	row, err := srv.repository.GetOne(&%s{ %s })
-	if err != nil {
-		logger.Error(err.Error())
-		return %s{}, errs.NewUnexpectedError(err.Error())
+
+func ApiAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !headerAuth(c) {
+			c.Error(errs.NewUnauthorizedError())
+			c.Abort()
+			return
+		}
+		c.Next()
 	}
-	return row, nil
-}`
+}
 
-const FORMAT_SERVICE_DELETE =
-`func (srv *service) Delete(input DeleteBody) error {
-	var model %s
-	utils.MapFields(&model, input)
 
-	err := srv.repository.Delete(&model, nil)
-	if err != nil {
-		logger.Error(err.Error())
-		return errs.NewUnexpectedError(err.Error())
+func headerAuth(c *gin.Context) bool {
+	cf := config.GetConfig()
+
+	user := strings.TrimSpace(c.GetHeader(cf.AuthHeader))
+	if user == "" {
+		return false
 	}
-	return nil
+	cc := jwt.CustomClaims{ AccountName: user, Role: cf.AuthRole }
+	jwt.SetPayload(c, jwt.NewPayload(cc))
+	return true
 }`
 
-const FORMAT_ROUTER =
-`package server
+const FORMAT_MIDDLEWARE_AUTH_NONE =
+`package middleware
 
 import (
 	"github.com/gin-gonic/gin"
+
 	"masmaint/config"
 	"masmaint/internal/core/jwt"
-	"masmaint/internal/middleware"
-
-%s
 )
 
-/*
- Routing for "/" 
-*/
-%s
-
-
-%s`
 
-const FORMAT_ROUTER_SETWEB =
-`func SetWebRouter(r *gin.RouterGroup) {
-%s
+//Authorization: Bearer では認証しない (ApiCsrf の確認を省略しない)
+const acceptsBearer = false
 
-	r.GET("/login", func(c *gin.Context) { c.HTML(200, "login.html", gin.H{}) })
 
-	auth := r.Group("", middleware.JwtAuth())
-	{
-		auth.GET("/", func(c *gin.Context) { c.HTML(200, "index.html", gin.H{}) })
-%s
-	}
-}`
+//認証なし (全てのアクセスを config の AUTH_ROLE の匿名ユーザとして扱う)
+func Auth() gin.HandlerFunc {
+	return anonymous()
+}
 
-const FORMAT_ROUTER_SETAPI =
-`func SetApiRouter(r *gin.RouterGroup) {
-	r.Use(middleware.ApiResponse())
 
-%s
+func ApiAuth() gin.HandlerFunc {
+	return anonymous()
+}
 
-	//カスタム推奨
-	r.POST("/login", func(c *gin.Context) { 
-		var body map[string]string
-		c.ShouldBindJSON(&body)
-		name := body["username"]
-		pass := body["password"]
 
+func anonymous() gin.HandlerFunc {
+	return func(c *gin.Context) {
 		cf := config.GetConfig()
-		if name == cf.AuthUser && pass == cf.AuthPass {
-			cc := jwt.CustomClaims{ AccountId: 1, AccountName: name}
-			jwt.SetTokenToCookie(c, jwt.NewPayload(cc))
-		} else {
-			c.JSON(401, gin.H{"error": "ユーザ名またはパスワードが異なります。"})
-		}
-	})
-
-	auth := r.Group("", middleware.ApiJwtAuth())
-	{
-%s
+		cc := jwt.CustomClaims{ AccountName: "anonymous", Role: cf.AuthRole }
+		jwt.SetPayload(c, jwt.NewPayload(cc))
+		c.Next()
 	}
 }`
 
@@ -483,10 +2532,17 @@ const FORMAT_JS_CREATETRNEW =
 const FORMAT_JS_CREATETR =
 `const createTr = (elem) => {
 	const tr = document.createElement('tr');
-	tr.innerHTML = `+"`%s`"+`;
+	tr.dataset.key = toKeyPath(elem);
+	tr.append(%s
+	);%s
 	return tr;
 }`
 
+const FORMAT_JS_TOKEYPATH =
+`const toKeyPath = (elem) => {
+	return [%s].map(v => encodeURIComponent(v)).join('/');
+}`
+
 const FORMAT_JS_GETROWS =
 `const getRows = async () => {
 	document.getElementById('records').innerHTML = '';
@@ -495,8 +2551,8 @@ const FORMAT_JS_GETROWS =
 %s
 }`
 
-const FORMAT_JS_PUTROWS =
-`const putRows = async () => {
+const FORMAT_JS_PATCHROWS =
+`const patchRows = async () => {
 	let successCount = 0;
 	let errorCount = 0;
 
@@ -514,13 +2570,18 @@ const FORMAT_JS_PUTROWS =
 		}
 
 		//差分がある行のみ更新
-		if (Object.keys(rowMap).some(key => rowMap[key].value !== rowBkMap[key].value)) {
-			const requestBody = {
+		const changedKeys = Object.keys(rowMap).filter(key => rowMap[key].value !== rowBkMap[key].value);
+		if (changedKeys.length > 0) {
+			const values = {
 %s
 			}
 
+			//変更されたカラムのみ送信
+			const requestBody = {};
+			changedKeys.forEach(key => requestBody[key] = values[key]);
+
 			try {
-				const data = await api.put('%s', requestBody);
+				const data = await api.patch(`+"`%s/${toKeyPath(values)}`"+`, requestBody);
 
 %s
 
@@ -566,6 +2627,7 @@ const FORMAT_JS_POSTROW =
 			tr.addEventListener('change', handleChange);
 			document.getElementById('records').appendChild(tr);
 			document.getElementById('records').appendChild(createTrNew());
+			applyPermission();
 
 			renderMessage('登録', 1, true);
 		} catch (e) {
@@ -589,7 +2651,7 @@ const FORMAT_JS_DELETEROWS =
 
 	for (let row of rows) {
 		try {
-			await api.delete('%s', row);
+			await api.delete(`+"`%s/${toKeyPath(row)}`"+`);
 			successCount += 1;
 		} catch (e) {
 			errorCount += 1;
@@ -614,16 +2676,35 @@ const FORMAT_TEMPLATE =
 	{{template "header" .}}
 	<div class="container-fluid">
 		{{template "menu" .}}
-		<main>
+		<main data-can-create="{{.perm.Create}}" data-can-update="{{.perm.Update}}" data-can-delete="{{.perm.Delete}}">
 			<div class="w-100 px-3 py-3">
 				<h1 class="h4">%s</h1>
 				<div id="message"></div>
+				{{if .perm.Delete}}
 				<button type="button" class="btn btn-danger" data-bs-toggle="modal"
 					data-bs-target="#modal-delete">削除</button>
+				{{end}}
+				{{if or .perm.Create .perm.Update}}
 				<button type="button" class="btn btn-primary" data-bs-toggle="modal"
 					data-bs-target="#modal-save">保存</button>
+				{{end}}
 				<button type="button" class="btn btn-secondary" id="reload">リロード</button>
-				<div class="table-responsive mt-2" style="max-height: calc(100vh - 190px);">
+				<div class="btn-group">
+					<button type="button" class="btn btn-outline-secondary dropdown-toggle"
+						data-bs-toggle="dropdown">出力</button>
+					<ul class="dropdown-menu">
+						<li><a class="dropdown-item" href="/api/%s/_export.xlsx">Excel (xlsx)</a></li>
+						<li><hr class="dropdown-divider"></li>
+						<li><a class="dropdown-item" href="/api/%s/_export.csv?encoding=utf8">CSV UTF-8</a></li>
+						<li><a class="dropdown-item" href="/api/%s/_export.csv?encoding=utf8bom">CSV UTF-8 BOM付き</a></li>
+						<li><a class="dropdown-item" href="/api/%s/_export.csv?encoding=sjis">CSV Shift_JIS</a></li>
+					</ul>
+				</div>
+				{{if and .perm.Create .perm.Update}}
+				<button type="button" class="btn btn-outline-secondary" data-bs-toggle="modal"
+					data-bs-target="#modal-csv-import">CSV取込</button>
+				{{end}}%s
+				<div class="table-responsive table-scroll mt-2">
 					<table class="table table-hover table-bordered table-sm">
 						<thead class="fixed-table-header bg-light">
 							<tr>
@@ -645,11 +2726,273 @@ const FORMAT_TEMPLATE =
 
 </html>`
 
+const FORMAT_TEMPLATE_HEADER =
+`{{define "header"}}
+<header>
+    マスタメンテナンス
+    <nav>%s
+    </nav>
+</header>
+{{end}}`
+
 const FORMAT_TEMPLATE_MENU =
 `{{define "menu"}}
-<div class="sidemenu vh-100" style="overflow-y: auto;">
+<div class="sidemenu vh-100">
 	<ul class="nav flex-column mb-5">
 %s
 	</ul>
+	<ul class="nav flex-column mb-5 border-top">
+		<li class='nav-item'><a href='/audit' class='nav-link py-1'>監査ログ</a></li>%s
+	</ul>
 </div>
-{{end}}`
\ No newline at end of file
+{{end}}`
+
+const FORMAT_DDL_AUDIT_LOG_POSTGRESQL = `
+
+CREATE TABLE audit_log (
+	audit_log_id SERIAL PRIMARY KEY,
+	table_name VARCHAR(64) NOT NULL,
+	record_key VARCHAR(255) NOT NULL,
+	operation VARCHAR(10) NOT NULL,
+	before_data TEXT,
+	after_data TEXT,
+	account_id INTEGER NOT NULL,
+	account_name VARCHAR(255) NOT NULL,
+	account_key VARCHAR(255) NOT NULL DEFAULT '',
+	created_at TIMESTAMP NOT NULL
+);
+CREATE INDEX audit_log_idx1 ON audit_log (table_name, record_key);
+CREATE INDEX audit_log_idx2 ON audit_log (created_at);
+`
+
+const FORMAT_DDL_AUDIT_LOG_MYSQL = `
+
+CREATE TABLE audit_log (
+	audit_log_id INT AUTO_INCREMENT PRIMARY KEY,
+	table_name VARCHAR(64) NOT NULL,
+	record_key VARCHAR(255) NOT NULL,
+	operation VARCHAR(10) NOT NULL,
+	before_data LONGTEXT,
+	after_data LONGTEXT,
+	account_id INT NOT NULL,
+	account_name VARCHAR(255) NOT NULL,
+	account_key VARCHAR(255) NOT NULL DEFAULT '',
+	created_at DATETIME NOT NULL,
+	INDEX audit_log_idx1 (table_name, record_key),
+	INDEX audit_log_idx2 (created_at)
+);
+`
+
+const FORMAT_DDL_AUDIT_LOG_SQLITE3 = `
+
+CREATE TABLE audit_log (
+	audit_log_id INTEGER PRIMARY KEY AUTOINCREMENT,
+	table_name TEXT NOT NULL,
+	record_key TEXT NOT NULL,
+	operation TEXT NOT NULL,
+	before_data TEXT,
+	after_data TEXT,
+	account_id INTEGER NOT NULL,
+	account_name TEXT NOT NULL,
+	account_key TEXT NOT NULL DEFAULT '',
+	created_at TEXT NOT NULL
+);
+CREATE INDEX audit_log_idx1 ON audit_log (table_name, record_key);
+CREATE INDEX audit_log_idx2 ON audit_log (created_at);
+`
+
+
+const FORMAT_DDL_USERS_POSTGRESQL = `
+
+CREATE TABLE users (
+	user_id SERIAL PRIMARY KEY,
+	user_name VARCHAR(255) NOT NULL UNIQUE,
+	password_hash VARCHAR(255) NOT NULL,
+	role VARCHAR(64) NOT NULL,
+	created_at TIMESTAMP NOT NULL,
+	updated_at TIMESTAMP NOT NULL
+);
+`
+
+const FORMAT_DDL_USERS_MYSQL = `
+
+CREATE TABLE users (
+	user_id INT AUTO_INCREMENT PRIMARY KEY,
+	user_name VARCHAR(255) NOT NULL UNIQUE,
+	password_hash VARCHAR(255) NOT NULL,
+	role VARCHAR(64) NOT NULL,
+	created_at DATETIME NOT NULL,
+	updated_at DATETIME NOT NULL
+);
+`
+
+const FORMAT_DDL_USERS_SQLITE3 = `
+
+CREATE TABLE users (
+	user_id INTEGER PRIMARY KEY AUTOINCREMENT,
+	user_name TEXT NOT NULL UNIQUE,
+	password_hash TEXT NOT NULL,
+	role TEXT NOT NULL,
+	created_at TEXT NOT NULL,
+	updated_at TEXT NOT NULL
+);
+`
+
+
+const FORMAT_DDL_API_TOKEN_POSTGRESQL = `
+
+CREATE TABLE api_token (
+	api_token_id SERIAL PRIMARY KEY,
+	token_name VARCHAR(255) NOT NULL,
+	token_hash CHAR(64) NOT NULL UNIQUE,
+	token_prefix VARCHAR(16) NOT NULL,
+	kind VARCHAR(16) NOT NULL,
+	account_id INTEGER NOT NULL,
+	account_name VARCHAR(255) NOT NULL,
+	account_key VARCHAR(255) NOT NULL DEFAULT '',
+	role VARCHAR(64) NOT NULL,
+	scopes TEXT NOT NULL,
+	expires_at TIMESTAMP,
+	last_used_at TIMESTAMP,
+	revoked_at TIMESTAMP,
+	created_by VARCHAR(255) NOT NULL,
+	created_at TIMESTAMP NOT NULL
+);
+`
+
+const FORMAT_DDL_API_TOKEN_MYSQL = `
+
+CREATE TABLE api_token (
+	api_token_id INT AUTO_INCREMENT PRIMARY KEY,
+	token_name VARCHAR(255) NOT NULL,
+	token_hash CHAR(64) NOT NULL UNIQUE,
+	token_prefix VARCHAR(16) NOT NULL,
+	kind VARCHAR(16) NOT NULL,
+	account_id INT NOT NULL,
+	account_name VARCHAR(255) NOT NULL,
+	account_key VARCHAR(255) NOT NULL DEFAULT '',
+	role VARCHAR(64) NOT NULL,
+	scopes TEXT NOT NULL,
+	expires_at DATETIME,
+	last_used_at DATETIME,
+	revoked_at DATETIME,
+	created_by VARCHAR(255) NOT NULL,
+	created_at DATETIME NOT NULL
+);
+`
+
+const FORMAT_DDL_API_TOKEN_SQLITE3 = `
+
+CREATE TABLE api_token (
+	api_token_id INTEGER PRIMARY KEY AUTOINCREMENT,
+	token_name TEXT NOT NULL,
+	token_hash TEXT NOT NULL UNIQUE,
+	token_prefix TEXT NOT NULL,
+	kind TEXT NOT NULL,
+	account_id INTEGER NOT NULL,
+	account_name TEXT NOT NULL,
+	account_key TEXT NOT NULL DEFAULT '',
+	role TEXT NOT NULL,
+	scopes TEXT NOT NULL,
+	expires_at TEXT,
+	last_used_at TEXT,
+	revoked_at TEXT,
+	created_by TEXT NOT NULL,
+	created_at TEXT NOT NULL
+);
+`
+
+const FORMAT_DDL_SCHEMA_MIGRATIONS_POSTGRESQL = `
+
+CREATE TABLE schema_migrations (
+	version VARCHAR(64) PRIMARY KEY,
+	applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+`
+
+const FORMAT_DDL_SCHEMA_MIGRATIONS_MYSQL = `
+
+CREATE TABLE schema_migrations (
+	version VARCHAR(64) PRIMARY KEY,
+	applied_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+`
+
+const FORMAT_DDL_SCHEMA_MIGRATIONS_SQLITE3 = `
+
+CREATE TABLE schema_migrations (
+	version TEXT PRIMARY KEY,
+	applied_at TEXT NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+`
+
+
+const FORMAT_TEMPLATE_AUDIT =
+`<!DOCTYPE html>
+<html>
+
+<head>
+	{{template "head" .}}
+</head>
+
+<body>
+	{{template "header" .}}
+	<div class="container-fluid">
+		{{template "menu" .}}
+		<main>
+			<div class="w-100 px-3 py-3">
+				<h1 class="h4">監査ログ</h1>
+				<form id="audit-search" class="row g-2 align-items-end">
+					<div class="col-auto">
+						<label for="audit-table_name" class="form-label">テーブル</label>
+						<select id="audit-table_name" class="form-select form-select-sm">
+							<option value=""></option>
+%s
+						</select>
+					</div>
+					<div class="col-auto">
+						<label for="audit-record_key" class="form-label">キー</label>
+						<input type="text" id="audit-record_key" class="form-control form-control-sm">
+					</div>
+					<div class="col-auto">
+						<label for="audit-account_name" class="form-label">ユーザ</label>
+						<input type="text" id="audit-account_name" class="form-control form-control-sm">
+					</div>
+					<div class="col-auto">
+						<label for="audit-from" class="form-label">日付</label>
+						<div class="input-group input-group-sm">
+							<input type="date" id="audit-from" class="form-control">
+							<span class="input-group-text">～</span>
+							<input type="date" id="audit-to" class="form-control">
+						</div>
+					</div>
+					<div class="col-auto">
+						<button type="submit" class="btn btn-primary btn-sm">検索</button>
+					</div>
+				</form>
+				<div id="message" class="mt-2"></div>
+				<div class="table-responsive mt-2">
+					<table class="table table-bordered table-sm">
+						<thead class="fixed-table-header bg-light">
+							<tr>
+								<th>日時</th>
+								<th>操作</th>
+								<th>テーブル</th>
+								<th>キー</th>
+								<th>ユーザ</th>
+								<th>変更内容</th>
+							</tr>
+						</thead>
+						<tbody id="records">
+						</tbody>
+					</table>
+				</div>
+			</div>
+		</main>
+	</div>
+	{{template "modal" .}}
+	<script type="module" src="js/audit.js"></script>
+	{{template "footer" .}}
+</body>
+
+</html>`
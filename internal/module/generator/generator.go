@@ -1,51 +1,204 @@
 package generator
 
 import (
+	"io"
 	"os"
 	"fmt"
+	"path"
+	"sort"
+	"bytes"
 	"strings"
 	"time"
+	"html"
 	"os/exec"
+	"io/fs"
+	"regexp"
+	"strconv"
+	"archive/zip"
 	"github.com/kodaimura/ddlparse"
 
 	"masmaint-cg/internal/core/logger"
 	"masmaint-cg/internal/core/utils"
+	"masmaint-cg/internal/module/input"
+	"masmaint-cg/internal/module/merge"
 )
 
 
 type generator struct {
 	ddl string
 	tables []ddlparse.Table
+	// テーブル・カラムのコメント (キーはテーブル名、カラムは <テーブル名>.<カラム名>)
+	comments map[string]string
+	// カラムの論理名 (画面の項目名、キーは <テーブル名>.<カラム名>)
+	labels map[string]string
+	// 他のRDBMSのDDLから変換した場合の変換できなかった構文
+	notes []string
 	rdbms string
+	option Option
 	output string
+	// 前回の生成の入力との差分のマイグレーション (差分がない場合は nil)
+	migration *migration
+	// 前回生成したプロジェクトにマージした結果 (競合など)
+	mergeReport []string
+}
+
+// 生成オプション
+type Option struct {
+	// 主キーをJSONボディで受け取る PUT/DELETE /api/<table> も生成する（互換用）
+	BodyKeyRoutes bool
+	// テーブルごとに <table>_history を生成し、変更前後の行を記録する（履歴の参照・復元）
+	History bool
+	// 認証方式 jwt（既定） / users / oidc / basic / header / none
+	// users: users テーブルのユーザでログインし、ユーザ管理画面・初期管理者の登録コマンドを生成する
+	// oidc: OpenID Connect（認可コードフロー + PKCE）でログインし、IdPのグループをロールに対応させる
+	// header: リバースプロキシが設定する X-Forwarded-User のユーザを信頼する
+	AuthMode string
+	// Authorization: Bearer で使用するAPIトークン（個人用・サービス用）と管理画面を生成する（jwt / users / oidc のみ）
+	ApiTokens bool
+	// 論理削除カラム名（空の場合は deleted_at / is_deleted / del_flg / delete_flag を自動判定）
+	SoftDeleteColumn string
+	// 前回の生成の入力（指定した場合は差分から scripts/migrations にマイグレーションを生成する）
+	Previous *Previous
+	// 前回生成したプロジェクト（Previous のzip）に今回の生成を3方向マージし、手書きの変更を残す
+	Merge bool
+}
+
+// 前回の生成の入力
+type Previous struct {
+	// 前回の入力から読み取ったテーブル定義（対象テーブルの差分のみ）
+	Definition *input.Definition
+	// 前回生成したzipの scripts/create-table.sql（指定した場合は Definition より優先し、監査ログ等のテーブルも比較する）
+	CreateTableSql string
+	// 前回生成したzipの scripts/migrations のファイル（ファイル名 -> 内容、引き継ぐ）
+	Migrations map[string]string
+	// 前回生成したzipのプロジェクトのファイル（プロジェクトからの相対パス -> 内容、.masmaint-cg を除く）
+	Files map[string][]byte
+	// 前回生成したzipの .masmaint-cg/base（前回の生成の内容、マージの基点）
+	Base map[string][]byte
 }
 
 type Generator interface {
 	Generate() (string, error)
+	// Option.Merge の場合のマージの結果（Generate の後）
+	MergeReport() []string
 }
 
-func NewGenerator(ddl string, rdbms string) (Generator, error) {
-	var tables []ddlparse.Table
-	var err error
-	if (rdbms == "postgresql") {
-		tables, err = ddlparse.ParsePostgreSQL(ddl)
-	} else if (rdbms == "mysql") {
-		tables, err = ddlparse.ParseMySQL(ddl)
-	} else if (rdbms == "sqlite3") {
-		tables, err = ddlparse.ParseSQLite(ddl)
-	} else {
-		tables, err = ddlparse.ParseSQLite(ddl)
+/*
+ 入力 (DDL・DBML・Prisma・テーブル定義書・データベース) から読み取ったテーブル定義から生成する
+ def.Ddl が空の場合、create-table.sql はテーブル定義から rdbms のDDLを作成する
+*/
+func NewGenerator(def input.Definition, rdbms string, option Option) (Generator, error) {
+	if def.Comments == nil {
+		def.Comments = map[string]string{}
 	}
-	if err != nil {
+	if def.Labels == nil {
+		def.Labels = map[string]string{}
+	}
+	return newGenerator(def, rdbms, option)
+}
+
+func newGenerator(def input.Definition, rdbms string, option Option) (Generator, error) {
+	if !Contains(rdbmses, rdbms) {
+		return &generator{}, fmt.Errorf("RDBMS '%s' は指定できません。", rdbms)
+	}
+	if option.AuthMode == "" {
+		option.AuthMode = "jwt"
+	}
+	if !Contains(authModes, option.AuthMode) {
+		return &generator{}, fmt.Errorf("認証方式 '%s' は指定できません。", option.AuthMode)
+	}
+	if err := validateTableNames(def.Tables, option); err != nil {
 		return &generator{}, err
 	}
 
-	return &generator{
-		ddl: ddl,
-		tables: tables,
+	gen := &generator{
+		ddl: def.Ddl,
+		tables: def.Tables,
+		comments: def.Comments,
+		labels: def.Labels,
+		notes: def.Notes,
 		rdbms: rdbms,
+		option: option,
 		output: "./output",
-	}, nil
+	}
+	if gen.option.ApiTokens && !gen.isLoginAuth() {
+		return &generator{}, fmt.Errorf("APIトークンは認証方式 jwt / users / oidc の場合のみ指定できます。")
+	}
+	if gen.option.Merge && (gen.option.Previous == nil || gen.option.Previous.Files == nil) {
+		return &generator{}, fmt.Errorf("マージする場合は前回の入力に前回生成したプロジェクトのzipを指定してください。")
+	}
+	if gen.option.Merge && len(gen.option.Previous.Base) == 0 {
+		return &generator{}, fmt.Errorf("前回生成したプロジェクトに %s がないためマージできません。", baseDir)
+	}
+	if err := gen.validateSoftDeleteColumns(); err != nil {
+		return &generator{}, err
+	}
+	m, err := gen.newMigration()
+	if err != nil {
+		return &generator{}, err
+	}
+	gen.migration = m
+	return gen, nil
+}
+
+// RDBMS
+var rdbmses = []string{"postgresql", "mysql", "sqlite3"}
+
+// 認証方式
+var authModes = []string{"jwt", "users", "oidc", "basic", "header", "none"}
+
+// 生成するアプリで使用するテーブル名・モジュール名
+var reservedTableNames = []string{"audit", "audit_log", "permission", "schema_migrations"}
+
+func validateTableNames(tables []ddlparse.Table, option Option) error {
+	reserved := append([]string{}, reservedTableNames...)
+	if option.ApiTokens {
+		reserved = append(reserved, "api_token")
+	}
+	if option.AuthMode == "users" {
+		reserved = append(reserved, "users")
+	}
+	if option.History {
+		for _, table := range tables {
+			reserved = append(reserved, strings.ToLower(table.Name) + "_history")
+		}
+	}
+
+	for _, table := range tables {
+		tn := strings.ToLower(table.Name)
+		for _, name := range reserved {
+			if tn == name {
+				return fmt.Errorf("テーブル名 '%s' は予約されているため使用できません。", table.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// users テーブルのユーザでログインするか
+func (gen *generator) isUsersAuth() bool {
+	return gen.option.AuthMode == "users"
+}
+
+// ログイン画面 (JWTのCookie) を使用するか
+func (gen *generator) isLoginAuth() bool {
+	return gen.option.AuthMode == "jwt" || gen.isUsersAuth() || gen.isOidcAuth()
+}
+
+// OpenID Connect でログインするか
+func (gen *generator) isOidcAuth() bool {
+	return gen.option.AuthMode == "oidc"
+}
+
+// 日時の論理削除カラムは未削除をNULLで表すためNULL許容であること
+func (gen *generator) validateSoftDeleteColumns() error {
+	for _, table := range gen.tables {
+		c, found := gen.getSoftDeleteColumn(table)
+		if found && gen.isSoftDeleteTimestamp(c) && !gen.isNullColumn(c, table.Constraints) {
+			return fmt.Errorf("論理削除カラム '%s.%s' はNULL許容である必要があります。", table.Name, c.Name)
+		}
+	}
+	return nil
 }
 
 func (gen *generator) Generate() (string, error) {
@@ -56,6 +209,9 @@ func (gen *generator) Generate() (string, error) {
 	if err := gen.generateSource(path); err != nil {
 		return "", err
 	}
+	if err := gen.mergeProject(path); err != nil {
+		return "", err
+	}
 	filename, err := gen.zipWorkDir(dir)
 	if err != nil {
 		return "", err
@@ -114,6 +270,18 @@ func (gen *generator) generateSource(path string) error {
 	if err := gen.copySomeFiles(path); err != nil {
 		return err
 	}
+	if err := gen.copyUsersFiles(path); err != nil {
+		return err
+	}
+	if err := gen.copyOidcFiles(path); err != nil {
+		return err
+	}
+	if err := gen.copyApiTokenFiles(path); err != nil {
+		return err
+	}
+	if err := gen.removeLoginFiles(path); err != nil {
+		return err
+	}
 	if err := gen.generateInternal(path); err != nil {
 		return err
 	}
@@ -123,6 +291,9 @@ func (gen *generator) generateSource(path string) error {
 	if err := gen.generateScripts(path); err != nil {
 		return err
 	}
+	if err := gen.generateBase(path); err != nil {
+		return err
+	}
 	return nil	
 }
 
@@ -146,6 +317,59 @@ func (gen *generator) copySomeFiles(path string) error {
 	return nil
 }
 
+// ユーザ管理のファイル (_template/users) をコピー
+func (gen *generator) copyUsersFiles(path string) error {
+	if !gen.isUsersAuth() {
+		return nil
+	}
+	if err := CopyDir("_template/users", path); err != nil {
+		logger.Error(err.Error())
+		return err
+	}
+	return nil
+}
+
+// OpenID Connect のファイル (_template/oidc) をコピー (login.html は上書き)
+func (gen *generator) copyOidcFiles(path string) error {
+	if !gen.isOidcAuth() {
+		return nil
+	}
+	if err := CopyDir("_template/oidc", path); err != nil {
+		logger.Error(err.Error())
+		return err
+	}
+	return nil
+}
+
+// APIトークンのファイル (_template/apitoken) をコピー
+func (gen *generator) copyApiTokenFiles(path string) error {
+	if !gen.option.ApiTokens {
+		return nil
+	}
+	if err := CopyDir("_template/apitoken", path); err != nil {
+		logger.Error(err.Error())
+		return err
+	}
+	return nil
+}
+
+// ログイン画面を使用しない認証方式ではログインのファイルを削除 (OIDCはログインフォームの login.js のみ)
+func (gen *generator) removeLoginFiles(path string) error {
+	files := []string{"web/template/login.html", "web/static/js/login.js"}
+	if gen.isOidcAuth() {
+		files = files[1:]
+	} else if gen.isLoginAuth() {
+		return nil
+	}
+	for _, f := range files {
+		if err := os.Remove(fmt.Sprintf("%s/%s", path, f)); err != nil {
+			logger.Error(err.Error())
+			return err
+		}
+	}
+	return nil
+}
+
 /////////////////////////////////////////////////////////////////////////////////
 //////////////////////////////////  internal  //////////////////////////////////
 ///////////////////////////////////////////////////////////////////////////////
@@ -157,6 +381,9 @@ func (gen *generator) generateInternal(path string) error {
 		logger.Error(err.Error())
 		return err
 	}
+	if err := gen.generateMiddleware(path); err != nil {
+		return err
+	}
 	if err := gen.generateModule(path); err != nil {
 		return err
 	}
@@ -166,6 +393,81 @@ func (gen *generator) generateInternal(path string) error {
 	return nil
 }
 
+/////////////////////////////////////////////////////////////////////////////////
+/////////////////////////////  internal/middleware  ////////////////////////////
+///////////////////////////////////////////////////////////////////////////////
+
+// middleware 生成
+func (gen *generator) generateMiddleware(path string) error {
+	path = fmt.Sprintf("%s/middleware", path)
+	if err := MakeDirAll(path); err != nil {
+		logger.Error(err.Error())
+		return err
+	}
+	if err := gen.generateAuthGoFile(path); err != nil {
+		return err
+	}
+	return nil
+}
+
+// auth.go 生成
+func (gen *generator) generateAuthGoFile(path string) error {
+	path = fmt.Sprintf("%s/auth.go", path)
+	code := gen.codeAuthGo()
+	if err := WriteFile(path, code); err != nil {
+		logger.Error(err.Error())
+		return err
+	}
+	return nil
+}
+
+// auth.go コード生成 (認証方式ごとの Auth・ApiAuth)
+func (gen *generator) codeAuthGo() string {
+	switch gen.option.AuthMode {
+	case "basic":
+		return FORMAT_MIDDLEWARE_AUTH_BASIC
+	case "header":
+		return FORMAT_MIDDLEWARE_AUTH_HEADER
+	case "none":
+		return FORMAT_MIDDLEWARE_AUTH_NONE
+	default:
+		imports, params, apiParams, tokens := "", "", []string{}, ""
+		//users のユーザはリクエストごとに削除・ロールの変更を確認
+		reload := "nil"
+		if gen.isUsersAuth() {
+			imports += "\n\t\"masmaint/internal/module/users\""
+			params = "accounts users.Service"
+			reload = "accounts.Reload"
+		}
+		if gen.option.ApiTokens {
+			imports = "\n\t\"masmaint/internal/module/api_token\"" + imports
+			apiParams = append(apiParams, "tokens api_token.Service")
+			tokens = fmt.Sprintf(FORMAT_MIDDLEWARE_AUTH_API_TOKEN, reload)
+		}
+		if params != "" {
+			apiParams = append(apiParams, params)
+		}
+		return fmt.Sprintf(
+			FORMAT_MIDDLEWARE_AUTH_JWT,
+			imports,
+			params, reload,
+			strings.Join(apiParams, ", "), tokens, reload,
+		)
+	}
+}
+
+// middleware.Auth・ApiAuth の引数
+func (gen *generator) codeAuthArgs(api bool) string {
+	args := []string{}
+	if api && gen.option.ApiTokens {
+		args = append(args, "app.ApiToken")
+	}
+	if gen.isUsersAuth() {
+		args = append(args, "app.Users")
+	}
+	return strings.Join(args, ", ")
+}
+
 /////////////////////////////////////////////////////////////////////////////////
 ///////////////////////////////  internal/module  //////////////////////////////
 ///////////////////////////////////////////////////////////////////////////////
@@ -180,9 +482,44 @@ func (gen *generator) generateModule(path string) error {
 	if err := gen.generateTableModules(path); err != nil {
 		return err
 	}
+	if err := gen.generatePermissionMatrixFile(path); err != nil {
+		return err
+	}
+	return nil
+}
+
+// permission/matrix.go 生成
+func (gen *generator) generatePermissionMatrixFile(path string) error {
+	path = fmt.Sprintf("%s/permission/matrix.go", path)
+	code := gen.codePermissionMatrix()
+	if err := WriteFile(path, code); err != nil {
+		logger.Error(err.Error())
+		return err
+	}
 	return nil
 }
 
+// permission/matrix.go コード生成 (admin・editor は全操作、viewer は参照のみ)
+func (gen *generator) codePermissionMatrix() string {
+	all := ""
+	read := ""
+	for _, table := range gen.tables {
+		tn := strings.ToLower(table.Name)
+		all += fmt.Sprintf("\t\t\"%s\": {Read, Create, Update, Delete},\n", tn)
+		read += fmt.Sprintf("\t\t\"%s\": {Read},\n", tn)
+	}
+	all = strings.TrimSuffix(all, "\n")
+	read = strings.TrimSuffix(read, "\n")
+	admin := all
+	if gen.isUsersAuth() {
+		admin = "\t\t\"users\": {Read, Create, Update, Delete},\n" + admin
+	}
+	if gen.option.ApiTokens {
+		admin = "\t\t\"api_token\": {Read, Create, Update, Delete},\n" + admin
+	}
+	return fmt.Sprintf(FORMAT_PERMISSION_MATRIX, admin, all, read)
+}
+
 func (gen *generator) generateTableModules(path string) error {
 	for _, table := range gen.tables {
 		if err := gen.generateTableModule(path, table); err != nil {
@@ -219,9 +556,27 @@ func (gen *generator) generateTableModuleFiles(path string, table ddlparse.Table
 	if err := gen.generateServiceGoFile(path, table); err != nil {
 		return err
 	}
+	if err := gen.generateHooksGoFile(path, table); err != nil {
+		return err
+	}
 	if err := gen.generateRepositoryGoFile(path, table); err != nil {
 		return err
 	}
+	if err := gen.generateCsvGoFile(path, table); err != nil {
+		return err
+	}
+	if err := gen.generateXlsxGoFile(path, table); err != nil {
+		return err
+	}
+	if err := gen.generateAuditGoFile(path, table); err != nil {
+		return err
+	}
+	if err := gen.generateEventGoFile(path, table); err != nil {
+		return err
+	}
+	if err := gen.generateTestGoFiles(path, table); err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -243,12 +598,46 @@ func (gen *generator) generateControllerGoFile(path string, table ddlparse.Table
 // controller.go コード生成
 func (gen *generator) codeControllerGo(table ddlparse.Table) string {
 	tn := strings.ToLower(table.Name)
+	kp := gen.getKeyRoutePath(table)
 	return fmt.Sprintf(
 		FORMAT_CONTROLLER, 
-		tn, tn, tn, tn, tn, tn, tn,
+		tn, tn, tn, tn, tn, tn, kp, tn, tn, kp, tn, kp, tn, kp, tn, tn, tn, tn, tn, tn,
+		gen.codeControllerBodyKey(table),
+		gen.codeControllerHistory(table),
+		gen.codeControllerSoftDelete(table),
 	)
 }
 
+func (gen *generator) codeControllerBodyKey(table ddlparse.Table) string {
+	if !gen.option.BodyKeyRoutes {
+		return ""
+	}
+	tn := strings.ToLower(table.Name)
+	return fmt.Sprintf(FORMAT_CONTROLLER_BODY_KEY, tn, tn)
+}
+
+func (gen *generator) codeControllerHistory(table ddlparse.Table) string {
+	if !gen.isHistoryRoutesTable(table) {
+		return ""
+	}
+	tn := strings.ToLower(table.Name)
+	kp := gen.getKeyRoutePath(table)
+	return fmt.Sprintf(FORMAT_CONTROLLER_HISTORY, tn, kp, tn, kp)
+}
+
+func (gen *generator) codeControllerSoftDelete(table ddlparse.Table) string {
+	if !gen.isTrashTable(table) {
+		return ""
+	}
+	tn := strings.ToLower(table.Name)
+	kp := gen.getKeyRoutePath(table)
+	keys := []string{}
+	for _, c := range gen.getPrimaryKeyColumns(table) {
+		keys = append(keys, strings.ToLower(c.Name))
+	}
+	return fmt.Sprintf(FORMAT_CONTROLLER_SOFT_DELETE, tn, tn, strings.Join(keys, ","), tn, tn, tn, kp)
+}
+
 /////////////////////////////////////////////////////////////////////////////////
 //////////////////////////////////  model.go  //////////////////////////////////
 ///////////////////////////////////////////////////////////////////////////////
@@ -283,9 +672,19 @@ func (gen *generator)codeModelGo(table ddlparse.Table) string {
 	return fmt.Sprintf(
 		FORMAT_MODEL, 
 		tn, tnp, fields,
+		gen.codeModelHistory(table),
 	)
 }
 
+func (gen *generator)codeModelHistory(table ddlparse.Table) string {
+	if !gen.option.History {
+		return ""
+	}
+	tn := strings.ToLower(table.Name)
+	tnp := SnakeToPascal(tn)
+	return fmt.Sprintf(FORMAT_MODEL_HISTORY, tn, tnp, tnp)
+}
+
 /////////////////////////////////////////////////////////////////////////////////
 /////////////////////////////////  request.go  /////////////////////////////////
 ///////////////////////////////////////////////////////////////////////////////
@@ -303,12 +702,17 @@ func (gen *generator) generateRequestGoFile(path string, table ddlparse.Table) e
 
 // request.go コード生成
 func (gen *generator)codeRequestGo(table ddlparse.Table) string {
+	tn := strings.ToLower(table.Name)
 	return fmt.Sprintf(
 		FORMAT_REQUEST, 
-		strings.ToLower(table.Name), 
+		tn,
 		gen.codeRequestPostBodyFields(table), 
 		gen.codeRequestPutBodyFields(table),
-		gen.codeRequestDeleteBodyFields(table),
+		gen.codeRequestPatchBodyFields(table),
+		gen.codeRequestKeyFields(table),
+		gen.codeRequestPatchDecodeFields(table),
+		SnakeToPascal(tn),
+		gen.codeRequestPatchApply(table),
 	)
 }
 
@@ -335,10 +739,7 @@ func (gen *generator)codeRequestPutBodyFields(table ddlparse.Table) string {
     tn := strings.ToLower(table.Name)
     
     code := ""
-    for _, c := range table.Columns {
-        if strings.Contains(c.Name, "_at") || strings.Contains(c.Name, "_AT") {
-            continue
-        }
+    for _, c := range gen.getUpdateColumns(table) {
         cn := strings.ToLower(c.Name)
         code += fmt.Sprintf("\t%s ", gen.getFieldName(cn ,tn))
         if gen.isNullColumn(c, table.Constraints) {
@@ -353,20 +754,55 @@ func (gen *generator)codeRequestPutBodyFields(table ddlparse.Table) string {
     return strings.TrimSuffix(code, "\n")
 }
 
-func (gen *generator)codeRequestDeleteBodyFields(table ddlparse.Table) string {
+func (gen *generator)codeRequestPatchBodyFields(table ddlparse.Table) string {
     tn := strings.ToLower(table.Name)
     code := ""
-    for _, c := range gen.getPrimaryKeyColumns(table) {
+    for _, c := range gen.getUpdateColumns(table) {
         cn := strings.ToLower(c.Name)
-        code += fmt.Sprintf("\t%s ", gen.getFieldName(cn ,tn))
+        code += fmt.Sprintf(
+            "\t%s optional.Value[%s] `json:\"%s\"`\n",
+            gen.getFieldName(cn ,tn), gen.dataTypeToGoType(c.DataType.Name), cn,
+        )
+    }
+    return strings.TrimSuffix(code, "\n")
+}
+
+func (gen *generator)codeRequestPatchDecodeFields(table ddlparse.Table) string {
+    tn := strings.ToLower(table.Name)
+    code := ""
+    for _, c := range gen.getUpdateColumns(table) {
+        cn := strings.ToLower(c.Name)
+        code += fmt.Sprintf("\n\t\t\"%s\": &b.%s,", cn, gen.getFieldName(cn ,tn))
+    }
+    return code
+}
+
+func (gen *generator)codeRequestPatchApply(table ddlparse.Table) string {
+    tn := strings.ToLower(table.Name)
+    code := ""
+    for _, c := range gen.getUpdateColumns(table) {
+        fn := gen.getFieldName(c.Name ,tn)
+        code += fmt.Sprintf("\n\tif b.%s.Set {\n", fn)
         if gen.isNullColumn(c, table.Constraints) {
-            code += "*"
-        }
-        code += fmt.Sprintf("%s `json:\"%s\"", gen.dataTypeToGoType(c.DataType.Name), cn)
-        if !gen.isNullColumn(c, table.Constraints) {
-            code += " binding:\"required\""
+            code += fmt.Sprintf("\t\tm.%s = b.%s.Ptr()\n", fn, fn)
+        } else {
+            code += fmt.Sprintf("\t\tm.%s = b.%s.Value\n", fn, fn)
         }
-        code += "`\n"
+        code += "\t}"
+    }
+    return code
+}
+
+func (gen *generator)codeRequestKeyFields(table ddlparse.Table) string {
+    tn := strings.ToLower(table.Name)
+    code := ""
+    for _, c := range gen.getPrimaryKeyColumns(table) {
+        cn := strings.ToLower(c.Name)
+        code += fmt.Sprintf("\t%s ", gen.getFieldName(cn ,tn))
+        code += fmt.Sprintf(
+            "%s `uri:\"%s\" json:\"%s\" binding:\"required\"`\n",
+            gen.dataTypeToGoType(c.DataType.Name), cn, cn,
+        )
     }
     return strings.TrimSuffix(code, "\n")
 }
@@ -399,6 +835,8 @@ func (gen *generator)codeRepositoryGo(table ddlparse.Table) string {
 		gen.codeRepositoryInsert(table),
 		gen.codeRepositoryUpdate(table),
 		gen.codeRepositoryDelete(table),
+		gen.codeRepositoryHistory(table),
+		gen.codeRepositorySoftDelete(table),
 	)
 }
 
@@ -420,6 +858,37 @@ func (gen *generator)codeRepositoryInterface(table ddlparse.Table) string {
 		tni, tnp, retType,
 		tni, tnp, 
 		tni, tnp,
+		gen.codeRepositoryInterfaceHistory(table),
+		gen.codeRepositoryInterfaceSoftDelete(table),
+	)
+}
+
+func (gen *generator)codeRepositoryInterfaceSoftDelete(table ddlparse.Table) string {
+	if !gen.isTrashTable(table) {
+		return ""
+	}
+	tn := strings.ToLower(table.Name)
+	tnp := SnakeToPascal(tn)
+	tni := GetSnakeInitial(tn)
+	return fmt.Sprintf(
+		FORMAT_REPOSITORY_INTERFACE_SOFT_DELETE,
+		tni, tnp, tnp,
+		tni, tnp,
+	)
+}
+
+func (gen *generator)codeRepositoryInterfaceHistory(table ddlparse.Table) string {
+	if !gen.option.History {
+		return ""
+	}
+	tn := strings.ToLower(table.Name)
+	tnp := SnakeToPascal(tn)
+	tni := GetSnakeInitial(tn)
+	return fmt.Sprintf(
+		FORMAT_REPOSITORY_INTERFACE_HISTORY,
+		tni, tnp,
+		tni, tnp, tnp,
+		tni, tnp, tnp,
 	)
 }
 
@@ -447,7 +916,7 @@ func (gen *generator)codeRepositoryGet(table ddlparse.Table) string {
 	return fmt.Sprintf(
 		FORMAT_REPOSITORY_GET,
 		tni, tnp, tnp, tni, 
-		query,
+		query, gen.codeRepositoryWhere(table),
 		tnp, tnp, tni, tnp,
 		scan,
 		tnp, tni,
@@ -478,10 +947,74 @@ func (gen *generator)codeRepositoryGetOne(table ddlparse.Table) string {
 	return fmt.Sprintf(
 		FORMAT_REPOSITORY_GETONE,
 		tni, tnp, tnp, tnp, tni, 
-		query, scan,
+		query, gen.codeRepositoryWhere(table), scan,
 	) 
 }
 
+// 取得時のWHERE句 (論理削除カラムがある場合は削除済みの行を除く)
+func (gen *generator)codeRepositoryWhere(table ddlparse.Table) string {
+	c, found := gen.getSoftDeleteColumn(table)
+	if !found {
+		return "where"
+	}
+	return fmt.Sprintf("db.AndWhere(where, \"%s\")", gen.getActiveCondition(c))
+}
+
+func (gen *generator)codeRepositoryHistory(table ddlparse.Table) string {
+	if !gen.option.History {
+		return ""
+	}
+	tn := strings.ToLower(table.Name)
+	tnp := SnakeToPascal(tn)
+	tni := GetSnakeInitial(tn)
+	htn := gen.getHistoryTableName(table)
+
+	//INSERT (プレースホルダは ? で記述し rep.db.Rebind で変換)
+	insert := fmt.Sprintf("\n\t`INSERT INTO %s (\n\t\thistory_operation\n\t\t,history_changed_at", htn)
+	for _, c := range table.Columns {
+		insert += fmt.Sprintf("\n\t\t,%s", c.Name)
+	}
+	insert += fmt.Sprintf("\n\t ) VALUES(%s)`\n", strings.TrimSuffix(strings.Repeat("?,", len(table.Columns) + 2), ","))
+	insertBinds := ""
+	for _, c := range table.Columns {
+		insertBinds += fmt.Sprintf("\n\t\t%s.%s,", tni, gen.getFieldName(c.Name ,tn))
+	}
+
+	query := "\n\t`SELECT\n\t\thistory_id\n\t\t,history_operation\n\t\t,history_changed_at"
+	for _, c := range table.Columns {
+		query += fmt.Sprintf("\n\t\t,%s", c.Name)
+	}
+	query += fmt.Sprintf("\n\t FROM %s\n\t WHERE ", htn)
+	pkcols := gen.getPrimaryKeyColumns(table)
+	if len(pkcols) == 0 {
+		query += "1 = 1"
+	}
+	keyBinds := []string{}
+	for i, c := range pkcols {
+		if i > 0 {
+			query += "\n\t   AND "
+		}
+		query += fmt.Sprintf("%s = ?", c.Name)
+		keyBinds = append(keyBinds, fmt.Sprintf("%s.%s", tni, gen.getFieldName(c.Name ,tn)))
+	}
+	query += "`"
+
+	scan := "\n\t\t\t&h.HistoryId,\n\t\t\t&h.HistoryOperation,\n\t\t\t&h.HistoryChangedAt,\n"
+	for _, c := range table.Columns {
+		scan += fmt.Sprintf("\t\t\t&h.%s,\n", gen.getFieldName(c.Name ,tn))
+	}
+	scan += "\t\t"
+	scanOne := strings.ReplaceAll(scan, "\n\t\t", "\n\t")
+
+	return fmt.Sprintf(
+		FORMAT_REPOSITORY_HISTORY,
+		tni, tnp, insert, insertBinds,
+		tni, tnp, tnp, query, strings.Join(keyBinds, ", "),
+		tnp, tnp, tnp, scan, tnp,
+		tni, tnp, tnp, tnp, query, strings.Join(append(keyBinds, "historyId"), ", "), scanOne,
+	)
+}
+
 func (gen *generator)getBindVar(n int) string {
 	if gen.rdbms == "postgresql" {
 		return fmt.Sprintf("$%d", n)
@@ -660,23 +1193,69 @@ func (gen *generator)codeRepositoryDelete(table ddlparse.Table) string {
 	tnp := SnakeToPascal(tn)
 	tni := GetSnakeInitial(tn)
 
+	//論理削除カラムがある場合は削除済みにする更新
+	cmd := fmt.Sprintf("\"DELETE FROM %s \" + where", tn)
+	if c, found := gen.getSoftDeleteColumn(table); found {
+		deleted, _ := gen.getSoftDeleteSetValues(c)
+		cmd = fmt.Sprintf(
+			"\"UPDATE %s SET %s = %s\" + db.AndWhere(where, \"%s\")",
+			tn, strings.ToLower(c.Name), deleted, gen.getActiveCondition(c),
+		)
+	}
+
 	return fmt.Sprintf(
 		FORMAT_REPOSITORY_DELETE, 
-		tni, tnp, tni, tn,
+		tni, tnp, tni, cmd,
 	) 
 }
 
-/////////////////////////////////////////////////////////////////////////////////
-/////////////////////////////////  service.go  /////////////////////////////////
-///////////////////////////////////////////////////////////////////////////////
-
-// service.go 生成
-func (gen *generator) generateServiceGoFile(path string, table ddlparse.Table) error {
-	path = fmt.Sprintf("%s/service.go", path)
-	code := gen.codeServiceGo(table)
-	if err := WriteFile(path, code); err != nil {
-		logger.Error(err.Error())
-		return err
+func (gen *generator)codeRepositorySoftDelete(table ddlparse.Table) string {
+	if !gen.isTrashTable(table) {
+		return ""
+	}
+	tn := strings.ToLower(table.Name)
+	tnp := SnakeToPascal(tn)
+	tni := GetSnakeInitial(tn)
+	c, _ := gen.getSoftDeleteColumn(table)
+	_, restored := gen.getSoftDeleteSetValues(c)
+
+	query := "\n\t`SELECT"
+	for i, c := range table.Columns {
+		if i == 0 {
+			query += fmt.Sprintf("\n\t\t%s", c.Name)
+		} else {
+			query += fmt.Sprintf("\n\t\t,%s", c.Name)
+		}
+	}
+	query += fmt.Sprintf("\n\t FROM %s`", tn)
+
+	scan := "\n"
+	for _, c := range table.Columns {
+		scan += fmt.Sprintf("\t\t\t&%s.%s,\n", tni, gen.getFieldName(c.Name ,tn))
+	}
+	scan += "\t\t"
+
+	return fmt.Sprintf(
+		FORMAT_REPOSITORY_SOFT_DELETE,
+		tni, tnp, tnp, tni,
+		query, gen.getDeletedCondition(c),
+		tnp, tnp, tni, tnp, scan, tnp, tni,
+		tni, tnp, tni,
+		tn, strings.ToLower(c.Name), restored, gen.getDeletedCondition(c),
+	)
+}
+
+/////////////////////////////////////////////////////////////////////////////////
+/////////////////////////////////  service.go  /////////////////////////////////
+///////////////////////////////////////////////////////////////////////////////
+
+// service.go 生成
+func (gen *generator) generateServiceGoFile(path string, table ddlparse.Table) error {
+	path = fmt.Sprintf("%s/service.go", path)
+	code := gen.codeServiceGo(table)
+	if err := WriteFile(path, code); err != nil {
+		logger.Error(err.Error())
+		return err
 	}
 	return nil
 }
@@ -687,11 +1266,21 @@ func (gen *generator) codeServiceGo(table ddlparse.Table) string {
 	tnp := SnakeToPascal(tn)
 	return fmt.Sprintf(
 		FORMAT_SERVICE, 
-		tn, tnp, tnp, tnp,
+		tn, tnp, tnp, tnp, tnp, tnp,
+		gen.codeServiceInterfaceHistory(table),
+		gen.codeServiceInterfaceSoftDelete(table),
+		tnp, tnp, tnp, tnp, tnp, tnp, tnp, tnp,
 		gen.codeServiceGet(table),
+		gen.codeServiceGetOne(table),
 		gen.codeServiceCreate(table),
 		gen.codeServiceUpdate(table),
+		gen.codeServicePatch(table),
 		gen.codeServiceDelete(table),
+		gen.codeServiceTx(table),
+		gen.codeServiceCsv(table),
+		gen.codeServiceXlsx(table),
+		gen.codeServiceHistory(table),
+		gen.codeServiceSoftDelete(table),
 	)
 }
 
@@ -705,34 +1294,82 @@ func (gen *generator)codeServiceGet(table ddlparse.Table) string {
 	) 
 }
 
+func (gen *generator)codeServiceGetOne(table ddlparse.Table) string {
+	tn := strings.ToLower(table.Name)
+	tnp := SnakeToPascal(tn)
+
+	return fmt.Sprintf(
+		FORMAT_SERVICE_GETONE,
+		tnp, tnp, tnp, tnp,
+	) 
+}
+
 func (gen *generator)codeServiceCreate(table ddlparse.Table) string {
+	tn := strings.ToLower(table.Name)
+	tnp := SnakeToPascal(tn)
+
+	return fmt.Sprintf(
+		FORMAT_SERVICE_CREATE,
+		tnp, tnp, tnp, tnp,
+	) 
+}
+
+func (gen *generator)codeServiceUpdate(table ddlparse.Table) string {
+	tn := strings.ToLower(table.Name)
+	tnp := SnakeToPascal(tn)
+
+	return fmt.Sprintf(
+		FORMAT_SERVICE_UPDATE,
+		tnp, tnp, tnp, tnp,
+	) 
+}
+
+func (gen *generator)codeServicePatch(table ddlparse.Table) string {
+	tn := strings.ToLower(table.Name)
+	tnp := SnakeToPascal(tn)
+
+	return fmt.Sprintf(
+		FORMAT_SERVICE_PATCH,
+		tnp, tnp, tnp, tnp,
+	) 
+}
+
+func (gen *generator)codeServiceDelete(table ddlparse.Table) string {
+	tn := strings.ToLower(table.Name)
+	tnp := SnakeToPascal(tn)
+
+	return fmt.Sprintf(FORMAT_SERVICE_DELETE, tnp) 
+}
+
+// トランザクション内の登録・更新・削除 (監査ログを記録)
+func (gen *generator)codeServiceTx(table ddlparse.Table) string {
+	return strings.Join([]string{
+		gen.codeServiceInsertTx(table),
+		gen.codeServiceUpdateTx(table),
+		gen.codeServiceDeleteTx(table),
+		gen.codeServiceChangeLog(table),
+	}, "\n\n\n")
+}
+
+func (gen *generator)codeServiceInsertTx(table ddlparse.Table) string {
 	_, found := gen.getAutoIncrementColumn(table)
 	if found {
-		return gen.codeServiceCreateAI(table)
+		return gen.codeServiceInsertTxAI(table)
 	}
-	return gen.codeServiceCreateNomal(table)
+	return gen.codeServiceInsertTxNomal(table)
 }
 
-func (gen *generator)codeServiceCreateNomal(table ddlparse.Table) string {
+func (gen *generator)codeServiceInsertTxNomal(table ddlparse.Table) string {
 	tn := strings.ToLower(table.Name)
 	tnp := SnakeToPascal(tn)
 
-	s1 := ""
-	for i, c := range gen.getPrimaryKeyColumns(table) {
-		if i > 0 {
-			s1 += ", "
-		} 
-		fn := gen.getFieldName(c.Name ,tn)
-		s1 += fmt.Sprintf("%s: input.%s", fn, fn)
-	}
-
 	return fmt.Sprintf(
-		FORMAT_SERVICE_CREATE,
-		tnp, tnp, tnp, tnp, tnp, s1, tnp,
+		FORMAT_SERVICE_INSERT,
+		tnp, tnp, tnp, tnp, tnp, tnp,
 	) 
 }
 
-func (gen *generator)codeServiceCreateAI(table ddlparse.Table) string {
+func (gen *generator)codeServiceInsertTxAI(table ddlparse.Table) string {
 	tn := strings.ToLower(table.Name)
 	tnp := SnakeToPascal(tn)
 	aicol, _ := gen.getAutoIncrementColumn(table)
@@ -741,477 +1378,2338 @@ func (gen *generator)codeServiceCreateAI(table ddlparse.Table) string {
 	fn := gen.getFieldName(aicn ,tn)
 
 	return fmt.Sprintf(
-		FORMAT_SERVICE_CREATE_AI,
-		tnp, tnp, aicnc, tnp, tnp, tnp, fmt.Sprintf("%s: %s", fn, aicnc), tnp,
+		FORMAT_SERVICE_INSERT_AI,
+		tnp, tnp, tnp, aicnc, tnp, tnp, fmt.Sprintf("%s: %s", fn, aicnc), tnp, tnp,
 	) 
 }
 
-func (gen *generator)codeServiceUpdate(table ddlparse.Table) string {
+func (gen *generator)codeServiceUpdateTx(table ddlparse.Table) string {
 	tn := strings.ToLower(table.Name)
 	tnp := SnakeToPascal(tn)
 
-	s1 := ""
-	for i, c := range gen.getPrimaryKeyColumns(table) {
-		if i > 0 {
-			s1 += ", "
-		} 
-		fn := gen.getFieldName(c.Name ,tn)
-		s1 += fmt.Sprintf("%s: input.%s", fn, fn)
-	}
-
 	return fmt.Sprintf(
-		FORMAT_SERVICE_UPDATE,
-		tnp, tnp, tnp, tnp, tnp, s1, tnp,
+		FORMAT_SERVICE_UPDATE_TX,
+		tnp, tnp, tnp, tnp, tnp, tnp, tnp,
 	) 
 }
 
-func (gen *generator)codeServiceDelete(table ddlparse.Table) string {
+func (gen *generator)codeServiceDeleteTx(table ddlparse.Table) string {
 	tn := strings.ToLower(table.Name)
 	tnp := SnakeToPascal(tn)
 
-	return fmt.Sprintf(FORMAT_SERVICE_DELETE, tnp) 
+	return fmt.Sprintf(FORMAT_SERVICE_DELETE_TX, tnp) 
 }
 
-/////////////////////////////////////////////////////////////////////////////////
-///////////////////////////////  internal/server  //////////////////////////////
-///////////////////////////////////////////////////////////////////////////////
+func (gen *generator)codeServiceChangeLog(table ddlparse.Table) string {
+	tn := strings.ToLower(table.Name)
+	tnp := SnakeToPascal(tn)
 
-// server 生成
-func (gen *generator) generateServer(path string) error {
-	path = fmt.Sprintf("%s/server", path)
-	if err := MakeDirAll(path); err != nil {
-		logger.Error(err.Error())
-		return err
-	}
-	if err := gen.generateRouterGoFile(path); err != nil {
-		return err
+	if gen.option.History {
+		return fmt.Sprintf(FORMAT_SERVICE_CHANGELOG_HISTORY, tnp, tnp)
 	}
-	return nil
+	return fmt.Sprintf(FORMAT_SERVICE_CHANGELOG, tnp, tnp)
 }
 
-// router.go 生成
-func (gen *generator) generateRouterGoFile(path string) error {
-	path = fmt.Sprintf("%s/router.go", path)
-	code := gen.codeRouterGo()
-	if err := WriteFile(path, code); err != nil {
-		logger.Error(err.Error())
-		return err
+func (gen *generator)codeServiceInterfaceHistory(table ddlparse.Table) string {
+	if !gen.option.History {
+		return ""
 	}
-	return nil
+	tn := strings.ToLower(table.Name)
+	tnp := SnakeToPascal(tn)
+
+	return fmt.Sprintf(FORMAT_SERVICE_INTERFACE_HISTORY, tnp, tnp)
 }
 
-// router.go コード生成
-func (gen *generator) codeRouterGo() string {
-	s1 := ""
-	for _, table := range gen.tables {
-		s1 += fmt.Sprintf("\t\"masmaint/internal/module/%s\"\n", strings.ToLower(table.Name))
+func (gen *generator)codeServiceHistory(table ddlparse.Table) string {
+	if !gen.option.History {
+		return ""
 	}
-	s1 = strings.TrimSuffix(s1, "\n")
+	tn := strings.ToLower(table.Name)
+	tnp := SnakeToPascal(tn)
+
 	return fmt.Sprintf(
-		FORMAT_ROUTER, 
-		s1,
-		gen.CodeRouterSetWebRouter(),
-		gen.CodeRouterSetApiRouter(),
+		FORMAT_SERVICE_HISTORY,
+		tnp, tnp, tnp, tnp,
+		tnp, tnp, tnp, tnp, tnp,
 	)
 }
 
-func (gen *generator) CodeRouterSetWebRouter() string {
-	s1 := ""
-	for _, table := range gen.tables {
-		tn := strings.ToLower(table.Name)
-		tnc := SnakeToCamel(tn)
-		s1 += fmt.Sprintf("\t%sController := %s.NewController()\n", tnc, tn)
-	}
-	s1 = strings.TrimSuffix(s1, "\n")
-	s2 := ""
-	for _, table := range gen.tables {
-		tn := strings.ToLower(table.Name)
-		tnc := SnakeToCamel(tn)
-		s2 += fmt.Sprintf("\t\tauth.GET(\"/%s\", %sController.GetPage)\n", tn, tnc)
+func (gen *generator)codeServiceInterfaceSoftDelete(table ddlparse.Table) string {
+	if !gen.isTrashTable(table) {
+		return ""
 	}
-	s2 = strings.TrimSuffix(s2, "\n")
-	return fmt.Sprintf(
-		FORMAT_ROUTER_SETWEB, 
-		s1, s2,
-	)
+	tn := strings.ToLower(table.Name)
+	tnp := SnakeToPascal(tn)
+
+	return fmt.Sprintf(FORMAT_SERVICE_INTERFACE_SOFT_DELETE, tnp, tnp)
 }
 
-func (gen *generator) CodeRouterSetApiRouter() string {
-	s1 := ""
-	for _, table := range gen.tables {
-		tn := strings.ToLower(table.Name)
-		tnc := SnakeToCamel(tn)
-		s1 += fmt.Sprintf("\t%sController := %s.NewController()\n", tnc, tn)
-	}
-	s1 = strings.TrimSuffix(s1, "\n")
-	s2 := ""
-	for _, table := range gen.tables {
-		tn := strings.ToLower(table.Name)
-		tnc := SnakeToCamel(tn)
-		s2 += fmt.Sprintf("\t\tauth.GET(\"/%s\", %sController.Get)\n", tn, tnc)
-		s2 += fmt.Sprintf("\t\tauth.POST(\"/%s\", %sController.Post)\n", tn, tnc)
-		s2 += fmt.Sprintf("\t\tauth.PUT(\"/%s\", %sController.Put)\n", tn, tnc)
-		s2 += fmt.Sprintf("\t\tauth.DELETE(\"/%s\", %sController.Delete)\n\n", tn, tnc)
+func (gen *generator)codeServiceSoftDelete(table ddlparse.Table) string {
+	if !gen.isTrashTable(table) {
+		return ""
 	}
-	s2 = strings.TrimSuffix(s2, "\n\n")
+	tn := strings.ToLower(table.Name)
+	tnp := SnakeToPascal(tn)
+
 	return fmt.Sprintf(
-		FORMAT_ROUTER_SETAPI, 
-		s1, s2,
+		FORMAT_SERVICE_SOFT_DELETE,
+		tnp, tnp, tnp,
+		tnp, tnp, tnp, tnp,
 	)
 }
 
-/////////////////////////////////////////////////////////////////////////////////
-/////////////////////////////////////  web  ////////////////////////////////////
-///////////////////////////////////////////////////////////////////////////////
+func (gen *generator)codeServiceCsv(table ddlparse.Table) string {
+	tn := strings.ToLower(table.Name)
+	tnp := SnakeToPascal(tn)
 
-// web 生成
-func (gen *generator) generateWeb(path string) error {
-	path = fmt.Sprintf("%s/web", path)
-	if err := MakeDirAll(path); err != nil {
-		logger.Error(err.Error())
-		return err
-	}
-	if err := gen.generateStatic(path); err != nil {
-		return err
-	}
-	if err := gen.generateTemplate(path); err != nil {
-		return err
-	}
-	return nil
+	return fmt.Sprintf(
+		FORMAT_SERVICE_CSV,
+		tnp, tnp, tnp, tnp,
+	) 
 }
 
-/////////////////////////////////////////////////////////////////////////////////
-/////////////////////////////////  web/static  /////////////////////////////////
-///////////////////////////////////////////////////////////////////////////////
+func (gen *generator)codeServiceXlsx(table ddlparse.Table) string {
+	tn := strings.ToLower(table.Name)
 
-// static 生成
-func (gen *generator) generateStatic(path string) error {
-	path = fmt.Sprintf("%s/static", path)
-	if err := MakeDirAll(path); err != nil {
-		logger.Error(err.Error())
-		return err
-	}
-	if err := gen.generateJs(path); err != nil {
-		return err
-	}
-	return nil
+	return fmt.Sprintf(FORMAT_SERVICE_XLSX, tn) 
 }
 
 /////////////////////////////////////////////////////////////////////////////////
-////////////////////////////////  web/static/js  ///////////////////////////////
+//////////////////////////////////  hooks.go  //////////////////////////////////
 ///////////////////////////////////////////////////////////////////////////////
 
-// js 生成
-func (gen *generator) generateJs(path string) error {
-	path = fmt.Sprintf("%s/js", path)
-	if err := MakeDirAll(path); err != nil {
+// hooks.go 生成 (利用者が編集するファイルのため、再生成では現在のプロジェクトの内容を残す)
+func (gen *generator) generateHooksGoFile(path string, table ddlparse.Table) error {
+	path = fmt.Sprintf("%s/hooks.go", path)
+	code := gen.codeHooksGo(table)
+	if err := WriteFile(path, code); err != nil {
 		logger.Error(err.Error())
 		return err
 	}
-	if err := gen.generateTableJsFiles(path); err != nil {
-		return err
-	}
 	return nil
 }
 
+// hooks.go コード生成
+func (gen *generator) codeHooksGo(table ddlparse.Table) string {
+	tn := strings.ToLower(table.Name)
+	tnp := SnakeToPascal(tn)
+	return fmt.Sprintf(
+		FORMAT_HOOKS,
+		tn, tnp, tnp, tnp, tnp, tnp, tnp, tnp, tnp,
+	)
+}
+
 /////////////////////////////////////////////////////////////////////////////////
-////////////////////////////////  table_name.js  ///////////////////////////////
+////////////////////////////////////  _test.go  ////////////////////////////////
 ///////////////////////////////////////////////////////////////////////////////
 
-func (gen *generator) generateTableJsFiles(path string) error {
-	for _, table := range gen.tables {
-		if err := gen.generateTableJsFile(path, table); err != nil {
+// テスト生成 (リポジトリのモック・サービスとコントローラのテスト・リポジトリの結合テスト)
+func (gen *generator) generateTestGoFiles(path string, table ddlparse.Table) error {
+	files := map[string]string{
+		"mock_repository_test.go": gen.codeMockRepositoryGo(table),
+		"service_test.go": gen.codeServiceTestGo(table),
+		"controller_test.go": gen.codeControllerTestGo(table),
+		"repository_test.go": gen.codeRepositoryTestGo(table),
+	}
+	for name, code := range files {
+		if err := WriteFile(fmt.Sprintf("%s/%s", path, name), code); err != nil {
+			logger.Error(err.Error())
 			return err
 		}
 	}
 	return nil
 }
 
-// table_name.js 生成
-func (gen *generator) generateTableJsFile(path string, table ddlparse.Table) error {
+// mock_repository_test.go コード生成
+func (gen *generator) codeMockRepositoryGo(table ddlparse.Table) string {
 	tn := strings.ToLower(table.Name)
-	path = fmt.Sprintf("%s/%s.js", path, tn)
-	code := gen.codeTableJs(table)
-	if err := WriteFile(path, code); err != nil {
-		logger.Error(err.Error())
-		return err
+	tnp := SnakeToPascal(tn)
+
+	nextId := ""
+	insert := fmt.Sprintf(FORMAT_MOCK_REPOSITORY_INSERT, tnp)
+	if aicol, found := gen.getAutoIncrementColumn(table); found {
+		nextId = "\n\tnextId int"
+		fn := gen.getFieldName(aicol.Name, tn)
+		insert = fmt.Sprintf(FORMAT_MOCK_REPOSITORY_INSERT_AI, tnp, fn, fn)
 	}
-	return nil
+	history := ""
+	if gen.option.History {
+		history = fmt.Sprintf(FORMAT_MOCK_REPOSITORY_HISTORY, tnp, tnp, tnp, tnp, tnp, tnp, tnp)
+	}
+	softDelete := ""
+	if gen.isTrashTable(table) {
+		softDelete = fmt.Sprintf(FORMAT_MOCK_REPOSITORY_SOFT_DELETE, tnp, tnp, tnp, tnp)
+	}
+
+	return fmt.Sprintf(
+		FORMAT_MOCK_REPOSITORY,
+		tn, tnp, nextId,
+		tnp, tnp, tnp,
+		tnp, tnp, tnp,
+		insert,
+		tnp, tnp, tnp,
+		history, softDelete,
+		tnp, tnp, tnp, tnp, tnp, tnp, tnp, tnp,
+	)
 }
 
-// table_name.js コード生成
-func (gen *generator) codeTableJs(table ddlparse.Table) string {
+// service_test.go コード生成
+func (gen *generator) codeServiceTestGo(table ddlparse.Table) string {
+	tn := strings.ToLower(table.Name)
+	cn := strings.ToLower(gen.getConflictColumn(table).Name)
+
+	//GetConflictColumn が返す名前 (PostgreSQL はカラム名、MySQL・SQLite は テーブル名.カラム名)
+	column := tn + "." + cn
+	if gen.rdbms == "postgresql" {
+		column = cn
+	}
 	return fmt.Sprintf(
-		FORMAT_JS, 
-		gen.codeJsCreateTrNew(table),
-		gen.codeJsCreateTr(table),
-		gen.codeJsGetRows(table),
-		gen.codeJsPutRows(table),
-		gen.codeJsPostRow(table),
-		gen.codeJsDeleteRows(table),
+		FORMAT_SERVICE_TEST,
+		tn, column,
+		gen.getDriverName(), tn, cn,
+		gen.codeTestFields(table, gen.getInsertColumns(table), 1),
+		gen.codeServiceTestKey(table),
 	)
 }
 
-func (gen *generator) codeJsCreateTrNew(table ddlparse.Table) string {
-	s1 := "\n\t\t<td></td>"
+func (gen *generator) codeServiceTestKey(table ddlparse.Table) string {
+	pkcols := gen.getPrimaryKeyColumns(table)
+	if len(pkcols) == 0 {
+		return ""
+	}
+	tnp := SnakeToPascal(strings.ToLower(table.Name))
+
+	//行は NOT NULL のカラムのみ (論理削除のカラムは除く)
+	cols := []ddlparse.Column{}
 	for _, c := range table.Columns {
-		if gen.isInsertColumn(c) {
-			s1 += fmt.Sprintf("\n\t\t<td><input type='text' id='%s_new'></td>", strings.ToLower(c.Name))
-		} else {
-			s1 += "\n\t\t<td><input type='text' disabled></td>"
+		if !gen.isNullColumn(c, table.Constraints) && !gen.isSoftDeleteColumn(c) {
+			cols = append(cols, c)
 		}
 	}
-	return fmt.Sprintf(FORMAT_JS_CREATETRNEW, s1)
+	return fmt.Sprintf(
+		FORMAT_SERVICE_TEST_KEY,
+		tnp, tnp, gen.codeTestFields(table, cols, 1),
+		gen.codeTestFields(table, pkcols, 1),
+		gen.codeTestFields(table, pkcols, 2),
+		gen.codeTestFields(table, gen.getUpdateColumns(table), 2),
+		tnp, tnp, tnp, tnp, tnp,
+	)
 }
 
-func (gen *generator) codeJsCreateTr(table ddlparse.Table) string {
-	s1 := "\n\t\t<td><input class='form-check-input' type='checkbox' name='del' value='${JSON.stringify(elem)}'></td>"
+// controller_test.go コード生成
+func (gen *generator) codeControllerTestGo(table ddlparse.Table) string {
+	tn := strings.ToLower(table.Name)
+	tnp := SnakeToPascal(tn)
+
+	badRequest := ""
+	for _, c := range gen.getInsertColumns(table) {
+		if !gen.isNullColumn(c, table.Constraints) {
+			badRequest = fmt.Sprintf(FORMAT_CONTROLLER_TEST_BAD_REQUEST, tn, strings.ToLower(c.Name))
+			break
+		}
+	}
+
+	routes := ""
+	keyTests := ""
+	if len(gen.getPrimaryKeyColumns(table)) > 0 {
+		kp := gen.getKeyRoutePath(table)
+		routes = fmt.Sprintf("\n\tg.PUT(\"%s\", ctr.Put)\n\tg.DELETE(\"%s\", ctr.Delete)", kp, kp)
+		path := gen.getTestKeyPath(table, 1)
+		missing := gen.getTestKeyPath(table, 2)
+		keyTests = fmt.Sprintf(
+			FORMAT_CONTROLLER_TEST_KEY,
+			tnp, tn, path,
+			tnp, tn, missing,
+			tnp, tn, path,
+			tnp, tn, missing,
+		)
+	}
+	return fmt.Sprintf(
+		FORMAT_CONTROLLER_TEST,
+		tn, tn, routes,
+		tn, badRequest,
+		tn, tn, keyTests,
+	)
+}
+
+// repository_test.go コード生成 (結合テスト)
+func (gen *generator) codeRepositoryTestGo(table ddlparse.Table) string {
+	tn := strings.ToLower(table.Name)
+	tnp := SnakeToPascal(tn)
+	pkcols := gen.getPrimaryKeyColumns(table)
+	aicol, ai := gen.getAutoIncrementColumn(table)
+
+	rowFields := ""
+	compare := []string{}
+	nulls := ""
+	nullFields := []string{}
+	hasUnique := false
+	for _, c := range gen.getInsertColumns(table) {
+		fn := gen.getFieldName(c.Name, tn)
+		//複合主キーは先頭のカラムを共通の値にする (残りのカラムで行を区別できるか確認)
+		n := "n"
+		if len(pkcols) > 1 && c.Name == pkcols[0].Name {
+			n = "1"
+		}
+		v := gen.getTestDbValue(c, n)
+		if cv, ok := gen.getTestCheckValue(c, table.Constraints.Check); ok {
+			v = cv
+		}
+		if gen.isNullColumn(c, table.Constraints) {
+			v = fmt.Sprintf("testutil.Ptr(%s)", v)
+			nulls += fmt.Sprintf("\n\trow.%s = nil", fn)
+			nullFields = append(nullFields, fmt.Sprintf("\"%s\"", fn))
+		}
+		rowFields += fmt.Sprintf("\n\t\t%s: %s,", fn, v)
+		if gen.isComparableColumn(c) {
+			compare = append(compare, fmt.Sprintf("\"%s\"", fn))
+		}
+		hasUnique = hasUnique || c.Constraint.IsUnique
+	}
+	if rowFields != "" {
+		rowFields += "\n\t"
+	}
+
+	stdImports, appImports := "", ""
+	if strings.Contains(rowFields, "fmt.") {
+		stdImports = "\n\t\"fmt\""
+	}
+	if gen.isHistoryRoutesTable(table) {
+		appImports = "\n\t\"masmaint/internal/module/audit\""
+	}
+
+	insert := FORMAT_REPOSITORY_TEST_INSERT
+	aiCheck := ""
+	if ai {
+		fn := gen.getFieldName(aicol.Name, tn)
+		insert = fmt.Sprintf(FORMAT_REPOSITORY_TEST_INSERT_AI, fn)
+		aiCheck = fmt.Sprintf(FORMAT_REPOSITORY_TEST_AI_CHECK, fn, strings.ToLower(aicol.Name))
+	}
+
+	nullTest := ""
+	if len(nullFields) > 0 {
+		nullTest = fmt.Sprintf(FORMAT_REPOSITORY_TEST_NULL, nulls, strings.Join(nullFields, ", "))
+	}
+
+	conflictTest := ""
+	if hasUnique || (len(pkcols) > 0 && !ai) {
+		conflictTest = FORMAT_REPOSITORY_TEST_CONFLICT
+	}
+
+	historyTest := ""
+	if gen.isHistoryRoutesTable(table) {
+		historyTest = FORMAT_REPOSITORY_TEST_HISTORY
+	}
+
+	return fmt.Sprintf(
+		FORMAT_REPOSITORY_TEST,
+		tn, stdImports, appImports,
+		tnp, tnp, rowFields,
+		strings.Join(compare, ", "),
+		gen.getDriverName(),
+		tnp, insert,
+		tnp, tnp,
+		aiCheck, nullTest,
+		tnp, tnp,
+		gen.codeRepositoryTestUpdate(table),
+		gen.codeRepositoryTestDelete(table),
+		conflictTest, historyTest,
+	)
+}
+
+func (gen *generator) codeRepositoryTestUpdate(table ddlparse.Table) string {
+	pkcols := gen.getPrimaryKeyColumns(table)
+	cols := gen.getUpdateColumns(table)
+	if len(pkcols) == 0 || len(cols) == 0 {
+		return ""
+	}
+	tn := strings.ToLower(table.Name)
+	tnp := SnakeToPascal(tn)
+
+	code := ""
+	for _, c := range pkcols {
+		fn := gen.getFieldName(c.Name, tn)
+		code += fmt.Sprintf("\n\tupd.%s = rows[0].%s", fn, fn)
+	}
+	compare := []string{}
+	for _, c := range cols {
+		fn := gen.getFieldName(c.Name, tn)
+		if gen.isNullColumn(c, table.Constraints) {
+			code += fmt.Sprintf("\n\tupd.%s = nil", fn)
+			compare = append(compare, fmt.Sprintf("\"%s\"", fn))
+		} else if gen.isComparableColumn(c) {
+			compare = append(compare, fmt.Sprintf("\"%s\"", fn))
+		}
+	}
+	return fmt.Sprintf(FORMAT_REPOSITORY_TEST_UPDATE, tnp, code, strings.Join(compare, ", "))
+}
+
+func (gen *generator) codeRepositoryTestDelete(table ddlparse.Table) string {
+	if len(gen.getPrimaryKeyColumns(table)) == 0 {
+		return ""
+	}
+	tnp := SnakeToPascal(strings.ToLower(table.Name))
+
+	softDelete := ""
+	if gen.isTrashTable(table) {
+		softDelete = fmt.Sprintf(FORMAT_REPOSITORY_TEST_SOFT_DELETE, tnp)
+	}
+	return fmt.Sprintf(FORMAT_REPOSITORY_TEST_DELETE, tnp, softDelete)
+}
+
+// 結合テストの値 (n の式または固定の値から、DBに登録できる型の値を作成)
+func (gen *generator) getTestDbValue(c ddlparse.Column, n string) string {
+	dt := strings.ToUpper(c.DataType.Name)
+	switch gen.dataTypeToGoType(dt) {
+	case "int":
+		return n
+	case "float64":
+		if n != "n" {
+			return n + ".5"
+		}
+		return fmt.Sprintf("float64(%s) + 0.5", n)
+	}
+
+	switch {
+	case gen.dateTypeKind(dt) == "datetime":
+		return fmt.Sprintf("fmt.Sprintf(\"2024-01-%%02d 00:00:00\", %s)", n)
+	case gen.dateTypeKind(dt) == "date":
+		return fmt.Sprintf("fmt.Sprintf(\"2024-01-%%02d\", %s)", n)
+	case strings.Contains(dt, "TIME"):
+		return fmt.Sprintf("fmt.Sprintf(\"00:00:%%02d\", %s)", n)
+	case strings.Contains(dt, "BOOL"):
+		return fmt.Sprintf("fmt.Sprint(%s %% 2)", n)
+	case strings.Contains(dt, "UUID"):
+		return fmt.Sprintf("fmt.Sprintf(\"00000000-0000-0000-0000-%%012d\", %s)", n)
+	case strings.Contains(dt, "JSON"):
+		return fmt.Sprintf("fmt.Sprintf(\"{\\\"n\\\": %%d}\", %s)", n)
+	case n != "n":
+		return fmt.Sprintf("\"%s\"", n)
+	}
+	return fmt.Sprintf("fmt.Sprint(%s)", n)
+}
+
+var reSqlLiteral = regexp.MustCompile(`'(?:[^']|'')*'|-?\d+(?:\.\d+)?`)
+
+/*
+ CHECK 制約 (カラム IN (...)) で許可された先頭の値 (結合テストの行で使用)
+ カラム・テーブルの CHECK 制約から探し、無い場合は false
+*/
+func (gen *generator) getTestCheckValue(c ddlparse.Column, checks []ddlparse.Check) (string, bool) {
+	exprs := []string{c.Constraint.Check}
+	for _, ck := range checks {
+		exprs = append(exprs, ck.Expr)
+	}
+	re := regexp.MustCompile(`(?is)^\(*\s*` + "[\"`]?" + regexp.QuoteMeta(c.Name) + "[\"`]?" + `\s*IN\s*\((.*?)\)\s*\)*$`)
+	for _, expr := range exprs {
+		m := re.FindStringSubmatch(strings.TrimSpace(expr))
+		if m == nil {
+			continue
+		}
+		v := reSqlLiteral.FindString(m[1])
+		if v == "" {
+			continue
+		}
+		if strings.HasPrefix(v, "'") {
+			v = strings.ReplaceAll(v[1:len(v)-1], "''", "'")
+		}
+		switch gen.dataTypeToGoType(strings.ToUpper(c.DataType.Name)) {
+		case "int":
+			if _, err := strconv.Atoi(v); err == nil {
+				return v, true
+			}
+		case "float64":
+			if _, err := strconv.ParseFloat(v, 64); err == nil {
+				return fmt.Sprintf("float64(%s)", v), true
+			}
+		default:
+			return strconv.Quote(v), true
+		}
+	}
+	return "", false
+}
+
+/*
+ 登録した値をそのまま読み取れるカラムか
+ (日付・真偽値などはDBにより読み取る形式が変わり、CHAR は空白で埋められ、小数部の無い NUMERIC は丸められる)
+*/
+func (gen *generator) isComparableColumn(c ddlparse.Column) bool {
+	dt := strings.ToUpper(c.DataType.Name)
+	switch gen.dataTypeToGoType(dt) {
+	case "int":
+		return true
+	case "float64":
+		return c.DataType.DigitN == 0 || c.DataType.DigitM > 0
+	}
+	if dt == "CHAR" || dt == "CHARACTER" || dt == "NCHAR" {
+		return false
+	}
+	return strings.Contains(dt, "CHAR") || strings.Contains(dt, "TEXT") || strings.Contains(dt, "CLOB") || strings.Contains(dt, "STRING")
+}
+
+// 一意制約違反のテストに使用するカラム (ユニーク・主キー・先頭のカラムの順)
+func (gen *generator) getConflictColumn(table ddlparse.Table) ddlparse.Column {
 	for _, c := range table.Columns {
-		cn := strings.ToLower(c.Name)
-		if gen.isUpdateColumn(c) {
-			s1 += fmt.Sprintf(
-				"\n\t\t<td><input type='text' name='%s' value='${nullToEmpty(elem.%s)}'><input type='hidden' name='%s_bk' value='${nullToEmpty(elem.%s)}'></td>",
-				cn, cn, cn, cn,
-			)
-		} else {
-			s1 += fmt.Sprintf(
-				"\n\t\t<td><input type='text' name='%s' value='${nullToEmpty(elem.%s)}' disabled></td>", 
-				cn, cn,
-			)
+		if c.Constraint.IsUnique {
+			return c
 		}
 	}
-	return fmt.Sprintf(FORMAT_JS_CREATETR, s1)
+	if pkcols := gen.getPrimaryKeyColumns(table); len(pkcols) > 0 {
+		return pkcols[0]
+	}
+	return table.Columns[0]
 }
 
-func (gen *generator) codeJsGetRows(table ddlparse.Table) string {
+// テストの値 (n から型に合わせて作成)
+func (gen *generator) getTestValue(c ddlparse.Column, n int) string {
+	switch gen.dataTypeToGoType(c.DataType.Name) {
+	case "int":
+		return fmt.Sprintf("%d", n)
+	case "float64":
+		return fmt.Sprintf("%d.5", n)
+	}
+	return fmt.Sprintf("\"%d\"", n)
+}
+
+// 構造体リテラルのフィールド (NULL許容のカラムは省略)
+func (gen *generator) codeTestFields(table ddlparse.Table, cols []ddlparse.Column, n int) string {
+	tn := strings.ToLower(table.Name)
+	code := ""
+	for _, c := range cols {
+		if gen.isNullColumn(c, table.Constraints) {
+			continue
+		}
+		code += fmt.Sprintf("\n\t\t%s: %s,", gen.getFieldName(c.Name, tn), gen.getTestValue(c, n))
+	}
+	if code == "" {
+		return ""
+	}
+	return code + "\n\t"
+}
+
+// テストの主キーのパス (例: /1/1)
+func (gen *generator) getTestKeyPath(table ddlparse.Table, n int) string {
+	ret := ""
+	for _, c := range gen.getPrimaryKeyColumns(table) {
+		ret += "/" + strings.Trim(gen.getTestValue(c, n), "\"")
+	}
+	return ret
+}
+
+/////////////////////////////////////////////////////////////////////////////////
+///////////////////////////////////  csv.go  ///////////////////////////////////
+///////////////////////////////////////////////////////////////////////////////
+
+// csv.go 生成
+func (gen *generator) generateCsvGoFile(path string, table ddlparse.Table) error {
+	path = fmt.Sprintf("%s/csv.go", path)
+	code := gen.codeCsvGo(table)
+	if err := WriteFile(path, code); err != nil {
+		logger.Error(err.Error())
+		return err
+	}
+	return nil
+}
+
+// csv.go コード生成
+func (gen *generator) codeCsvGo(table ddlparse.Table) string {
 	tn := strings.ToLower(table.Name)
+	tnp := SnakeToPascal(tn)
+
 	s1 := ""
-	for _, c := range gen.getUpdateColumns(table) {
-		cn := strings.ToLower(c.Name)
-		s1 += fmt.Sprintf("\taddChangeEvent('%s');\n", cn)
+	for _, c := range table.Columns {
+		s1 += fmt.Sprintf(
+			"\t{Name: \"%s\", Type: %s},\n",
+			strings.ToLower(c.Name), gen.csvColumnType(c),
+		)
 	}
 	s1 = strings.TrimSuffix(s1, "\n")
-	return fmt.Sprintf(FORMAT_JS_GETROWS, tn, s1)
+
+	s2 := ""
+	for _, c := range table.Columns {
+		s2 += fmt.Sprintf("\t\tcsvutil.Format(m.%s),\n", gen.getFieldName(c.Name, tn))
+	}
+	s2 = strings.TrimSuffix(s2, "\n")
+
+	s3 := "\"\""
+	if ls := gen.getPrimaryKeyFieldRefs(table); len(ls) > 0 {
+		s3 = fmt.Sprintf("csvutil.JoinKey(%s)", strings.Join(ls, ", "))
+	}
+
+	return fmt.Sprintf(
+		FORMAT_CSV,
+		tn, s1,
+		tnp, tnp, tnp, tnp, tnp, tnp,
+		tnp, s2,
+		tnp, s3,
+	)
+}
+
+// データ型 -> CSVカラムの型
+func (gen *generator) csvColumnType(c ddlparse.Column) string {
+	switch gen.dataTypeToGoType(c.DataType.Name) {
+	case "int":
+		return "csvutil.Int"
+	case "float64":
+		return "csvutil.Float"
+	default:
+		return "csvutil.String"
+	}
+}
+
+/////////////////////////////////////////////////////////////////////////////////
+//////////////////////////////////  audit.go  //////////////////////////////////
+///////////////////////////////////////////////////////////////////////////////
+
+// audit.go 生成
+func (gen *generator) generateAuditGoFile(path string, table ddlparse.Table) error {
+	path = fmt.Sprintf("%s/audit.go", path)
+	code := gen.codeAuditGo(table)
+	if err := WriteFile(path, code); err != nil {
+		logger.Error(err.Error())
+		return err
+	}
+	return nil
 }
 
-func (gen *generator) codeJsPutRows(table ddlparse.Table) string {
+// audit.go コード生成
+func (gen *generator) codeAuditGo(table ddlparse.Table) string {
 	tn := strings.ToLower(table.Name)
-	updcols := gen.getUpdateColumns(table)
+	tnp := SnakeToPascal(tn)
+
+	s1 := "m"
+	s2 := "\"\""
+	if ls := gen.getPrimaryKeyFieldRefs(table); len(ls) > 0 {
+		ls1 := []string{}
+		for _, c := range gen.getPrimaryKeyColumns(table) {
+			fn := gen.getFieldName(c.Name, tn)
+			ls1 = append(ls1, fmt.Sprintf("%s: m.%s", fn, fn))
+		}
+		s1 = fmt.Sprintf("%s{ %s }", tnp, strings.Join(ls1, ", "))
+		s2 = fmt.Sprintf("audit.Key(%s)", strings.Join(ls, ", "))
+	}
+
+	return fmt.Sprintf(
+		FORMAT_AUDIT,
+		tn,
+		tnp, tnp, s1,
+		tnp, s2,
+		tnp, tnp, tn,
+	)
+}
+
+/////////////////////////////////////////////////////////////////////////////////
+/////////////////////////////////  event.go  ///////////////////////////////////
+///////////////////////////////////////////////////////////////////////////////
+
+// event.go 生成
+func (gen *generator) generateEventGoFile(path string, table ddlparse.Table) error {
+	path = fmt.Sprintf("%s/event.go", path)
+	code := gen.codeEventGo(table)
+	if err := WriteFile(path, code); err != nil {
+		logger.Error(err.Error())
+		return err
+	}
+	return nil
+}
+
+// event.go コード生成
+func (gen *generator) codeEventGo(table ddlparse.Table) string {
+	tn := strings.ToLower(table.Name)
+	tnp := SnakeToPascal(tn)
+	return fmt.Sprintf(FORMAT_EVENT, tn, tnp, tnp, tn, tn, tn, tn, tn)
+}
+
+/////////////////////////////////////////////////////////////////////////////////
+///////////////////////////////////  xlsx.go  //////////////////////////////////
+///////////////////////////////////////////////////////////////////////////////
+
+// xlsx.go 生成
+func (gen *generator) generateXlsxGoFile(path string, table ddlparse.Table) error {
+	path = fmt.Sprintf("%s/xlsx.go", path)
+	code := gen.codeXlsxGo(table)
+	if err := WriteFile(path, code); err != nil {
+		logger.Error(err.Error())
+		return err
+	}
+	return nil
+}
+
+// xlsx.go コード生成
+func (gen *generator) codeXlsxGo(table ddlparse.Table) string {
+	tn := strings.ToLower(table.Name)
+	tnp := SnakeToPascal(tn)
+
 	s1 := ""
 	for _, c := range table.Columns {
 		cn := strings.ToLower(c.Name)
-		s1 += fmt.Sprintf("\tconst %s = document.getElementsByName('%s');\n", cn, cn)
+		s1 += fmt.Sprintf(
+			"\t{Label: \"%s\", Type: %s, Width: %d},\n",
+			cn, gen.xlsxColumnType(c), gen.xlsxColumnWidth(c, cn),
+		)
 	}
 	s1 = strings.TrimSuffix(s1, "\n")
+
 	s2 := ""
-	for _, c := range updcols {
-		cn := strings.ToLower(c.Name)
-		s2 += fmt.Sprintf("\tconst %s_bk = document.getElementsByName('%s_bk');\n", cn, cn)
+	for _, c := range table.Columns {
+		s2 += fmt.Sprintf("\t\tm.%s,\n", gen.getFieldName(c.Name, tn))
+	}
+	s2 = strings.TrimSuffix(s2, "\n")
+
+	return fmt.Sprintf(
+		FORMAT_XLSX,
+		tn, s1, tnp, s2,
+	)
+}
+
+// データ型 -> XLSXセルの型
+func (gen *generator) xlsxColumnType(c ddlparse.Column) string {
+	switch gen.dataTypeToGoType(c.DataType.Name) {
+	case "int", "float64":
+		return "xlsx.Number"
+	}
+	switch gen.dateTypeKind(c.DataType.Name) {
+	case "date":
+		return "xlsx.Date"
+	case "datetime":
+		return "xlsx.DateTime"
+	default:
+		return "xlsx.String"
+	}
+}
+
+// XLSXの列幅 (ラベルとデータ型の桁数から概算)
+func (gen *generator) xlsxColumnWidth(c ddlparse.Column, label string) int {
+	width := 10
+	switch gen.dateTypeKind(c.DataType.Name) {
+	case "date":
+		width = 12
+	case "datetime":
+		width = 20
+	default:
+		if c.DataType.DigitN > width {
+			width = c.DataType.DigitN
+		}
+	}
+	if len(label) > width {
+		width = len(label)
+	}
+	if width > 50 {
+		width = 50
+	}
+	return width + 2
+}
+
+/////////////////////////////////////////////////////////////////////////////////
+///////////////////////////////  internal/server  //////////////////////////////
+///////////////////////////////////////////////////////////////////////////////
+
+// server 生成
+func (gen *generator) generateServer(path string) error {
+	path = fmt.Sprintf("%s/server", path)
+	if err := MakeDirAll(path); err != nil {
+		logger.Error(err.Error())
+		return err
+	}
+	if err := gen.generateRouterGoFile(path); err != nil {
+		return err
+	}
+	if err := gen.generateAppGoFile(path); err != nil {
+		return err
+	}
+	return nil
+}
+
+// app.go 生成
+func (gen *generator) generateAppGoFile(path string) error {
+	path = fmt.Sprintf("%s/app.go", path)
+	code := gen.codeAppGo()
+	if err := WriteFile(path, code); err != nil {
+		logger.Error(err.Error())
+		return err
+	}
+	return nil
+}
+
+// app.go コード生成 (テーブルごとのサービスをDB接続から組み立てる)
+func (gen *generator) codeAppGo() string {
+	imports := ""
+	fields := ""
+	services := ""
+	for _, table := range gen.tables {
+		tn := strings.ToLower(table.Name)
+		tnp := SnakeToPascal(tn)
+		imports += fmt.Sprintf("\t\"masmaint/internal/module/%s\"\n", tn)
+		fields += fmt.Sprintf("\t%s %s.Service\n", tnp, tn)
+		services += fmt.Sprintf("\t\t%s: %s.NewService(conn, %s.NewRepository(conn)),\n", tnp, tn, tn)
+	}
+	if gen.isUsersAuth() {
+		imports += "\t\"masmaint/internal/module/users\"\n"
+		fields += "\tUsers users.Service\n"
+		services += "\t\tUsers: users.NewService(conn, users.NewRepository(conn)),\n"
+	}
+	if gen.option.ApiTokens {
+		imports += "\t\"masmaint/internal/module/api_token\"\n"
+		fields += "\tApiToken api_token.Service\n"
+		services += "\t\tApiToken: api_token.NewService(conn, api_token.NewRepository(conn)),\n"
+	}
+	return fmt.Sprintf(
+		FORMAT_APP,
+		strings.TrimSuffix(imports, "\n"),
+		strings.TrimSuffix(fields, "\n"),
+		strings.TrimSuffix(services, "\n"),
+	)
+}
+
+// router.go 生成
+func (gen *generator) generateRouterGoFile(path string) error {
+	path = fmt.Sprintf("%s/router.go", path)
+	code := gen.codeRouterGo()
+	if err := WriteFile(path, code); err != nil {
+		logger.Error(err.Error())
+		return err
+	}
+	return nil
+}
+
+// router.go コード生成
+func (gen *generator) codeRouterGo() string {
+	s0 := ""
+	s1 := ""
+	if gen.option.AuthMode == "jwt" {
+		s0 += "\t\"crypto/subtle\"\n\n"
+		s1 += "\t\"masmaint/config\"\n"
+	}
+	if gen.isLoginAuth() {
+		s1 += "\t\"masmaint/internal/core/jwt\"\n"
+	}
+	s2 := ""
+	for _, table := range gen.tables {
+		s2 += fmt.Sprintf("\t\"masmaint/internal/module/%s\"\n", strings.ToLower(table.Name))
+	}
+	if gen.isUsersAuth() {
+		s2 += "\t\"masmaint/internal/module/users\"\n"
+	}
+	if gen.isOidcAuth() {
+		s2 += "\t\"masmaint/internal/module/oidcauth\"\n"
+	}
+	if gen.option.ApiTokens {
+		s2 += "\t\"masmaint/internal/module/api_token\"\n"
+	}
+	s2 = strings.TrimSuffix(s2, "\n")
+	return fmt.Sprintf(
+		FORMAT_ROUTER, 
+		s0, s1, s2,
+		gen.CodeRouterSetWebRouter(),
+		gen.CodeRouterSetApiRouter(),
+	)
+}
+
+func (gen *generator) CodeRouterSetWebRouter() string {
+	s1 := ""
+	for _, table := range gen.tables {
+		tn := strings.ToLower(table.Name)
+		tnc := SnakeToCamel(tn)
+		s1 += fmt.Sprintf("\t%sController := %s.NewController(app.%s)\n", tnc, tn, SnakeToPascal(tn))
+	}
+	s1 = strings.TrimSuffix(s1, "\n")
+	s2 := ""
+	for _, table := range gen.tables {
+		tn := strings.ToLower(table.Name)
+		tnc := SnakeToCamel(tn)
+		s2 += fmt.Sprintf("\t\tauth.GET(\"/%s\", middleware.Permission(\"%s\", permission.Read), %sController.GetPage)\n", tn, tn, tnc)
+		if gen.isTrashTable(table) {
+			s2 += fmt.Sprintf("\t\tauth.GET(\"/%s/trash\", middleware.Permission(\"%s\", permission.Read), %sController.GetTrashPage)\n", tn, tn, tnc)
+		}
+	}
+	if gen.isUsersAuth() {
+		s1 += "\n\tusersController := users.NewController(app.Users)"
+		s2 += "\t\tauth.GET(\"/users\", middleware.Permission(\"users\", permission.Read), usersController.GetPage)\n"
+		s2 += "\t\tauth.GET(\"/password\", usersController.GetPasswordPage)\n"
+	}
+	if gen.option.ApiTokens {
+		s1 += "\n\tapiTokenController := api_token.NewController(app.ApiToken)"
+		s2 += "\t\tauth.GET(\"/api_tokens\", apiTokenController.GetPage)\n"
+	}
+	s2 = strings.TrimSuffix(s2, "\n")
+	login := ""
+	if gen.isOidcAuth() {
+		s1 += "\n\toidcController := oidcauth.NewController()"
+		login = FORMAT_ROUTER_WEB_LOGIN_OIDC
+	} else if gen.isLoginAuth() {
+		login = FORMAT_ROUTER_WEB_LOGIN
+	}
+	return fmt.Sprintf(
+		FORMAT_ROUTER_SETWEB, 
+		s1, login, gen.codeAuthArgs(false), s2,
+	)
+}
+
+func (gen *generator) CodeRouterSetApiRouter() string {
+	s1 := ""
+	for _, table := range gen.tables {
+		tn := strings.ToLower(table.Name)
+		tnc := SnakeToCamel(tn)
+		s1 += fmt.Sprintf("\t%sController := %s.NewController(app.%s)\n", tnc, tn, SnakeToPascal(tn))
+	}
+	s1 = strings.TrimSuffix(s1, "\n")
+	s2 := ""
+	for _, table := range gen.tables {
+		tn := strings.ToLower(table.Name)
+		tnc := SnakeToCamel(tn)
+		//テーブルごとのグループ (操作ごとに権限をチェック)
+		s2 += "\t\t{\n"
+		s2 += fmt.Sprintf("\t\t\tp := middleware.ApiTablePermission(\"%s\")\n", tn)
+		s2 += fmt.Sprintf("\t\t\tg := auth.Group(\"/%s\")\n", tn)
+		s2 += fmt.Sprintf("\t\t\tg.GET(\"\", p.Read, %sController.Get)\n", tnc)
+		s2 += fmt.Sprintf("\t\t\tg.POST(\"\", p.Create, %sController.Post)\n", tnc)
+		//行以外のパスは主キーの値と重ならないよう _ を付ける
+		s2 += fmt.Sprintf("\t\t\tg.GET(\"/_export.csv\", p.Read, %sController.ExportCsv)\n", tnc)
+		s2 += fmt.Sprintf("\t\t\tg.POST(\"/_import.csv\", p.Create, p.Update, %sController.ImportCsv)\n", tnc)
+		s2 += fmt.Sprintf("\t\t\tg.GET(\"/_export.xlsx\", p.Read, %sController.ExportXlsx)\n", tnc)
+		if len(gen.getPrimaryKeyColumns(table)) > 0 {
+			kp := gen.getKeyRoutePath(table)
+			s2 += fmt.Sprintf("\t\t\tg.GET(\"%s\", p.Read, %sController.GetOne)\n", kp, tnc)
+			s2 += fmt.Sprintf("\t\t\tg.PUT(\"%s\", p.Update, %sController.Put)\n", kp, tnc)
+			s2 += fmt.Sprintf("\t\t\tg.PATCH(\"%s\", p.Update, %sController.Patch)\n", kp, tnc)
+			s2 += fmt.Sprintf("\t\t\tg.DELETE(\"%s\", p.Delete, %sController.Delete)\n", kp, tnc)
+		}
+		if gen.isLiveTable(table) {
+			s2 += fmt.Sprintf("\t\t\tg.GET(\"/_events\", p.Read, %sController.Events)\n", tnc)
+			s2 += fmt.Sprintf("\t\t\tg.POST(\"/_editing\", p.Update, %sController.Editing)\n", tnc)
+		}
+		if gen.isHistoryRoutesTable(table) {
+			kp := gen.getKeyRoutePath(table)
+			s2 += fmt.Sprintf("\t\t\tg.GET(\"%s/_history\", p.Read, %sController.GetHistory)\n", kp, tnc)
+			s2 += fmt.Sprintf("\t\t\tg.POST(\"%s/_history/:history_id/restore\", p.Update, %sController.Restore)\n", kp, tnc)
+		}
+		if gen.isTrashTable(table) {
+			kp := gen.getKeyRoutePath(table)
+			s2 += fmt.Sprintf("\t\t\tg.GET(\"/_trash\", p.Read, %sController.GetTrash)\n", tnc)
+			s2 += fmt.Sprintf("\t\t\tg.POST(\"%s/restore\", p.Delete, %sController.Undelete)\n", kp, tnc)
+		}
+		if gen.option.BodyKeyRoutes {
+			s2 += fmt.Sprintf("\t\t\tg.PUT(\"\", p.Update, %sController.PutByBody)\n", tnc)
+			s2 += fmt.Sprintf("\t\t\tg.DELETE(\"\", p.Delete, %sController.DeleteByBody)\n", tnc)
+		}
+		s2 += "\t\t}\n"
+	}
+	login := ""
+	if gen.option.AuthMode == "jwt" {
+		login = FORMAT_ROUTER_LOGIN + "\n" + FORMAT_ROUTER_LOGOUT + "\n\n"
+	}
+	if gen.isOidcAuth() {
+		login = FORMAT_ROUTER_LOGOUT + "\n\n"
+	}
+	if gen.isUsersAuth() {
+		s1 += "\n\tusersController := users.NewController(app.Users)"
+		login = FORMAT_ROUTER_LOGIN_USERS + "\n" + FORMAT_ROUTER_LOGOUT + "\n\n"
+		s2 += "\t\t{\n"
+		s2 += "\t\t\tp := middleware.ApiTablePermission(\"users\")\n"
+		s2 += "\t\t\tg := auth.Group(\"/users\")\n"
+		s2 += "\t\t\tg.GET(\"\", p.Read, usersController.Get)\n"
+		s2 += "\t\t\tg.POST(\"\", p.Create, usersController.Post)\n"
+		s2 += "\t\t\tg.PUT(\"/:user_id\", p.Update, usersController.Put)\n"
+		s2 += "\t\t\tg.PUT(\"/:user_id/password\", p.Update, usersController.PutPassword)\n"
+		s2 += "\t\t\tg.DELETE(\"/:user_id\", p.Delete, usersController.Delete)\n"
+		s2 += "\t\t}\n"
+		s2 += "\t\tauth.PUT(\"/password\", usersController.ChangePassword)\n"
+	}
+	if gen.option.ApiTokens {
+		//権限はサービスで確認 (個人用トークンは全ユーザが作成可)
+		s1 += "\n\tapiTokenController := api_token.NewController(app.ApiToken)"
+		s2 += "\t\t{\n"
+		s2 += "\t\t\tg := auth.Group(\"/api_tokens\")\n"
+		s2 += "\t\t\tg.GET(\"\", apiTokenController.Get)\n"
+		s2 += "\t\t\tg.POST(\"\", apiTokenController.Post)\n"
+		s2 += "\t\t\tg.DELETE(\"/:api_token_id\", apiTokenController.Delete)\n"
+		s2 += "\t\t}\n"
+	}
+	s2 = strings.TrimSuffix(s2, "\n")
+	return fmt.Sprintf(
+		FORMAT_ROUTER_SETAPI, 
+		s1, login, gen.codeAuthArgs(true), s2,
+	)
+}
+
+/////////////////////////////////////////////////////////////////////////////////
+/////////////////////////////////////  web  ////////////////////////////////////
+///////////////////////////////////////////////////////////////////////////////
+
+// web 生成
+func (gen *generator) generateWeb(path string) error {
+	path = fmt.Sprintf("%s/web", path)
+	if err := MakeDirAll(path); err != nil {
+		logger.Error(err.Error())
+		return err
+	}
+	if err := gen.generateStatic(path); err != nil {
+		return err
+	}
+	if err := gen.generateTemplate(path); err != nil {
+		return err
+	}
+	return nil
+}
+
+/////////////////////////////////////////////////////////////////////////////////
+/////////////////////////////////  web/static  /////////////////////////////////
+///////////////////////////////////////////////////////////////////////////////
+
+// static 生成
+func (gen *generator) generateStatic(path string) error {
+	path = fmt.Sprintf("%s/static", path)
+	if err := MakeDirAll(path); err != nil {
+		logger.Error(err.Error())
+		return err
+	}
+	if err := gen.generateJs(path); err != nil {
+		return err
+	}
+	return nil
+}
+
+/////////////////////////////////////////////////////////////////////////////////
+////////////////////////////////  web/static/js  ///////////////////////////////
+///////////////////////////////////////////////////////////////////////////////
+
+// js 生成
+func (gen *generator) generateJs(path string) error {
+	path = fmt.Sprintf("%s/js", path)
+	if err := MakeDirAll(path); err != nil {
+		logger.Error(err.Error())
+		return err
+	}
+	if err := gen.generateTableJsFiles(path); err != nil {
+		return err
+	}
+	return nil
+}
+
+/////////////////////////////////////////////////////////////////////////////////
+////////////////////////////////  table_name.js  ///////////////////////////////
+///////////////////////////////////////////////////////////////////////////////
+
+func (gen *generator) generateTableJsFiles(path string) error {
+	for _, table := range gen.tables {
+		if err := gen.generateTableJsFile(path, table); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// table_name.js 生成
+func (gen *generator) generateTableJsFile(path string, table ddlparse.Table) error {
+	tn := strings.ToLower(table.Name)
+	path = fmt.Sprintf("%s/%s.js", path, tn)
+	code := gen.codeTableJs(table)
+	if err := WriteFile(path, code); err != nil {
+		logger.Error(err.Error())
+		return err
+	}
+	return nil
+}
+
+// table_name.js コード生成
+func (gen *generator) codeTableJs(table ddlparse.Table) string {
+	return fmt.Sprintf(
+		FORMAT_JS, 
+		gen.codeJsImportHistory(table) + gen.codeJsImportLive(table),
+		strings.ToLower(table.Name),
+		gen.codeJsSetupHistory(table) + gen.codeJsSetupLive(table),
+		gen.codeJsCreateTrNew(table),
+		gen.codeJsCreateTr(table),
+		gen.codeJsToKeyPath(table),
+		gen.codeJsGetRows(table),
+		gen.codeJsPatchRows(table),
+		gen.codeJsPostRow(table),
+		gen.codeJsDeleteRows(table),
+	)
+}
+
+func (gen *generator) codeJsCreateTrNew(table ddlparse.Table) string {
+	s1 := "\n\t\t<td></td>"
+	for _, c := range table.Columns {
+		if gen.isInsertColumn(c) {
+			s1 += fmt.Sprintf("\n\t\t<td><input type='text' id='%s_new'></td>", strings.ToLower(c.Name))
+		} else {
+			s1 += "\n\t\t<td><input type='text' disabled></td>"
+		}
+	}
+	if gen.isHistoryRoutesTable(table) {
+		s1 += "\n\t\t<td></td>"
+	}
+	return fmt.Sprintf(FORMAT_JS_CREATETRNEW, s1)
+}
+
+func (gen *generator) codeJsImportHistory(table ddlparse.Table) string {
+	if !gen.isHistoryRoutesTable(table) {
+		return ""
+	}
+	return "\nimport { setupHistory, createHistoryCell } from './history.js';"
+}
+
+func (gen *generator) codeJsSetupHistory(table ddlparse.Table) string {
+	if !gen.isHistoryRoutesTable(table) {
+		return ""
+	}
+	return fmt.Sprintf("\n    setupHistory('%s', () => {\n        clearMessage();\n        getRows();\n    });", strings.ToLower(table.Name))
+}
+
+func (gen *generator) codeJsImportLive(table ddlparse.Table) string {
+	if !gen.isLiveTable(table) {
+		return ""
+	}
+	return "\nimport { setupLive } from './live.js';"
+}
+
+func (gen *generator) codeJsSetupLive(table ddlparse.Table) string {
+	if !gen.isLiveTable(table) {
+		return ""
+	}
+	return fmt.Sprintf("\n    setupLive('%s', { createTr, toKeyPath, onChange: handleChange });", strings.ToLower(table.Name))
+}
+
+func (gen *generator) codeJsCreateTr(table ddlparse.Table) string {
+	ls := []string{"\n\t\tcreateCheckboxCell('del', JSON.stringify(elem))"}
+	for _, c := range table.Columns {
+		cn := strings.ToLower(c.Name)
+		if gen.isUpdateColumn(c, table.Constraints) {
+			ls = append(ls, fmt.Sprintf("\n\t\tcreateInputCell('%s', elem.%s, { backup: true })", cn, cn))
+		} else {
+			ls = append(ls, fmt.Sprintf("\n\t\tcreateInputCell('%s', elem.%s, { disabled: true })", cn, cn))
+		}
+	}
+	s1 := strings.Join(ls, ",")
+	s2 := ""
+	if gen.isHistoryRoutesTable(table) {
+		s2 = "\n\ttr.appendChild(createHistoryCell(toKeyPath(elem)));"
+	}
+	return fmt.Sprintf(FORMAT_JS_CREATETR, s1, s2)
+}
+
+func (gen *generator) codeJsToKeyPath(table ddlparse.Table) string {
+	ls := []string{}
+	for _, c := range gen.getPrimaryKeyColumns(table) {
+		ls = append(ls, fmt.Sprintf("elem.%s", strings.ToLower(c.Name)))
+	}
+	return fmt.Sprintf(FORMAT_JS_TOKEYPATH, strings.Join(ls, ", "))
+}
+
+func (gen *generator) codeJsGetRows(table ddlparse.Table) string {
+	tn := strings.ToLower(table.Name)
+	s1 := ""
+	for _, c := range gen.getUpdateColumns(table) {
+		cn := strings.ToLower(c.Name)
+		s1 += fmt.Sprintf("\taddChangeEvent('%s');\n", cn)
+	}
+	s1 = strings.TrimSuffix(s1, "\n")
+	return fmt.Sprintf(FORMAT_JS_GETROWS, tn, s1)
+}
+
+func (gen *generator) codeJsPatchRows(table ddlparse.Table) string {
+	tn := strings.ToLower(table.Name)
+	updcols := gen.getUpdateColumns(table)
+	s1 := ""
+	for _, c := range table.Columns {
+		cn := strings.ToLower(c.Name)
+		s1 += fmt.Sprintf("\tconst %s = document.getElementsByName('%s');\n", cn, cn)
+	}
+	s1 = strings.TrimSuffix(s1, "\n")
+	s2 := ""
+	for _, c := range updcols {
+		cn := strings.ToLower(c.Name)
+		s2 += fmt.Sprintf("\tconst %s_bk = document.getElementsByName('%s_bk');\n", cn, cn)
+	}
+	s2 = strings.TrimSuffix(s2, "\n")
+	s3 := strings.ToLower(table.Columns[0].Name)
+	s4 := ""
+	for _, c := range updcols {
+		cn := strings.ToLower(c.Name)
+		s4 += fmt.Sprintf("\t\t\t'%s': %s[i],\n", cn, cn)
+	}
+	s4 = strings.TrimSuffix(s4, "\n")
+	s5 := ""
+	for _, c := range updcols {
+		cn := strings.ToLower(c.Name)
+		s5 += fmt.Sprintf("\t\t\t'%s': %s_bk[i],\n", cn, cn)
+	}
+	s5 = strings.TrimSuffix(s5, "\n")
+	s6 := ""
+	for _, c := range table.Columns {
+		cn := strings.ToLower(c.Name)
+		ctype := gen.dataTypeToGoType(c.DataType.Name)
+		if ctype == "int" {
+			s6 += fmt.Sprintf("\t\t\t\t%s: parseIntOrReturnOriginal(%s[i].value),\n", cn, cn)
+		} else if ctype == "float64" {
+			s6 += fmt.Sprintf("\t\t\t\t%s: parseFloatOrReturnOriginal(%s[i].value),\n", cn, cn)
+		} else if gen.isNullColumn(c, table.Constraints) {
+			s6 += fmt.Sprintf("\t\t\t\t%s: emptyToNull(%s[i].value),\n", cn, cn)
+		} else {
+			s6 += fmt.Sprintf("\t\t\t\t%s: %s[i].value,\n", cn, cn)
+		}
+	}
+	s6 = strings.TrimSuffix(s6, "\n")
+	s7 := ""
+	for _, c := range table.Columns {
+		cn := strings.ToLower(c.Name)
+		s7 += fmt.Sprintf("\t\t\t\t%s[i].value = data.%s;\n", cn, cn)
+	}
+	for _, c := range updcols {
+		cn := strings.ToLower(c.Name)
+		s7 += fmt.Sprintf("\t\t\t\t%s_bk[i].value = data.%s;\n", cn, cn)
+	}
+	s7 = strings.TrimSuffix(s7, "\n")
+
+	return fmt.Sprintf(
+		FORMAT_JS_PATCHROWS, 
+		s1, s2, s3, s4, s5, s6, tn, s7, tn,
+	)
+}
+
+func (gen *generator) codeJsPostRow(table ddlparse.Table) string {
+	tn := strings.ToLower(table.Name)
+	inscols := gen.getInsertColumns(table)
+	s1 := ""
+	for _, c := range inscols {
+		cn := strings.ToLower(c.Name)
+		s1 += fmt.Sprintf("\t\t'%s': document.getElementById('%s_new'),\n", cn, cn)
+	}
+	s1 = strings.TrimSuffix(s1, "\n")
+	s2 := ""
+	for _, c := range inscols{
+		cn := strings.ToLower(c.Name)
+		ctype := gen.dataTypeToGoType(c.DataType.Name)
+		if ctype == "int" {
+			s2 += fmt.Sprintf("\t\t\t%s: parseIntOrReturnOriginal(rowMap.%s.value),\n", cn, cn)
+		} else if ctype == "float64" {
+			s2 += fmt.Sprintf("\t\t\t%s: parseFloatOrReturnOriginal(rowMap.%s.value),\n", cn, cn)
+		} else if gen.isNullColumn(c, table.Constraints) {
+			s2 += fmt.Sprintf("\t\t\t%s: emptyToNull(rowMap.%s.value),\n", cn, cn)
+		} else {
+			s2 += fmt.Sprintf("\t\t\t%s: rowMap.%s.value,\n", cn, cn)
+		}
+	}
+	s2 = strings.TrimSuffix(s2, "\n")
+
+	return fmt.Sprintf(
+		FORMAT_JS_POSTROW, 
+		s1, s2, tn, tn,
+	)
+}
+
+func (gen *generator) codeJsDeleteRows(table ddlparse.Table) string {
+	return fmt.Sprintf(
+		FORMAT_JS_DELETEROWS,
+		strings.ToLower(table.Name),
+	)
+}
+
+/////////////////////////////////////////////////////////////////////////////////
+////////////////////////////////  web/template  ////////////////////////////////
+///////////////////////////////////////////////////////////////////////////////
+
+// template 生成
+func (gen *generator) generateTemplate(path string) error {
+	path = fmt.Sprintf("%s/template", path)
+	if err := MakeDirAll(path); err != nil {
+		logger.Error(err.Error())
+		return err
+	}
+	if err := gen.generateHeaderHtmlFile(path); err != nil {
+		return err
+	}
+	if err := gen.generateMenuHtmlFile(path); err != nil {
+		return err
+	}
+	if err := gen.generateTableHtmlFiles(path); err != nil {
+		return err
+	}
+	if err := gen.generateAuditHtmlFile(path); err != nil {
+		return err
+	}
+	return nil
+}
+
+/////////////////////////////////////////////////////////////////////////////////
+////////////////////////////////  _header.html  ////////////////////////////////
+///////////////////////////////////////////////////////////////////////////////
+
+// _header.html 生成
+func (gen *generator) generateHeaderHtmlFile(path string) error {
+	path = fmt.Sprintf("%s/_header.html", path)
+	code := gen.codeHeaderHtml()
+	if err := WriteFile(path, code); err != nil {
+		logger.Error(err.Error())
+		return err
+	}
+	return nil
+}
+
+// _header.html コード生成 (ログイン画面を使用する場合のみログアウト)
+func (gen *generator) codeHeaderHtml() string {
+	s1 := ""
+	if gen.isUsersAuth() {
+		s1 += "\n        <a href=\"/password\" class=\"link-light me-3\">パスワード変更</a>"
+	}
+	if gen.isLoginAuth() {
+		s1 += "\n        <a href=\"/logout\" class=\"link-light\">ログアウト</a>"
+	}
+	return fmt.Sprintf(FORMAT_TEMPLATE_HEADER, s1)
+}
+
+/////////////////////////////////////////////////////////////////////////////////
+/////////////////////////////////  _menu.html  /////////////////////////////////
+///////////////////////////////////////////////////////////////////////////////
+
+// _menu.html 生成
+func (gen *generator) generateMenuHtmlFile(path string) error {
+	path = fmt.Sprintf("%s/_menu.html", path)
+	code := gen.codeMenuHtml()
+	if err := WriteFile(path, code); err != nil {
+		logger.Error(err.Error())
+		return err
+	}
+	return nil
+}
+
+// _menu.html コード生成
+func (gen *generator) codeMenuHtml() string {
+	s1 := ""
+	for _, table := range gen.tables {
+		tn := strings.ToLower(table.Name)
+		s1 += fmt.Sprintf("\t\t<li class='nav-item'><a href='/%s' class='nav-link py-1'>%s</a></li>\n", tn, tn)
+	}
+	s1 = strings.TrimSuffix(s1, "\n")
+	s2 := ""
+	if gen.isUsersAuth() {
+		s2 += "\n\t\t<li class='nav-item'><a href='/users' class='nav-link py-1'>ユーザ</a></li>"
+	}
+	if gen.option.ApiTokens {
+		s2 += "\n\t\t<li class='nav-item'><a href='/api_tokens' class='nav-link py-1'>APIトークン</a></li>"
+	}
+	return fmt.Sprintf(FORMAT_TEMPLATE_MENU, s1, s2)
+}
+
+/////////////////////////////////////////////////////////////////////////////////
+///////////////////////////////  table_name.html  //////////////////////////////
+///////////////////////////////////////////////////////////////////////////////
+
+func (gen *generator) generateTableHtmlFiles(path string) error {
+	for _, table := range gen.tables {
+		if err := gen.generateTableHtmlFile(path, table); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// table_name.html 生成
+func (gen *generator) generateTableHtmlFile(path string, table ddlparse.Table) error {
+	tn := strings.ToLower(table.Name)
+	path = fmt.Sprintf("%s/%s.html", path, tn)
+	code := gen.codeTableHtml(table)
+	if err := WriteFile(path, code); err != nil {
+		logger.Error(err.Error())
+		return err
+	}
+	return nil
+}
+
+// table_name.html コード生成
+func (gen *generator) codeTableHtml(table ddlparse.Table) string {
+	tn := strings.ToLower(table.Name)
+	s1 := ""
+	for _, c := range table.Columns {
+		if gen.isNullColumn(c, table.Constraints) || !gen.isInsertColumn(c) {
+			s1 += fmt.Sprintf("\t\t\t\t\t\t\t\t<th%s>%s</th>\n", gen.codeThTitle(table, c), gen.codeThLabel(table, c))
+		} else {
+			s1 += fmt.Sprintf("\t\t\t\t\t\t\t\t<th%s>%s<spnn class=\"text-danger\">*</spnn></th>\n", gen.codeThTitle(table, c), gen.codeThLabel(table, c))
+		}
+	}
+	if gen.isHistoryRoutesTable(table) {
+		s1 += "\t\t\t\t\t\t\t\t<th>履歴</th>\n"
+	}
+	s1 = strings.TrimSuffix(s1, "\n")
+	s2 := ""
+	if gen.isTrashTable(table) {
+		s2 = fmt.Sprintf("\n\t\t\t\t<a href=\"/%s/trash\" class=\"btn btn-outline-secondary\">ゴミ箱</a>", tn)
+	}
+	return fmt.Sprintf(
+		FORMAT_TEMPLATE, 
+		tn, tn, tn, tn, tn, s2, s1, tn,
+	)
+}
+
+// 見出しは論理名 (無い場合はカラム名)
+func (gen *generator) codeThLabel(table ddlparse.Table, c ddlparse.Column) string {
+	if label, ok := gen.labels[table.Name + "." + c.Name]; ok {
+		return escapeTemplateText(label)
+	}
+	return strings.ToLower(c.Name)
+}
+
+// カラムのコメントを見出しの title に表示 (論理名を見出しとする場合、コメントが無ければカラム名)
+func (gen *generator) codeThTitle(table ddlparse.Table, c ddlparse.Column) string {
+	comment, ok := gen.comments[table.Name + "." + c.Name]
+	if !ok {
+		if _, ok := gen.labels[table.Name + "." + c.Name]; !ok {
+			return ""
+		}
+		comment = strings.ToLower(c.Name)
+	}
+	return fmt.Sprintf(" title=\"%s\"", escapeTemplateText(comment))
+}
+
+// HTMLテンプレートに埋め込む文字列 (テンプレートとして解釈されないよう { もエスケープ)
+func escapeTemplateText(s string) string {
+	return strings.ReplaceAll(html.EscapeString(s), "{", "&#123;")
+}
+
+/////////////////////////////////////////////////////////////////////////////////
+/////////////////////////////////  audit.html  /////////////////////////////////
+///////////////////////////////////////////////////////////////////////////////
+
+// audit.html 生成
+func (gen *generator) generateAuditHtmlFile(path string) error {
+	path = fmt.Sprintf("%s/audit.html", path)
+	code := gen.codeAuditHtml()
+	if err := WriteFile(path, code); err != nil {
+		logger.Error(err.Error())
+		return err
+	}
+	return nil
+}
+
+// audit.html コード生成
+func (gen *generator) codeAuditHtml() string {
+	s1 := ""
+	for _, table := range gen.tables {
+		tn := strings.ToLower(table.Name)
+		s1 += fmt.Sprintf("\t\t\t\t\t\t\t<option value=\"%s\">%s</option>\n", tn, tn)
+	}
+	s1 = strings.TrimSuffix(s1, "\n")
+	return fmt.Sprintf(FORMAT_TEMPLATE_AUDIT, s1)
+}
+
+/////////////////////////////////////////////////////////////////////////////////
+///////////////////////////////////  scripts  //////////////////////////////////
+///////////////////////////////////////////////////////////////////////////////
+
+// scripts 生成
+func (gen *generator) generateScripts(path string) error {
+	path = fmt.Sprintf("%s/scripts", path)
+	if err := MakeDirAll(path); err != nil {
+		logger.Error(err.Error())
+		return err
+	}
+	versions, err := gen.generateMigrationFiles(path)
+	if err != nil {
+		return err
+	}
+	if err := gen.generateCreateTableSqlFile(path, versions); err != nil {
+		return err
+	}
+	return nil
+}
+
+/////////////////////////////////////////////////////////////////////////////////
+/////////////////////////////  create-table.sql  ///////////////////////////////
+///////////////////////////////////////////////////////////////////////////////
+
+// create-table.sql 生成 (versions: 適用済みとするマイグレーションのバージョン)
+func (gen *generator) generateCreateTableSqlFile(path string, versions []string) error {
+	path = fmt.Sprintf("%s/create-table.sql", path)
+	code := gen.codeCreateTableSql() + gen.codeSchemaMigrationsDdl(versions)
+	if err := WriteFile(path, code); err != nil {
+		logger.Error(err.Error())
+		return err
+	}
+	return nil
+}
+
+// create-table.sql のテーブル (schema_migrations を除く)
+func (gen *generator) codeCreateTableSql() string {
+	return gen.codeTablesDdl() + gen.codeAuditLogDdl() + gen.codeUsersDdl() + gen.codeApiTokenDdl() + gen.codeHistoryDdl()
+}
+
+// 対象テーブルのDDL (DDLファイルから生成する場合はファイルの内容)
+func (gen *generator) codeTablesDdl() string {
+	if gen.ddl != "" {
+		return strings.TrimRight(gen.ddl, "\n") + "\n"
+	}
+	code := gen.codeNotesDdl()
+	for _, table := range gen.tables {
+		code += gen.codeTableDdl(table)
+	}
+	return strings.TrimLeft(code, "\n")
+}
+
+// 他のRDBMSのDDLから変換できなかった構文 (先頭のコメント)
+func (gen *generator) codeNotesDdl() string {
+	if len(gen.notes) == 0 {
+		return ""
+	}
+	code := "-- DDLの変換で変換しなかった構文 (必要に応じて手動で追加してください)\n"
+	for _, note := range gen.notes {
+		code += "-- " + strings.ReplaceAll(note, "\n", " ") + "\n"
+	}
+	return code
+}
+
+// テーブル定義から CREATE TABLE を作成 (コメントは PostgreSQL は COMMENT ON、MySQL は COMMENT 句)
+func (gen *generator) codeTableDdl(table ddlparse.Table) string {
+	ls := []string{}
+	for _, c := range table.Columns {
+		ls = append(ls, "\t" + gen.codeColumnDdl(table, c))
+	}
+	for _, pk := range table.Constraints.PrimaryKey {
+		ls = append(ls, fmt.Sprintf("\t%sPRIMARY KEY (%s)", codeConstraintName(pk.Name), strings.Join(pk.ColumnNames, ", ")))
+	}
+	for _, uq := range table.Constraints.Unique {
+		ls = append(ls, fmt.Sprintf("\t%sUNIQUE (%s)", codeConstraintName(uq.Name), strings.Join(uq.ColumnNames, ", ")))
+	}
+	for _, ck := range table.Constraints.Check {
+		ls = append(ls, fmt.Sprintf("\t%sCHECK %s", codeConstraintName(ck.Name), ck.Expr))
+	}
+	//MySQL はカラムの REFERENCES を無視するため、テーブルの制約とする
+	for _, c := range table.Columns {
+		if ref := c.Constraint.References; ref.TableName != "" && gen.rdbms == "mysql" {
+			ls = append(ls, fmt.Sprintf("\tFOREIGN KEY (%s) REFERENCES %s (%s)", c.Name, ref.TableName, strings.Join(ref.ColumnNames, ", ")))
+		}
+	}
+	for _, fk := range table.Constraints.ForeignKey {
+		ls = append(ls, fmt.Sprintf(
+			"\t%sFOREIGN KEY (%s) REFERENCES %s (%s)",
+			codeConstraintName(fk.Name), strings.Join(fk.ColumnNames, ", "),
+			fk.References.TableName, strings.Join(fk.References.ColumnNames, ", "),
+		))
+	}
+
+	code := fmt.Sprintf("\nCREATE TABLE %s (\n%s\n)", table.Name, strings.Join(ls, ",\n"))
+	comment, hasComment := gen.comments[table.Name]
+	if gen.rdbms == "mysql" && hasComment {
+		code += " COMMENT=" + quoteSqlString(comment)
+	}
+	code += ";\n"
+	if gen.rdbms == "postgresql" {
+		if hasComment {
+			code += fmt.Sprintf("COMMENT ON TABLE %s IS %s;\n", table.Name, quoteSqlString(comment))
+		}
+		for _, c := range table.Columns {
+			if comment, ok := gen.comments[table.Name + "." + c.Name]; ok {
+				code += fmt.Sprintf("COMMENT ON COLUMN %s.%s IS %s;\n", table.Name, c.Name, quoteSqlString(comment))
+			}
+		}
+	}
+	return code
+}
+
+func (gen *generator) codeColumnDdl(table ddlparse.Table, c ddlparse.Column) string {
+	code := c.Name + " " + gen.codeDataTypeDdl(c)
+	if c.Constraint.IsPrimaryKey {
+		code += " PRIMARY KEY"
+	}
+	if c.Constraint.IsAutoincrement {
+		if gen.rdbms == "mysql" {
+			code += " AUTO_INCREMENT"
+		} else if gen.rdbms == "sqlite3" {
+			code += " AUTOINCREMENT"
+		}
+	}
+	if c.Constraint.IsNotNull {
+		code += " NOT NULL"
+	}
+	if c.Constraint.IsUnique {
+		code += " UNIQUE"
+	}
+	if c.Constraint.Default != nil {
+		code += " DEFAULT " + gen.codeDefaultDdl(c.Constraint.Default)
+	}
+	if c.Constraint.Check != "" {
+		code += " CHECK " + c.Constraint.Check
+	}
+	if ref := c.Constraint.References; ref.TableName != "" && gen.rdbms != "mysql" {
+		code += fmt.Sprintf(" REFERENCES %s (%s)", ref.TableName, strings.Join(ref.ColumnNames, ", "))
+	}
+	if comment, ok := gen.comments[table.Name + "." + c.Name]; ok && gen.rdbms == "mysql" {
+		code += " COMMENT " + quoteSqlString(comment)
+	}
+	return code
+}
+
+func (gen *generator) codeDataTypeDdl(c ddlparse.Column) string {
+	name := c.DataType.Name
+	if c.DataType.DigitN > 0 && c.DataType.DigitM > 0 {
+		return fmt.Sprintf("%s(%d,%d)", name, c.DataType.DigitN, c.DataType.DigitM)
+	} else if c.DataType.DigitN > 0 {
+		//TIMESTAMPTZ(3) 等の接尾辞の付く型も型名の直後に桁数
+		return fmt.Sprintf("%s(%d)", name, c.DataType.DigitN)
+	}
+	return name
+}
+
+var reSqlFunction = regexp.MustCompile(`^\w+\(.*\)$`)
+
+// デフォルト値 (文字列は関数・式の場合はそのまま、SQLite・MySQL の関数は括弧で囲む)
+func (gen *generator) codeDefaultDdl(v interface{}) string {
+	switch d := v.(type) {
+	case float64:
+		return strconv.FormatFloat(d, 'f', -1, 64)
+	case bool:
+		if d {
+			return "TRUE"
+		}
+		return "FALSE"
+	case string:
+		upper := strings.ToUpper(d)
+		if strings.HasPrefix(d, "(") ||
+			strings.HasPrefix(upper, "CURRENT_") ||
+			strings.HasPrefix(upper, "LOCALTIME") {
+			return d
+		}
+		if reSqlFunction.MatchString(d) {
+			if gen.rdbms == "postgresql" {
+				return d
+			}
+			return "(" + d + ")"
+		}
+		return quoteSqlString(d)
+	}
+	return fmt.Sprint(v)
+}
+
+func codeConstraintName(name string) string {
+	if name == "" {
+		return ""
+	}
+	return "CONSTRAINT " + name + " "
+}
+
+func quoteSqlString(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// audit_log のDDL
+func (gen *generator) codeAuditLogDdl() string {
+	if gen.rdbms == "postgresql" {
+		return FORMAT_DDL_AUDIT_LOG_POSTGRESQL
+	} else if gen.rdbms == "mysql" {
+		return FORMAT_DDL_AUDIT_LOG_MYSQL
+	} else {
+		return FORMAT_DDL_AUDIT_LOG_SQLITE3
+	}
+}
+
+// users のDDL
+func (gen *generator) codeUsersDdl() string {
+	if !gen.isUsersAuth() {
+		return ""
+	}
+	if gen.rdbms == "postgresql" {
+		return FORMAT_DDL_USERS_POSTGRESQL
+	} else if gen.rdbms == "mysql" {
+		return FORMAT_DDL_USERS_MYSQL
+	} else {
+		return FORMAT_DDL_USERS_SQLITE3
+	}
+}
+
+// api_token のDDL
+func (gen *generator) codeApiTokenDdl() string {
+	if !gen.option.ApiTokens {
+		return ""
+	}
+	if gen.rdbms == "postgresql" {
+		return FORMAT_DDL_API_TOKEN_POSTGRESQL
+	} else if gen.rdbms == "mysql" {
+		return FORMAT_DDL_API_TOKEN_MYSQL
+	} else {
+		return FORMAT_DDL_API_TOKEN_SQLITE3
+	}
+}
+
+// <table>_history のDDL (制約は付けず、元テーブルのカラムと履歴の管理カラムを持つ)
+func (gen *generator) codeHistoryDdl() string {
+	if !gen.option.History {
+		return ""
+	}
+
+	idcol := "history_id INTEGER PRIMARY KEY AUTOINCREMENT"
+	opType := "TEXT"
+	atType := "TEXT"
+	if gen.rdbms == "postgresql" {
+		idcol = "history_id SERIAL PRIMARY KEY"
+		opType = "VARCHAR(10)"
+		atType = "TIMESTAMP"
+	} else if gen.rdbms == "mysql" {
+		idcol = "history_id INT AUTO_INCREMENT PRIMARY KEY"
+		opType = "VARCHAR(10)"
+		atType = "DATETIME"
+	}
+
+	code := ""
+	for _, table := range gen.tables {
+		htn := gen.getHistoryTableName(table)
+		code += fmt.Sprintf("\nCREATE TABLE %s (\n\t%s,\n", htn, idcol)
+		code += fmt.Sprintf("\thistory_operation %s NOT NULL,\n", opType)
+		code += fmt.Sprintf("\thistory_changed_at %s NOT NULL", atType)
+		for _, c := range table.Columns {
+			code += fmt.Sprintf(",\n\t%s %s", strings.ToLower(c.Name), gen.getHistoryColumnType(c))
+		}
+
+		ls := []string{}
+		for _, c := range gen.getPrimaryKeyColumns(table) {
+			ls = append(ls, strings.ToLower(c.Name))
+		}
+		if len(ls) == 0 {
+			code += "\n);\n"
+			continue
+		}
+		ls = append(ls, "history_changed_at")
+		if gen.rdbms == "mysql" {
+			code += fmt.Sprintf(",\n\tINDEX %s_idx1 (%s)\n);\n", htn, strings.Join(ls, ", "))
+		} else {
+			code += fmt.Sprintf("\n);\nCREATE INDEX %s_idx1 ON %s (%s);\n", htn, htn, strings.Join(ls, ", "))
+		}
+	}
+	return code
+}
+
+// 履歴テーブルのカラムのデータ型 (連番型は整数型にする)
+func (gen *generator) getHistoryColumnType(c ddlparse.Column) string {
+	name := strings.ToUpper(c.DataType.Name)
+	switch name {
+	case "SERIAL":
+		return "INTEGER"
+	case "BIGSERIAL":
+		return "BIGINT"
+	case "SMALLSERIAL":
+		return "SMALLINT"
+	}
+	if c.DataType.DigitN > 0 && c.DataType.DigitM > 0 {
+		return fmt.Sprintf("%s(%d,%d)", name, c.DataType.DigitN, c.DataType.DigitM)
+	} else if c.DataType.DigitN > 0 {
+		return fmt.Sprintf("%s(%d)", name, c.DataType.DigitN)
+	}
+	return name
+}
+
+// schema_migrations のDDL (create-table.sql で作成したデータベースは versions のマイグレーションを適用済みとする)
+func (gen *generator) codeSchemaMigrationsDdl(versions []string) string {
+	code := FORMAT_DDL_SCHEMA_MIGRATIONS_SQLITE3
+	if gen.rdbms == "postgresql" {
+		code = FORMAT_DDL_SCHEMA_MIGRATIONS_POSTGRESQL
+	} else if gen.rdbms == "mysql" {
+		code = FORMAT_DDL_SCHEMA_MIGRATIONS_MYSQL
+	}
+	for _, v := range versions {
+		code += fmt.Sprintf("INSERT INTO schema_migrations (version) VALUES (%s);\n", quoteSqlString(v))
+	}
+	return code
+}
+
+/////////////////////////////////////////////////////////////////////////////////
+/////////////////////////////  scripts/migrations  /////////////////////////////
+///////////////////////////////////////////////////////////////////////////////
+
+type migration struct {
+	// <バージョン>_<名前> (ファイル名は <name>.up.sql / <name>.down.sql)
+	name string
+	up string
+	down string
+}
+
+var reMigrationFile = regexp.MustCompile(`^(\d+)_\w+\.(up|down)\.sql$`)
+
+// 前回生成したzipの1ファイル・合計の上限 (展開後のサイズ)
+const maxPreviousFileSize = 10 << 20
+const maxPreviousTotalSize = 100 << 20
+
+// 前回生成したzipから create-table.sql・migrations・プロジェクトのファイルを読み取る
+// create-table.sql は手書きの変更を含まない .masmaint-cg/base のものを優先する
+// 出力先の外に書き込まないよう、絶対パス・.. を含むパスのファイルはエラーとする
+func ReadPreviousZip(b []byte) (Previous, error) {
+	r, err := zip.NewReader(bytes.NewReader(b), int64(len(b)))
+	if err != nil {
+		return Previous{}, fmt.Errorf("前回生成したzipを読み込めませんでした。")
+	}
+	root, ok := projectRoot(r.File)
+	if !ok {
+		return Previous{}, fmt.Errorf("前回生成したzipに scripts/create-table.sql がありません。")
+	}
+
+	prev := Previous{Migrations: map[string]string{}, Files: map[string][]byte{}, Base: map[string][]byte{}}
+	total := 0
+	for _, f := range r.File {
+		if f.FileInfo().IsDir() || !strings.HasPrefix(f.Name, root) {
+			continue
+		}
+		rel := strings.TrimPrefix(f.Name, root)
+		if !isSafeRelPath(rel) {
+			return Previous{}, fmt.Errorf("前回生成したzipに不正なパス %s があります。", f.Name)
+		}
+		if f.UncompressedSize64 > maxPreviousFileSize {
+			return Previous{}, fmt.Errorf("前回生成したzipの %s が大きすぎます。", rel)
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return Previous{}, fmt.Errorf("前回生成したzipの %s を読み込めませんでした。", rel)
+		}
+		//ヘッダのサイズは偽装できるため、読み込む量も制限する
+		content, err := io.ReadAll(io.LimitReader(rc, maxPreviousFileSize + 1))
+		rc.Close()
+		if err != nil {
+			return Previous{}, fmt.Errorf("前回生成したzipの %s を読み込めませんでした。", rel)
+		}
+		if len(content) > maxPreviousFileSize {
+			return Previous{}, fmt.Errorf("前回生成したzipの %s が大きすぎます。", rel)
+		}
+		total += len(content)
+		if total > maxPreviousTotalSize {
+			return Previous{}, fmt.Errorf("前回生成したzipが大きすぎます。")
+		}
+
+		if strings.HasPrefix(rel, baseDir + "/") {
+			prev.Base[strings.TrimPrefix(rel, baseDir + "/")] = content
+			continue
+		}
+		if strings.HasPrefix(rel, metaDir + "/") {
+			continue
+		}
+		prev.Files[rel] = content
+		if path.Dir(rel) == "scripts/migrations" && reMigrationFile.MatchString(path.Base(rel)) {
+			prev.Migrations[path.Base(rel)] = string(content)
+		}
+	}
+	if content, ok := prev.Base["scripts/create-table.sql"]; ok {
+		prev.CreateTableSql = string(content)
+	} else {
+		prev.CreateTableSql = string(prev.Files["scripts/create-table.sql"])
+	}
+	return prev, nil
+}
+
+// プロジェクト内の相対パスか (絶対パス・.. を含むパスは不可)
+func isSafeRelPath(rel string) bool {
+	if path.IsAbs(rel) || strings.Contains(rel, "\\") {
+		return false
+	}
+	if strings.Contains(path.Clean(rel), "..") {
+		return false
+	}
+	return fs.ValidPath(rel)
+}
+
+// zip内のプロジェクトのディレクトリ (.masmaint-cg、なければ scripts/create-table.sql のあるディレクトリ)
+func projectRoot(files []*zip.File) (string, bool) {
+	for _, f := range files {
+		if i := strings.Index(f.Name, metaDir + "/"); i == 0 || (i > 0 && f.Name[i-1] == '/') {
+			return f.Name[:i], true
+		}
+	}
+	for _, f := range files {
+		name := "scripts/create-table.sql"
+		if i := len(f.Name) - len(name); strings.HasSuffix(f.Name, name) && (i == 0 || f.Name[i-1] == '/') {
+			return f.Name[:i], true
+		}
+	}
+	return "", false
+}
+
+// 前回の入力と今回の create-table.sql を比較してマイグレーションを作成
+func (gen *generator) newMigration() (*migration, error) {
+	prev := gen.option.Previous
+	if prev == nil {
+		return nil, nil
+	}
+	var from, to string
+	if prev.CreateTableSql != "" {
+		from, to = prev.CreateTableSql, gen.codeCreateTableSql()
+	} else if prev.Definition != nil {
+		pg := &generator{
+			ddl: prev.Definition.Ddl,
+			tables: prev.Definition.Tables,
+			comments: prev.Definition.Comments,
+			rdbms: gen.rdbms,
+		}
+		from, to = pg.codeTablesDdl(), gen.codeTablesDdl()
+	} else {
+		return nil, nil
+	}
+
+	fromSchema, err := input.ReadSchema([]byte(from), gen.rdbms)
+	if err != nil {
+		return nil, fmt.Errorf("前回の定義を読み取れませんでした。（%s）", err.Error())
+	}
+	toSchema, err := input.ReadSchema([]byte(to), gen.rdbms)
+	if err != nil {
+		return nil, fmt.Errorf("今回の定義を比較できませんでした。（%s）", err.Error())
+	}
+	up, name := gen.codeMigrationSql(fromSchema, toSchema)
+	if up == "" {
+		return nil, nil
+	}
+	down, _ := gen.codeMigrationSql(toSchema, fromSchema)
+
+	name = time.Now().Format("20060102150405") + "_" + name
+	header := "-- %s.%s.sql (前回の定義との差分から生成、適用前に内容を確認してください)\n\n"
+	return &migration{
+		name: name,
+		up: fmt.Sprintf(header, name, "up") + up,
+		down: fmt.Sprintf(header, name, "down") + down,
+	}, nil
+}
+
+// scripts/migrations 生成 (前回のマイグレーションを引き継ぎ、差分のマイグレーションを追加)
+// 戻り値は適用済みとするバージョン (create-table.sql はすべて反映した定義のため)
+func (gen *generator) generateMigrationFiles(path string) ([]string, error) {
+	if gen.option.Previous == nil {
+		return nil, nil
+	}
+	files := map[string]string{}
+	for name, content := range gen.option.Previous.Migrations {
+		files[name] = content
+	}
+	if gen.migration != nil {
+		files[gen.migration.name + ".up.sql"] = gen.migration.up
+		files[gen.migration.name + ".down.sql"] = gen.migration.down
+	}
+	if len(files) == 0 {
+		return nil, nil
+	}
+
+	path = fmt.Sprintf("%s/migrations", path)
+	if err := MakeDirAll(path); err != nil {
+		logger.Error(err.Error())
+		return nil, err
+	}
+	versions := []string{}
+	for name, content := range files {
+		if err := WriteFile(fmt.Sprintf("%s/%s", path, name), content); err != nil {
+			logger.Error(err.Error())
+			return nil, err
+		}
+		if m := reMigrationFile.FindStringSubmatch(name); m != nil && m[2] == "up" {
+			versions = append(versions, m[1])
+		}
+	}
+	sort.Strings(versions)
+	return versions, nil
+}
+
+func isSchemaMigrationsTable(name string) bool {
+	return strings.EqualFold(name, "schema_migrations")
+}
+
+// from の定義を to にするSQL (テーブルの追加・変更・削除の順) と名前
+func (gen *generator) codeMigrationSql(from, to input.Schema) (string, string) {
+	ls := []string{}
+	names := []string{}
+	for _, t := range to.Tables {
+		if _, ok := from.Table(t.Name); ok || isSchemaMigrationsTable(t.Name) {
+			continue
+		}
+		ls = append(ls, strings.Join(t.Statements, "\n") + "\n")
+		names = append(names, "create_" + strings.ToLower(t.Name))
+	}
+	created := len(names)
+	for _, t := range to.Tables {
+		ft, ok := from.Table(t.Name)
+		if !ok || isSchemaMigrationsTable(t.Name) {
+			continue
+		}
+		if code := gen.codeAlterTableSql(ft, t); code != "" {
+			ls = append(ls, code)
+			names = append(names, "alter_" + strings.ToLower(t.Name))
+		}
+	}
+	//参照するテーブルより先に削除するため逆順
+	for i := len(from.Tables) - 1; i >= 0; i-- {
+		t := from.Tables[i]
+		if _, ok := to.Table(t.Name); ok || isSchemaMigrationsTable(t.Name) {
+			continue
+		}
+		code := fmt.Sprintf("DROP TABLE %s;\n", t.Name)
+		if created > 0 {
+			code = fmt.Sprintf("-- テーブル '%s' の削除は名前の変更の可能性があります。変更の場合は ALTER TABLE ... RENAME TO に書き換えてください (削除するとデータは失われます)。\n", t.Name) + code
+		}
+		ls = append(ls, code)
+		names = append(names, "drop_" + strings.ToLower(t.Name))
+	}
+
+	name := "update_schema"
+	if len(names) == 1 {
+		name = names[0]
+	}
+	return strings.Join(ls, "\n"), name
+}
+
+// カラムの型・NOT NULL・デフォルト値・自動採番の変更
+func isColumnChanged(from, to input.SchemaColumn) bool {
+	return !strings.EqualFold(from.Type, to.Type) || from.NotNull != to.NotNull ||
+		from.Default != to.Default || from.Autoincrement != to.Autoincrement
+}
+
+// カラムの主キー・ユニーク・CHECK・REFERENCES の変更
+func isColumnConstraintChanged(from, to input.SchemaColumn) bool {
+	return from.PrimaryKey != to.PrimaryKey || from.Unique != to.Unique ||
+		strings.Join(from.Constraints, "\n") != strings.Join(to.Constraints, "\n")
+}
+
+// テーブルの変更 (カラムの追加・変更・削除)
+// 制約・インデックスの変更は ALTER TABLE の構文がRDBMSごとに異なるためコメントで知らせる (SQLite は作り直す)
+func (gen *generator) codeAlterTableSql(from, to input.SchemaTable) string {
+	added := []input.SchemaColumn{}
+	changed := [][2]input.SchemaColumn{}
+	dropped := []input.SchemaColumn{}
+	constraintChanged := strings.Join(from.Constraints, "\n") != strings.Join(to.Constraints, "\n")
+	for _, c := range to.Columns {
+		fc, ok := from.Column(c.Name)
+		if !ok {
+			added = append(added, c)
+			continue
+		}
+		if isColumnChanged(fc, c) || (gen.rdbms == "mysql" && fc.Comment != c.Comment) {
+			changed = append(changed, [2]input.SchemaColumn{fc, c})
+		}
+		if isColumnConstraintChanged(fc, c) {
+			constraintChanged = true
+		}
+	}
+	for _, c := range from.Columns {
+		if _, ok := to.Column(c.Name); !ok {
+			dropped = append(dropped, c)
+		}
+	}
+	if len(added) == 0 && len(changed) == 0 && len(dropped) == 0 && !constraintChanged {
+		return ""
+	}
+	code := ""
+	if len(added) > 0 && len(dropped) > 0 {
+		ds, as := []string{}, []string{}
+		for _, c := range dropped {
+			ds = append(ds, c.Name)
+		}
+		for _, c := range added {
+			as = append(as, c.Name)
+		}
+		code += fmt.Sprintf(
+			"-- テーブル '%s' のカラム %s の削除と %s の追加は名前の変更の可能性があります。変更の場合は RENAME COLUMN に書き換えてください (削除するとデータは失われます)。\n",
+			to.Name, strings.Join(ds, ", "), strings.Join(as, ", "),
+		)
 	}
-	s2 = strings.TrimSuffix(s2, "\n")
-	s3 := strings.ToLower(table.Columns[0].Name)
-	s4 := ""
-	for _, c := range updcols {
-		cn := strings.ToLower(c.Name)
-		s4 += fmt.Sprintf("\t\t\t'%s': %s[i],\n", cn, cn)
+	if gen.rdbms == "sqlite3" && (len(changed) > 0 || len(dropped) > 0 || constraintChanged || !canAddColumnsSqlite(added)) {
+		return code + gen.codeRebuildTableSql(from, to)
 	}
-	s4 = strings.TrimSuffix(s4, "\n")
-	s5 := ""
-	for _, c := range updcols {
-		cn := strings.ToLower(c.Name)
-		s5 += fmt.Sprintf("\t\t\t'%s': %s_bk[i],\n", cn, cn)
+
+	for _, c := range added {
+		//PostgreSQL はデフォルト値のない NOT NULL のカラムを行のあるテーブルに追加できない (MySQL は型の既定値とする)
+		if gen.rdbms == "postgresql" && c.NotNull && !hasDefault(c) && !c.Autoincrement {
+			code += fmt.Sprintf("-- カラム '%s.%s' は NOT NULL でデフォルト値がないため、行がある場合は失敗します。デフォルト値を指定するか、NULL を許可して追加し値を設定してから NOT NULL にしてください。\n", to.Name, c.Name)
+		}
+		code += fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s;\n", to.Name, c.Definition)
+		//MySQL はカラムの REFERENCES を無視するため、外部キーを追加する
+		for _, ct := range c.Constraints {
+			if gen.rdbms == "mysql" && strings.HasPrefix(ct, "FOREIGN KEY") {
+				code += fmt.Sprintf("ALTER TABLE %s ADD %s;\n", to.Name, ct)
+			}
+		}
 	}
-	s5 = strings.TrimSuffix(s5, "\n")
-	s6 := ""
-	for _, c := range table.Columns {
-		cn := strings.ToLower(c.Name)
-		ctype := gen.dataTypeToGoType(c.DataType.Name)
-		if ctype == "int" {
-			s6 += fmt.Sprintf("\t\t\t\t%s: parseIntOrReturnOriginal(%s[i].value),\n", cn, cn)
-		} else if ctype == "float64" {
-			s6 += fmt.Sprintf("\t\t\t\t%s: parseFloatOrReturnOriginal(%s[i].value),\n", cn, cn)
-		} else if gen.isNullColumn(c, table.Constraints) {
-			s6 += fmt.Sprintf("\t\t\t\t%s: emptyToNull(%s[i].value),\n", cn, cn)
+	for _, cs := range changed {
+		if gen.rdbms == "mysql" {
+			if cs[1].NotNull && !cs[0].NotNull {
+				code += codeFillNullSql(to.Name, cs[1])
+			}
+			code += gen.codeModifyColumnSqlMySQL(to.Name, cs[1])
 		} else {
-			s6 += fmt.Sprintf("\t\t\t\t%s: %s[i].value,\n", cn, cn)
+			code += gen.codeAlterColumnSqlPostgreSQL(to.Name, cs[0], cs[1])
 		}
 	}
-	s6 = strings.TrimSuffix(s6, "\n")
-	s7 := ""
-	for _, c := range table.Columns {
-		cn := strings.ToLower(c.Name)
-		s7 += fmt.Sprintf("\t\t\t\t%s[i].value = data.%s;\n", cn, cn)
+	for _, c := range dropped {
+		code += fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s;\n", to.Name, c.Name)
 	}
-	for _, c := range updcols {
-		cn := strings.ToLower(c.Name)
-		s7 += fmt.Sprintf("\t\t\t\t%s_bk[i].value = data.%s;\n", cn, cn)
+	if constraintChanged {
+		code += fmt.Sprintf("-- テーブル '%s' の制約・インデックスの変更は含みません。必要に応じて追加してください。\n", to.Name)
 	}
-	s7 = strings.TrimSuffix(s7, "\n")
-
-	return fmt.Sprintf(
-		FORMAT_JS_PUTROWS, 
-		s1, s2, s3, s4, s5, s6, tn, s7, tn,
-	)
+	return code
 }
 
-func (gen *generator) codeJsPostRow(table ddlparse.Table) string {
-	tn := strings.ToLower(table.Name)
-	inscols := gen.getInsertColumns(table)
-	s1 := ""
-	for _, c := range inscols {
-		cn := strings.ToLower(c.Name)
-		s1 += fmt.Sprintf("\t\t'%s': document.getElementById('%s_new'),\n", cn, cn)
+func (gen *generator) codeAlterColumnSqlPostgreSQL(tn string, from, to input.SchemaColumn) string {
+	code := ""
+	if from.Autoincrement != to.Autoincrement {
+		code += fmt.Sprintf("-- カラム '%s.%s' の自動採番の変更は含みません。必要に応じて追加してください。\n", tn, to.Name)
 	}
-	s1 = strings.TrimSuffix(s1, "\n")
-	s2 := ""
-	for _, c := range inscols{
-		cn := strings.ToLower(c.Name)
-		ctype := gen.dataTypeToGoType(c.DataType.Name)
-		if ctype == "int" {
-			s2 += fmt.Sprintf("\t\t\t%s: parseIntOrReturnOriginal(rowMap.%s.value),\n", cn, cn)
-		} else if ctype == "float64" {
-			s2 += fmt.Sprintf("\t\t\t%s: parseFloatOrReturnOriginal(rowMap.%s.value),\n", cn, cn)
-		} else if gen.isNullColumn(c, table.Constraints) {
-			s2 += fmt.Sprintf("\t\t\t%s: emptyToNull(rowMap.%s.value),\n", cn, cn)
+	if !strings.EqualFold(from.Type, to.Type) {
+		if strings.HasSuffix(strings.ToUpper(to.Type), "SERIAL") {
+			code += fmt.Sprintf("-- カラム '%s.%s' の型を %s に変更する場合は手動で行ってください。\n", tn, to.Name, to.Type)
 		} else {
-			s2 += fmt.Sprintf("\t\t\t%s: rowMap.%s.value,\n", cn, cn)
+			code += fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s TYPE %s USING %s::%s;\n", tn, to.Name, to.Type, to.Name, to.Type)
 		}
 	}
-	s2 = strings.TrimSuffix(s2, "\n")
-
-	return fmt.Sprintf(
-		FORMAT_JS_POSTROW, 
-		s1, s2, tn, tn,
-	)
+	if from.NotNull != to.NotNull {
+		if to.NotNull {
+			code += codeFillNullSql(tn, to)
+			code += fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s SET NOT NULL;\n", tn, to.Name)
+		} else {
+			code += fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s DROP NOT NULL;\n", tn, to.Name)
+		}
+	}
+	if from.Default != to.Default {
+		if to.Default == "" {
+			code += fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s DROP DEFAULT;\n", tn, to.Name)
+		} else {
+			code += fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s SET DEFAULT %s;\n", tn, to.Name, to.Default)
+		}
+	}
+	return code
 }
 
-func (gen *generator) codeJsDeleteRows(table ddlparse.Table) string {
-	return fmt.Sprintf(
-		FORMAT_JS_DELETEROWS,
-		strings.ToLower(table.Name),
-	)
+func hasDefault(c input.SchemaColumn) bool {
+	return c.Default != "" && !strings.EqualFold(c.Default, "NULL")
 }
 
-/////////////////////////////////////////////////////////////////////////////////
-////////////////////////////////  web/template  ////////////////////////////////
-///////////////////////////////////////////////////////////////////////////////
+// NOT NULL にする前に既存の NULL をデフォルト値にする (デフォルト値がない場合はコメントで知らせる)
+func codeFillNullSql(tn string, c input.SchemaColumn) string {
+	if !hasDefault(c) {
+		return fmt.Sprintf("-- カラム '%s.%s' は NOT NULL にするため、NULL の行がある場合は失敗します。値を設定してから実行してください。\n", tn, c.Name)
+	}
+	return fmt.Sprintf("UPDATE %s SET %s = %s WHERE %s IS NULL;\n", tn, c.Name, c.Default, c.Name)
+}
 
-// template 生成
-func (gen *generator) generateTemplate(path string) error {
-	path = fmt.Sprintf("%s/template", path)
-	if err := MakeDirAll(path); err != nil {
-		logger.Error(err.Error())
-		return err
+// MODIFY COLUMN はカラムの定義を置き換えるため、主キー・ユニーク・外部キー以外を指定する
+func (gen *generator) codeModifyColumnSqlMySQL(tn string, c input.SchemaColumn) string {
+	code := c.Name + " " + c.Type
+	if c.NotNull {
+		code += " NOT NULL"
 	}
-	if err := gen.generateMenuHtmlFile(path); err != nil {
-		return err
+	if c.Default != "" {
+		code += " DEFAULT " + c.Default
 	}
-	if err := gen.generateTableHtmlFiles(path); err != nil {
-		return err
+	if c.Autoincrement {
+		code += " AUTO_INCREMENT"
 	}
-	return nil
+	if c.Comment != "" {
+		code += " COMMENT " + quoteSqlString(c.Comment)
+	}
+	return fmt.Sprintf("ALTER TABLE %s MODIFY COLUMN %s;\n", tn, code)
 }
 
-/////////////////////////////////////////////////////////////////////////////////
-/////////////////////////////////  _menu.html  /////////////////////////////////
-///////////////////////////////////////////////////////////////////////////////
-
-// _menu.html 生成
-func (gen *generator) generateMenuHtmlFile(path string) error {
-	path = fmt.Sprintf("%s/_menu.html", path)
-	code := gen.codeMenuHtml()
-	if err := WriteFile(path, code); err != nil {
-		logger.Error(err.Error())
-		return err
+// SQLite の ADD COLUMN で追加できるカラム (主キー・ユニーク・デフォルト値のない NOT NULL・式のデフォルト値は不可)
+func canAddColumnsSqlite(columns []input.SchemaColumn) bool {
+	for _, c := range columns {
+		def := strings.ToUpper(c.Default)
+		if c.PrimaryKey || c.Unique || (c.NotNull && (def == "" || def == "NULL")) ||
+			strings.HasPrefix(def, "(") || strings.HasPrefix(def, "CURRENT_") {
+			return false
+		}
 	}
-	return nil
+	return true
 }
 
-// _menu.html コード生成
-func (gen *generator) codeMenuHtml() string {
-	s1 := ""
-	for _, table := range gen.tables {
-		tn := strings.ToLower(table.Name)
-		s1 += fmt.Sprintf("\t\t<li class='nav-item'><a href='/%s' class='nav-link py-1'>%s</a></li>\n", tn, tn)
+/*
+ SQLite はカラムの変更・削除ができないため、テーブルを作り直して共通のカラムのデータを移す
+ NOT NULL にしたカラムの NULL はデフォルト値にする (デフォルト値がない場合はコメントで知らせる)
+*/
+func (gen *generator) codeRebuildTableSql(from, to input.SchemaTable) string {
+	tmp := to.Name + "__new"
+	code := fmt.Sprintf("-- テーブル '%s' を作り直す (SQLite はカラムの変更・削除ができないため)\n", to.Name)
+	cols := []string{}
+	values := []string{}
+	for _, c := range to.Columns {
+		fc, ok := from.Column(c.Name)
+		if !ok {
+			if c.NotNull && !hasDefault(c) && !c.PrimaryKey {
+				code += fmt.Sprintf("-- カラム '%s.%s' は NOT NULL でデフォルト値がないため、行がある場合は失敗します。\n", to.Name, c.Name)
+			}
+			continue
+		}
+		cols = append(cols, c.Name)
+		if c.NotNull && !fc.NotNull && hasDefault(c) {
+			values = append(values, fmt.Sprintf("COALESCE(%s, %s)", c.Name, c.Default))
+		} else {
+			if c.NotNull && !fc.NotNull {
+				code += fmt.Sprintf("-- カラム '%s.%s' は NOT NULL にするため、NULL の行がある場合は失敗します。\n", to.Name, c.Name)
+			}
+			values = append(values, c.Name)
+		}
 	}
-	s1 = strings.TrimSuffix(s1, "\n")
-	return fmt.Sprintf(FORMAT_TEMPLATE_MENU, s1)
+	code += to.CreateTableAs(tmp) + "\n"
+	if len(cols) > 0 {
+		code += fmt.Sprintf("INSERT INTO %s (%s) SELECT %s FROM %s;\n", tmp, strings.Join(cols, ", "), strings.Join(values, ", "), from.Name)
+	}
+	code += fmt.Sprintf("DROP TABLE %s;\n", from.Name)
+	code += fmt.Sprintf("ALTER TABLE %s RENAME TO %s;\n", tmp, to.Name)
+	for _, stmt := range to.Statements[1:] {
+		code += stmt + "\n"
+	}
+	return code
 }
 
 /////////////////////////////////////////////////////////////////////////////////
-///////////////////////////////  table_name.html  //////////////////////////////
+////////////////////////////////  再生成 (マージ)  ////////////////////////////////
 ///////////////////////////////////////////////////////////////////////////////
 
-func (gen *generator) generateTableHtmlFiles(path string) error {
-	for _, table := range gen.tables {
-		if err := gen.generateTableHtmlFile(path, table); err != nil {
-			return err
+// 生成したプロジェクトの管理用ディレクトリ
+const metaDir = ".masmaint-cg"
+// 生成した内容の写し (次回の再生成でマージの基点とする)
+const baseDir = metaDir + "/base"
+
+// 生成後は利用者が編集するファイル (再生成では現在のプロジェクトの内容を残す)
+var userOwnedFiles = []string{"internal/module/*/hooks.go"}
+
+func isUserOwnedFile(name string) bool {
+	for _, pattern := range userOwnedFiles {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
 		}
 	}
-	return nil
+	return false
 }
 
-// table_name.html 生成
-func (gen *generator) generateTableHtmlFile(path string, table ddlparse.Table) error {
-	tn := strings.ToLower(table.Name)
-	path = fmt.Sprintf("%s/%s.html", path, tn)
-	code := gen.codeTableHtml(table)
-	if err := WriteFile(path, code); err != nil {
+// 生成したファイルを .masmaint-cg/base に写す
+func (gen *generator) generateBase(path string) error {
+	files, err := ReadDirFiles(path, metaDir)
+	if err != nil {
 		logger.Error(err.Error())
 		return err
 	}
-	return nil
-}
-
-// table_name.html コード生成
-func (gen *generator) codeTableHtml(table ddlparse.Table) string {
-	tn := strings.ToLower(table.Name)
-	s1 := ""
-	for _, c := range table.Columns {
-		cn := strings.ToLower(c.Name)
-		if gen.isNullColumn(c, table.Constraints) || !gen.isInsertColumn(c) {
-			s1 += fmt.Sprintf("\t\t\t\t\t\t\t\t<th>%s</th>\n", cn)
-		} else {
-			s1 += fmt.Sprintf("\t\t\t\t\t\t\t\t<th>%s<spnn class=\"text-danger\">*</spnn></th>\n", cn)
+	for name, content := range files {
+		if err := writeProjectFile(fmt.Sprintf("%s/%s", path, baseDir), name, content); err != nil {
+			return err
 		}
 	}
-	s1 = strings.TrimSuffix(s1, "\n")
-	return fmt.Sprintf(
-		FORMAT_TEMPLATE, 
-		tn, s1, tn,
-	)
+	return nil
 }
 
-/////////////////////////////////////////////////////////////////////////////////
-///////////////////////////////////  scripts  //////////////////////////////////
-///////////////////////////////////////////////////////////////////////////////
-
-// scripts 生成
-func (gen *generator) generateScripts(path string) error {
-	path = fmt.Sprintf("%s/scripts", path)
-	if err := MakeDirAll(path); err != nil {
+func writeProjectFile(root string, name string, content []byte) error {
+	if !isSafeRelPath(name) {
+		return fmt.Errorf("不正なパスです。(%s)", name)
+	}
+	p := fmt.Sprintf("%s/%s", root, name)
+	if err := MakeDirAll(path.Dir(p)); err != nil {
 		logger.Error(err.Error())
 		return err
 	}
-	if err := gen.generateCreateTableSqlFile(path); err != nil {
+	if err := os.WriteFile(p, content, 0644); err != nil {
+		logger.Error(err.Error())
 		return err
 	}
 	return nil
 }
 
-/////////////////////////////////////////////////////////////////////////////////
-/////////////////////////////  create-table.sql  ///////////////////////////////
-///////////////////////////////////////////////////////////////////////////////
+func (gen *generator) MergeReport() []string {
+	return gen.mergeReport
+}
 
-// create-table.sql 生成
-func (gen *generator) generateCreateTableSqlFile(path string) error {
-	path = fmt.Sprintf("%s/create-table.sql", path)
-	code := gen.ddl
-	if err := WriteFile(path, code); err != nil {
+/*
+ 前回生成したプロジェクト (現在のプロジェクト) に今回の生成をマージする
+ 前回の生成 (.masmaint-cg/base) からの両方の変更をファイルごとに3方向マージし、
+ 同じ箇所を両方で変更した場合は競合のマーカーで両方を残す
+ 現在のプロジェクトにのみあるファイル (手書きのファイル) はそのまま残す
+*/
+func (gen *generator) mergeProject(path string) error {
+	if !gen.option.Merge {
+		return nil
+	}
+	path = fmt.Sprintf("%s/masmaint", path)
+	prev := gen.option.Previous
+	generated, err := ReadDirFiles(path, metaDir)
+	if err != nil {
 		logger.Error(err.Error())
 		return err
 	}
-	return nil
+
+	names := []string{}
+	for _, files := range []map[string][]byte{generated, prev.Files, prev.Base} {
+		for name := range files {
+			if !Contains(names, name) {
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+
+	labels := merge.Labels{Ours: "現在のプロジェクト", Base: "前回の生成", Theirs: "今回の生成"}
+	conflicts := []string{}
+	notes := []string{}
+	merged := 0
+	for _, name := range names {
+		theirs, inTheirs := generated[name]
+		ours, inOurs := prev.Files[name]
+		base, inBase := prev.Base[name]
+
+		switch {
+		case inOurs && inTheirs && isUserOwnedFile(name):
+			//利用者が編集するファイルはマージしない
+			if bytes.Equal(ours, theirs) {
+				continue
+			}
+			if err := writeProjectFile(path, name, ours); err != nil {
+				return err
+			}
+			merged++
+		case !inOurs && !inBase:
+			//今回新たに生成したファイル
+		case !inOurs:
+			//現在のプロジェクトで削除したファイルは生成しない
+			if inTheirs {
+				if err := os.Remove(fmt.Sprintf("%s/%s", path, name)); err != nil {
+					logger.Error(err.Error())
+					return err
+				}
+				if !bytes.Equal(base, theirs) {
+					notes = append(notes, fmt.Sprintf("削除済みのため今回の生成の変更を反映していません: %s", name))
+				}
+			}
+		case !inTheirs:
+			//今回生成しないファイル (削除したテーブルなど) は変更がなければ削除する
+			if inBase && bytes.Equal(ours, base) {
+				notes = append(notes, fmt.Sprintf("今回は生成しないため削除しました: %s", name))
+				continue
+			}
+			if err := writeProjectFile(path, name, ours); err != nil {
+				return err
+			}
+			if inBase {
+				notes = append(notes, fmt.Sprintf("今回は生成しないファイルですが、変更があるため残しました: %s", name))
+			}
+		case !merge.IsText(ours) || !merge.IsText(theirs) || !merge.IsText(base):
+			if bytes.Equal(ours, base) || bytes.Equal(ours, theirs) {
+				continue
+			}
+			if err := writeProjectFile(path, name, ours); err != nil {
+				return err
+			}
+			if !bytes.Equal(theirs, base) {
+				conflicts = append(conflicts, fmt.Sprintf("競合: %s (テキストではないため現在のプロジェクトの内容を残しました)", name))
+			}
+		default:
+			if bytes.Equal(ours, base) {
+				continue
+			}
+			r := merge.Merge(base, ours, theirs, labels)
+			if err := writeProjectFile(path, name, r.Text); err != nil {
+				return err
+			}
+			merged++
+			if r.Conflicts > 0 {
+				conflicts = append(conflicts, fmt.Sprintf("競合: %s (%d箇所)", name, r.Conflicts))
+			}
+		}
+	}
+
+	report := []string{fmt.Sprintf("前回生成したプロジェクトにマージしました。（変更を残したファイル %d・競合 %d）", merged, len(conflicts))}
+	report = append(report, conflicts...)
+	report = append(report, notes...)
+	gen.mergeReport = report
+
+	code := strings.Join(report, "\n") + "\n"
+	if len(conflicts) > 0 {
+		code += "\n競合は <<<<<<< から >>>>>>> の間を編集して解消してください。\n"
+	}
+	return writeProjectFile(path, metaDir + "/merge-report.txt", []byte(code))
 }
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -1229,6 +3727,100 @@ func (gen *generator) getFieldName(columnName, tableName string) string {
 	return SnakeToPascal(cn)
 }
 
+// 履歴テーブル名
+func (gen *generator) getHistoryTableName(table ddlparse.Table) string {
+	return strings.ToLower(table.Name) + "_history"
+}
+
+// 履歴の参照・復元APIを生成するテーブルか (履歴オプションかつ主キーあり)
+func (gen *generator) isHistoryRoutesTable(table ddlparse.Table) bool {
+	return gen.option.History && len(gen.getPrimaryKeyColumns(table)) > 0
+}
+
+// 画面に他のユーザの変更を反映するテーブルか (行を主キーで特定するため主キーあり)
+func (gen *generator) isLiveTable(table ddlparse.Table) bool {
+	return len(gen.getPrimaryKeyColumns(table)) > 0
+}
+
+// 論理削除カラムとして自動判定するカラム名
+var softDeleteColumnNames = []string{"deleted_at", "is_deleted", "del_flg", "delete_flag"}
+
+// 論理削除カラムか判定 (オプションで指定された場合はそのカラムのみ)
+func (gen *generator) isSoftDeleteColumn(c ddlparse.Column) bool {
+	cn := strings.ToLower(c.Name)
+	if gen.option.SoftDeleteColumn != "" {
+		return cn == strings.ToLower(strings.TrimSpace(gen.option.SoftDeleteColumn))
+	}
+	return Contains(softDeleteColumnNames, cn)
+}
+
+// 論理削除カラムを取得
+func (gen *generator) getSoftDeleteColumn(table ddlparse.Table) (ddlparse.Column, bool) {
+	for _, c := range table.Columns {
+		if gen.isSoftDeleteColumn(c) {
+			return c, true
+		}
+	}
+	return ddlparse.Column{}, false
+}
+
+// 論理削除カラムが日時か (日時: 削除日時を設定、それ以外: 削除フラグ)
+func (gen *generator) isSoftDeleteTimestamp(c ddlparse.Column) bool {
+	return gen.dateTypeKind(c.DataType.Name) != "" || strings.HasSuffix(strings.ToLower(c.Name), "_at")
+}
+
+// 削除フラグの値 (boolean型は TRUE / FALSE、それ以外は 1 / 0)
+func (gen *generator) getSoftDeleteFlagValues(c ddlparse.Column) (string, string) {
+	if strings.Contains(strings.ToUpper(c.DataType.Name), "BOOL") {
+		return "TRUE", "FALSE"
+	}
+	return "1", "0"
+}
+
+// 論理削除されていない行の条件
+func (gen *generator) getActiveCondition(c ddlparse.Column) string {
+	cn := strings.ToLower(c.Name)
+	if gen.isSoftDeleteTimestamp(c) {
+		return fmt.Sprintf("%s IS NULL", cn)
+	}
+	_, off := gen.getSoftDeleteFlagValues(c)
+	return fmt.Sprintf("COALESCE(%s, %s) = %s", cn, off, off)
+}
+
+// 論理削除された行の条件
+func (gen *generator) getDeletedCondition(c ddlparse.Column) string {
+	cn := strings.ToLower(c.Name)
+	if gen.isSoftDeleteTimestamp(c) {
+		return fmt.Sprintf("%s IS NOT NULL", cn)
+	}
+	on, _ := gen.getSoftDeleteFlagValues(c)
+	return fmt.Sprintf("%s = %s", cn, on)
+}
+
+// 論理削除・復元で設定する値
+func (gen *generator) getSoftDeleteSetValues(c ddlparse.Column) (string, string) {
+	if gen.isSoftDeleteTimestamp(c) {
+		return "CURRENT_TIMESTAMP", "NULL"
+	}
+	return gen.getSoftDeleteFlagValues(c)
+}
+
+// ゴミ箱 (論理削除した行の一覧・復元) を生成するテーブルか (論理削除カラムかつ主キーあり)
+func (gen *generator) isTrashTable(table ddlparse.Table) bool {
+	_, found := gen.getSoftDeleteColumn(table)
+	return found && len(gen.getPrimaryKeyColumns(table)) > 0
+}
+
+// 主キーのフィールド参照 (m.Field) のリスト
+func (gen *generator) getPrimaryKeyFieldRefs(table ddlparse.Table) []string {
+	tn := strings.ToLower(table.Name)
+	ret := []string{}
+	for _, c := range gen.getPrimaryKeyColumns(table) {
+		ret = append(ret, fmt.Sprintf("m.%s", gen.getFieldName(c.Name, tn)))
+	}
+	return ret
+}
+
 // データ型 -> Goデータ型
 func (gen *generator) dataTypeToGoType(dataType string) string {
 	dataType = strings.ToUpper(dataType)
@@ -1246,6 +3838,19 @@ func (gen *generator) dataTypeToGoType(dataType string) string {
 	}
 }
 
+// 日付型の判定 (date / datetime / 日付型以外は空文字)
+func (gen *generator) dateTypeKind(dataType string) string {
+	dataType = strings.ToUpper(dataType)
+
+	if strings.Contains(dataType, "DATETIME") || strings.Contains(dataType, "TIMESTAMP") {
+		return "datetime"
+	} else if strings.Contains(dataType, "DATE") {
+		return "date"
+	} else {
+		return ""
+	}
+}
+
 // Null許容のカラムか判定
 func (gen *generator) isNullColumn(column ddlparse.Column, constraints ddlparse.TableConstraint) bool {
 	if (column.Constraint.IsNotNull) {
@@ -1273,6 +3878,9 @@ func (gen *generator)isInsertColumn(c ddlparse.Column) bool {
 	if c.Constraint.IsAutoincrement {
 		return false
 	}
+	if gen.isSoftDeleteColumn(c) {
+		return false
+	}
 	if strings.Contains(strings.ToUpper(c.DataType.Name), "SERIAL") {
 		return false
 	}
@@ -1283,16 +3891,24 @@ func (gen *generator)isInsertColumn(c ddlparse.Column) bool {
 }
 
 // UPDATEで指定するカラムか判定
-func (gen *generator)isUpdateColumn(c ddlparse.Column) bool {
+func (gen *generator)isUpdateColumn(c ddlparse.Column, constraints ddlparse.TableConstraint) bool {
 	if c.Constraint.IsAutoincrement {
 		return false
 	}
+	if gen.isSoftDeleteColumn(c) {
+		return false
+	}
 	if strings.Contains(strings.ToUpper(c.DataType.Name), "SERIAL") {
 		return false
 	}
 	if c.Constraint.IsPrimaryKey {
 		return false
 	}
+	for _, pk := range constraints.PrimaryKey {
+		if Contains(pk.ColumnNames, c.Name) {
+			return false
+		}
+	}
 	if strings.Contains(c.Name, "_at") || strings.Contains(c.Name, "_AT") {
 		return false
 	}
@@ -1314,7 +3930,7 @@ func (gen *generator)getInsertColumns(table ddlparse.Table) []ddlparse.Column {
 func (gen *generator)getUpdateColumns(table ddlparse.Table) []ddlparse.Column {
 	ret := []ddlparse.Column{}
 	for _, c := range table.Columns {
-		if gen.isUpdateColumn(c) {
+		if gen.isUpdateColumn(c, table.Constraints) {
 			ret = append(ret, c)
 		}	
 	}
@@ -1343,6 +3959,15 @@ func (gen *generator)getPrimaryKeyColumns(table ddlparse.Table) []ddlparse.Colum
 	return ret
 }
 
+// 主キーのルートパスを取得 (例: /:shop_code/:product_code)
+func (gen *generator)getKeyRoutePath(table ddlparse.Table) string {
+	ret := ""
+	for _, c := range gen.getPrimaryKeyColumns(table) {
+		ret += fmt.Sprintf("/:%s", strings.ToLower(c.Name))
+	}
+	return ret
+}
+
 // AUTO_INCREMENTのカラムを取得（1つ以下である前提）
 func (gen *generator)getAutoIncrementColumn(table ddlparse.Table) (ddlparse.Column, bool) {
 	for _, c := range table.Columns {
@@ -1354,4 +3979,11 @@ func (gen *generator)getAutoIncrementColumn(table ddlparse.Table) (ddlparse.Colu
 		}
 	}
 	return ddlparse.Column{}, false
-}
\ No newline at end of file
+}
+// DB_DRIVER に指定するドライバ名
+func (gen *generator) getDriverName() string {
+	if gen.rdbms == "postgresql" {
+		return "postgres"
+	}
+	return gen.rdbms
+}
@@ -146,4 +146,34 @@ func Contains(slice []string, element string) bool {
         }
     }
     return false
-}
\ No newline at end of file
+}
+// ディレクトリ配下のファイル (キーは root からの相対パス、/ 区切り) skip で始まるパスは除く
+func ReadDirFiles(root string, skip string) (map[string][]byte, error) {
+	files := map[string][]byte{}
+	err := filepath.WalkDir(root, func(p string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if skip != "" && (rel == skip || strings.HasPrefix(rel, skip + "/")) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			return nil
+		}
+		b, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		files[rel] = b
+		return nil
+	})
+	return files, err
+}
@@ -0,0 +1,851 @@
+package input
+
+import (
+	"fmt"
+	"strings"
+	"strconv"
+	"unicode"
+	"github.com/kodaimura/ddlparse"
+)
+
+
+/*
+ DBML (https://dbml.dbdiagram.io/docs/) を読み取る
+ Table (カラムの設定・indexes の pk / unique・Note)、Enum、Ref (インラインを含む) に対応
+ Project・TableGroup などテーブル定義以外のブロックは読み飛ばす
+*/
+
+type dbmlReader struct {}
+
+type dbmlTokenKind int
+
+const (
+	dbmlIdent dbmlTokenKind = iota
+	dbmlString
+	dbmlExpr
+	dbmlNumber
+	dbmlSymbol
+	dbmlNewline
+	dbmlEOF
+)
+
+type dbmlToken struct {
+	kind dbmlTokenKind
+	value string
+	line int
+}
+
+// Ref の片側 (table.column または table.(column1, column2))
+type dbmlEndpoint struct {
+	table string
+	columns []string
+}
+
+type dbmlRef struct {
+	from dbmlEndpoint
+	op string
+	to dbmlEndpoint
+	line int
+}
+
+type dbmlParser struct {
+	tokens []dbmlToken
+	pos int
+	rdbms string
+	tables []ddlparse.Table
+	comments map[string]string
+	enums map[string][]string
+	aliases map[string]string
+	refs []dbmlRef
+	// カラムの型名 (列挙型の判定用、キーは <テーブル名>.<カラム名>)
+	typeNames map[string]string
+	increments []dbmlEndpoint
+}
+
+
+func (r dbmlReader) Read(src []byte, rdbms string) (Definition, error) {
+	tokens, err := lexDbml(string(src))
+	if err != nil {
+		return Definition{}, err
+	}
+	p := &dbmlParser{
+		tokens: tokens,
+		rdbms: rdbms,
+		comments: map[string]string{},
+		enums: map[string][]string{},
+		aliases: map[string]string{},
+		typeNames: map[string]string{},
+	}
+	if err := p.parse(); err != nil {
+		return Definition{}, err
+	}
+	if err := p.resolve(); err != nil {
+		return Definition{}, err
+	}
+	if len(p.tables) == 0 {
+		return Definition{}, fmt.Errorf("テーブルが見つかりません。")
+	}
+	return Definition{Tables: p.tables, Comments: p.comments}, nil
+}
+
+
+func lexDbml(src string) ([]dbmlToken, error) {
+	tokens := []dbmlToken{}
+	rs := []rune(src)
+	line := 1
+	for i := 0; i < len(rs); {
+		ch := rs[i]
+		switch {
+		case ch == '\n':
+			tokens = append(tokens, dbmlToken{dbmlNewline, "", line})
+			line++
+			i++
+		case unicode.IsSpace(ch):
+			i++
+		case ch == '/' && i + 1 < len(rs) && rs[i+1] == '/':
+			for i < len(rs) && rs[i] != '\n' {
+				i++
+			}
+		case ch == '/' && i + 1 < len(rs) && rs[i+1] == '*':
+			end := strings.Index(string(rs[i+2:]), "*/")
+			if end < 0 {
+				return nil, fmt.Errorf("%d行目: コメントが閉じられていません。", line)
+			}
+			body := []rune(string(rs[i+2:])[:end])
+			line += strings.Count(string(body), "\n")
+			i += 2 + len(body) + 2
+		case ch == '\'' && strings.HasPrefix(string(rs[i:]), "'''"):
+			end := strings.Index(string(rs[i+3:]), "'''")
+			if end < 0 {
+				return nil, fmt.Errorf("%d行目: 文字列が閉じられていません。", line)
+			}
+			body := string(rs[i+3:])[:end]
+			tokens = append(tokens, dbmlToken{dbmlString, trimMultilineString(body), line})
+			line += strings.Count(body, "\n")
+			i += 3 + len([]rune(body)) + 3
+		case ch == '\'' || ch == '"' || ch == '`':
+			j := i + 1
+			var b strings.Builder
+			for ; j < len(rs) && rs[j] != ch; j++ {
+				if rs[j] == '\\' && j + 1 < len(rs) {
+					j++
+				}
+				if rs[j] == '\n' {
+					return nil, fmt.Errorf("%d行目: 文字列が閉じられていません。", line)
+				}
+				b.WriteRune(rs[j])
+			}
+			if j >= len(rs) {
+				return nil, fmt.Errorf("%d行目: 文字列が閉じられていません。", line)
+			}
+			kind := map[rune]dbmlTokenKind{'\'': dbmlString, '"': dbmlIdent, '`': dbmlExpr}[ch]
+			tokens = append(tokens, dbmlToken{kind, b.String(), line})
+			i = j + 1
+		case unicode.IsDigit(ch):
+			j := i
+			for j < len(rs) && (unicode.IsDigit(rs[j]) || rs[j] == '.') {
+				j++
+			}
+			tokens = append(tokens, dbmlToken{dbmlNumber, string(rs[i:j]), line})
+			i = j
+		case ch == '_' || ch == '#' || unicode.IsLetter(ch):
+			j := i + 1
+			for j < len(rs) && (rs[j] == '_' || unicode.IsLetter(rs[j]) || unicode.IsDigit(rs[j])) {
+				j++
+			}
+			tokens = append(tokens, dbmlToken{dbmlIdent, string(rs[i:j]), line})
+			i = j
+		case ch == '<' && i + 1 < len(rs) && rs[i+1] == '>':
+			tokens = append(tokens, dbmlToken{dbmlSymbol, "<>", line})
+			i += 2
+		case strings.ContainsRune("{}[](),:.<>-~", ch):
+			tokens = append(tokens, dbmlToken{dbmlSymbol, string(ch), line})
+			i++
+		default:
+			return nil, fmt.Errorf("%d行目: '%c' を解釈できません。", line, ch)
+		}
+	}
+	return append(tokens, dbmlToken{dbmlEOF, "", line}), nil
+}
+
+// ''' の複数行の文字列は共通の字下げを除く
+func trimMultilineString(s string) string {
+	lines := strings.Split(strings.Trim(s, "\n"), "\n")
+	indent := -1
+	for _, l := range lines {
+		if strings.TrimSpace(l) == "" {
+			continue
+		}
+		n := len(l) - len(strings.TrimLeft(l, " \t"))
+		if indent < 0 || n < indent {
+			indent = n
+		}
+	}
+	for i, l := range lines {
+		if len(l) >= indent && indent > 0 {
+			lines[i] = l[indent:]
+		}
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+
+func (p *dbmlParser) peek() dbmlToken {
+	return p.tokens[p.pos]
+}
+
+func (p *dbmlParser) next() dbmlToken {
+	t := p.tokens[p.pos]
+	if t.kind != dbmlEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *dbmlParser) isSymbol(s string) bool {
+	t := p.peek()
+	return t.kind == dbmlSymbol && t.value == s
+}
+
+func (p *dbmlParser) isKeyword(s string) bool {
+	t := p.peek()
+	return t.kind == dbmlIdent && strings.EqualFold(t.value, s)
+}
+
+func (p *dbmlParser) skipNewlines() {
+	for p.peek().kind == dbmlNewline {
+		p.pos++
+	}
+}
+
+func (p *dbmlParser) expectSymbol(s string) error {
+	p.skipNewlines()
+	t := p.next()
+	if t.kind != dbmlSymbol || t.value != s {
+		return p.syntaxError(t)
+	}
+	return nil
+}
+
+func (p *dbmlParser) expectName() (string, error) {
+	t := p.next()
+	if t.kind != dbmlIdent {
+		return "", p.syntaxError(t)
+	}
+	return t.value, nil
+}
+
+// schema.name の name (スキーマは使用しない)
+func (p *dbmlParser) qualifiedName() (string, error) {
+	name, err := p.expectName()
+	if err != nil {
+		return "", err
+	}
+	for p.isSymbol(".") {
+		p.next()
+		if name, err = p.expectName(); err != nil {
+			return "", err
+		}
+	}
+	return name, nil
+}
+
+func (p *dbmlParser) syntaxError(t dbmlToken) error {
+	if t.kind == dbmlEOF {
+		return fmt.Errorf("%d行目: 予期しないファイルの終わりです。", t.line)
+	}
+	if t.kind == dbmlNewline {
+		return fmt.Errorf("%d行目: 予期しない改行です。", t.line)
+	}
+	return fmt.Errorf("%d行目: '%s' の付近を解釈できません。", t.line, t.value)
+}
+
+// { から対応する } まで読み飛ばす
+func (p *dbmlParser) skipBlock() error {
+	if err := p.expectSymbol("{"); err != nil {
+		return err
+	}
+	depth := 1
+	for depth > 0 {
+		t := p.next()
+		switch {
+		case t.kind == dbmlEOF:
+			return p.syntaxError(t)
+		case t.kind == dbmlSymbol && t.value == "{":
+			depth++
+		case t.kind == dbmlSymbol && t.value == "}":
+			depth--
+		}
+	}
+	return nil
+}
+
+
+func (p *dbmlParser) parse() error {
+	for {
+		p.skipNewlines()
+		t := p.peek()
+		if t.kind == dbmlEOF {
+			return nil
+		}
+		if t.kind != dbmlIdent {
+			return p.syntaxError(t)
+		}
+		var err error
+		switch strings.ToLower(t.value) {
+		case "table":
+			p.next()
+			err = p.parseTable()
+		case "enum":
+			p.next()
+			err = p.parseEnum()
+		case "ref":
+			p.next()
+			err = p.parseRefDefinition()
+		default:
+			//Project・TableGroup・TablePartial・Note などは読み飛ばす
+			for p.peek().kind != dbmlEOF && !p.isSymbol("{") {
+				if p.peek().kind == dbmlNewline {
+					return p.syntaxError(p.peek())
+				}
+				p.next()
+			}
+			err = p.skipBlock()
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// 設定 [key, key: value, ...] (値はトークンの並び)
+type dbmlSetting struct {
+	key string
+	values []dbmlToken
+}
+
+func (p *dbmlParser) parseSettings() ([]dbmlSetting, error) {
+	if !p.isSymbol("[") {
+		return nil, nil
+	}
+	p.next()
+	ret := []dbmlSetting{}
+	for {
+		p.skipNewlines()
+		words := []string{}
+		for p.peek().kind == dbmlIdent {
+			words = append(words, strings.ToLower(p.next().value))
+		}
+		if len(words) == 0 {
+			return nil, p.syntaxError(p.peek())
+		}
+		s := dbmlSetting{key: strings.Join(words, " ")}
+		if p.isSymbol(":") {
+			p.next()
+			depth := 0
+			for {
+				t := p.peek()
+				if t.kind == dbmlEOF || t.kind == dbmlNewline {
+					return nil, p.syntaxError(t)
+				}
+				if depth == 0 && t.kind == dbmlSymbol && (t.value == "," || t.value == "]") {
+					break
+				}
+				if t.kind == dbmlSymbol && t.value == "(" {
+					depth++
+				} else if t.kind == dbmlSymbol && t.value == ")" {
+					depth--
+				}
+				s.values = append(s.values, p.next())
+			}
+		}
+		ret = append(ret, s)
+		p.skipNewlines()
+		t := p.next()
+		if t.kind == dbmlSymbol && t.value == "]" {
+			return ret, nil
+		}
+		if t.kind != dbmlSymbol || t.value != "," {
+			return nil, p.syntaxError(t)
+		}
+	}
+}
+
+func settingNote(s dbmlSetting) (string, bool) {
+	if s.key == "note" && len(s.values) == 1 && s.values[0].kind == dbmlString {
+		return s.values[0].value, true
+	}
+	return "", false
+}
+
+// Note: 'text' または Note { 'text' }
+func (p *dbmlParser) parseNote() (string, error) {
+	p.next()
+	if p.isSymbol(":") {
+		p.next()
+		t := p.next()
+		if t.kind != dbmlString {
+			return "", p.syntaxError(t)
+		}
+		return t.value, nil
+	}
+	if err := p.expectSymbol("{"); err != nil {
+		return "", err
+	}
+	p.skipNewlines()
+	t := p.next()
+	if t.kind != dbmlString {
+		return "", p.syntaxError(t)
+	}
+	return t.value, p.expectSymbol("}")
+}
+
+func (p *dbmlParser) parseTable() error {
+	name, err := p.qualifiedName()
+	if err != nil {
+		return err
+	}
+	if p.isKeyword("as") {
+		p.next()
+		alias, err := p.expectName()
+		if err != nil {
+			return err
+		}
+		p.aliases[alias] = name
+	}
+	settings, err := p.parseSettings()
+	if err != nil {
+		return err
+	}
+	for _, s := range settings {
+		if note, ok := settingNote(s); ok {
+			p.comments[name] = note
+		}
+	}
+	if err := p.expectSymbol("{"); err != nil {
+		return err
+	}
+
+	if err := validateIdentifier("テーブル名", name, p.rdbms); err != nil {
+		return err
+	}
+	table := ddlparse.Table{Name: name}
+	pks := []string{}
+	for {
+		p.skipNewlines()
+		if p.isSymbol("}") {
+			p.next()
+			break
+		}
+		switch {
+		case p.isKeyword("note") && p.tokens[p.pos+1].kind == dbmlSymbol:
+			note, err := p.parseNote()
+			if err != nil {
+				return err
+			}
+			p.comments[name] = note
+		case p.isKeyword("indexes") && p.tokens[p.pos+1].kind != dbmlIdent:
+			p.next()
+			if err := p.parseIndexes(&table, &pks); err != nil {
+				return err
+			}
+		default:
+			column, isPk, err := p.parseColumn(name)
+			if err != nil {
+				return err
+			}
+			if findColumn(&table, column.Name) != nil {
+				return fmt.Errorf("テーブル '%s': カラム '%s' が重複しています。", name, column.Name)
+			}
+			table.Columns = append(table.Columns, column)
+			if isPk {
+				pks = append(pks, column.Name)
+			}
+		}
+	}
+	if len(pks) > 0 {
+		setPrimaryKey(&table, pks)
+	}
+	p.tables = append(p.tables, table)
+	return nil
+}
+
+func (p *dbmlParser) parseColumn(tn string) (ddlparse.Column, bool, error) {
+	cn, err := p.expectName()
+	if err != nil {
+		return ddlparse.Column{}, false, err
+	}
+	if err := validateIdentifier("カラム名", cn, p.rdbms); err != nil {
+		return ddlparse.Column{}, false, fmt.Errorf("テーブル '%s': %s", tn, err.Error())
+	}
+
+	//型 (schema.type・varchar(255)・"double precision"・"int[]")
+	line := p.peek().line
+	typeName, err := p.qualifiedName()
+	if err != nil {
+		return ddlparse.Column{}, false, err
+	}
+	p.typeNames[tn + "." + cn] = typeName
+	typeText := typeName
+	if p.isSymbol("(") {
+		p.next()
+		args := []string{}
+		for !p.isSymbol(")") {
+			t := p.next()
+			if t.kind == dbmlEOF || t.kind == dbmlNewline {
+				return ddlparse.Column{}, false, p.syntaxError(t)
+			}
+			args = append(args, t.value)
+		}
+		p.next()
+		typeText += "(" + strings.Join(args, "") + ")"
+	}
+	dt, err := dataTypeOf(typeText, p.rdbms)
+	if err != nil {
+		return ddlparse.Column{}, false, fmt.Errorf("%d行目: %s", line, err.Error())
+	}
+	column := ddlparse.Column{Name: cn, DataType: dt}
+
+	settings, err := p.parseSettings()
+	if err != nil {
+		return ddlparse.Column{}, false, err
+	}
+	isPk := false
+	for _, s := range settings {
+		switch s.key {
+		case "pk", "primary key":
+			isPk = true
+		case "not null":
+			column.Constraint.IsNotNull = true
+		case "null":
+			column.Constraint.IsNotNull = false
+		case "unique":
+			column.Constraint.IsUnique = true
+		case "increment":
+			p.increments = append(p.increments, dbmlEndpoint{tn, []string{cn}})
+		case "default":
+			v, err := dbmlValue(s.values)
+			if err != nil {
+				return ddlparse.Column{}, false, fmt.Errorf("%d行目: %s", line, err.Error())
+			}
+			if s.values[len(s.values)-1].kind == dbmlExpr {
+				v = currentTimeDefault(v.(string), dt, p.rdbms)
+			}
+			column.Constraint.Default = v
+		case "note":
+			if note, ok := settingNote(s); ok {
+				p.comments[tn + "." + cn] = note
+			}
+		case "ref":
+			ref, err := p.inlineRef(tn, cn, s.values, line)
+			if err != nil {
+				return ddlparse.Column{}, false, err
+			}
+			p.refs = append(p.refs, ref)
+		}
+	}
+	if t := p.peek(); t.kind != dbmlNewline && !p.isSymbol("}") {
+		return ddlparse.Column{}, false, p.syntaxError(t)
+	}
+	return column, isPk, nil
+}
+
+// デフォルト値 (数値・'文字列'・`式`・true / false / null)
+func dbmlValue(values []dbmlToken) (interface{}, error) {
+	negative := len(values) == 2 && values[0].kind == dbmlSymbol && values[0].value == "-"
+	if negative {
+		values = values[1:]
+	}
+	if len(values) != 1 {
+		return nil, fmt.Errorf("デフォルト値を解釈できません。")
+	}
+	t := values[0]
+	switch t.kind {
+	case dbmlNumber:
+		n, err := strconv.ParseFloat(t.value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("デフォルト値 '%s' を解釈できません。", t.value)
+		}
+		if negative {
+			n = -n
+		}
+		return n, nil
+	case dbmlString, dbmlExpr:
+		return t.value, nil
+	case dbmlIdent:
+		switch strings.ToLower(t.value) {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		case "null":
+			return nil, nil
+		}
+		return t.value, nil
+	}
+	return nil, fmt.Errorf("デフォルト値 '%s' を解釈できません。", t.value)
+}
+
+/*
+ indexes { (a, b) [pk] / column [unique, name: 'uq'] }
+ 索引 (pk・unique 以外) は生成するアプリで使用しないため読み飛ばす
+*/
+func (p *dbmlParser) parseIndexes(table *ddlparse.Table, pks *[]string) error {
+	if err := p.expectSymbol("{"); err != nil {
+		return err
+	}
+	for {
+		p.skipNewlines()
+		if p.isSymbol("}") {
+			p.next()
+			return nil
+		}
+		columns := []string{}
+		isColumn := true
+		if p.isSymbol("(") {
+			p.next()
+			for !p.isSymbol(")") {
+				t := p.next()
+				switch {
+				case t.kind == dbmlIdent:
+					columns = append(columns, t.value)
+				case t.kind == dbmlExpr:
+					isColumn = false
+				case t.kind == dbmlSymbol && t.value == ",":
+				default:
+					return p.syntaxError(t)
+				}
+			}
+			p.next()
+		} else {
+			t := p.next()
+			if t.kind == dbmlIdent {
+				columns = append(columns, t.value)
+			} else if t.kind == dbmlExpr {
+				isColumn = false
+			} else {
+				return p.syntaxError(t)
+			}
+		}
+		settings, err := p.parseSettings()
+		if err != nil {
+			return err
+		}
+		if !isColumn {
+			continue
+		}
+		for _, cn := range columns {
+			if findColumn(table, cn) == nil {
+				return fmt.Errorf("テーブル '%s': indexes のカラム '%s' が見つかりません。", table.Name, cn)
+			}
+		}
+		name := ""
+		for _, s := range settings {
+			if s.key == "name" && len(s.values) == 1 {
+				name = s.values[0].value
+			}
+		}
+		for _, s := range settings {
+			switch s.key {
+			case "pk":
+				*pks = append(*pks, columns...)
+			case "unique":
+				setUnique(table, name, columns)
+			}
+		}
+	}
+}
+
+func (p *dbmlParser) parseEnum() error {
+	name, err := p.qualifiedName()
+	if err != nil {
+		return err
+	}
+	if err := p.expectSymbol("{"); err != nil {
+		return err
+	}
+	values := []string{}
+	for {
+		p.skipNewlines()
+		if p.isSymbol("}") {
+			p.next()
+			break
+		}
+		t := p.next()
+		if t.kind != dbmlIdent && t.kind != dbmlString {
+			return p.syntaxError(t)
+		}
+		values = append(values, t.value)
+		if _, err := p.parseSettings(); err != nil {
+			return err
+		}
+	}
+	if len(values) == 0 {
+		return fmt.Errorf("Enum '%s' に値がありません。", name)
+	}
+	p.enums[name] = values
+	return nil
+}
+
+// Ref name: a.b > c.d または Ref name { a.b > c.d ... }
+func (p *dbmlParser) parseRefDefinition() error {
+	if p.peek().kind == dbmlIdent {
+		p.next()
+	}
+	if p.isSymbol(":") {
+		p.next()
+		ref, err := p.parseRef()
+		if err != nil {
+			return err
+		}
+		p.refs = append(p.refs, ref)
+		return nil
+	}
+	if err := p.expectSymbol("{"); err != nil {
+		return err
+	}
+	for {
+		p.skipNewlines()
+		if p.isSymbol("}") {
+			p.next()
+			return nil
+		}
+		ref, err := p.parseRef()
+		if err != nil {
+			return err
+		}
+		p.refs = append(p.refs, ref)
+	}
+}
+
+func (p *dbmlParser) parseRef() (dbmlRef, error) {
+	line := p.peek().line
+	from, err := p.parseEndpoint()
+	if err != nil {
+		return dbmlRef{}, err
+	}
+	op := p.next()
+	if op.kind != dbmlSymbol || !strings.Contains(" > < - <> ", " " + op.value + " ") {
+		return dbmlRef{}, p.syntaxError(op)
+	}
+	to, err := p.parseEndpoint()
+	if err != nil {
+		return dbmlRef{}, err
+	}
+	if _, err := p.parseSettings(); err != nil {
+		return dbmlRef{}, err
+	}
+	return dbmlRef{from, op.value, to, line}, nil
+}
+
+// schema.table.column / table.column / table.(column1, column2)
+func (p *dbmlParser) parseEndpoint() (dbmlEndpoint, error) {
+	names := []string{}
+	name, err := p.expectName()
+	if err != nil {
+		return dbmlEndpoint{}, err
+	}
+	names = append(names, name)
+	for p.isSymbol(".") {
+		p.next()
+		if p.isSymbol("(") {
+			p.next()
+			columns := []string{}
+			for !p.isSymbol(")") {
+				t := p.next()
+				if t.kind == dbmlIdent {
+					columns = append(columns, t.value)
+				} else if t.kind != dbmlSymbol || t.value != "," {
+					return dbmlEndpoint{}, p.syntaxError(t)
+				}
+			}
+			p.next()
+			return dbmlEndpoint{names[len(names)-1], columns}, nil
+		}
+		if name, err = p.expectName(); err != nil {
+			return dbmlEndpoint{}, err
+		}
+		names = append(names, name)
+	}
+	if len(names) < 2 {
+		return dbmlEndpoint{}, fmt.Errorf("%d行目: Ref は <テーブル名>.<カラム名> で指定してください。", p.peek().line)
+	}
+	return dbmlEndpoint{names[len(names)-2], []string{names[len(names)-1]}}, nil
+}
+
+// カラムの設定の ref: > table.column
+func (p *dbmlParser) inlineRef(tn string, cn string, values []dbmlToken, line int) (dbmlRef, error) {
+	if len(values) < 2 || values[0].kind != dbmlSymbol {
+		return dbmlRef{}, fmt.Errorf("%d行目: ref は > <テーブル名>.<カラム名> の形式で指定してください。", line)
+	}
+	sub := &dbmlParser{tokens: append(append([]dbmlToken{}, values[1:]...), dbmlToken{dbmlEOF, "", line})}
+	to, err := sub.parseEndpoint()
+	if err != nil || sub.peek().kind != dbmlEOF {
+		return dbmlRef{}, fmt.Errorf("%d行目: ref は > <テーブル名>.<カラム名> の形式で指定してください。", line)
+	}
+	return dbmlRef{dbmlEndpoint{tn, []string{cn}}, values[0].value, to, line}, nil
+}
+
+
+// 列挙型・自動採番・外部キーをテーブルに反映する (定義の順序に依らないよう最後に行う)
+func (p *dbmlParser) resolve() error {
+	for i := range p.tables {
+		table := &p.tables[i]
+		for j := range table.Columns {
+			c := &table.Columns[j]
+			if values, ok := p.enums[p.typeNames[table.Name + "." + c.Name]]; ok {
+				setEnum(c, values, p.rdbms)
+			}
+		}
+	}
+	for _, inc := range p.increments {
+		table := p.table(inc.table)
+		if err := setAutoincrement(findColumn(table, inc.columns[0]), p.rdbms); err != nil {
+			return fmt.Errorf("テーブル '%s': %s", inc.table, err.Error())
+		}
+	}
+
+	for _, ref := range p.refs {
+		from, to := ref.from, ref.to
+		switch ref.op {
+		case "<":
+			from, to = to, from
+		case "<>":
+			return fmt.Errorf("%d行目: 多対多 (<>) の Ref には対応していません。中間テーブルを定義してください。", ref.line)
+		}
+		table := p.table(from.table)
+		if table == nil {
+			return fmt.Errorf("%d行目: Ref のテーブル '%s' が見つかりません。", ref.line, from.table)
+		}
+		if len(from.columns) != len(to.columns) {
+			return fmt.Errorf("%d行目: Ref の両側のカラム数が異なります。", ref.line)
+		}
+		for _, cn := range from.columns {
+			if findColumn(table, cn) == nil {
+				return fmt.Errorf("%d行目: Ref のカラム '%s.%s' が見つかりません。", ref.line, from.table, cn)
+			}
+		}
+		toTable := to.table
+		if t := p.table(to.table); t != nil {
+			toTable = t.Name
+		}
+		table.Constraints.ForeignKey = append(table.Constraints.ForeignKey, ddlparse.ForeignKey{
+			ColumnNames: from.columns,
+			References: ddlparse.Reference{TableName: toTable, ColumnNames: to.columns},
+		})
+	}
+	return nil
+}
+
+// テーブル名または別名 (as) のテーブル
+func (p *dbmlParser) table(name string) *ddlparse.Table {
+	if n, ok := p.aliases[name]; ok {
+		name = n
+	}
+	for i := range p.tables {
+		if p.tables[i].Name == name {
+			return &p.tables[i]
+		}
+	}
+	return nil
+}
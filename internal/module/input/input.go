@@ -0,0 +1,109 @@
+package input
+
+import (
+	"fmt"
+	"strings"
+	"path/filepath"
+	"github.com/kodaimura/ddlparse"
+)
+
+
+/*
+ 生成の入力 (DDL・DBML・Prisma・テーブル定義書) を読み取り、
+ DDLを ddlparse で解析した場合と同じテーブルのモデルを作成する
+*/
+
+// 読み取ったテーブル定義
+type Definition struct {
+	Tables []ddlparse.Table
+	// コメント (キーはテーブル名、カラムは <テーブル名>.<カラム名>)
+	Comments map[string]string
+	// カラムの論理名 (画面の項目名、キーは <テーブル名>.<カラム名>)
+	Labels map[string]string
+	// DDLファイルの場合は元のDDL (create-table.sql にそのまま出力)、それ以外は空 (テーブル定義から作成)
+	Ddl string
+	// 他のRDBMSのDDLから変換した場合の変換できなかった構文 (create-table.sql の先頭に記載し、画面に表示する)
+	Notes []string
+}
+
+// 入力形式
+type Reader interface {
+	// rdbms: postgresql / mysql / sqlite3 (データ型・自動採番などは rdbms に合わせる)
+	Read(src []byte, rdbms string) (Definition, error)
+}
+
+const (
+	FormatDdl = "ddl"
+	FormatDbml = "dbml"
+	FormatPrisma = "prisma"
+	FormatCsv = "csv"
+	FormatXlsx = "xlsx"
+)
+
+var readers = map[string]Reader{
+	FormatDdl: ddlReader{},
+	FormatDbml: dbmlReader{},
+	FormatPrisma: prismaReader{},
+	FormatCsv: csvReader{},
+	FormatXlsx: xlsxReader{},
+}
+
+var extensions = map[string]string{
+	".sql": FormatDdl,
+	".ddl": FormatDdl,
+	".dbml": FormatDbml,
+	".prisma": FormatPrisma,
+	".csv": FormatCsv,
+	".xlsx": FormatXlsx,
+}
+
+
+// ファイル名の拡張子から入力形式 (不明な場合は空)
+func FormatOf(filename string) string {
+	return extensions[strings.ToLower(filepath.Ext(filename))]
+}
+
+var rdbmses = []string{"postgresql", "mysql", "sqlite3"}
+
+func isRdbms(rdbms string) bool {
+	for _, r := range rdbmses {
+		if r == rdbms {
+			return true
+		}
+	}
+	return false
+}
+
+func Read(format string, src []byte, rdbms string) (Definition, error) {
+	if !isRdbms(rdbms) {
+		return Definition{}, fmt.Errorf("RDBMS '%s' は指定できません。", rdbms)
+	}
+	r, ok := readers[format]
+	if !ok {
+		return Definition{}, fmt.Errorf("入力形式 '%s' は指定できません。", format)
+	}
+	def, err := r.Read(src, rdbms)
+	if err != nil {
+		return Definition{}, err
+	}
+	if def.Comments == nil {
+		def.Comments = map[string]string{}
+	}
+	if def.Labels == nil {
+		def.Labels = map[string]string{}
+	}
+	return def, nil
+}
+
+
+// DDL (ddlparse で rdbms の構文として解析)
+type ddlReader struct {}
+
+func (r ddlReader) Read(src []byte, rdbms string) (Definition, error) {
+	ddl := string(src)
+	tables, err := ddlparse.Parse(ddl, rdbmsOf(rdbms))
+	if err != nil {
+		return Definition{}, err
+	}
+	return Definition{Tables: tables, Ddl: ddl}, nil
+}
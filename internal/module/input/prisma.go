@@ -0,0 +1,865 @@
+package input
+
+import (
+	"fmt"
+	"strings"
+	"strconv"
+	"unicode"
+	"github.com/kodaimura/ddlparse"
+)
+
+
+/*
+ Prisma スキーマ (schema.prisma) を読み取る
+ model (@id・@unique・@default・@map・@relation・@db.* と @@id・@@unique・@@map)、enum、/// のコメントに対応
+ datasource・generator など model・enum 以外のブロックは読み飛ばす
+*/
+
+type prismaReader struct {}
+
+type prismaTokenKind int
+
+const (
+	prismaIdent prismaTokenKind = iota
+	prismaString
+	prismaNumber
+	prismaSymbol
+	prismaDoc
+	prismaNewline
+	prismaEOF
+)
+
+type prismaToken struct {
+	kind prismaTokenKind
+	value string
+	line int
+}
+
+// 属性の引数の値 (文字列・数値・名前・関数呼び出し・配列)
+type prismaValue struct {
+	kind prismaTokenKind
+	value string
+	// 関数呼び出しの引数 (例: now()、dbgenerated("...")) または配列の要素
+	args []prismaArg
+	isCall bool
+	isList bool
+}
+
+type prismaArg struct {
+	name string
+	value prismaValue
+}
+
+// 属性 (例: @default(now())、@db.VarChar(255)、@@id([a, b]))
+type prismaAttr struct {
+	name string
+	args []prismaArg
+	line int
+}
+
+type prismaField struct {
+	name string
+	typeName string
+	typeArgs []prismaArg
+	optional bool
+	list bool
+	attrs []prismaAttr
+	doc string
+	line int
+}
+
+type prismaModel struct {
+	name string
+	fields []prismaField
+	attrs []prismaAttr
+	doc string
+}
+
+type prismaEnum struct {
+	name string
+	values []string
+	// @map の値 (キーは Prisma の値)
+	mapped map[string]string
+}
+
+type prismaParser struct {
+	tokens []prismaToken
+	pos int
+	models []prismaModel
+	enums map[string]prismaEnum
+	// datasource の provider (@db.* は provider と rdbms が同じ場合のみ使用する)
+	provider string
+}
+
+
+func (r prismaReader) Read(src []byte, rdbms string) (Definition, error) {
+	tokens, err := lexPrisma(string(src))
+	if err != nil {
+		return Definition{}, err
+	}
+	p := &prismaParser{tokens: tokens, enums: map[string]prismaEnum{}}
+	if err := p.parse(); err != nil {
+		return Definition{}, err
+	}
+	if len(p.models) == 0 {
+		return Definition{}, fmt.Errorf("model が見つかりません。")
+	}
+	return p.convert(rdbms)
+}
+
+
+func lexPrisma(src string) ([]prismaToken, error) {
+	tokens := []prismaToken{}
+	rs := []rune(src)
+	line := 1
+	for i := 0; i < len(rs); {
+		ch := rs[i]
+		switch {
+		case ch == '\n':
+			tokens = append(tokens, prismaToken{prismaNewline, "", line})
+			line++
+			i++
+		case unicode.IsSpace(ch):
+			i++
+		case ch == '/' && i + 1 < len(rs) && rs[i+1] == '/':
+			j := i
+			for j < len(rs) && rs[j] != '\n' {
+				j++
+			}
+			if i + 2 < j && rs[i+2] == '/' {
+				tokens = append(tokens, prismaToken{prismaDoc, strings.TrimSpace(string(rs[i+3:j])), line})
+			}
+			i = j
+		case ch == '"':
+			j := i + 1
+			var b strings.Builder
+			for ; j < len(rs) && rs[j] != '"'; j++ {
+				if rs[j] == '\\' && j + 1 < len(rs) {
+					j++
+					switch rs[j] {
+					case 'n':
+						b.WriteRune('\n')
+						continue
+					case 't':
+						b.WriteRune('\t')
+						continue
+					}
+				}
+				if rs[j] == '\n' {
+					return nil, fmt.Errorf("%d行目: 文字列が閉じられていません。", line)
+				}
+				b.WriteRune(rs[j])
+			}
+			if j >= len(rs) {
+				return nil, fmt.Errorf("%d行目: 文字列が閉じられていません。", line)
+			}
+			tokens = append(tokens, prismaToken{prismaString, b.String(), line})
+			i = j + 1
+		case unicode.IsDigit(ch) || (ch == '-' && i + 1 < len(rs) && unicode.IsDigit(rs[i+1])):
+			j := i + 1
+			for j < len(rs) && (unicode.IsDigit(rs[j]) || rs[j] == '.') {
+				j++
+			}
+			tokens = append(tokens, prismaToken{prismaNumber, string(rs[i:j]), line})
+			i = j
+		case ch == '_' || unicode.IsLetter(ch):
+			j := i + 1
+			for j < len(rs) && (rs[j] == '_' || unicode.IsLetter(rs[j]) || unicode.IsDigit(rs[j])) {
+				j++
+			}
+			tokens = append(tokens, prismaToken{prismaIdent, string(rs[i:j]), line})
+			i = j
+		case ch == '@' && i + 1 < len(rs) && rs[i+1] == '@':
+			tokens = append(tokens, prismaToken{prismaSymbol, "@@", line})
+			i += 2
+		case strings.ContainsRune("{}[](),:=@?.", ch):
+			tokens = append(tokens, prismaToken{prismaSymbol, string(ch), line})
+			i++
+		default:
+			return nil, fmt.Errorf("%d行目: '%c' を解釈できません。", line, ch)
+		}
+	}
+	return append(tokens, prismaToken{prismaEOF, "", line}), nil
+}
+
+
+func (p *prismaParser) peek() prismaToken {
+	return p.tokens[p.pos]
+}
+
+func (p *prismaParser) next() prismaToken {
+	t := p.tokens[p.pos]
+	if t.kind != prismaEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *prismaParser) isSymbol(s string) bool {
+	t := p.peek()
+	return t.kind == prismaSymbol && t.value == s
+}
+
+func (p *prismaParser) skipNewlines() {
+	for p.peek().kind == prismaNewline {
+		p.pos++
+	}
+}
+
+// 改行と /// のコメントを読み飛ばし、コメントを返す
+func (p *prismaParser) skipNewlinesAndDocs() string {
+	docs := []string{}
+	for {
+		switch p.peek().kind {
+		case prismaNewline:
+			p.pos++
+		case prismaDoc:
+			docs = append(docs, p.next().value)
+		default:
+			return strings.Join(docs, "\n")
+		}
+	}
+}
+
+func (p *prismaParser) expectSymbol(s string) error {
+	t := p.next()
+	if t.kind != prismaSymbol || t.value != s {
+		return p.syntaxError(t)
+	}
+	return nil
+}
+
+func (p *prismaParser) expectName() (string, error) {
+	t := p.next()
+	if t.kind != prismaIdent {
+		return "", p.syntaxError(t)
+	}
+	return t.value, nil
+}
+
+func (p *prismaParser) syntaxError(t prismaToken) error {
+	if t.kind == prismaEOF {
+		return fmt.Errorf("%d行目: 予期しないファイルの終わりです。", t.line)
+	}
+	if t.kind == prismaNewline {
+		return fmt.Errorf("%d行目: 予期しない改行です。", t.line)
+	}
+	return fmt.Errorf("%d行目: '%s' の付近を解釈できません。", t.line, t.value)
+}
+
+func (p *prismaParser) skipBlock() error {
+	for !p.isSymbol("{") {
+		if t := p.peek(); t.kind == prismaEOF || t.kind == prismaNewline {
+			return p.syntaxError(t)
+		}
+		p.next()
+	}
+	p.next()
+	depth := 1
+	for depth > 0 {
+		t := p.next()
+		switch {
+		case t.kind == prismaEOF:
+			return p.syntaxError(t)
+		case t.kind == prismaSymbol && t.value == "{":
+			depth++
+		case t.kind == prismaSymbol && t.value == "}":
+			depth--
+		}
+	}
+	return nil
+}
+
+
+func (p *prismaParser) parse() error {
+	for {
+		doc := p.skipNewlinesAndDocs()
+		t := p.peek()
+		if t.kind == prismaEOF {
+			return nil
+		}
+		if t.kind != prismaIdent {
+			return p.syntaxError(t)
+		}
+		var err error
+		switch t.value {
+		case "model":
+			p.next()
+			err = p.parseModel(doc)
+		case "enum":
+			p.next()
+			err = p.parseEnum()
+		case "datasource":
+			err = p.parseDatasource()
+		default:
+			//datasource・generator・type・view などは読み飛ばす
+			err = p.skipBlock()
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// datasource db { provider = "postgresql" ... } (provider 以外は読み飛ばす)
+func (p *prismaParser) parseDatasource() error {
+	start := p.pos
+	if err := p.skipBlock(); err != nil {
+		return err
+	}
+	for i := start; i + 2 < p.pos; i++ {
+		t := p.tokens[i]
+		if t.kind == prismaIdent && t.value == "provider" && p.tokens[i+1].value == "=" && p.tokens[i+2].kind == prismaString {
+			p.provider = p.tokens[i+2].value
+		}
+	}
+	return nil
+}
+
+func (p *prismaParser) parseModel(doc string) error {
+	name, err := p.expectName()
+	if err != nil {
+		return err
+	}
+	if err := p.expectSymbol("{"); err != nil {
+		return err
+	}
+	model := prismaModel{name: name, doc: doc}
+	for {
+		doc := p.skipNewlinesAndDocs()
+		if p.isSymbol("}") {
+			p.next()
+			break
+		}
+		if p.isSymbol("@@") {
+			attr, err := p.parseAttr()
+			if err != nil {
+				return err
+			}
+			model.attrs = append(model.attrs, attr)
+			continue
+		}
+		field, err := p.parseField()
+		if err != nil {
+			return err
+		}
+		field.doc = doc
+		model.fields = append(model.fields, field)
+	}
+	p.models = append(p.models, model)
+	return nil
+}
+
+// name Type[]? @attr ...
+func (p *prismaParser) parseField() (prismaField, error) {
+	line := p.peek().line
+	name, err := p.expectName()
+	if err != nil {
+		return prismaField{}, err
+	}
+	typeName, err := p.expectName()
+	if err != nil {
+		return prismaField{}, err
+	}
+	field := prismaField{name: name, typeName: typeName, line: line}
+	if p.isSymbol("(") {
+		//Unsupported("型")
+		if field.typeArgs, err = p.parseArgs(); err != nil {
+			return prismaField{}, err
+		}
+	}
+	if p.isSymbol("[") {
+		p.next()
+		if err := p.expectSymbol("]"); err != nil {
+			return prismaField{}, err
+		}
+		field.list = true
+	}
+	if p.isSymbol("?") {
+		p.next()
+		field.optional = true
+	}
+	for p.isSymbol("@") {
+		attr, err := p.parseAttr()
+		if err != nil {
+			return prismaField{}, err
+		}
+		field.attrs = append(field.attrs, attr)
+	}
+	if t := p.peek(); t.kind != prismaNewline && t.kind != prismaDoc && !p.isSymbol("}") {
+		return prismaField{}, p.syntaxError(t)
+	}
+	return field, nil
+}
+
+// @name.sub(args) / @@name(args)
+func (p *prismaParser) parseAttr() (prismaAttr, error) {
+	line := p.peek().line
+	prefix := p.next().value
+	name, err := p.expectName()
+	if err != nil {
+		return prismaAttr{}, err
+	}
+	for p.isSymbol(".") {
+		p.next()
+		sub, err := p.expectName()
+		if err != nil {
+			return prismaAttr{}, err
+		}
+		name += "." + sub
+	}
+	attr := prismaAttr{name: prefix + name, line: line}
+	if p.isSymbol("(") {
+		if attr.args, err = p.parseArgs(); err != nil {
+			return prismaAttr{}, err
+		}
+	}
+	return attr, nil
+}
+
+// (value, name: value, ...)
+func (p *prismaParser) parseArgs() ([]prismaArg, error) {
+	p.next()
+	args := []prismaArg{}
+	for {
+		p.skipNewlines()
+		if p.isSymbol(")") {
+			p.next()
+			return args, nil
+		}
+		arg := prismaArg{}
+		if p.peek().kind == prismaIdent && p.tokens[p.pos+1].kind == prismaSymbol && p.tokens[p.pos+1].value == ":" {
+			arg.name = p.next().value
+			p.next()
+		}
+		v, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		arg.value = v
+		args = append(args, arg)
+		p.skipNewlines()
+		if p.isSymbol(",") {
+			p.next()
+		} else if !p.isSymbol(")") {
+			return nil, p.syntaxError(p.peek())
+		}
+	}
+}
+
+func (p *prismaParser) parseValue() (prismaValue, error) {
+	t := p.peek()
+	switch {
+	case t.kind == prismaString || t.kind == prismaNumber:
+		p.next()
+		return prismaValue{kind: t.kind, value: t.value}, nil
+	case t.kind == prismaIdent:
+		p.next()
+		v := prismaValue{kind: prismaIdent, value: t.value}
+		if p.isSymbol("(") {
+			args, err := p.parseArgs()
+			if err != nil {
+				return prismaValue{}, err
+			}
+			v.args = args
+			v.isCall = true
+		}
+		return v, nil
+	case t.kind == prismaSymbol && t.value == "[":
+		p.next()
+		v := prismaValue{isList: true}
+		for {
+			p.skipNewlines()
+			if p.isSymbol("]") {
+				p.next()
+				return v, nil
+			}
+			item, err := p.parseValue()
+			if err != nil {
+				return prismaValue{}, err
+			}
+			v.args = append(v.args, prismaArg{value: item})
+			p.skipNewlines()
+			if p.isSymbol(",") {
+				p.next()
+			} else if !p.isSymbol("]") {
+				return prismaValue{}, p.syntaxError(p.peek())
+			}
+		}
+	}
+	return prismaValue{}, p.syntaxError(t)
+}
+
+func (p *prismaParser) parseEnum() error {
+	name, err := p.expectName()
+	if err != nil {
+		return err
+	}
+	if err := p.expectSymbol("{"); err != nil {
+		return err
+	}
+	enum := prismaEnum{name: name, mapped: map[string]string{}}
+	for {
+		p.skipNewlinesAndDocs()
+		if p.isSymbol("}") {
+			p.next()
+			break
+		}
+		if p.isSymbol("@@") {
+			if _, err := p.parseAttr(); err != nil {
+				return err
+			}
+			continue
+		}
+		value, err := p.expectName()
+		if err != nil {
+			return err
+		}
+		enum.values = append(enum.values, value)
+		for p.isSymbol("@") {
+			attr, err := p.parseAttr()
+			if err != nil {
+				return err
+			}
+			if s, ok := attr.stringArg("", 0); ok && attr.name == "@map" {
+				enum.mapped[value] = s
+			}
+		}
+	}
+	p.enums[name] = enum
+	return nil
+}
+
+
+// 名前付きの引数、または index 番目の名前の無い引数
+func (a prismaAttr) arg(name string, index int) (prismaValue, bool) {
+	if name != "" {
+		for _, arg := range a.args {
+			if arg.name == name {
+				return arg.value, true
+			}
+		}
+	}
+	i := 0
+	for _, arg := range a.args {
+		if arg.name != "" {
+			continue
+		}
+		if i == index {
+			return arg.value, true
+		}
+		i++
+	}
+	return prismaValue{}, false
+}
+
+func (a prismaAttr) stringArg(name string, index int) (string, bool) {
+	v, ok := a.arg(name, index)
+	if !ok || v.kind != prismaString || v.isCall || v.isList {
+		return "", false
+	}
+	return v.value, true
+}
+
+// [a, b] の名前
+func (a prismaAttr) namesArg(name string, index int) []string {
+	v, ok := a.arg(name, index)
+	if !ok || !v.isList {
+		return nil
+	}
+	ret := []string{}
+	for _, item := range v.args {
+		ret = append(ret, item.value.value)
+	}
+	return ret
+}
+
+func findAttr(attrs []prismaAttr, name string) (prismaAttr, bool) {
+	for _, a := range attrs {
+		if a.name == name {
+			return a, true
+		}
+	}
+	return prismaAttr{}, false
+}
+
+func (m prismaModel) tableName() string {
+	if a, ok := findAttr(m.attrs, "@@map"); ok {
+		if s, ok := a.stringArg("name", 0); ok {
+			return s
+		}
+	}
+	return m.name
+}
+
+func (f prismaField) columnName() string {
+	if a, ok := findAttr(f.attrs, "@map"); ok {
+		if s, ok := a.stringArg("name", 0); ok {
+			return s
+		}
+	}
+	return f.name
+}
+
+func (m prismaModel) field(name string) (prismaField, bool) {
+	for _, f := range m.fields {
+		if f.name == name {
+			return f, true
+		}
+	}
+	return prismaField{}, false
+}
+
+func (p *prismaParser) model(name string) (prismaModel, bool) {
+	for _, m := range p.models {
+		if m.name == name {
+			return m, true
+		}
+	}
+	return prismaModel{}, false
+}
+
+// フィールド名 -> カラム名
+func (m prismaModel) columnNames(fields []string) ([]string, error) {
+	ret := []string{}
+	for _, name := range fields {
+		f, ok := m.field(name)
+		if !ok {
+			return nil, fmt.Errorf("model '%s': フィールド '%s' が見つかりません。", m.name, name)
+		}
+		ret = append(ret, f.columnName())
+	}
+	return ret, nil
+}
+
+
+// Prisma のスカラー型 -> PostgreSQL / MySQL の型 (SQLite は型親和性に読み替える)
+var prismaScalarTypes = map[string][2]string{
+	"String": {"TEXT", "VARCHAR(191)"},
+	"Boolean": {"BOOLEAN", "BOOLEAN"},
+	"Int": {"INTEGER", "INT"},
+	"BigInt": {"BIGINT", "BIGINT"},
+	"Float": {"DOUBLE PRECISION", "DOUBLE"},
+	"Decimal": {"DECIMAL(65,30)", "DECIMAL(65,30)"},
+	"DateTime": {"TIMESTAMP(3)", "DATETIME(3)"},
+	"Json": {"JSONB", "JSON"},
+	"Bytes": {"BYTEA", "LONGBLOB"},
+}
+
+// provider と rdbms が異なる場合も長さ・精度を使用する @db.* の型
+var prismaPortableNativeTypes = map[string]bool{"VarChar": true, "Char": true, "Decimal": true}
+
+func (p *prismaParser) convert(rdbms string) (Definition, error) {
+	def := Definition{Comments: map[string]string{}}
+	for _, m := range p.models {
+		tn := m.tableName()
+		if err := validateIdentifier("テーブル名", tn, rdbms); err != nil {
+			return Definition{}, fmt.Errorf("model '%s': %s（@@map でテーブル名を指定できます）", m.name, err.Error())
+		}
+		table := ddlparse.Table{Name: tn}
+		if m.doc != "" {
+			def.Comments[tn] = m.doc
+		}
+		pks := []string{}
+		increments := []string{}
+
+		for _, f := range m.fields {
+			//リレーションのフィールド (外部キーは @relation(fields: ...) から作成)
+			if _, ok := p.model(f.typeName); ok {
+				continue
+			}
+			column, err := p.column(f, rdbms)
+			if err != nil {
+				return Definition{}, fmt.Errorf("%d行目: %s", f.line, err.Error())
+			}
+			if _, ok := findAttr(f.attrs, "@id"); ok {
+				pks = append(pks, column.Name)
+			}
+			if a, ok := findAttr(f.attrs, "@default"); ok {
+				if v, ok := a.arg("value", 0); ok && v.isCall && v.value == "autoincrement" {
+					increments = append(increments, column.Name)
+				}
+			}
+			table.Columns = append(table.Columns, column)
+			if f.doc != "" {
+				def.Comments[tn + "." + column.Name] = f.doc
+			}
+		}
+
+		for _, a := range m.attrs {
+			switch a.name {
+			case "@@id":
+				columns, err := m.columnNames(a.namesArg("fields", 0))
+				if err != nil {
+					return Definition{}, err
+				}
+				pks = append(pks, columns...)
+			case "@@unique":
+				columns, err := m.columnNames(a.namesArg("fields", 0))
+				if err != nil {
+					return Definition{}, err
+				}
+				name, _ := a.stringArg("map", -1)
+				setUnique(&table, name, columns)
+			}
+		}
+		if len(pks) > 0 {
+			setPrimaryKey(&table, pks)
+		}
+		for _, cn := range increments {
+			if err := setAutoincrement(findColumn(&table, cn), rdbms); err != nil {
+				return Definition{}, fmt.Errorf("model '%s': %s", m.name, err.Error())
+			}
+		}
+
+		for _, f := range m.fields {
+			ref, ok := p.model(f.typeName)
+			a, hasRelation := findAttr(f.attrs, "@relation")
+			if !ok || !hasRelation || len(a.namesArg("fields", -1)) == 0 {
+				continue
+			}
+			columns, err := m.columnNames(a.namesArg("fields", -1))
+			if err != nil {
+				return Definition{}, err
+			}
+			refColumns, err := ref.columnNames(a.namesArg("references", -1))
+			if err != nil {
+				return Definition{}, err
+			}
+			if len(columns) != len(refColumns) {
+				return Definition{}, fmt.Errorf("%d行目: @relation の fields と references の数が異なります。", f.line)
+			}
+			name, _ := a.stringArg("map", -1)
+			table.Constraints.ForeignKey = append(table.Constraints.ForeignKey, ddlparse.ForeignKey{
+				Name: name,
+				ColumnNames: columns,
+				References: ddlparse.Reference{TableName: ref.tableName(), ColumnNames: refColumns},
+			})
+		}
+		def.Tables = append(def.Tables, table)
+	}
+	return def, nil
+}
+
+func (p *prismaParser) column(f prismaField, rdbms string) (ddlparse.Column, error) {
+	column := ddlparse.Column{Name: f.columnName()}
+	if err := validateIdentifier("カラム名", column.Name, rdbms); err != nil {
+		return column, fmt.Errorf("%s（@map でカラム名を指定できます）", err.Error())
+	}
+	column.Constraint.IsNotNull = !f.optional
+	if _, ok := findAttr(f.attrs, "@unique"); ok {
+		column.Constraint.IsUnique = true
+	}
+
+	enum, isEnum := p.enums[f.typeName]
+	if isEnum {
+		values := []string{}
+		for _, v := range enum.values {
+			if mapped, ok := enum.mapped[v]; ok {
+				v = mapped
+			}
+			values = append(values, v)
+		}
+		setEnum(&column, values, rdbms)
+	} else {
+		typeText := ""
+		if types, ok := prismaScalarTypes[f.typeName]; ok {
+			typeText = types[0]
+			if rdbms == "mysql" {
+				typeText = types[1]
+			}
+		} else if f.typeName == "Unsupported" && len(f.typeArgs) == 1 && f.typeArgs[0].value.kind == prismaString {
+			typeText = f.typeArgs[0].value.value
+		} else {
+			return column, fmt.Errorf("型 '%s' には対応していません。", f.typeName)
+		}
+		for _, a := range f.attrs {
+			native, ok := strings.CutPrefix(a.name, "@db.")
+			if ok && (p.provider == rdbms || prismaPortableNativeTypes[native]) {
+				typeText = prismaNativeType(native, a.args)
+			}
+		}
+		dt, err := dataTypeOf(typeText, rdbms)
+		if err != nil {
+			return column, err
+		}
+		column.DataType = dt
+	}
+	if f.list {
+		if rdbms != "postgresql" || isEnum {
+			return column, fmt.Errorf("配列 '%s[]' は PostgreSQL のスカラー型のみ指定できます。", f.typeName)
+		}
+		column.DataType.Name += "[]"
+	}
+
+	if a, ok := findAttr(f.attrs, "@default"); ok {
+		v, ok := a.arg("value", 0)
+		if !ok {
+			return column, fmt.Errorf("@default の値がありません。")
+		}
+		column.Constraint.Default = prismaDefault(v, enum)
+		//MySQL の DATETIME(3) のデフォルトは同じ精度の CURRENT_TIMESTAMP(3)
+		if column.Constraint.Default == "CURRENT_TIMESTAMP" && rdbms == "mysql" && column.DataType.DigitN > 0 {
+			column.Constraint.Default = fmt.Sprintf("CURRENT_TIMESTAMP(%d)", column.DataType.DigitN)
+		}
+	}
+	return column, nil
+}
+
+/*
+ @default の値
+ now() は CURRENT_TIMESTAMP、dbgenerated("式") は式、
+ autoincrement() は自動採番、uuid() などアプリで採番する値は無し
+*/
+func prismaDefault(v prismaValue, enum prismaEnum) interface{} {
+	if v.isCall {
+		switch v.value {
+		case "now":
+			return "CURRENT_TIMESTAMP"
+		case "dbgenerated":
+			if len(v.args) > 0 && v.args[0].value.kind == prismaString {
+				return v.args[0].value.value
+			}
+		}
+		return nil
+	}
+	switch v.kind {
+	case prismaNumber:
+		n, err := strconv.ParseFloat(v.value, 64)
+		if err == nil {
+			return n
+		}
+	case prismaIdent:
+		switch v.value {
+		case "true":
+			return true
+		case "false":
+			return false
+		}
+		if mapped, ok := enum.mapped[v.value]; ok {
+			return mapped
+		}
+	}
+	return v.value
+}
+
+// @db.VarChar(255) → VARCHAR(255)、@db.DoublePrecision → DOUBLE PRECISION、@db.UnsignedInt → INT UNSIGNED
+func prismaNativeType(native string, args []prismaArg) string {
+	name := strings.ToUpper(native)
+	if native == "DoublePrecision" {
+		name = "DOUBLE PRECISION"
+	} else if base, ok := strings.CutPrefix(native, "Unsigned"); ok {
+		name = strings.ToUpper(base) + " UNSIGNED"
+	}
+	if len(args) == 0 {
+		return name
+	}
+	ls := []string{}
+	for _, a := range args {
+		ls = append(ls, a.value.value)
+	}
+	if base, ok := strings.CutSuffix(name, " UNSIGNED"); ok {
+		return base + "(" + strings.Join(ls, ",") + ") UNSIGNED"
+	}
+	return name + "(" + strings.Join(ls, ",") + ")"
+}
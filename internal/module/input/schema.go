@@ -0,0 +1,209 @@
+package input
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+
+/*
+ マイグレーションの差分に使用するテーブル定義 (生成するRDBMSのDDLを読み取る)
+ 型・デフォルト値は変換せず、DDLの記述のまま比較する
+*/
+
+type Schema struct {
+	Tables []SchemaTable
+}
+
+type SchemaTable struct {
+	Name string
+	Columns []SchemaColumn
+	// テーブルの文 (先頭は CREATE TABLE、以降は ALTER TABLE・CREATE INDEX・COMMENT ON)
+	Statements []string
+	// テーブルの主キー・ユニーク・CHECK・外部キーとインデックス (変更の有無の判定に使用)
+	Constraints []string
+	create []sqlToken
+}
+
+type SchemaColumn struct {
+	Name string
+	// 型 (例: VARCHAR(255)、DOUBLE PRECISION)
+	Type string
+	NotNull bool
+	// デフォルト値の式 (指定なしの場合は空)
+	Default string
+	PrimaryKey bool
+	Unique bool
+	Autoincrement bool
+	Comment string
+	// カラムの CHECK・REFERENCES
+	Constraints []string
+	// カラムの定義 (カラム名・型・制約)
+	Definition string
+}
+
+func ReadSchema(src []byte, rdbms string) (Schema, error) {
+	if !isRdbms(rdbms) {
+		return Schema{}, fmt.Errorf("RDBMS '%s' は指定できません。", rdbms)
+	}
+	p, err := readSql(string(src), rdbms)
+	if err != nil {
+		return Schema{}, err
+	}
+	schema := Schema{}
+	for _, st := range p.tables {
+		table := SchemaTable{Name: st.name}
+		for i, tokens := range st.statements {
+			if i == 0 {
+				table.create = tokens
+			}
+			table.Statements = append(table.Statements, statementText(tokens, ""))
+			if i > 0 && strings.EqualFold(tokens[0].value, "CREATE") {
+				table.Constraints = append(table.Constraints, tokensText(tokens))
+			}
+		}
+		if len(st.primaryKey) > 0 {
+			table.Constraints = append(table.Constraints, "PRIMARY KEY (" + strings.ToLower(strings.Join(st.primaryKey, ", ")) + ")")
+		}
+		for _, u := range st.uniques {
+			table.Constraints = append(table.Constraints, "UNIQUE (" + strings.ToLower(strings.Join(u.ColumnNames, ", ")) + ")")
+		}
+		for _, ck := range st.checks {
+			table.Constraints = append(table.Constraints, "CHECK " + tokensText(ck.expr))
+		}
+		for _, fk := range st.foreignKeys {
+			table.Constraints = append(table.Constraints, referenceText(fk.columns, fk.ref))
+		}
+
+		for _, sc := range st.columns {
+			c := SchemaColumn{
+				Name: sc.name,
+				Type: columnTypeText(sc),
+				NotNull: sc.notNull,
+				Default: tokensText(sc.def),
+				PrimaryKey: sc.primaryKey || containsFold(st.primaryKey, sc.name),
+				Unique: sc.unique,
+				Autoincrement: sc.autoincrement,
+				Comment: p.comments[st.name + "." + sc.name],
+				Definition: tokensText(sc.tokens),
+			}
+			//主キーは NOT NULL
+			c.NotNull = c.NotNull || c.PrimaryKey
+			for _, ck := range sc.checks {
+				c.Constraints = append(c.Constraints, "CHECK " + tokensText(ck))
+			}
+			if sc.ref != nil {
+				c.Constraints = append(c.Constraints, referenceText([]string{sc.name}, *sc.ref))
+			}
+			table.Columns = append(table.Columns, c)
+		}
+		sort.Strings(table.Constraints)
+		schema.Tables = append(schema.Tables, table)
+	}
+	return schema, nil
+}
+
+func (s Schema) Table(name string) (SchemaTable, bool) {
+	for _, t := range s.Tables {
+		if strings.EqualFold(t.Name, name) {
+			return t, true
+		}
+	}
+	return SchemaTable{}, false
+}
+
+func (t SchemaTable) Column(name string) (SchemaColumn, bool) {
+	for _, c := range t.Columns {
+		if strings.EqualFold(c.Name, name) {
+			return c, true
+		}
+	}
+	return SchemaColumn{}, false
+}
+
+// テーブル名を変えた CREATE TABLE (SQLite でテーブルを作り直す場合に使用)
+func (t SchemaTable) CreateTableAs(name string) string {
+	return statementText(t.create, name)
+}
+
+func columnTypeText(sc *sqlColumn) string {
+	s := sc.typeName
+	if len(sc.values) > 0 {
+		vs := []string{}
+		for _, v := range sc.values {
+			vs = append(vs, quoteSqlString(v))
+		}
+		s += "(" + strings.Join(vs, ", ") + ")"
+	} else if sc.n > 0 && sc.m > 0 {
+		s += fmt.Sprintf("(%d,%d)", sc.n, sc.m)
+	} else if sc.n > 0 {
+		s += fmt.Sprintf("(%d)", sc.n)
+	}
+	if sc.unsigned {
+		s += " UNSIGNED"
+	}
+	if sc.array {
+		s += "[]"
+	}
+	return s
+}
+
+func referenceText(columns []string, ref sqlReference) string {
+	return fmt.Sprintf(
+		"FOREIGN KEY (%s) REFERENCES %s (%s)",
+		strings.Join(columns, ", "), ref.table, strings.Join(ref.columns, ", "),
+	)
+}
+
+// 文の文字列 (CREATE TABLE は1行に1つの定義、name を指定した場合はテーブル名を置き換える)
+func statementText(tokens []sqlToken, name string) string {
+	open := -1
+	if len(tokens) >= 2 && strings.EqualFold(tokens[0].value, "CREATE") && strings.EqualFold(tokens[1].value, "TABLE") {
+		for i, t := range tokens {
+			if t.kind == sqlSymbol && t.value == "(" {
+				open = i
+				break
+			}
+		}
+	}
+	if open < 0 {
+		return tokensText(tokens) + ";"
+	}
+	head := tokens[:open]
+	if name != "" {
+		//CREATE TABLE [IF NOT EXISTS] name
+		n := 2
+		if len(head) > 5 && strings.EqualFold(head[2].value, "IF") {
+			n = 5
+		}
+		head = append(append([]sqlToken{}, head[:n]...), sqlToken{sqlWord, name, head[0].line})
+	}
+
+	closing := closingParen(tokens, open)
+	ls := []string{}
+	start := open + 1
+	depth := 0
+	for i := open + 1; i < closing; i++ {
+		t := tokens[i]
+		if t.kind != sqlSymbol {
+			continue
+		}
+		switch {
+		case t.value == "(":
+			depth++
+		case t.value == ")":
+			depth--
+		case t.value == "," && depth == 0:
+			ls = append(ls, "\t" + tokensText(tokens[start:i]))
+			start = i + 1
+		}
+	}
+	ls = append(ls, "\t" + tokensText(tokens[start:closing]))
+
+	code := tokensText(head) + " (\n" + strings.Join(ls, ",\n") + "\n)"
+	if rest := tokens[closing+1:]; len(rest) > 0 {
+		code += " " + tokensText(rest)
+	}
+	return code + ";"
+}
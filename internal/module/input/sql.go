@@ -0,0 +1,1131 @@
+package input
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"github.com/kodaimura/ddlparse"
+)
+
+
+/*
+ 他のRDBMSのDDLを変換するために読み取る (ddlparse より寛容に解釈する)
+ CREATE TABLE・CREATE INDEX・COMMENT ON・ALTER TABLE (制約の追加など)・CREATE TYPE ... AS ENUM に対応
+ mysqldump・pg_dump の出力にある SET・DROP などは読み飛ばし、変換しない文は notes に記録する
+*/
+
+type sqlTokenKind int
+
+const (
+	sqlWord sqlTokenKind = iota
+	sqlQuoted
+	sqlString
+	sqlNumber
+	sqlSymbol
+	sqlEOF
+)
+
+type sqlToken struct {
+	kind sqlTokenKind
+	value string
+	line int
+}
+
+type sqlReference struct {
+	table string
+	columns []string
+	// ON DELETE / ON UPDATE の指定の有無 (変換しない)
+	hasActions bool
+}
+
+type sqlColumn struct {
+	name string
+	// 型名 (大文字、DOUBLE PRECISION のような複数語は空白区切り)
+	typeName string
+	n int
+	m int
+	// ENUM・SET の値
+	values []string
+	unsigned bool
+	array bool
+	notNull bool
+	primaryKey bool
+	unique bool
+	autoincrement bool
+	// デフォルト値 (トークンの並び、型変換 ::type は除く)
+	def []sqlToken
+	checks [][]sqlToken
+	ref *sqlReference
+	line int
+	// カラムの定義のトークン (カラム名から、マイグレーションの ADD COLUMN に使用)
+	tokens []sqlToken
+}
+
+type sqlCheck struct {
+	name string
+	expr []sqlToken
+}
+
+type sqlForeignKey struct {
+	name string
+	columns []string
+	ref sqlReference
+}
+
+type sqlTable struct {
+	name string
+	columns []*sqlColumn
+	primaryKey []string
+	uniques []ddlparse.Unique
+	checks []sqlCheck
+	foreignKeys []sqlForeignKey
+	// テーブルの CREATE TABLE・ALTER TABLE・CREATE INDEX・COMMENT ON 文のトークン (マイグレーションに使用)
+	statements [][]sqlToken
+}
+
+type sqlParser struct {
+	tokens []sqlToken
+	pos int
+	rdbms string
+	tables []*sqlTable
+	comments map[string]string
+	// CREATE TYPE ... AS ENUM (PostgreSQL)
+	enums map[string][]string
+	notes []string
+	// 解析中の文の対象のテーブル
+	target *sqlTable
+}
+
+
+func readSql(src string, rdbms string) (*sqlParser, error) {
+	tokens, err := lexSql(src, rdbms)
+	if err != nil {
+		return nil, err
+	}
+	p := &sqlParser{
+		tokens: tokens,
+		rdbms: rdbms,
+		comments: map[string]string{},
+		enums: map[string][]string{},
+	}
+	if err := p.parse(); err != nil {
+		return nil, err
+	}
+	if len(p.tables) == 0 {
+		return nil, fmt.Errorf("テーブルが見つかりません。")
+	}
+	return p, nil
+}
+
+
+func lexSql(src string, rdbms string) ([]sqlToken, error) {
+	tokens := []sqlToken{}
+	rs := []rune(src)
+	line := 1
+	for i := 0; i < len(rs); {
+		ch := rs[i]
+		switch {
+		case ch == '\n':
+			line++
+			i++
+		case unicode.IsSpace(ch):
+			i++
+		case ch == '-' && i + 1 < len(rs) && rs[i+1] == '-', ch == '#' && rdbms == "mysql":
+			for i < len(rs) && rs[i] != '\n' {
+				i++
+			}
+		case ch == '/' && i + 1 < len(rs) && rs[i+1] == '*':
+			//MySQL の /*!40101 ... */ もコメントとして読み飛ばす
+			end := strings.Index(string(rs[i+2:]), "*/")
+			if end < 0 {
+				return nil, fmt.Errorf("%d行目: コメントが閉じられていません。", line)
+			}
+			body := []rune(string(rs[i+2:])[:end])
+			line += strings.Count(string(body), "\n")
+			i += 2 + len(body) + 2
+		case ch == '\'' || ch == '"' || ch == '`' || (ch == '[' && rdbms == "sqlite3"):
+			closing := ch
+			if ch == '[' {
+				closing = ']'
+			}
+			start := line
+			j := i + 1
+			var b strings.Builder
+			for ; j < len(rs); j++ {
+				if rs[j] == closing && j + 1 < len(rs) && rs[j+1] == closing && ch != '[' {
+					b.WriteRune(closing)
+					j++
+					continue
+				}
+				if rs[j] == closing {
+					break
+				}
+				if rs[j] == '\\' && ch == '\'' && rdbms == "mysql" && j + 1 < len(rs) {
+					j++
+				}
+				if rs[j] == '\n' {
+					line++
+				}
+				b.WriteRune(rs[j])
+			}
+			if j >= len(rs) {
+				return nil, fmt.Errorf("%d行目: 文字列が閉じられていません。", start)
+			}
+			kind := sqlQuoted
+			if ch == '\'' {
+				kind = sqlString
+			}
+			tokens = append(tokens, sqlToken{kind, b.String(), start})
+			i = j + 1
+		case ch == '$' && rdbms == "postgresql":
+			//$$ ... $$・$tag$ ... $tag$ (関数の本体など)
+			j := i + 1
+			for j < len(rs) && (rs[j] == '_' || unicode.IsLetter(rs[j]) || unicode.IsDigit(rs[j])) {
+				j++
+			}
+			if j >= len(rs) || rs[j] != '$' {
+				return nil, fmt.Errorf("%d行目: '%c' を解釈できません。", line, ch)
+			}
+			tag := string(rs[i:j+1])
+			end := strings.Index(string(rs[j+1:]), tag)
+			if end < 0 {
+				return nil, fmt.Errorf("%d行目: 文字列が閉じられていません。", line)
+			}
+			body := []rune(string(rs[j+1:])[:end])
+			tokens = append(tokens, sqlToken{sqlString, string(body), line})
+			line += strings.Count(string(body), "\n")
+			i = j + 1 + len(body) + len([]rune(tag))
+		case unicode.IsDigit(ch) || (ch == '.' && i + 1 < len(rs) && unicode.IsDigit(rs[i+1])):
+			j := i
+			for j < len(rs) && (unicode.IsDigit(rs[j]) || rs[j] == '.') {
+				j++
+			}
+			if j < len(rs) && (rs[j] == 'e' || rs[j] == 'E') {
+				k := j + 1
+				if k < len(rs) && (rs[k] == '+' || rs[k] == '-') {
+					k++
+				}
+				if k < len(rs) && unicode.IsDigit(rs[k]) {
+					for j = k; j < len(rs) && unicode.IsDigit(rs[j]); j++ {}
+				}
+			}
+			tokens = append(tokens, sqlToken{sqlNumber, string(rs[i:j]), line})
+			i = j
+		case ch == '_' || unicode.IsLetter(ch):
+			j := i + 1
+			for j < len(rs) && (rs[j] == '_' || rs[j] == '$' || unicode.IsLetter(rs[j]) || unicode.IsDigit(rs[j])) {
+				j++
+			}
+			tokens = append(tokens, sqlToken{sqlWord, string(rs[i:j]), line})
+			i = j
+		default:
+			symbol := string(ch)
+			if i + 1 < len(rs) {
+				switch two := string(rs[i:i+2]); two {
+				case "::", "<>", "<=", ">=", "!=", "||", "[]":
+					symbol = two
+				}
+			}
+			if !strings.Contains("::<><=>=!=||[]();,.=<>+-*/%~!&|^@?:", symbol) {
+				return nil, fmt.Errorf("%d行目: '%c' を解釈できません。", line, ch)
+			}
+			tokens = append(tokens, sqlToken{sqlSymbol, symbol, line})
+			i += len([]rune(symbol))
+		}
+	}
+	return append(tokens, sqlToken{sqlEOF, "", line}), nil
+}
+
+
+func (p *sqlParser) peek() sqlToken {
+	return p.tokens[p.pos]
+}
+
+func (p *sqlParser) peekAt(n int) sqlToken {
+	if p.pos + n >= len(p.tokens) {
+		return p.tokens[len(p.tokens)-1]
+	}
+	return p.tokens[p.pos+n]
+}
+
+func (p *sqlParser) next() sqlToken {
+	t := p.tokens[p.pos]
+	if t.kind != sqlEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *sqlParser) isSymbol(s string) bool {
+	t := p.peek()
+	return t.kind == sqlSymbol && t.value == s
+}
+
+func (p *sqlParser) isKeyword(s ...string) bool {
+	for i, kw := range s {
+		t := p.peekAt(i)
+		if t.kind != sqlWord || !strings.EqualFold(t.value, kw) {
+			return false
+		}
+	}
+	return true
+}
+
+// キーワードが続く場合は読み進める
+func (p *sqlParser) acceptKeyword(s ...string) bool {
+	if !p.isKeyword(s...) {
+		return false
+	}
+	p.pos += len(s)
+	return true
+}
+
+func (p *sqlParser) isEnd() bool {
+	return p.isSymbol(";") || p.peek().kind == sqlEOF
+}
+
+func (p *sqlParser) expectSymbol(s string) error {
+	t := p.next()
+	if t.kind != sqlSymbol || t.value != s {
+		return p.syntaxError(t)
+	}
+	return nil
+}
+
+func (p *sqlParser) expectName() (string, error) {
+	t := p.next()
+	if t.kind != sqlWord && t.kind != sqlQuoted {
+		return "", p.syntaxError(t)
+	}
+	return t.value, nil
+}
+
+// schema.name の name (スキーマは使用しない)
+func (p *sqlParser) qualifiedName() (string, error) {
+	name, err := p.expectName()
+	if err != nil {
+		return "", err
+	}
+	for p.isSymbol(".") {
+		p.next()
+		if name, err = p.expectName(); err != nil {
+			return "", err
+		}
+	}
+	return name, nil
+}
+
+func (p *sqlParser) syntaxError(t sqlToken) error {
+	if t.kind == sqlEOF {
+		return fmt.Errorf("%d行目: 予期しないファイルの終わりです。", t.line)
+	}
+	return fmt.Errorf("%d行目: '%s' の付近を解釈できません。", t.line, t.value)
+}
+
+func (p *sqlParser) note(format string, a ...interface{}) {
+	msg := fmt.Sprintf(format, a...)
+	for _, n := range p.notes {
+		if n == msg {
+			return
+		}
+	}
+	p.notes = append(p.notes, msg)
+}
+
+// ( から対応する ) までのトークン (括弧を含む)
+func (p *sqlParser) parenthesized() ([]sqlToken, error) {
+	start := p.pos
+	if err := p.expectSymbol("("); err != nil {
+		return nil, err
+	}
+	depth := 1
+	for depth > 0 {
+		t := p.next()
+		switch {
+		case t.kind == sqlEOF:
+			return nil, p.syntaxError(t)
+		case t.kind == sqlSymbol && t.value == "(":
+			depth++
+		case t.kind == sqlSymbol && t.value == ")":
+			depth--
+		}
+	}
+	return p.tokens[start:p.pos], nil
+}
+
+// 文の終わり (;) まで読み飛ばす
+func (p *sqlParser) skipStatement() {
+	for !p.isEnd() {
+		p.next()
+	}
+}
+
+// , または ) (括弧の外)・文の終わりまで読み飛ばす
+func (p *sqlParser) skipItem() error {
+	for !p.isSymbol(",") && !p.isSymbol(")") && !p.isEnd() {
+		if p.isSymbol("(") {
+			if _, err := p.parenthesized(); err != nil {
+				return err
+			}
+			continue
+		}
+		p.next()
+	}
+	return nil
+}
+
+
+func (p *sqlParser) parse() error {
+	for {
+		for p.isSymbol(";") {
+			p.next()
+		}
+		t := p.peek()
+		if t.kind == sqlEOF {
+			return nil
+		}
+		if t.kind != sqlWord {
+			return p.syntaxError(t)
+		}
+		start := p.pos
+		p.target = nil
+		var err error
+		switch strings.ToUpper(t.value) {
+		case "CREATE":
+			err = p.parseCreate()
+		case "COMMENT":
+			err = p.parseCommentOn()
+		case "ALTER":
+			err = p.parseAlter()
+		case "INSERT", "UPDATE", "DELETE", "COPY", "REPLACE":
+			p.note("データ (%s) は変換しません。", strings.ToUpper(t.value))
+			p.skipStatement()
+		case "SET", "DROP", "LOCK", "UNLOCK", "BEGIN", "COMMIT", "START", "ROLLBACK", "SAVEPOINT", "RELEASE",
+			"PRAGMA", "USE", "SELECT", "GRANT", "REVOKE", "ANALYZE", "VACUUM":
+			p.skipStatement()
+		default:
+			p.note("%d行目: %s 文は変換しません。", t.line, strings.ToUpper(t.value))
+			p.skipStatement()
+		}
+		if err != nil {
+			return err
+		}
+		if !p.isEnd() {
+			return p.syntaxError(p.peek())
+		}
+		if p.target != nil {
+			p.target.statements = append(p.target.statements, p.tokens[start:p.pos])
+		}
+	}
+}
+
+func (p *sqlParser) parseCreate() error {
+	line := p.next().line
+	p.acceptKeyword("OR", "REPLACE")
+	for p.acceptKeyword("TEMPORARY") || p.acceptKeyword("TEMP") || p.acceptKeyword("UNLOGGED") ||
+		p.acceptKeyword("GLOBAL") || p.acceptKeyword("LOCAL") {}
+
+	switch {
+	case p.acceptKeyword("TABLE"):
+		return p.parseCreateTable()
+	case p.acceptKeyword("UNIQUE", "INDEX"):
+		return p.parseCreateIndex(true, line)
+	case p.acceptKeyword("INDEX"):
+		return p.parseCreateIndex(false, line)
+	case p.acceptKeyword("TYPE"):
+		return p.parseCreateType(line)
+	case p.isKeyword("SEQUENCE"), p.isKeyword("EXTENSION"), p.isKeyword("SCHEMA"), p.isKeyword("DATABASE"):
+		p.skipStatement()
+		return nil
+	}
+	p.note("%d行目: CREATE %s は変換しません。", line, strings.ToUpper(p.peek().value))
+	p.skipStatement()
+	return nil
+}
+
+func (p *sqlParser) parseCreateTable() error {
+	p.acceptKeyword("IF", "NOT", "EXISTS")
+	line := p.peek().line
+	name, err := p.qualifiedName()
+	if err != nil {
+		return err
+	}
+	if !p.isSymbol("(") {
+		//CREATE TABLE ... AS SELECT・LIKE など
+		p.note("%d行目: テーブル '%s' は列の定義がないため変換しません。", line, name)
+		p.skipStatement()
+		return nil
+	}
+	if p.table(name) != nil {
+		return fmt.Errorf("%d行目: テーブル '%s' が重複しています。", line, name)
+	}
+	table := &sqlTable{name: name}
+	p.next()
+	for {
+		if err := p.parseTableElement(table); err != nil {
+			return err
+		}
+		if p.isSymbol(",") {
+			p.next()
+			continue
+		}
+		if err := p.expectSymbol(")"); err != nil {
+			return err
+		}
+		break
+	}
+	p.tables = append(p.tables, table)
+	p.target = table
+	return p.parseTableOptions(table)
+}
+
+// テーブルのオプション (COMMENT のみ使用し、ENGINE・CHARSET などは読み飛ばす)
+func (p *sqlParser) parseTableOptions(table *sqlTable) error {
+	for !p.isEnd() {
+		if p.isKeyword("COLLATE") || p.isKeyword("CHARSET") || p.isKeyword("CHARACTER") {
+			p.note("テーブルの照合順序・文字セット (COLLATE・CHARSET) の指定は変換しません。")
+		}
+		if p.acceptKeyword("COMMENT") {
+			if p.isSymbol("=") {
+				p.next()
+			}
+			t := p.next()
+			if t.kind != sqlString {
+				return p.syntaxError(t)
+			}
+			p.comments[table.name] = t.value
+			continue
+		}
+		if p.isSymbol("(") {
+			if _, err := p.parenthesized(); err != nil {
+				return err
+			}
+			continue
+		}
+		p.next()
+	}
+	return nil
+}
+
+// カラムの定義またはテーブルの制約
+func (p *sqlParser) parseTableElement(table *sqlTable) error {
+	line := p.peek().line
+	name := ""
+	if p.acceptKeyword("CONSTRAINT") {
+		n, err := p.expectName()
+		if err != nil {
+			return err
+		}
+		name = n
+	}
+	switch {
+	case p.acceptKeyword("PRIMARY", "KEY"):
+		cols, err := p.parseIndexColumns()
+		if err != nil {
+			return err
+		}
+		table.primaryKey = cols
+		return p.skipItem()
+	case p.isKeyword("UNIQUE"):
+		p.next()
+		if !p.acceptKeyword("KEY") {
+			p.acceptKeyword("INDEX")
+		}
+		if !p.isSymbol("(") {
+			n, err := p.expectName()
+			if err != nil {
+				return err
+			}
+			if name == "" {
+				name = n
+			}
+		}
+		cols, err := p.parseIndexColumns()
+		if err != nil {
+			return err
+		}
+		table.uniques = append(table.uniques, ddlparse.Unique{Name: name, ColumnNames: cols})
+		return p.skipItem()
+	case p.acceptKeyword("CHECK"):
+		expr, err := p.parenthesized()
+		if err != nil {
+			return err
+		}
+		table.checks = append(table.checks, sqlCheck{name, expr})
+		return p.skipItem()
+	case p.acceptKeyword("FOREIGN", "KEY"):
+		if !p.isSymbol("(") {
+			if _, err := p.expectName(); err != nil {
+				return err
+			}
+		}
+		cols, err := p.parseIndexColumns()
+		if err != nil {
+			return err
+		}
+		if !p.acceptKeyword("REFERENCES") {
+			return p.syntaxError(p.peek())
+		}
+		ref, err := p.parseReference()
+		if err != nil {
+			return err
+		}
+		table.foreignKeys = append(table.foreignKeys, sqlForeignKey{name, cols, ref})
+		return p.skipItem()
+	case name == "" && (p.isKeyword("KEY") || p.isKeyword("INDEX") || p.isKeyword("FULLTEXT") || p.isKeyword("SPATIAL")):
+		p.note("%d行目: テーブル '%s' のインデックスは変換しません。", line, table.name)
+		return p.skipItem()
+	case p.isKeyword("EXCLUDE"):
+		p.note("%d行目: テーブル '%s' の EXCLUDE 制約は変換しません。", line, table.name)
+		return p.skipItem()
+	case name != "":
+		return p.syntaxError(p.peek())
+	}
+
+	column, err := p.parseColumn(table)
+	if err != nil {
+		return err
+	}
+	if table.column(column.name) != nil {
+		return fmt.Errorf("%d行目: テーブル '%s': カラム '%s' が重複しています。", line, table.name, column.name)
+	}
+	table.columns = append(table.columns, column)
+	return nil
+}
+
+// インデックス・制約のカラム (name(10) の長さ・ASC / DESC は使用しない)
+func (p *sqlParser) parseIndexColumns() ([]string, error) {
+	if err := p.expectSymbol("("); err != nil {
+		return nil, err
+	}
+	cols := []string{}
+	for {
+		if p.isSymbol("(") {
+			//式のインデックス
+			return nil, fmt.Errorf("%d行目: 式を含むインデックス・制約は変換できません。", p.peek().line)
+		}
+		cn, err := p.expectName()
+		if err != nil {
+			return nil, err
+		}
+		cols = append(cols, cn)
+		for !p.isSymbol(",") && !p.isSymbol(")") {
+			if p.isEnd() {
+				return nil, p.syntaxError(p.peek())
+			}
+			if p.isSymbol("(") {
+				if _, err := p.parenthesized(); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			p.next()
+		}
+		if p.next().value == ")" {
+			return cols, nil
+		}
+	}
+}
+
+// REFERENCES の後 (テーブル (カラム) と ON DELETE などの指定)
+func (p *sqlParser) parseReference() (sqlReference, error) {
+	tn, err := p.qualifiedName()
+	if err != nil {
+		return sqlReference{}, err
+	}
+	ref := sqlReference{table: tn}
+	if p.isSymbol("(") {
+		if ref.columns, err = p.parseIndexColumns(); err != nil {
+			return sqlReference{}, err
+		}
+	}
+	for {
+		switch {
+		case p.acceptKeyword("ON", "DELETE"), p.acceptKeyword("ON", "UPDATE"):
+			ref.hasActions = true
+			if !p.acceptKeyword("SET", "NULL") && !p.acceptKeyword("SET", "DEFAULT") && !p.acceptKeyword("NO", "ACTION") {
+				p.next()
+			}
+		case p.acceptKeyword("MATCH"):
+			p.next()
+		case p.acceptKeyword("NOT", "DEFERRABLE"), p.acceptKeyword("DEFERRABLE"),
+			p.acceptKeyword("INITIALLY", "DEFERRED"), p.acceptKeyword("INITIALLY", "IMMEDIATE"):
+		default:
+			return ref, nil
+		}
+	}
+}
+
+// 複数語の型名 (先頭の語に続く語)
+var sqlTypeWords = map[string][]string{
+	"DOUBLE": {"PRECISION"},
+	"CHARACTER": {"VARYING"},
+	"CHAR": {"VARYING"},
+	"NATIONAL": {"CHARACTER", "CHAR", "VARYING"},
+	"BIT": {"VARYING"},
+	"LONG": {"VARCHAR", "VARBINARY"},
+}
+
+func (p *sqlParser) parseColumn(table *sqlTable) (*sqlColumn, error) {
+	start := p.pos
+	line := p.peek().line
+	cn, err := p.expectName()
+	if err != nil {
+		return nil, err
+	}
+	column := &sqlColumn{name: cn, line: line}
+	if err := p.parseDataType(column); err != nil {
+		return nil, err
+	}
+	for !p.isSymbol(",") && !p.isSymbol(")") && !p.isEnd() {
+		if err := p.parseColumnConstraint(table, column); err != nil {
+			return nil, err
+		}
+	}
+	column.tokens = p.tokens[start:p.pos]
+	return column, nil
+}
+
+// SQLite で型を省略した場合の型名の次のキーワード
+var sqlColumnConstraintWords = []string{"CONSTRAINT", "PRIMARY", "NOT", "NULL", "UNIQUE", "DEFAULT", "CHECK", "REFERENCES", "COLLATE", "GENERATED", "AS"}
+
+func (p *sqlParser) parseDataType(column *sqlColumn) error {
+	if p.rdbms == "sqlite3" && (p.isSymbol(",") || p.isSymbol(")") || (p.peek().kind == sqlWord && containsFold(sqlColumnConstraintWords, p.peek().value))) {
+		return nil
+	}
+	t := p.next()
+	if t.kind != sqlWord && t.kind != sqlQuoted {
+		return p.syntaxError(t)
+	}
+	words := []string{strings.ToUpper(t.value)}
+	//schema.type
+	for p.isSymbol(".") {
+		p.next()
+		name, err := p.expectName()
+		if err != nil {
+			return err
+		}
+		words = []string{strings.ToUpper(name)}
+	}
+	for {
+		next, ok := sqlTypeWords[words[len(words)-1]]
+		if !ok || !containsFold(next, p.peek().value) || p.peek().kind != sqlWord {
+			break
+		}
+		words = append(words, strings.ToUpper(p.next().value))
+	}
+	column.typeName = strings.Join(words, " ")
+
+	if p.isSymbol("(") {
+		p.next()
+		args := []sqlToken{}
+		for !p.isSymbol(")") {
+			t := p.next()
+			if t.kind == sqlEOF {
+				return p.syntaxError(t)
+			}
+			if t.kind != sqlSymbol || t.value != "," {
+				args = append(args, t)
+			}
+		}
+		p.next()
+		for i, a := range args {
+			switch {
+			case a.kind == sqlString:
+				column.values = append(column.values, a.value)
+			case a.kind == sqlNumber && i == 0:
+				fmt.Sscan(a.value, &column.n)
+			case a.kind == sqlNumber && i == 1:
+				fmt.Sscan(a.value, &column.m)
+			}
+		}
+	}
+	//TIMESTAMP(3) WITH TIME ZONE など
+	if p.acceptKeyword("WITH", "TIME", "ZONE") || p.acceptKeyword("WITH", "LOCAL", "TIME", "ZONE") {
+		column.typeName += " WITH TIME ZONE"
+	} else {
+		p.acceptKeyword("WITHOUT", "TIME", "ZONE")
+	}
+	for {
+		switch {
+		case p.isSymbol("[]"):
+			p.next()
+			column.array = true
+		case p.isSymbol("["):
+			//INTEGER[3]
+			for !p.isSymbol("]") && !p.isEnd() {
+				p.next()
+			}
+			p.next()
+			column.array = true
+		case p.acceptKeyword("ARRAY"):
+			column.array = true
+		case p.acceptKeyword("UNSIGNED"):
+			column.unsigned = true
+		case p.acceptKeyword("SIGNED"), p.acceptKeyword("ZEROFILL"):
+		default:
+			return nil
+		}
+	}
+}
+
+func (p *sqlParser) parseColumnConstraint(table *sqlTable, column *sqlColumn) error {
+	t := p.peek()
+	if p.acceptKeyword("CONSTRAINT") {
+		if _, err := p.expectName(); err != nil {
+			return err
+		}
+		return nil
+	}
+	switch {
+	case p.acceptKeyword("PRIMARY", "KEY"):
+		column.primaryKey = true
+		p.acceptKeyword("ASC")
+		p.acceptKeyword("DESC")
+	case p.acceptKeyword("NOT", "NULL"):
+		column.notNull = true
+	case p.acceptKeyword("NULL"):
+	case p.acceptKeyword("UNIQUE"):
+		column.unique = true
+		p.acceptKeyword("KEY")
+	case p.acceptKeyword("KEY"):
+		//MySQL の KEY は PRIMARY KEY
+		column.primaryKey = true
+	case p.acceptKeyword("AUTO_INCREMENT"), p.acceptKeyword("AUTOINCREMENT"):
+		column.autoincrement = true
+	case p.acceptKeyword("GENERATED", "ALWAYS", "AS", "IDENTITY"), p.acceptKeyword("GENERATED", "BY", "DEFAULT", "AS", "IDENTITY"),
+		p.acceptKeyword("GENERATED", "BY", "DEFAULT", "ON", "NULL", "AS", "IDENTITY"):
+		column.autoincrement = true
+		if p.isSymbol("(") {
+			if _, err := p.parenthesized(); err != nil {
+				return err
+			}
+		}
+	case p.acceptKeyword("GENERATED", "ALWAYS", "AS"), p.acceptKeyword("AS"):
+		//生成列は通常のカラムとする
+		if _, err := p.parenthesized(); err != nil {
+			return err
+		}
+		p.note("%d行目: カラム '%s.%s' の生成列の式は変換しません。", t.line, table.name, column.name)
+		for p.acceptKeyword("STORED") || p.acceptKeyword("VIRTUAL") || p.acceptKeyword("PERSISTENT") {}
+	case p.acceptKeyword("DEFAULT"):
+		def, err := p.parseDefault()
+		if err != nil {
+			return err
+		}
+		column.def = def
+	case p.acceptKeyword("CHECK"):
+		expr, err := p.parenthesized()
+		if err != nil {
+			return err
+		}
+		column.checks = append(column.checks, expr)
+	case p.acceptKeyword("REFERENCES"):
+		ref, err := p.parseReference()
+		if err != nil {
+			return err
+		}
+		column.ref = &ref
+	case p.acceptKeyword("COMMENT"):
+		s := p.next()
+		if s.kind != sqlString {
+			return p.syntaxError(s)
+		}
+		p.comments[table.name + "." + column.name] = s.value
+	case p.acceptKeyword("COLLATE"), p.acceptKeyword("CHARACTER", "SET"), p.acceptKeyword("CHARSET"):
+		name, err := p.qualifiedName()
+		if err != nil {
+			return err
+		}
+		p.note("%d行目: カラム '%s.%s' の照合順序・文字セット '%s' は変換しません。", t.line, table.name, column.name, name)
+	case p.acceptKeyword("ON", "UPDATE"):
+		if _, err := p.parseDefault(); err != nil {
+			return err
+		}
+		p.note("%d行目: カラム '%s.%s' の ON UPDATE は変換しません。", t.line, table.name, column.name)
+	case p.acceptKeyword("ON", "CONFLICT"):
+		p.next()
+	case p.acceptKeyword("VISIBLE"), p.acceptKeyword("INVISIBLE"), p.acceptKeyword("ASC"), p.acceptKeyword("DESC"):
+	default:
+		p.note("%d行目: カラム '%s.%s' の '%s' は変換しません。", t.line, table.name, column.name, t.value)
+		p.next()
+		if p.isSymbol("(") {
+			if _, err := p.parenthesized(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// デフォルト値 (リテラル・関数・括弧の式、PostgreSQL の ::type は除く)
+func (p *sqlParser) parseDefault() ([]sqlToken, error) {
+	tokens := []sqlToken{}
+	switch {
+	case p.isSymbol("("):
+		expr, err := p.parenthesized()
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, expr...)
+	case p.isSymbol("-") || p.isSymbol("+"):
+		tokens = append(tokens, p.next(), p.next())
+	default:
+		t := p.next()
+		if t.kind == sqlEOF {
+			return nil, p.syntaxError(t)
+		}
+		tokens = append(tokens, t)
+		if t.kind == sqlWord && p.isSymbol("(") {
+			args, err := p.parenthesized()
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, args...)
+		} else if t.kind == sqlWord && p.peek().kind == sqlString {
+			//b'0'・E'...' など
+			tokens = append(tokens, p.next())
+		}
+	}
+	for p.isSymbol("::") {
+		p.next()
+		p.parseDataType(&sqlColumn{})
+	}
+	return tokens, nil
+}
+
+func (p *sqlParser) parseCreateIndex(unique bool, line int) error {
+	p.acceptKeyword("CONCURRENTLY")
+	p.acceptKeyword("IF", "NOT", "EXISTS")
+	name := ""
+	if !p.isKeyword("ON") {
+		n, err := p.qualifiedName()
+		if err != nil {
+			return err
+		}
+		name = n
+	}
+	if !p.acceptKeyword("ON") {
+		return p.syntaxError(p.peek())
+	}
+	p.acceptKeyword("ONLY")
+	tn, err := p.qualifiedName()
+	if err != nil {
+		return err
+	}
+	p.target = p.table(tn)
+	if !unique {
+		p.note("%d行目: インデックス '%s' は変換しません。", line, name)
+		p.skipStatement()
+		return nil
+	}
+	if p.acceptKeyword("USING") {
+		p.next()
+	}
+	cols, err := p.parseIndexColumns()
+	if err != nil {
+		return err
+	}
+	table := p.table(tn)
+	if table == nil {
+		return fmt.Errorf("%d行目: テーブル '%s' が見つかりません。", line, tn)
+	}
+	if !p.isEnd() {
+		//部分インデックス (WHERE ...) など
+		p.note("%d行目: インデックス '%s' の条件は変換しません。", line, name)
+		p.skipStatement()
+	}
+	table.uniques = append(table.uniques, ddlparse.Unique{Name: name, ColumnNames: cols})
+	return nil
+}
+
+// CREATE TYPE name AS ENUM ('a', 'b')
+func (p *sqlParser) parseCreateType(line int) error {
+	name, err := p.qualifiedName()
+	if err != nil {
+		return err
+	}
+	if !p.acceptKeyword("AS", "ENUM") {
+		p.note("%d行目: 型 '%s' は変換しません。", line, name)
+		p.skipStatement()
+		return nil
+	}
+	tokens, err := p.parenthesized()
+	if err != nil {
+		return err
+	}
+	values := []string{}
+	for _, t := range tokens {
+		if t.kind == sqlString {
+			values = append(values, t.value)
+		}
+	}
+	p.enums[strings.ToUpper(name)] = values
+	return nil
+}
+
+// COMMENT ON TABLE t IS '...' / COMMENT ON COLUMN t.c IS '...'
+func (p *sqlParser) parseCommentOn() error {
+	line := p.next().line
+	if !p.acceptKeyword("ON") {
+		return p.syntaxError(p.peek())
+	}
+	isColumn := false
+	switch {
+	case p.acceptKeyword("TABLE"):
+	case p.acceptKeyword("COLUMN"):
+		isColumn = true
+	default:
+		p.skipStatement()
+		return nil
+	}
+	names := []string{}
+	for {
+		n, err := p.expectName()
+		if err != nil {
+			return err
+		}
+		names = append(names, n)
+		if !p.isSymbol(".") {
+			break
+		}
+		p.next()
+	}
+	if !p.acceptKeyword("IS") {
+		return p.syntaxError(p.peek())
+	}
+	t := p.next()
+	if t.kind != sqlString {
+		//IS NULL
+		return nil
+	}
+	key := names[len(names)-1]
+	if isColumn {
+		if len(names) < 2 {
+			return fmt.Errorf("%d行目: カラムのコメントはテーブル名.カラム名で指定してください。", line)
+		}
+		key = names[len(names)-2] + "." + key
+	}
+	p.target = p.table(strings.SplitN(key, ".", 2)[0])
+	p.comments[key] = t.value
+	return nil
+}
+
+// ALTER TABLE (制約・カラムの追加、デフォルト値・NOT NULL の変更、MySQL の MODIFY)
+func (p *sqlParser) parseAlter() error {
+	line := p.next().line
+	if p.isKeyword("SEQUENCE") {
+		p.skipStatement()
+		return nil
+	}
+	if !p.acceptKeyword("TABLE") {
+		p.note("%d行目: ALTER %s は変換しません。", line, strings.ToUpper(p.peek().value))
+		p.skipStatement()
+		return nil
+	}
+	p.acceptKeyword("IF", "EXISTS")
+	p.acceptKeyword("ONLY")
+	tn, err := p.qualifiedName()
+	if err != nil {
+		return err
+	}
+	table := p.table(tn)
+	if table == nil {
+		return fmt.Errorf("%d行目: テーブル '%s' が見つかりません。", line, tn)
+	}
+	p.target = table
+	for {
+		if err := p.parseAlterAction(table); err != nil {
+			return err
+		}
+		if !p.isSymbol(",") {
+			return nil
+		}
+		p.next()
+	}
+}
+
+func (p *sqlParser) parseAlterAction(table *sqlTable) error {
+	t := p.peek()
+	switch {
+	case p.acceptKeyword("ADD"):
+		p.acceptKeyword("COLUMN")
+		p.acceptKeyword("IF", "NOT", "EXISTS")
+		return p.parseTableElement(table)
+	case p.acceptKeyword("ALTER"):
+		p.acceptKeyword("COLUMN")
+		cn, err := p.expectName()
+		if err != nil {
+			return err
+		}
+		column := table.column(cn)
+		if column == nil {
+			return fmt.Errorf("%d行目: テーブル '%s': カラム '%s' が見つかりません。", t.line, table.name, cn)
+		}
+		switch {
+		case p.acceptKeyword("SET", "DEFAULT"):
+			def, err := p.parseDefault()
+			if err != nil {
+				return err
+			}
+			column.def = def
+		case p.acceptKeyword("SET", "NOT", "NULL"):
+			column.notNull = true
+		case p.acceptKeyword("DROP", "NOT", "NULL"):
+			column.notNull = false
+		case p.acceptKeyword("DROP", "DEFAULT"):
+			column.def = nil
+		case p.acceptKeyword("ADD", "GENERATED"):
+			column.autoincrement = true
+			return p.skipItem()
+		default:
+			p.note("%d行目: テーブル '%s' の ALTER COLUMN は変換しません。", t.line, table.name)
+			return p.skipItem()
+		}
+		return nil
+	case p.acceptKeyword("MODIFY"), p.isKeyword("CHANGE"):
+		if p.acceptKeyword("CHANGE") {
+			//CHANGE old new ...
+			p.acceptKeyword("COLUMN")
+			if _, err := p.expectName(); err != nil {
+				return err
+			}
+		} else {
+			p.acceptKeyword("COLUMN")
+		}
+		column, err := p.parseColumn(table)
+		if err != nil {
+			return err
+		}
+		for i, c := range table.columns {
+			if strings.EqualFold(c.name, column.name) {
+				table.columns[i] = column
+				return nil
+			}
+		}
+		return fmt.Errorf("%d行目: テーブル '%s': カラム '%s' が見つかりません。", t.line, table.name, column.name)
+	case p.isKeyword("OWNER"), p.isKeyword("ENABLE"), p.isKeyword("DISABLE"), p.isKeyword("AUTO_INCREMENT"):
+		return p.skipItem()
+	}
+	p.note("%d行目: テーブル '%s' の ALTER TABLE %s は変換しません。", t.line, table.name, strings.ToUpper(t.value))
+	return p.skipItem()
+}
+
+
+func (p *sqlParser) table(name string) *sqlTable {
+	for _, t := range p.tables {
+		if strings.EqualFold(t.name, name) {
+			return t
+		}
+	}
+	return nil
+}
+
+func (t *sqlTable) column(name string) *sqlColumn {
+	for _, c := range t.columns {
+		if strings.EqualFold(c.name, name) {
+			return c
+		}
+	}
+	return nil
+}
@@ -0,0 +1,453 @@
+package input
+
+import (
+	"fmt"
+	"bytes"
+	"sort"
+	"regexp"
+	"strings"
+	"strconv"
+	"unicode/utf8"
+	"encoding/csv"
+	"github.com/kodaimura/ddlparse"
+	"golang.org/x/text/encoding/japanese"
+)
+
+
+/*
+ テーブル定義書 (CSV・XLSX) を読み取り、DDLを ddlparse で解析した場合と同じテーブルのモデルを作成する
+ 論理名は Definition.Labels (画面の項目名) とする
+
+ 列の並び (見出し行の次の行から1行に1カラム)
+  A テーブル名 (空の場合は上の行と同じテーブル)
+  B カラム名
+  C 論理名 (画面の項目名)
+  D データ型 (VARCHAR(50) のように桁数を含めてもよい)
+  E 長さ (10 または 10,2)
+  F NOT NULL (○ など)
+  G PK (○ など、複合主キーは 1, 2 … で順序を指定してもよい)
+  H デフォルト (SQLと同じ表記、AUTOINCREMENT / AUTO_INCREMENT は自動採番)
+  I 外部キー (<テーブル名>.<カラム名> または <テーブル名>(<カラム名>))
+*/
+
+// 行単位のエラー (Line はファイルの行番号、Sheet はXLSXのシート名)
+type RowError struct {
+	Sheet string
+	Line int
+	Message string
+}
+
+type RowErrors []RowError
+
+func (errs RowErrors) Error() string {
+	return strings.Join(errs.Messages(), "\n")
+}
+
+func (errs RowErrors) Messages() []string {
+	ret := []string{}
+	for _, e := range errs {
+		if e.Sheet != "" {
+			ret = append(ret, fmt.Sprintf("シート '%s' %d行目: %s", e.Sheet, e.Line, e.Message))
+		} else {
+			ret = append(ret, fmt.Sprintf("%d行目: %s", e.Line, e.Message))
+		}
+	}
+	return ret
+}
+
+const (
+	colTable = iota
+	colColumn
+	colLabel
+	colDataType
+	colLength
+	colNotNull
+	colPrimaryKey
+	colDefault
+	colForeignKey
+	colCount
+)
+
+// 見出し行のA列 (この行より上の表題などは読み飛ばす)
+var headerNames = []string{"テーブル名", "テーブル物理名", "table", "table_name"}
+
+// ○ などの印
+var trueValues = []string{"○", "〇", "◯", "●", "✓", "✔", "*", "Y", "YES", "TRUE", "1"}
+var falseValues = []string{"", "-", "－", "ー", "×", "N", "NO", "FALSE", "0"}
+
+var reIdentifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+var reSheetDataType = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_ ]*(\(\s*\d+\s*(,\s*\d+\s*)?\))?( UNSIGNED)?(\[\])?$`)
+var reLength = regexp.MustCompile(`^(\d+)(?:\s*[,，]\s*(\d+))?$`)
+var reForeignKey = regexp.MustCompile(`^([A-Za-z_][A-Za-z0-9_]*)\s*(?:\.\s*([A-Za-z_][A-Za-z0-9_]*)|\(\s*([A-Za-z_][A-Za-z0-9_]*)\s*\))$`)
+
+
+type csvReader struct {}
+
+func (r csvReader) Read(src []byte, rdbms string) (Definition, error) {
+	records, err := readCsv(src)
+	if err != nil {
+		return Definition{}, err
+	}
+	return parseTableDef(records, "", rdbms)
+}
+
+type xlsxReader struct {}
+
+func (r xlsxReader) Read(src []byte, rdbms string) (Definition, error) {
+	records, sheet, err := readXlsx(src)
+	if err != nil {
+		return Definition{}, err
+	}
+	return parseTableDef(records, sheet, rdbms)
+}
+
+// UTF-8 (BOM付きを含む) でなければ Shift_JIS として読み取る
+func readCsv(data []byte) ([][]string, error) {
+	data = bytes.TrimPrefix(data, []byte("\xEF\xBB\xBF"))
+	if !utf8.Valid(data) {
+		b, err := japanese.ShiftJIS.NewDecoder().Bytes(data)
+		if err != nil {
+			return nil, fmt.Errorf("CSVの文字コードを判定できませんでした。（UTF-8 または Shift_JIS）")
+		}
+		data = b
+	}
+	cr := csv.NewReader(bytes.NewReader(data))
+	cr.FieldsPerRecord = -1
+	records, err := cr.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("CSVを読み込めませんでした。（%s）", err.Error())
+	}
+	return records, nil
+}
+
+
+// 1カラム分の行
+type row struct {
+	line int
+	cells []string
+	pkSeq int
+}
+
+func (r row) cell(i int) string {
+	if i >= len(r.cells) {
+		return ""
+	}
+	return strings.TrimSpace(r.cells[i])
+}
+
+func parseTableDef(records [][]string, sheet string, rdbms string) (Definition, error) {
+	header := -1
+	for i, rec := range records {
+		if len(rec) > 0 && containsFold(headerNames, strings.TrimSpace(rec[0])) {
+			header = i
+			break
+		}
+	}
+	if header < 0 {
+		return Definition{}, fmt.Errorf("見出し行（A列が「テーブル名」の行）が見つかりません。")
+	}
+
+	errs := RowErrors{}
+	names := []string{}
+	rows := map[string][]row{}
+	current := ""
+	for i := header + 1; i < len(records); i++ {
+		r := row{line: i + 1, cells: records[i]}
+		if isBlank(r) {
+			continue
+		}
+		if tn := r.cell(colTable); tn != "" {
+			current = tn
+		}
+		if current == "" {
+			errs = append(errs, RowError{Line: r.line, Message: "テーブル名が指定されていません。"})
+			continue
+		}
+		if !reIdentifier.MatchString(current) {
+			errs = append(errs, RowError{Line: r.line, Message: fmt.Sprintf("テーブル名 '%s' は英数字と _ で指定してください。", current)})
+			continue
+		}
+		if _, ok := rows[current]; !ok {
+			names = append(names, current)
+		}
+		rows[current] = append(rows[current], r)
+	}
+
+	ret := Definition{Comments: map[string]string{}, Labels: map[string]string{}}
+	for _, tn := range names {
+		table, rerrs := parseTable(tn, rows[tn], rdbms, ret.Labels)
+		errs = append(errs, rerrs...)
+		ret.Tables = append(ret.Tables, table)
+	}
+	errs = append(errs, validateForeignKeys(ret.Tables, rows)...)
+
+	if len(errs) > 0 {
+		sort.SliceStable(errs, func(i, j int) bool { return errs[i].Line < errs[j].Line })
+		for i := range errs {
+			errs[i].Sheet = sheet
+		}
+		return Definition{}, errs
+	}
+	if len(ret.Tables) == 0 {
+		return Definition{}, fmt.Errorf("テーブルが見つかりません。")
+	}
+	return ret, nil
+}
+
+func parseTable(tn string, rows []row, rdbms string, labels map[string]string) (ddlparse.Table, RowErrors) {
+	table := ddlparse.Table{Name: tn}
+	errs := RowErrors{}
+
+	//主キーの印を先に読み取る (1カラムの主キーはカラムの制約とするため)
+	pks := []row{}
+	for i := range rows {
+		seq, err := parsePrimaryKey(rows[i].cell(colPrimaryKey), len(pks) + 1)
+		if err != nil {
+			errs = append(errs, RowError{Line: rows[i].line, Message: err.Error()})
+			continue
+		}
+		if seq > 0 {
+			rows[i].pkSeq = seq
+			pks = append(pks, rows[i])
+		}
+	}
+
+	for _, r := range rows {
+		cn := r.cell(colColumn)
+		if cn == "" {
+			errs = append(errs, RowError{Line: r.line, Message: "カラム名が指定されていません。"})
+			continue
+		}
+		if !reIdentifier.MatchString(cn) {
+			errs = append(errs, RowError{Line: r.line, Message: fmt.Sprintf("カラム名 '%s' は英数字と _ で指定してください。", cn)})
+			continue
+		}
+		if containsColumn(table, cn) {
+			errs = append(errs, RowError{Line: r.line, Message: fmt.Sprintf("カラム '%s' が重複しています。", cn)})
+			continue
+		}
+		column, err := parseColumn(tn, r, len(pks) == 1 && r.pkSeq > 0, rdbms)
+		if err != nil {
+			errs = append(errs, RowError{Line: r.line, Message: err.Error()})
+			continue
+		}
+		table.Columns = append(table.Columns, column)
+		if label := r.cell(colLabel); label != "" {
+			labels[tn + "." + cn] = label
+		}
+	}
+
+	if len(pks) > 1 {
+		pkcols := make([]string, len(pks))
+		for _, r := range pks {
+			if r.pkSeq > len(pks) || pkcols[r.pkSeq - 1] != "" {
+				errs = append(errs, RowError{Line: r.line, Message: fmt.Sprintf("PKの順序 %d が正しくありません。", r.pkSeq)})
+				continue
+			}
+			pkcols[r.pkSeq - 1] = r.cell(colColumn)
+		}
+		table.Constraints.PrimaryKey = append(table.Constraints.PrimaryKey, ddlparse.PrimaryKey{ColumnNames: pkcols})
+	}
+	return table, errs
+}
+
+/*
+ 行からカラム定義を組み立てて ddlparse で解析する
+ (データ型・デフォルト値の表記は DDL ファイルと同じ規則で検証する)
+*/
+func parseColumn(tn string, r row, isPrimaryKey bool, rdbms string) (ddlparse.Column, error) {
+	dt := strings.ToUpper(r.cell(colDataType))
+	if dt == "" {
+		return ddlparse.Column{}, fmt.Errorf("データ型が指定されていません。")
+	}
+	if !reSheetDataType.MatchString(dt) || !isKnownDataType(dt) {
+		return ddlparse.Column{}, fmt.Errorf("データ型 '%s' は指定できません。", r.cell(colDataType))
+	}
+	if length := r.cell(colLength); length != "" {
+		m := reLength.FindStringSubmatch(length)
+		if m == nil {
+			return ddlparse.Column{}, fmt.Errorf("長さ '%s' は 10 または 10,2 の形式で指定してください。", length)
+		}
+		if strings.Contains(dt, "(") {
+			return ddlparse.Column{}, fmt.Errorf("長さがデータ型と長さの列の両方に指定されています。")
+		}
+		digits := m[1]
+		if m[2] != "" {
+			digits += "," + m[2]
+		}
+		if i := strings.Index(dt, " UNSIGNED"); i >= 0 {
+			dt = dt[:i] + "(" + digits + ")" + dt[i:]
+		} else {
+			dt += "(" + digits + ")"
+		}
+	}
+	//CHAR は省略すると長さ 1 となるため指定させる
+	if rdbms == "mysql" && (requiresLength(dt, rdbms) || dt == "CHAR") {
+		return ddlparse.Column{}, fmt.Errorf("%s には長さが必要です。", dt)
+	}
+	if rdbms == "sqlite3" {
+		dt = sqliteAffinity(dt)
+	}
+
+	notNull, err := parseMark(r.cell(colNotNull), "NOT NULL")
+	if err != nil {
+		return ddlparse.Column{}, err
+	}
+
+	def := dt
+	if notNull {
+		def += " NOT NULL"
+	}
+	dflt := r.cell(colDefault)
+	upper := strings.ToUpper(dflt)
+	if upper == "AUTOINCREMENT" || upper == "AUTO_INCREMENT" {
+		switch rdbms {
+		case "postgresql":
+			serial, ok := postgresqlSerialTypes[dt]
+			if !ok {
+				return ddlparse.Column{}, fmt.Errorf("自動採番は整数型 (SMALLINT / INTEGER / BIGINT) のみ指定できます。")
+			}
+			def = strings.Replace(def, dt, serial, 1)
+		case "mysql":
+			def += " AUTO_INCREMENT"
+		default:
+			if !isPrimaryKey {
+				return ddlparse.Column{}, fmt.Errorf("AUTOINCREMENT は1カラムの主キーのみ指定できます。")
+			}
+			def += " PRIMARY KEY AUTOINCREMENT"
+			isPrimaryKey = false
+		}
+	} else if dflt != "" {
+		def += " DEFAULT " + dflt
+	}
+	if isPrimaryKey {
+		def += " PRIMARY KEY"
+	}
+
+	cn := r.cell(colColumn)
+	ddl := fmt.Sprintf("CREATE TABLE %s (%s %s);", tn, cn, def)
+	tables, err := ddlparse.Parse(ddl, rdbmsOf(rdbms))
+	if err != nil || len(tables) != 1 || len(tables[0].Columns) != 1 {
+		return ddlparse.Column{}, fmt.Errorf("カラム定義 '%s %s' を解釈できません。（データ型・デフォルト値を確認してください）", cn, def)
+	}
+	return tables[0].Columns[0], nil
+}
+
+/*
+ 外部キー (同じテーブルを参照する行は1つの複合外部キーとする)
+ 参照先は定義書にあるテーブルのカラムであること
+*/
+func validateForeignKeys(tables []ddlparse.Table, rows map[string][]row) RowErrors {
+	errs := RowErrors{}
+	for i := range tables {
+		table := &tables[i]
+		index := map[string]int{}
+		for _, r := range rows[table.Name] {
+			fk := r.cell(colForeignKey)
+			if fk == "" {
+				continue
+			}
+			m := reForeignKey.FindStringSubmatch(fk)
+			if m == nil {
+				errs = append(errs, RowError{Line: r.line, Message: fmt.Sprintf("外部キー '%s' は <テーブル名>.<カラム名> の形式で指定してください。", fk)})
+				continue
+			}
+			refTable, refColumn := m[1], m[2] + m[3]
+			refRows, found := rows[refTable]
+			if !found {
+				errs = append(errs, RowError{Line: r.line, Message: fmt.Sprintf("外部キーの参照先のテーブル '%s' が定義書にありません。", refTable)})
+				continue
+			}
+			if !containsRow(refRows, refColumn) {
+				errs = append(errs, RowError{Line: r.line, Message: fmt.Sprintf("外部キーの参照先 '%s.%s' が見つかりません。", refTable, refColumn)})
+				continue
+			}
+			j, found := index[refTable]
+			if !found {
+				j = len(table.Constraints.ForeignKey)
+				index[refTable] = j
+				table.Constraints.ForeignKey = append(table.Constraints.ForeignKey, ddlparse.ForeignKey{
+					References: ddlparse.Reference{TableName: refTable},
+				})
+			}
+			fkc := &table.Constraints.ForeignKey[j]
+			fkc.ColumnNames = append(fkc.ColumnNames, r.cell(colColumn))
+			fkc.References.ColumnNames = append(fkc.References.ColumnNames, refColumn)
+		}
+	}
+	return errs
+}
+
+
+/*
+ いずれかのRDBMSの型 (長さ・UNSIGNED・[] を除いた名前で判定)
+ SQLite は任意の型名を受け付けるが、誤記を NUMERIC として扱わないようにする
+*/
+func isKnownDataType(dt string) bool {
+	name := strings.TrimSuffix(dt, "[]")
+	name = strings.TrimSuffix(name, " UNSIGNED")
+	if i := strings.Index(name, "("); i >= 0 {
+		name = name[:i]
+	}
+	t := &translator{}
+	return t.genericType(&sqlColumn{typeName: strings.TrimSpace(name)}).kind != "unknown"
+}
+
+
+// 印あり: 1以上の順序 (数字の場合はその値、それ以外は next)
+func parsePrimaryKey(s string, next int) (int, error) {
+	if n, err := strconv.Atoi(s); err == nil && n >= 1 {
+		return n, nil
+	}
+	mark, err := parseMark(s, "PK")
+	if err != nil || !mark {
+		return 0, err
+	}
+	return next, nil
+}
+
+func parseMark(s string, name string) (bool, error) {
+	if containsFold(trueValues, s) || strings.EqualFold(s, name) {
+		return true, nil
+	}
+	if containsFold(falseValues, s) {
+		return false, nil
+	}
+	return false, fmt.Errorf("%s の値 '%s' は ○ または空欄で指定してください。", name, s)
+}
+
+func isBlank(r row) bool {
+	for i := 0; i < colCount; i++ {
+		if r.cell(i) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+func containsRow(rows []row, column string) bool {
+	for _, r := range rows {
+		if r.cell(colColumn) == column {
+			return true
+		}
+	}
+	return false
+}
+
+func containsColumn(table ddlparse.Table, name string) bool {
+	for _, c := range table.Columns {
+		if c.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func containsFold(ls []string, s string) bool {
+	for _, v := range ls {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
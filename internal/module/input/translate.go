@@ -0,0 +1,814 @@
+package input
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"strconv"
+	"github.com/kodaimura/ddlparse"
+)
+
+
+/*
+ 他のRDBMSのDDLを rdbms のテーブル定義に変換する (例: MySQL のDDLから PostgreSQL のアプリを生成)
+ 型・自動採番・識別子の引用符・デフォルト値・CHECK 制約を変換し、
+ 変換できなかった構文は Definition.Notes に記録する (create-table.sql は変換後のテーブル定義から作成)
+*/
+func Translate(src []byte, from string, to string) (Definition, error) {
+	for _, rdbms := range []string{from, to} {
+		if !isRdbms(rdbms) {
+			return Definition{}, fmt.Errorf("RDBMS '%s' は指定できません。", rdbms)
+		}
+	}
+	p, err := readSql(string(src), from)
+	if err != nil {
+		return Definition{}, err
+	}
+	t := &translator{
+		from: from,
+		to: to,
+		tables: p.tables,
+		enums: p.enums,
+		notes: p.notes,
+	}
+	tables, err := t.translate()
+	if err != nil {
+		return Definition{}, err
+	}
+	return Definition{Tables: tables, Comments: p.comments, Labels: map[string]string{}, Notes: t.notes}, nil
+}
+
+type translator struct {
+	from string
+	to string
+	tables []*sqlTable
+	enums map[string][]string
+	notes []string
+	// 主キー・ユニーク・外部キーのカラム (キーは <テーブル名>.<カラム名>、MySQL の TEXT 型は使用できない)
+	keys map[string]bool
+}
+
+func (t *translator) note(format string, a ...interface{}) {
+	msg := fmt.Sprintf(format, a...)
+	for _, n := range t.notes {
+		if n == msg {
+			return
+		}
+	}
+	t.notes = append(t.notes, msg)
+}
+
+func (t *translator) translate() ([]ddlparse.Table, error) {
+	t.keys = map[string]bool{}
+	for _, st := range t.tables {
+		key := func(tn string, cols []string) {
+			for _, cn := range cols {
+				t.keys[strings.ToLower(tn + "." + cn)] = true
+			}
+		}
+		key(st.name, st.primaryKey)
+		for _, u := range st.uniques {
+			key(st.name, u.ColumnNames)
+		}
+		for _, fk := range st.foreignKeys {
+			key(st.name, fk.columns)
+			key(fk.ref.table, fk.ref.columns)
+		}
+		for _, c := range st.columns {
+			if c.primaryKey || c.unique || c.ref != nil {
+				key(st.name, []string{c.name})
+			}
+			if c.ref != nil {
+				key(c.ref.table, c.ref.columns)
+			}
+		}
+	}
+
+	tables := []ddlparse.Table{}
+	for _, st := range t.tables {
+		table, err := t.translateTable(st)
+		if err != nil {
+			return nil, err
+		}
+		tables = append(tables, table)
+	}
+	return tables, nil
+}
+
+func (t *translator) translateTable(st *sqlTable) (ddlparse.Table, error) {
+	if err := validateIdentifier("テーブル名", st.name, t.to); err != nil {
+		return ddlparse.Table{}, err
+	}
+	table := ddlparse.Table{Name: st.name}
+
+	//カラム名は定義の表記に揃える
+	columnNames := func(cols []string) ([]string, error) {
+		ret := []string{}
+		for _, cn := range cols {
+			c := st.column(cn)
+			if c == nil {
+				return nil, fmt.Errorf("テーブル '%s': カラム '%s' が見つかりません。", st.name, cn)
+			}
+			ret = append(ret, c.name)
+		}
+		return ret, nil
+	}
+	pks, err := columnNames(st.primaryKey)
+	if err != nil {
+		return ddlparse.Table{}, err
+	}
+	autoincrements := []string{}
+	for _, sc := range st.columns {
+		if sc.primaryKey {
+			pks = append(pks, sc.name)
+		}
+	}
+
+	for _, sc := range st.columns {
+		if err := validateIdentifier("カラム名", sc.name, t.to); err != nil {
+			return ddlparse.Table{}, err
+		}
+		c, ai, err := t.translateColumn(st, sc, pks, &table)
+		if err != nil {
+			return ddlparse.Table{}, err
+		}
+		table.Columns = append(table.Columns, c)
+		if ai {
+			autoincrements = append(autoincrements, c.Name)
+		}
+	}
+	if len(pks) > 0 {
+		setPrimaryKey(&table, pks)
+	}
+	for _, cn := range autoincrements {
+		t.setAutoincrement(&table, findColumn(&table, cn))
+	}
+
+	for _, u := range st.uniques {
+		cols, err := columnNames(u.ColumnNames)
+		if err != nil {
+			return ddlparse.Table{}, err
+		}
+		setUnique(&table, constraintName(u.Name), cols)
+	}
+	for _, ck := range st.checks {
+		if expr, ok := t.translateExpr(ck.expr, st.name); ok {
+			table.Constraints.Check = append(table.Constraints.Check, ddlparse.Check{Name: constraintName(ck.name), Expr: expr})
+		}
+	}
+	for _, fk := range st.foreignKeys {
+		cols, err := columnNames(fk.columns)
+		if err != nil {
+			return ddlparse.Table{}, err
+		}
+		ref, err := t.translateReference(st.name, fk.ref)
+		if err != nil {
+			return ddlparse.Table{}, err
+		}
+		table.Constraints.ForeignKey = append(table.Constraints.ForeignKey, ddlparse.ForeignKey{
+			Name: constraintName(fk.name), ColumnNames: cols, References: ref,
+		})
+	}
+	return table, nil
+}
+
+// 制約名 (英数字と _ 以外を含む場合は名前を付けない)
+func constraintName(name string) string {
+	if !reIdentifier.MatchString(name) {
+		return ""
+	}
+	return name
+}
+
+// カラムの変換 (戻り値の bool は自動採番)
+func (t *translator) translateColumn(st *sqlTable, sc *sqlColumn, pks []string, table *ddlparse.Table) (ddlparse.Column, bool, error) {
+	name := st.name + "." + sc.name
+	c := ddlparse.Column{Name: sc.name}
+	c.Constraint.IsNotNull = sc.notNull
+	c.Constraint.IsUnique = sc.unique
+	isKey := t.keys[strings.ToLower(name)]
+
+	g := t.genericType(sc)
+	c.DataType = t.dataType(g, sc, isKey, name)
+	if g.kind == "enum" {
+		setEnum(&c, g.values, t.to)
+	}
+
+	ai := sc.autoincrement || g.serial
+	//SQLite の INTEGER PRIMARY KEY は ROWID (自動採番)
+	if t.from == "sqlite3" && sc.typeName == "INTEGER" && len(pks) == 1 && strings.EqualFold(pks[0], sc.name) {
+		ai = true
+	}
+	if len(sc.def) > 0 && isNextval(sc.def) {
+		ai = true
+	} else if len(sc.def) > 0 && !ai {
+		c.Constraint.Default = t.translateDefault(sc.def, g, c.DataType, name)
+	}
+
+	for _, expr := range sc.checks {
+		e, ok := t.translateExpr(expr, st.name)
+		if !ok {
+			continue
+		}
+		if c.Constraint.Check == "" {
+			c.Constraint.Check = e
+		} else {
+			table.Constraints.Check = append(table.Constraints.Check, ddlparse.Check{Expr: e})
+		}
+	}
+
+	if sc.ref != nil {
+		ref, err := t.translateReference(st.name, *sc.ref)
+		if err != nil {
+			return ddlparse.Column{}, false, err
+		}
+		c.Constraint.References = ref
+	}
+	return c, ai, nil
+}
+
+// 自動採番 (PostgreSQL は SERIAL 型、SQLite は1カラムの整数型の主キー、MySQL はキーのカラム)
+func (t *translator) setAutoincrement(table *ddlparse.Table, c *ddlparse.Column) {
+	if t.to == "mysql" && !c.Constraint.IsPrimaryKey && !c.Constraint.IsUnique && !t.keys[strings.ToLower(table.Name + "." + c.Name)] {
+		t.note("カラム '%s.%s': MySQL の自動採番はキーのカラムのみ指定できるため変換しません。", table.Name, c.Name)
+		return
+	}
+	if err := setAutoincrement(c, t.to); err != nil {
+		t.note("カラム '%s.%s': %s の自動採番は整数型の主キー (1カラム) のみのため変換しません。", table.Name, c.Name, map[string]string{"postgresql": "PostgreSQL", "sqlite3": "SQLite"}[t.to])
+	}
+}
+
+func (t *translator) translateReference(tn string, ref sqlReference) (ddlparse.Reference, error) {
+	if err := validateIdentifier("テーブル名", ref.table, t.to); err != nil {
+		return ddlparse.Reference{}, err
+	}
+	if ref.hasActions {
+		t.note("テーブル '%s': 外部キーの ON DELETE / ON UPDATE は変換しません。", tn)
+	}
+	cols := ref.columns
+	if len(cols) == 0 {
+		//REFERENCES t は参照先の主キー
+		for _, st := range t.tables {
+			if strings.EqualFold(st.name, ref.table) {
+				cols = append(cols, st.primaryKey...)
+				for _, c := range st.columns {
+					if c.primaryKey {
+						cols = append(cols, c.name)
+					}
+				}
+			}
+		}
+		if len(cols) == 0 {
+			return ddlparse.Reference{}, fmt.Errorf("テーブル '%s': 外部キーの参照先 '%s' のカラムが見つかりません。", tn, ref.table)
+		}
+	}
+	return ddlparse.Reference{TableName: ref.table, ColumnNames: cols}, nil
+}
+
+
+// 型の分類 (変換先の型を決めるための中間の表現)
+type genericType struct {
+	// int / bool / decimal / money / float / double / char / varchar / text / blob / date / time / timestamp /
+	// year / interval / json / jsonb / uuid / enum / set / bit / inet / xml / unknown
+	kind string
+	// 整数型のバイト数
+	size int
+	// SERIAL 型 (PostgreSQL)
+	serial bool
+	// タイムゾーン付き (PostgreSQL)
+	tz bool
+	values []string
+}
+
+func (t *translator) genericType(sc *sqlColumn) genericType {
+	name := sc.typeName
+	switch name {
+	case "TINYINT":
+		return genericType{kind: "int", size: 1}
+	case "SMALLINT", "INT2":
+		return genericType{kind: "int", size: 2}
+	case "MEDIUMINT":
+		return genericType{kind: "int", size: 3}
+	case "INT", "INTEGER", "INT4":
+		return genericType{kind: "int", size: 4}
+	case "BIGINT", "INT8":
+		return genericType{kind: "int", size: 8}
+	case "SMALLSERIAL", "SERIAL2":
+		return genericType{kind: "int", size: 2, serial: true}
+	case "SERIAL", "SERIAL4":
+		return genericType{kind: "int", size: 4, serial: true}
+	case "BIGSERIAL", "SERIAL8":
+		return genericType{kind: "int", size: 8, serial: true}
+	case "BOOLEAN", "BOOL":
+		return genericType{kind: "bool"}
+	case "DECIMAL", "NUMERIC", "DEC", "FIXED":
+		return genericType{kind: "decimal"}
+	case "MONEY":
+		return genericType{kind: "money"}
+	case "FLOAT4":
+		return genericType{kind: "float"}
+	case "REAL":
+		//PostgreSQL の REAL は単精度、MySQL・SQLite は倍精度
+		if t.from == "postgresql" {
+			return genericType{kind: "float"}
+		}
+		return genericType{kind: "double"}
+	case "FLOAT":
+		//MySQL の FLOAT は単精度、PostgreSQL・SQLite は倍精度 (FLOAT(p) は p が 24 以下で単精度)
+		if (sc.n > 0 && sc.n <= 24) || (sc.n == 0 && t.from == "mysql") {
+			return genericType{kind: "float"}
+		}
+		return genericType{kind: "double"}
+	case "DOUBLE", "DOUBLE PRECISION", "FLOAT8":
+		return genericType{kind: "double"}
+	case "CHAR", "CHARACTER", "NCHAR", "NATIONAL CHAR", "NATIONAL CHARACTER":
+		return genericType{kind: "char"}
+	case "VARCHAR", "CHARACTER VARYING", "CHAR VARYING", "NVARCHAR", "VARCHAR2",
+		"NATIONAL CHARACTER VARYING", "NATIONAL CHAR VARYING":
+		return genericType{kind: "varchar"}
+	case "TEXT", "TINYTEXT", "MEDIUMTEXT", "LONGTEXT", "LONG VARCHAR", "CLOB", "CITEXT", "NTEXT":
+		return genericType{kind: "text"}
+	case "BLOB", "TINYBLOB", "MEDIUMBLOB", "LONGBLOB", "BYTEA", "BINARY", "VARBINARY", "LONG VARBINARY":
+		return genericType{kind: "blob"}
+	case "DATE":
+		return genericType{kind: "date"}
+	case "TIME":
+		return genericType{kind: "time"}
+	case "TIMETZ", "TIME WITH TIME ZONE":
+		return genericType{kind: "time", tz: true}
+	case "DATETIME", "TIMESTAMP":
+		return genericType{kind: "timestamp"}
+	case "TIMESTAMPTZ", "TIMESTAMP WITH TIME ZONE":
+		return genericType{kind: "timestamp", tz: true}
+	case "YEAR":
+		return genericType{kind: "year"}
+	case "INTERVAL":
+		return genericType{kind: "interval"}
+	case "JSON":
+		return genericType{kind: "json"}
+	case "JSONB":
+		return genericType{kind: "jsonb"}
+	case "UUID":
+		return genericType{kind: "uuid"}
+	case "ENUM":
+		return genericType{kind: "enum", values: sc.values}
+	case "SET":
+		return genericType{kind: "set", values: sc.values}
+	case "BIT", "BIT VARYING", "VARBIT":
+		return genericType{kind: "bit"}
+	case "INET", "CIDR", "MACADDR", "MACADDR8":
+		return genericType{kind: "inet"}
+	case "XML":
+		return genericType{kind: "xml"}
+	}
+	if values, ok := t.enums[name]; ok {
+		return genericType{kind: "enum", values: values}
+	}
+	//SQLite は任意の型名 (型親和性で分類する)
+	if t.from == "sqlite3" && name != "" {
+		switch sqliteAffinity(name) {
+		case "INTEGER":
+			return genericType{kind: "int", size: 8}
+		case "TEXT":
+			return genericType{kind: "text"}
+		case "REAL":
+			return genericType{kind: "double"}
+		case "NONE":
+			return genericType{kind: "blob"}
+		}
+		return genericType{kind: "decimal"}
+	}
+	return genericType{kind: "unknown"}
+}
+
+// 変換先の型 (isKey: 主キー・ユニーク・外部キーのカラム)
+func (t *translator) dataType(g genericType, sc *sqlColumn, isKey bool, name string) ddlparse.DataType {
+	n, m := sc.n, sc.m
+	if sc.array && t.to != "postgresql" {
+		t.note("カラム '%s': 配列型は %s とします。", name, map[string]string{"mysql": "JSON", "sqlite3": "TEXT"}[t.to])
+		if t.to == "mysql" {
+			return ddlparse.DataType{Name: "JSON"}
+		}
+		return ddlparse.DataType{Name: "TEXT"}
+	}
+	if g.kind == "unknown" && sc.typeName == "" {
+		t.note("カラム '%s': データ型の指定がないため文字列型とします。", name)
+	} else if g.kind == "unknown" {
+		t.note("カラム '%s': データ型 '%s' は変換できないため文字列型とします。", name, sc.typeName)
+	}
+	if g.kind == "set" {
+		t.note("カラム '%s': SET 型は文字列型とします (値の検証は行いません)。", name)
+		n = len(strings.Join(g.values, ","))
+	}
+	if g.kind == "interval" && t.to != "postgresql" {
+		t.note("カラム '%s': INTERVAL 型は文字列型とします。", name)
+	}
+	if g.tz && t.to != "postgresql" {
+		t.note("カラム '%s': タイムゾーンは保持しません。", name)
+	}
+
+	var dt ddlparse.DataType
+	switch t.to {
+	case "postgresql":
+		dt = postgresqlDataType(g, n, m, sc.unsigned)
+	case "mysql":
+		dt = mysqlDataType(g, n, m, sc.unsigned)
+		//TEXT・BLOB 型はキーに使用できない
+		if isKey && strings.Contains(dt.Name, "TEXT") {
+			t.note("カラム '%s': キーのカラムは VARCHAR(255) とします。", name)
+			dt = ddlparse.DataType{Name: "VARCHAR", DigitN: 255}
+		} else if isKey && strings.Contains(dt.Name, "BLOB") {
+			t.note("カラム '%s': キーのカラムは VARBINARY(255) とします。", name)
+			dt = ddlparse.DataType{Name: "VARBINARY", DigitN: 255}
+		}
+		if g.kind == "decimal" && n == 0 {
+			t.note("カラム '%s': 精度の指定がない数値型は DECIMAL(65,30) とします。", name)
+		}
+	default:
+		dt = ddlparse.DataType{Name: sqliteGenericAffinity(g)}
+	}
+	if sc.array {
+		dt.Name += "[]"
+	}
+	return dt
+}
+
+func postgresqlDataType(g genericType, n int, m int, unsigned bool) ddlparse.DataType {
+	switch g.kind {
+	case "int":
+		size := g.size
+		if unsigned {
+			//符号なしは1つ大きい型
+			size = map[int]int{1: 2, 2: 4, 3: 4, 4: 8, 8: 16}[size]
+		}
+		switch {
+		case size <= 2:
+			return ddlparse.DataType{Name: "SMALLINT"}
+		case size <= 4:
+			return ddlparse.DataType{Name: "INTEGER"}
+		case size <= 8:
+			return ddlparse.DataType{Name: "BIGINT"}
+		}
+		return ddlparse.DataType{Name: "NUMERIC", DigitN: 20}
+	case "bool":
+		return ddlparse.DataType{Name: "BOOLEAN"}
+	case "decimal":
+		return ddlparse.DataType{Name: "NUMERIC", DigitN: n, DigitM: m}
+	case "money":
+		return ddlparse.DataType{Name: "MONEY"}
+	case "float":
+		return ddlparse.DataType{Name: "REAL"}
+	case "double":
+		return ddlparse.DataType{Name: "DOUBLE PRECISION"}
+	case "char":
+		return ddlparse.DataType{Name: "CHAR", DigitN: max(n, 1)}
+	case "varchar", "set":
+		return ddlparse.DataType{Name: "VARCHAR", DigitN: n}
+	case "blob":
+		return ddlparse.DataType{Name: "BYTEA"}
+	case "date":
+		return ddlparse.DataType{Name: "DATE"}
+	case "time":
+		if g.tz {
+			return ddlparse.DataType{Name: "TIMETZ", DigitN: n}
+		}
+		return ddlparse.DataType{Name: "TIME", DigitN: n}
+	case "timestamp":
+		if g.tz {
+			return ddlparse.DataType{Name: "TIMESTAMPTZ", DigitN: n}
+		}
+		return ddlparse.DataType{Name: "TIMESTAMP", DigitN: n}
+	case "year":
+		return ddlparse.DataType{Name: "SMALLINT"}
+	case "interval":
+		return ddlparse.DataType{Name: "INTERVAL"}
+	case "json":
+		return ddlparse.DataType{Name: "JSON"}
+	case "jsonb":
+		return ddlparse.DataType{Name: "JSONB"}
+	case "uuid":
+		return ddlparse.DataType{Name: "UUID"}
+	case "bit":
+		return ddlparse.DataType{Name: "BIT", DigitN: max(n, 1)}
+	case "inet":
+		return ddlparse.DataType{Name: "INET"}
+	case "xml":
+		return ddlparse.DataType{Name: "XML"}
+	}
+	return ddlparse.DataType{Name: "TEXT"}
+}
+
+func mysqlDataType(g genericType, n int, m int, unsigned bool) ddlparse.DataType {
+	switch g.kind {
+	case "int":
+		name := map[int]string{1: "TINYINT", 2: "SMALLINT", 3: "MEDIUMINT", 4: "INT", 8: "BIGINT"}[g.size]
+		if unsigned {
+			name += " UNSIGNED"
+		}
+		return ddlparse.DataType{Name: name}
+	case "bool":
+		return ddlparse.DataType{Name: "BOOLEAN"}
+	case "decimal":
+		if n == 0 {
+			return ddlparse.DataType{Name: "DECIMAL", DigitN: 65, DigitM: 30}
+		}
+		return ddlparse.DataType{Name: "DECIMAL", DigitN: n, DigitM: m}
+	case "money":
+		return ddlparse.DataType{Name: "DECIMAL", DigitN: 19, DigitM: 2}
+	case "float":
+		return ddlparse.DataType{Name: "FLOAT"}
+	case "double":
+		return ddlparse.DataType{Name: "DOUBLE"}
+	case "char":
+		return ddlparse.DataType{Name: "CHAR", DigitN: max(n, 1)}
+	case "varchar", "set":
+		if n == 0 {
+			return ddlparse.DataType{Name: "LONGTEXT"}
+		}
+		return ddlparse.DataType{Name: "VARCHAR", DigitN: n}
+	case "blob":
+		return ddlparse.DataType{Name: "LONGBLOB"}
+	case "date":
+		return ddlparse.DataType{Name: "DATE"}
+	case "time":
+		return ddlparse.DataType{Name: "TIME", DigitN: n}
+	case "timestamp":
+		return ddlparse.DataType{Name: "DATETIME", DigitN: n}
+	case "year":
+		return ddlparse.DataType{Name: "YEAR"}
+	case "interval":
+		return ddlparse.DataType{Name: "VARCHAR", DigitN: 255}
+	case "json", "jsonb":
+		return ddlparse.DataType{Name: "JSON"}
+	case "uuid":
+		return ddlparse.DataType{Name: "CHAR", DigitN: 36}
+	case "bit":
+		return ddlparse.DataType{Name: "BIT", DigitN: max(n, 1)}
+	case "inet":
+		return ddlparse.DataType{Name: "VARCHAR", DigitN: 43}
+	}
+	return ddlparse.DataType{Name: "LONGTEXT"}
+}
+
+// SQLite の型親和性 (生成するアプリで文字列として扱う型は TEXT)
+func sqliteGenericAffinity(g genericType) string {
+	switch g.kind {
+	case "int", "bool", "year", "bit":
+		return "INTEGER"
+	case "decimal", "money":
+		return "NUMERIC"
+	case "float", "double":
+		return "REAL"
+	case "blob":
+		return "NONE"
+	}
+	//変換できない型 (例: POINT) は型名から親和性を決めず文字列型とする
+	return "TEXT"
+}
+
+
+// デフォルト値の変換 (変換できない式は除く)
+func (t *translator) translateDefault(tokens []sqlToken, g genericType, dt ddlparse.DataType, name string) interface{} {
+	tokens = unwrapParens(tokens)
+	text := tokensText(tokens)
+	if len(tokens) == 2 && tokens[0].kind == sqlSymbol && (tokens[0].value == "-" || tokens[0].value == "+") && tokens[1].kind == sqlNumber {
+		tokens = []sqlToken{{sqlNumber, tokens[0].value + tokens[1].value, tokens[1].line}}
+	}
+
+	if len(tokens) == 1 {
+		tk := tokens[0]
+		switch {
+		case tk.kind == sqlString:
+			return t.literalDefault(tk.value, g, dt)
+		case tk.kind == sqlNumber:
+			if v, err := strconv.ParseFloat(strings.TrimPrefix(tk.value, "+"), 64); err == nil {
+				return t.literalDefault(v, g, dt)
+			}
+		case tk.kind == sqlWord && strings.EqualFold(tk.value, "NULL"):
+			return nil
+		case tk.kind == sqlWord && (strings.EqualFold(tk.value, "TRUE") || strings.EqualFold(tk.value, "FALSE")):
+			return t.literalDefault(strings.EqualFold(tk.value, "TRUE"), g, dt)
+		}
+	}
+
+	upper := strings.ToUpper(strings.ReplaceAll(text, " ", ""))
+	if v, ok := currentTimeFunctions[upper]; ok {
+		return t.currentDefault(v, dt)
+	}
+	switch upper {
+	case "LOCALTIME", "LOCALTIME()":
+		if t.from == "mysql" {
+			return t.currentDefault("CURRENT_TIMESTAMP", dt)
+		}
+		return t.currentDefault("CURRENT_TIME", dt)
+	case "UUID()", "GEN_RANDOM_UUID()", "UUID_GENERATE_V4()":
+		switch t.to {
+		case "postgresql":
+			return "gen_random_uuid()"
+		case "mysql":
+			return "(UUID())"
+		}
+		t.note("カラム '%s': SQLite には UUID を生成する関数がないため、デフォルト値 %s は変換しません。", name, text)
+		return nil
+	}
+	if strings.HasPrefix(upper, "CURRENT_TIMESTAMP(") || strings.HasPrefix(upper, "LOCALTIMESTAMP(") || strings.HasPrefix(upper, "NOW(") {
+		return t.currentDefault("CURRENT_TIMESTAMP", dt)
+	}
+	t.note("カラム '%s': デフォルト値 %s は変換できないため除きます。", name, text)
+	return nil
+}
+
+var reFunctionCall = regexp.MustCompile(`^\w+\(.*\)$`)
+
+// リテラルのデフォルト値 (真偽値・数値は変換先の型に合わせる)
+func (t *translator) literalDefault(v interface{}, g genericType, dt ddlparse.DataType) interface{} {
+	switch d := v.(type) {
+	case bool:
+		if g.kind != "bool" && t.to == "postgresql" {
+			if d {
+				return float64(1)
+			}
+			return float64(0)
+		}
+		return d
+	case float64:
+		if g.kind == "bool" && t.to == "postgresql" {
+			return d != 0
+		}
+		return d
+	case string:
+		if g.kind == "bool" {
+			switch strings.ToLower(d) {
+			case "t", "true", "y", "yes", "1":
+				return t.literalDefault(true, g, dt)
+			case "f", "false", "n", "no", "0":
+				return t.literalDefault(false, g, dt)
+			}
+		}
+		switch g.kind {
+		case "int", "decimal", "float", "double":
+			if f, err := strconv.ParseFloat(d, 64); err == nil {
+				return f
+			}
+		}
+		//MySQL の TEXT・BLOB・JSON 型のデフォルト値は式、関数のような文字列も式として囲む
+		upper := strings.ToUpper(d)
+		if (t.to == "mysql" && (strings.Contains(dt.Name, "TEXT") || strings.Contains(dt.Name, "BLOB") || dt.Name == "JSON")) ||
+			strings.HasPrefix(d, "(") || strings.HasPrefix(upper, "CURRENT_") || strings.HasPrefix(upper, "LOCALTIME") ||
+			reFunctionCall.MatchString(d) {
+			return "(" + quoteSqlString(d) + ")"
+		}
+		return d
+	}
+	return v
+}
+
+// 現在日時のデフォルト値 (MySQL の DATETIME(n) は同じ精度、DATETIME・TIMESTAMP 以外は式)
+func (t *translator) currentDefault(expr string, dt ddlparse.DataType) string {
+	if t.to != "mysql" {
+		return expr
+	}
+	if expr == "CURRENT_TIMESTAMP" && (dt.Name == "DATETIME" || dt.Name == "TIMESTAMP") {
+		if dt.DigitN > 0 {
+			return fmt.Sprintf("CURRENT_TIMESTAMP(%d)", dt.DigitN)
+		}
+		return expr
+	}
+	return "(" + expr + ")"
+}
+
+func isNextval(tokens []sqlToken) bool {
+	tokens = unwrapParens(tokens)
+	return len(tokens) > 0 && tokens[0].kind == sqlWord && strings.EqualFold(tokens[0].value, "nextval")
+}
+
+// 全体を囲む括弧を除く
+func unwrapParens(tokens []sqlToken) []sqlToken {
+	for len(tokens) >= 2 && tokens[0].value == "(" && tokens[0].kind == sqlSymbol && closingParen(tokens, 0) == len(tokens) - 1 {
+		tokens = tokens[1:len(tokens)-1]
+	}
+	return tokens
+}
+
+// tokens[i] の ( に対応する ) の位置
+func closingParen(tokens []sqlToken, i int) int {
+	depth := 0
+	for j := i; j < len(tokens); j++ {
+		if tokens[j].kind != sqlSymbol {
+			continue
+		}
+		switch tokens[j].value {
+		case "(":
+			depth++
+		case ")":
+			depth--
+			if depth == 0 {
+				return j
+			}
+		}
+	}
+	return -1
+}
+
+
+// どのRDBMSでも使用できる関数 (CHECK 制約)
+var portableFunctions = []string{"LENGTH", "LOWER", "UPPER", "ABS", "COALESCE", "NULLIF", "TRIM", "LTRIM", "RTRIM", "ROUND", "SUBSTR", "REPLACE"}
+
+// 式の前後が演算子のキーワード (関数ではない)
+var sqlOperatorWords = []string{"IN", "AND", "OR", "NOT", "IS", "BETWEEN", "LIKE", "EXISTS", "WHEN", "THEN", "ELSE", "CASE", "END", "ANY", "ALL", "CHECK"}
+
+/*
+ CHECK 制約の式の変換
+ PostgreSQL の型変換 (::type) を除き、pg_dump の col = ANY (ARRAY[...]) は IN (...) とする
+ RDBMS 固有の関数・演算子を含む場合は変換しない
+*/
+func (t *translator) translateExpr(tokens []sqlToken, tn string) (string, bool) {
+	tokens = rewriteAnyArray(stripCasts(tokens))
+	text := tokensText(tokens)
+	for i, tk := range tokens {
+		portable := true
+		switch tk.kind {
+		case sqlWord:
+			upper := strings.ToUpper(tk.value)
+			if containsFold([]string{"REGEXP", "RLIKE", "GLOB", "ILIKE", "SIMILAR", "ARRAY", "ANY", "ALL"}, upper) {
+				portable = false
+			} else if i + 1 < len(tokens) && tokens[i+1].value == "(" && tokens[i+1].kind == sqlSymbol {
+				portable = containsFold(portableFunctions, upper) || containsFold(sqlOperatorWords, upper)
+			}
+		case sqlQuoted:
+			portable = reIdentifier.MatchString(tk.value)
+		case sqlSymbol:
+			portable = !containsFold([]string{"::", "~", "@", "[", "]", "[]", "!"}, tk.value) && !(tk.value == "||" && t.to == "mysql")
+		}
+		if !portable {
+			t.note("テーブル '%s': CHECK 制約 %s は変換できないため除きます。", tn, text)
+			return "", false
+		}
+	}
+	return text, true
+}
+
+// ::type を除く
+func stripCasts(tokens []sqlToken) []sqlToken {
+	ret := []sqlToken{}
+	for i := 0; i < len(tokens); i++ {
+		if tokens[i].kind != sqlSymbol || tokens[i].value != "::" {
+			ret = append(ret, tokens[i])
+			continue
+		}
+		i++
+		for i + 1 < len(tokens) && tokens[i+1].kind == sqlWord &&
+			containsFold([]string{"VARYING", "PRECISION", "WITH", "WITHOUT", "TIME", "ZONE"}, tokens[i+1].value) {
+			i++
+		}
+		if i + 1 < len(tokens) && tokens[i+1].value == "(" && tokens[i+1].kind == sqlSymbol {
+			i = closingParen(tokens, i + 1)
+		}
+		if i + 1 < len(tokens) && tokens[i+1].value == "[]" {
+			i++
+		}
+	}
+	return ret
+}
+
+// = ANY (ARRAY[a, b]) を IN (a, b) とする
+func rewriteAnyArray(tokens []sqlToken) []sqlToken {
+	for i := 0; i + 2 < len(tokens); i++ {
+		if tokens[i].value != "=" || !strings.EqualFold(tokens[i+1].value, "ANY") || tokens[i+2].value != "(" {
+			continue
+		}
+		end := closingParen(tokens, i + 2)
+		inner := unwrapParens(tokens[i+2:end+1])
+		if len(inner) < 3 || !strings.EqualFold(inner[0].value, "ARRAY") || inner[1].value != "[" || inner[len(inner)-1].value != "]" {
+			continue
+		}
+		line := tokens[i].line
+		replaced := append([]sqlToken{{sqlWord, "IN", line}, {sqlSymbol, "(", line}}, inner[2:len(inner)-1]...)
+		replaced = append(replaced, sqlToken{sqlSymbol, ")", line})
+		tokens = append(append(append([]sqlToken{}, tokens[:i]...), replaced...), tokens[end+1:]...)
+	}
+	return tokens
+}
+
+// トークンを式の文字列にする
+func tokensText(tokens []sqlToken) string {
+	var b strings.Builder
+	for i, tk := range tokens {
+		if i > 0 {
+			prev := tokens[i-1]
+			noSpace := (prev.kind == sqlSymbol && (prev.value == "(" || prev.value == ".")) ||
+				(tk.kind == sqlSymbol && (tk.value == ")" || tk.value == "," || tk.value == ".")) ||
+				(tk.kind == sqlSymbol && tk.value == "(" && prev.kind == sqlWord && !containsFold(sqlOperatorWords, prev.value))
+			if !noSpace {
+				b.WriteString(" ")
+			}
+		}
+		switch tk.kind {
+		case sqlString:
+			b.WriteString(quoteSqlString(tk.value))
+		default:
+			b.WriteString(tk.value)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,228 @@
+package input
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"strconv"
+	"github.com/kodaimura/ddlparse"
+)
+
+
+/*
+ DBML・Prisma・テーブル定義書の型や制約を rdbms に合わせる
+*/
+
+func rdbmsOf(rdbms string) ddlparse.Rdbms {
+	switch rdbms {
+	case "postgresql":
+		return ddlparse.PostgreSQL
+	case "mysql":
+		return ddlparse.MySQL
+	default:
+		return ddlparse.SQLite
+	}
+}
+
+/*
+ 生成するアプリは識別子を引用符で囲まないため、英数字と _ の名前のみ変換する
+ rdbms の予約語 (例: order・group) はDDLファイルと同じく ddlparse で確認する
+*/
+func validateIdentifier(kind string, name string, rdbms string) error {
+	if !reIdentifier.MatchString(name) {
+		return fmt.Errorf("%s '%s' は英数字と _ 以外を含むため変換できません。", kind, name)
+	}
+	ddl := fmt.Sprintf("CREATE TABLE %s (x INTEGER);", name)
+	if kind == "カラム名" {
+		ddl = fmt.Sprintf("CREATE TABLE t (%s INTEGER);", name)
+	}
+	if _, err := ddlparse.Parse(ddl, rdbmsOf(rdbms)); err != nil {
+		return fmt.Errorf("%s '%s' は予約語のため使用できません。", kind, name)
+	}
+	return nil
+}
+
+var postgresqlSerialTypes = map[string]string{
+	"SMALLINT": "SMALLSERIAL",
+	"INT2": "SMALLSERIAL",
+	"INTEGER": "SERIAL",
+	"INT": "SERIAL",
+	"INT4": "SERIAL",
+	"BIGINT": "BIGSERIAL",
+	"INT8": "BIGSERIAL",
+}
+
+/*
+ SQLite は型の名前から型親和性を決める (VARCHAR(50) → TEXT など)
+ 汎用的な型名を SQLite の型に読み替える (長さは使用しない)
+ 生成するアプリで文字列として扱うため、日付・時刻・JSON・UUID は TEXT、真偽値は INTEGER とする
+*/
+func sqliteAffinity(dt string) string {
+	switch {
+	case strings.Contains(dt, "INT"):
+		return "INTEGER"
+	case strings.Contains(dt, "CHAR"), strings.Contains(dt, "CLOB"), strings.Contains(dt, "TEXT"),
+		strings.Contains(dt, "DATE"), strings.Contains(dt, "TIME"),
+		strings.Contains(dt, "JSON"), strings.Contains(dt, "UUID"):
+		return "TEXT"
+	case strings.Contains(dt, "BLOB"), strings.Contains(dt, "BYTEA"), strings.Contains(dt, "BINARY"):
+		return "NONE"
+	case strings.Contains(dt, "REAL"), strings.Contains(dt, "FLOA"), strings.Contains(dt, "DOUB"):
+		return "REAL"
+	case strings.Contains(dt, "BOOL"):
+		return "INTEGER"
+	}
+	return "NUMERIC"
+}
+
+
+var reDataType = regexp.MustCompile(`^([A-Za-z_][A-Za-z0-9_]*(?: [A-Za-z_][A-Za-z0-9_]*)*)\s*(?:\(\s*(\d+)\s*(?:,\s*(\d+)\s*)?\))?\s*(.*)$`)
+
+/*
+ 型 (例: varchar(255)) を rdbms の型にする
+ SQLite は型親和性、それ以外は大文字の名前と桁数 (UNSIGNED・[] などの接尾辞は名前に含める)
+ MySQL で長さが必要な型 (VARCHAR 等) に長さが無い場合は 191 とする
+*/
+func dataTypeOf(s string, rdbms string) (ddlparse.DataType, error) {
+	s = strings.TrimSpace(s)
+	m := reDataType.FindStringSubmatch(s)
+	if m == nil {
+		return ddlparse.DataType{}, fmt.Errorf("データ型 '%s' は指定できません。", s)
+	}
+	name := strings.ToUpper(m[1])
+	if rdbms == "sqlite3" {
+		return ddlparse.DataType{Name: sqliteAffinity(name)}, nil
+	}
+	if rest := strings.ToUpper(strings.TrimSpace(m[4])); rest == "[]" {
+		name += rest
+	} else if rest != "" {
+		name += " " + rest
+	}
+	n, _ := strconv.Atoi(m[2])
+	d, _ := strconv.Atoi(m[3])
+	//長さが無い場合は Prisma の String と同じ 191 (utf8mb4 のインデックスの上限)
+	if n == 0 && requiresLength(name, rdbms) {
+		n = 191
+	}
+	return ddlparse.DataType{Name: name, DigitN: n, DigitM: d}, nil
+}
+
+// MySQL で長さの指定が必要な型 (長さが無い場合は CREATE TABLE がエラーとなる)
+var mysqlLengthTypes = []string{"VARCHAR", "CHARACTER VARYING", "NVARCHAR", "NATIONAL VARCHAR", "VARBINARY"}
+
+func requiresLength(name string, rdbms string) bool {
+	return rdbms == "mysql" && containsFold(mysqlLengthTypes, name)
+}
+
+// 現在日時の関数 (空白を除いた大文字) → 標準SQLの値
+var currentTimeFunctions = map[string]string{
+	"CURRENT_TIMESTAMP": "CURRENT_TIMESTAMP",
+	"CURRENT_TIMESTAMP()": "CURRENT_TIMESTAMP",
+	"NOW()": "CURRENT_TIMESTAMP",
+	"LOCALTIMESTAMP": "CURRENT_TIMESTAMP",
+	"LOCALTIMESTAMP()": "CURRENT_TIMESTAMP",
+	"TRANSACTION_TIMESTAMP()": "CURRENT_TIMESTAMP",
+	"STATEMENT_TIMESTAMP()": "CURRENT_TIMESTAMP",
+	"CLOCK_TIMESTAMP()": "CURRENT_TIMESTAMP",
+	"SYSDATE()": "CURRENT_TIMESTAMP",
+	"DATETIME('NOW')": "CURRENT_TIMESTAMP",
+	"CURRENT_DATE": "CURRENT_DATE",
+	"CURRENT_DATE()": "CURRENT_DATE",
+	"CURDATE()": "CURRENT_DATE",
+	"DATE('NOW')": "CURRENT_DATE",
+	"CURRENT_TIME": "CURRENT_TIME",
+	"CURRENT_TIME()": "CURRENT_TIME",
+	"CURTIME()": "CURRENT_TIME",
+	"TIME('NOW')": "CURRENT_TIME",
+}
+
+// 式のデフォルト値の現在日時の関数 (例: now()) は rdbms で使用できる値とする (SQLite には now() が無い)
+func currentTimeDefault(expr string, dt ddlparse.DataType, rdbms string) string {
+	if v, ok := currentTimeFunctions[strings.ToUpper(strings.ReplaceAll(expr, " ", ""))]; ok {
+		t := &translator{to: rdbms}
+		return t.currentDefault(v, dt)
+	}
+	return expr
+}
+
+// 自動採番 (PostgreSQL は SERIAL 型、SQLite は1カラムの主キーのみ)
+func setAutoincrement(c *ddlparse.Column, rdbms string) error {
+	switch rdbms {
+	case "postgresql":
+		serial, ok := postgresqlSerialTypes[c.DataType.Name]
+		if !ok {
+			return fmt.Errorf("カラム '%s': 自動採番は整数型 (SMALLINT / INTEGER / BIGINT) のみ指定できます。", c.Name)
+		}
+		c.DataType = ddlparse.DataType{Name: serial}
+	case "sqlite3":
+		if !c.Constraint.IsPrimaryKey || c.DataType.Name != "INTEGER" {
+			return fmt.Errorf("カラム '%s': SQLite の自動採番は1カラムの整数型の主キーのみ指定できます。", c.Name)
+		}
+		c.Constraint.IsAutoincrement = true
+	default:
+		c.Constraint.IsAutoincrement = true
+	}
+	return nil
+}
+
+/*
+ 列挙型は文字列型と CHECK 制約とする (長さは最も長い値)
+ SQLite は TEXT、PostgreSQL・MySQL は VARCHAR
+*/
+func setEnum(c *ddlparse.Column, values []string, rdbms string) {
+	quoted := []string{}
+	length := 1
+	for _, v := range values {
+		quoted = append(quoted, quoteSqlString(v))
+		if n := len([]rune(v)); n > length {
+			length = n
+		}
+	}
+	if rdbms == "sqlite3" {
+		c.DataType = ddlparse.DataType{Name: "TEXT"}
+	} else {
+		c.DataType = ddlparse.DataType{Name: "VARCHAR", DigitN: length}
+	}
+	c.Constraint.Check = fmt.Sprintf("(%s IN (%s))", c.Name, strings.Join(quoted, ", "))
+}
+
+func quoteSqlString(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+/*
+ 主キーが1カラムの場合はカラムの制約、複数の場合はテーブルの制約とする
+ (ddlparse で解析した場合と同じ)
+*/
+func setPrimaryKey(table *ddlparse.Table, columns []string) {
+	if len(columns) == 1 {
+		for i := range table.Columns {
+			if table.Columns[i].Name == columns[0] {
+				table.Columns[i].Constraint.IsPrimaryKey = true
+			}
+		}
+		return
+	}
+	table.Constraints.PrimaryKey = append(table.Constraints.PrimaryKey, ddlparse.PrimaryKey{ColumnNames: columns})
+}
+
+func setUnique(table *ddlparse.Table, name string, columns []string) {
+	if len(columns) == 1 && name == "" {
+		for i := range table.Columns {
+			if table.Columns[i].Name == columns[0] {
+				table.Columns[i].Constraint.IsUnique = true
+			}
+		}
+		return
+	}
+	table.Constraints.Unique = append(table.Constraints.Unique, ddlparse.Unique{Name: name, ColumnNames: columns})
+}
+
+func findColumn(table *ddlparse.Table, name string) *ddlparse.Column {
+	for i := range table.Columns {
+		if table.Columns[i].Name == name {
+			return &table.Columns[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,177 @@
+package input
+
+import (
+	"io"
+	"fmt"
+	"path"
+	"bytes"
+	"strings"
+	"archive/zip"
+	"encoding/xml"
+)
+
+
+/*
+ 標準ライブラリのみで XLSX (Office Open XML) の先頭シートを読み取る
+ セルの値は文字列 (共有文字列・インライン文字列・数値は表示前の値) とし、ふりがな (rPh) は含めない
+*/
+
+// 展開後のサイズの上限 (1ファイル)
+const maxXlsxPartSize = 32 << 20
+
+type xlsxWorkbook struct {
+	Sheets []struct {
+		Name string `xml:"name,attr"`
+		Id string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+	} `xml:"sheets>sheet"`
+}
+
+type xlsxRelationships struct {
+	Relationships []struct {
+		Id string `xml:"Id,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+type xlsxText struct {
+	T string `xml:"t"`
+	R []struct {
+		T string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (t xlsxText) String() string {
+	s := t.T
+	for _, r := range t.R {
+		s += r.T
+	}
+	return s
+}
+
+type xlsxSharedStrings struct {
+	Items []xlsxText `xml:"si"`
+}
+
+type xlsxSheet struct {
+	Rows []struct {
+		R int `xml:"r,attr"`
+		Cells []struct {
+			R string `xml:"r,attr"`
+			T string `xml:"t,attr"`
+			V string `xml:"v"`
+			Is xlsxText `xml:"is"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+
+//先頭のシートの行とシート名
+func readXlsx(data []byte) ([][]string, string, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, "", fmt.Errorf("XLSXを読み込めませんでした。")
+	}
+	files := map[string]*zip.File{}
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+
+	var wb xlsxWorkbook
+	if err := readXlsxPart(files, "xl/workbook.xml", &wb); err != nil || len(wb.Sheets) == 0 {
+		return nil, "", fmt.Errorf("XLSXのシートが見つかりません。")
+	}
+	var rels xlsxRelationships
+	if err := readXlsxPart(files, "xl/_rels/workbook.xml.rels", &rels); err != nil {
+		return nil, "", fmt.Errorf("XLSXのシートが見つかりません。")
+	}
+	sheetPath := ""
+	for _, rel := range rels.Relationships {
+		if rel.Id == wb.Sheets[0].Id {
+			if strings.HasPrefix(rel.Target, "/") {
+				sheetPath = strings.TrimPrefix(rel.Target, "/")
+			} else {
+				sheetPath = path.Join("xl", rel.Target)
+			}
+		}
+	}
+
+	var ss xlsxSharedStrings
+	if _, ok := files["xl/sharedStrings.xml"]; ok {
+		if err := readXlsxPart(files, "xl/sharedStrings.xml", &ss); err != nil {
+			return nil, "", fmt.Errorf("XLSXを読み込めませんでした。")
+		}
+	}
+	var sheet xlsxSheet
+	if err := readXlsxPart(files, sheetPath, &sheet); err != nil {
+		return nil, "", fmt.Errorf("XLSXのシートを読み込めませんでした。")
+	}
+
+	records := [][]string{}
+	for _, r := range sheet.Rows {
+		//行番号 (r属性) が無い場合は前の行の次
+		line := r.R
+		if line == 0 {
+			line = len(records) + 1
+		}
+		for len(records) < line {
+			records = append(records, []string{})
+		}
+		rec := []string{}
+		for j, c := range r.Cells {
+			col := j
+			if c.R != "" {
+				col = xlsxColumnIndex(c.R)
+			}
+			for len(rec) <= col {
+				rec = append(rec, "")
+			}
+			switch c.T {
+			case "s":
+				var idx int
+				if _, err := fmt.Sscan(c.V, &idx); err == nil && idx < len(ss.Items) {
+					rec[col] = ss.Items[idx].String()
+				}
+			case "inlineStr":
+				rec[col] = c.Is.String()
+			case "b":
+				rec[col] = map[string]string{"1": "TRUE", "0": "FALSE"}[c.V]
+			default:
+				rec[col] = c.V
+			}
+		}
+		records[line - 1] = rec
+	}
+	return records, wb.Sheets[0].Name, nil
+}
+
+func readXlsxPart(files map[string]*zip.File, name string, v interface{}) error {
+	f, ok := files[name]
+	if !ok {
+		return fmt.Errorf("%s not found", name)
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	b, err := io.ReadAll(io.LimitReader(rc, maxXlsxPartSize + 1))
+	if err != nil {
+		return err
+	}
+	if len(b) > maxXlsxPartSize {
+		return fmt.Errorf("%s too large", name)
+	}
+	return xml.Unmarshal(b, v)
+}
+
+// セル参照の列 (例: C12 → 2)
+func xlsxColumnIndex(ref string) int {
+	n := 0
+	for _, ch := range ref {
+		if ch < 'A' || ch > 'Z' {
+			break
+		}
+		n = n * 26 + int(ch - 'A' + 1)
+	}
+	return n - 1
+}
@@ -0,0 +1,267 @@
+package introspect
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"database/sql"
+	"github.com/kodaimura/ddlparse"
+)
+
+
+/*
+ 接続したデータベースのテーブル定義を読み取り、
+ DDLを ddlparse で解析した場合と同じテーブルのモデルを作成する
+*/
+
+// 読み取ったテーブル定義
+type Result struct {
+	Tables []ddlparse.Table
+	// コメント (キーはテーブル名、カラムは <テーブル名>.<カラム名>)
+	Comments map[string]string
+}
+
+/*
+ 生成するアプリが作成するテーブル (読み取りの対象外)
+ 同じ名前の利用者のテーブルを除かないよう、生成するDDLのカラムを全て持つ場合のみ該当とする
+ (<table>_history は元のテーブルがあり、履歴の管理カラムを持つ場合)
+*/
+var generatedTableColumns = map[string][]string{
+	"audit_log": {"audit_log_id", "table_name", "record_key", "operation", "before_data", "after_data", "account_id", "account_name", "created_at"},
+	"api_token": {"api_token_id", "token_name", "token_hash", "token_prefix", "kind", "account_id", "role", "scopes"},
+	"users": {"user_id", "user_name", "password_hash", "role", "created_at", "updated_at"},
+	"schema_migrations": {"version", "applied_at"},
+}
+
+var historyColumns = []string{"history_id", "history_operation", "history_changed_at"}
+
+// rdbms: postgresql / mysql / sqlite3
+// schema: PostgreSQL のスキーマ (空の場合は current_schema)、MySQL・SQLite では使用しない
+// tables: 対象のテーブル名 (空の場合は全て)
+func Read(db *sql.DB, rdbms string, schema string, tables []string) (Result, error) {
+	var r reader
+	if rdbms == "postgresql" {
+		r = &postgresqlReader{db: db, schema: schema}
+	} else if rdbms == "mysql" {
+		r = &mysqlReader{db: db}
+	} else if rdbms == "sqlite3" {
+		r = &sqliteReader{db: db}
+	} else {
+		return Result{}, fmt.Errorf("RDBMS '%s' は指定できません。", rdbms)
+	}
+
+	names, err := r.tableNames()
+	if err != nil {
+		return Result{}, err
+	}
+	all := names
+	names = filterTableNames(names, tables)
+	if len(tables) > 0 {
+		for _, t := range tables {
+			if !contains(names, t) {
+				return Result{}, fmt.Errorf("テーブル '%s' が見つかりません。", t)
+			}
+		}
+	}
+
+	ret := Result{Comments: map[string]string{}}
+	for _, name := range names {
+		comments := map[string]string{}
+		table, err := r.table(name, comments)
+		if err != nil {
+			return Result{}, fmt.Errorf("%s: %w", name, err)
+		}
+		if len(tables) == 0 && isGeneratedTable(table, all) {
+			continue
+		}
+		for k, v := range comments {
+			ret.Comments[k] = v
+		}
+		ret.Tables = append(ret.Tables, table)
+	}
+	if len(ret.Tables) == 0 {
+		return Result{}, fmt.Errorf("テーブルが見つかりません。")
+	}
+	return ret, nil
+}
+
+type reader interface {
+	tableNames() ([]string, error)
+	// テーブルのモデル (コメントは comments に追加)
+	table(name string, comments map[string]string) (ddlparse.Table, error)
+}
+
+// 指定したテーブル (指定が無い場合は全て)
+func filterTableNames(names []string, tables []string) []string {
+	if len(tables) == 0 {
+		return names
+	}
+	ret := []string{}
+	for _, name := range names {
+		if contains(tables, name) {
+			ret = append(ret, name)
+		}
+	}
+	return ret
+}
+
+// 生成するアプリが作成するテーブル (監査ログ・APIトークン・ユーザ・マイグレーション・<table>_history) か
+func isGeneratedTable(table ddlparse.Table, names []string) bool {
+	name := strings.ToLower(table.Name)
+	if columns, ok := generatedTableColumns[name]; ok {
+		return hasColumns(table, columns)
+	}
+	if base, ok := strings.CutSuffix(name, "_history"); ok && containsFold(names, base) {
+		return hasColumns(table, historyColumns)
+	}
+	return false
+}
+
+func hasColumns(table ddlparse.Table, columns []string) bool {
+	for _, name := range columns {
+		found := false
+		for _, c := range table.Columns {
+			if strings.EqualFold(c.Name, name) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+
+// 主キー・一意制約・外部キー (1カラムの主キー・一意制約はカラムの制約とする)
+type constraint struct {
+	name string
+	kind string
+	columns []string
+	refTable string
+	refColumns []string
+}
+
+const (
+	kindPrimaryKey = "p"
+	kindUnique = "u"
+	kindForeignKey = "f"
+)
+
+func applyConstraints(table *ddlparse.Table, constraints []constraint) {
+	for _, con := range constraints {
+		switch con.kind {
+		case kindPrimaryKey:
+			if len(con.columns) == 1 {
+				column(table, con.columns[0]).Constraint.IsPrimaryKey = true
+			} else {
+				table.Constraints.PrimaryKey = append(table.Constraints.PrimaryKey, ddlparse.PrimaryKey{
+					Name: con.name,
+					ColumnNames: con.columns,
+				})
+			}
+		case kindUnique:
+			if len(con.columns) == 1 {
+				column(table, con.columns[0]).Constraint.IsUnique = true
+			} else {
+				table.Constraints.Unique = append(table.Constraints.Unique, ddlparse.Unique{
+					Name: con.name,
+					ColumnNames: con.columns,
+				})
+			}
+		case kindForeignKey:
+			table.Constraints.ForeignKey = append(table.Constraints.ForeignKey, ddlparse.ForeignKey{
+				Name: con.name,
+				ColumnNames: con.columns,
+				References: ddlparse.Reference{
+					TableName: con.refTable,
+					ColumnNames: con.refColumns,
+				},
+			})
+		}
+	}
+}
+
+func column(table *ddlparse.Table, name string) *ddlparse.Column {
+	for i := range table.Columns {
+		if table.Columns[i].Name == name {
+			return &table.Columns[i]
+		}
+	}
+	return &ddlparse.Column{}
+}
+
+
+var reDataType = regexp.MustCompile(`^([A-Za-z_][A-Za-z0-9_]*(?: [A-Za-z_][A-Za-z0-9_]*)*)\s*(?:\(\s*(\d+)\s*(?:,\s*(\d+)\s*)?\))?\s*(.*)$`)
+
+// 型の名前と桁数 (例: NUMERIC(10,2) → NUMERIC, 10, 2)、rest は桁数より後ろ (例: unsigned)
+func parseDataType(s string) (ddlparse.DataType, string) {
+	m := reDataType.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
+		return ddlparse.DataType{Name: strings.ToUpper(s)}, ""
+	}
+	n, _ := strconv.Atoi(m[2])
+	d, _ := strconv.Atoi(m[3])
+	return ddlparse.DataType{Name: strings.ToUpper(m[1]), DigitN: n, DigitM: d}, m[4]
+}
+
+func isNumericType(name string) bool {
+	name = strings.ToUpper(name)
+	for _, s := range []string{"INT", "SERIAL", "NUMERIC", "DECIMAL", "FLOAT", "REAL", "DOUBLE"} {
+		if strings.Contains(name, s) {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+ デフォルト値を ddlparse と同じ表現にする
+ 数値は float64、文字列リテラルは引用符を除いた文字列、NULL は nil、TRUE・FALSE は bool、
+ それ以外 (関数・式) はそのままの文字列
+*/
+func parseDefault(s string, numeric bool) interface{} {
+	s = strings.TrimSpace(s)
+	if s == "" || strings.ToUpper(s) == "NULL" {
+		return nil
+	}
+	if len(s) >= 2 && s[0] == '\'' && s[len(s)-1] == '\'' {
+		v := strings.ReplaceAll(s[1:len(s)-1], "''", "'")
+		if numeric {
+			if n, err := strconv.ParseFloat(v, 64); err == nil {
+				return n
+			}
+		}
+		return v
+	}
+	if n, err := strconv.ParseFloat(s, 64); err == nil {
+		return n
+	}
+	switch strings.ToUpper(s) {
+	case "TRUE":
+		return true
+	case "FALSE":
+		return false
+	}
+	return s
+}
+
+func contains(ls []string, s string) bool {
+	for _, v := range ls {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func containsFold(ls []string, s string) bool {
+	for _, v := range ls {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,189 @@
+package introspect
+
+import (
+	"strings"
+	"database/sql"
+	"github.com/kodaimura/ddlparse"
+)
+
+
+// information_schema から読み取る (接続先のデータベースが対象)
+type mysqlReader struct {
+	db *sql.DB
+}
+
+func (r *mysqlReader) tableNames() ([]string, error) {
+	rows, err := r.db.Query(
+		`SELECT TABLE_NAME
+		 FROM information_schema.TABLES
+		 WHERE TABLE_SCHEMA = DATABASE() AND TABLE_TYPE = 'BASE TABLE'
+		 ORDER BY TABLE_NAME`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ret := []string{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		ret = append(ret, name)
+	}
+	return ret, rows.Err()
+}
+
+func (r *mysqlReader) table(name string, comments map[string]string) (ddlparse.Table, error) {
+	table := ddlparse.Table{Name: name}
+
+	var comment string
+	err := r.db.QueryRow(
+		`SELECT TABLE_COMMENT FROM information_schema.TABLES WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ?`,
+		name,
+	).Scan(&comment)
+	if err != nil {
+		return table, err
+	}
+	if comment != "" {
+		comments[name] = comment
+	}
+
+	rows, err := r.db.Query(
+		`SELECT COLUMN_NAME, COLUMN_TYPE, IS_NULLABLE, COLUMN_DEFAULT, EXTRA, COLUMN_COMMENT
+		 FROM information_schema.COLUMNS
+		 WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ?
+		 ORDER BY ORDINAL_POSITION`,
+		name,
+	)
+	if err != nil {
+		return table, err
+	}
+	for rows.Next() {
+		var cn, ct, nullable, extra, comment string
+		var dflt sql.NullString
+		if err := rows.Scan(&cn, &ct, &nullable, &dflt, &extra, &comment); err != nil {
+			rows.Close()
+			return table, err
+		}
+		dt := mysqlDataType(ct)
+		table.Columns = append(table.Columns, ddlparse.Column{
+			Name: cn,
+			DataType: dt,
+			Constraint: ddlparse.Constraint{
+				IsNotNull: nullable == "NO",
+				IsAutoincrement: strings.Contains(strings.ToLower(extra), "auto_increment"),
+				Default: mysqlDefault(dflt, extra, isNumericType(dt.Name)),
+			},
+		})
+		if comment != "" {
+			comments[name + "." + cn] = comment
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return table, err
+	}
+
+	constraints, err := r.constraints(name)
+	if err != nil {
+		return table, err
+	}
+	applyConstraints(&table, constraints)
+	return table, nil
+}
+
+// 主キー・一意制約・外部キー (カラムは制約の定義順)
+func (r *mysqlReader) constraints(name string) ([]constraint, error) {
+	rows, err := r.db.Query(
+		`SELECT
+			tc.CONSTRAINT_NAME
+			,tc.CONSTRAINT_TYPE
+			,k.COLUMN_NAME
+			,COALESCE(k.REFERENCED_TABLE_NAME, '')
+			,COALESCE(k.REFERENCED_COLUMN_NAME, '')
+		 FROM information_schema.TABLE_CONSTRAINTS tc
+		 JOIN information_schema.KEY_COLUMN_USAGE k
+		   ON k.CONSTRAINT_SCHEMA = tc.CONSTRAINT_SCHEMA
+		  AND k.TABLE_NAME = tc.TABLE_NAME
+		  AND k.CONSTRAINT_NAME = tc.CONSTRAINT_NAME
+		 WHERE tc.TABLE_SCHEMA = DATABASE() AND tc.TABLE_NAME = ?
+		   AND tc.CONSTRAINT_TYPE IN ('PRIMARY KEY', 'UNIQUE', 'FOREIGN KEY')
+		 ORDER BY tc.CONSTRAINT_TYPE, tc.CONSTRAINT_NAME, k.ORDINAL_POSITION`,
+		name,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	kinds := map[string]string{
+		"PRIMARY KEY": kindPrimaryKey,
+		"UNIQUE": kindUnique,
+		"FOREIGN KEY": kindForeignKey,
+	}
+	ret := []constraint{}
+	for rows.Next() {
+		var conName, conType, cn, refTable, refColumn string
+		if err := rows.Scan(&conName, &conType, &cn, &refTable, &refColumn); err != nil {
+			return nil, err
+		}
+		if len(ret) == 0 || ret[len(ret)-1].name != conName || ret[len(ret)-1].kind != kinds[conType] {
+			ret = append(ret, constraint{name: conName, kind: kinds[conType], refTable: refTable})
+		}
+		con := &ret[len(ret)-1]
+		con.columns = append(con.columns, cn)
+		if refColumn != "" {
+			con.refColumns = append(con.refColumns, refColumn)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	//主キーの制約名は常に PRIMARY のため指定しない
+	for i := range ret {
+		if ret[i].kind == kindPrimaryKey {
+			ret[i].name = ""
+		}
+	}
+	return ret, nil
+}
+
+
+// 例: int unsigned → INT UNSIGNED、varchar(255) → VARCHAR(255)、enum('a','b') はそのまま
+func mysqlDataType(s string) ddlparse.DataType {
+	lower := strings.ToLower(s)
+	if strings.HasPrefix(lower, "enum(") || strings.HasPrefix(lower, "set(") {
+		i := strings.Index(s, "(")
+		return ddlparse.DataType{Name: strings.ToUpper(s[:i]) + s[i:]}
+	}
+	dt, rest := parseDataType(s)
+	if strings.Contains(strings.ToLower(rest), "unsigned") {
+		dt.Name += " UNSIGNED"
+	}
+	return dt
+}
+
+/*
+ MySQL 8 は文字列を引用符無しで返し、式には EXTRA に DEFAULT_GENERATED が付く
+ MariaDB は文字列を引用符付きで返す
+*/
+func mysqlDefault(dflt sql.NullString, extra string, numeric bool) interface{} {
+	if !dflt.Valid {
+		return nil
+	}
+	s := dflt.String
+	upper := strings.ToUpper(s)
+	if strings.Contains(strings.ToUpper(extra), "DEFAULT_GENERATED") || strings.HasPrefix(upper, "CURRENT_TIMESTAMP") {
+		return s
+	}
+	if strings.HasPrefix(s, "'") || upper == "NULL" {
+		return parseDefault(s, numeric)
+	}
+	if numeric {
+		return parseDefault(s, true)
+	}
+	return s
+}
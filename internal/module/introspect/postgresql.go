@@ -0,0 +1,223 @@
+package introspect
+
+import (
+	"regexp"
+	"strings"
+	"database/sql"
+	"github.com/kodaimura/ddlparse"
+)
+
+
+// pg_catalog から読み取る
+type postgresqlReader struct {
+	db *sql.DB
+	schema string
+}
+
+func (r *postgresqlReader) schemaName() (string, error) {
+	if r.schema != "" {
+		return r.schema, nil
+	}
+	err := r.db.QueryRow(`SELECT current_schema()`).Scan(&r.schema)
+	return r.schema, err
+}
+
+func (r *postgresqlReader) tableNames() ([]string, error) {
+	schema, err := r.schemaName()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := r.db.Query(
+		`SELECT c.relname
+		 FROM pg_class c
+		 JOIN pg_namespace n ON n.oid = c.relnamespace
+		 WHERE n.nspname = $1 AND c.relkind IN ('r', 'p')
+		 ORDER BY c.relname`,
+		schema,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ret := []string{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		ret = append(ret, name)
+	}
+	return ret, rows.Err()
+}
+
+func (r *postgresqlReader) table(name string, comments map[string]string) (ddlparse.Table, error) {
+	table := ddlparse.Table{Name: name}
+	schema, err := r.schemaName()
+	if err != nil {
+		return table, err
+	}
+
+	var comment string
+	err = r.db.QueryRow(
+		`SELECT COALESCE(obj_description(c.oid, 'pg_class'), '')
+		 FROM pg_class c
+		 JOIN pg_namespace n ON n.oid = c.relnamespace
+		 WHERE n.nspname = $1 AND c.relname = $2`,
+		schema, name,
+	).Scan(&comment)
+	if err != nil {
+		return table, err
+	}
+	if comment != "" {
+		comments[name] = comment
+	}
+
+	rows, err := r.db.Query(
+		`SELECT
+			a.attname
+			,format_type(a.atttypid, a.atttypmod)
+			,a.attnotnull
+			,COALESCE(pg_get_expr(d.adbin, d.adrelid), '')
+			,a.attidentity <> ''
+			,COALESCE(col_description(c.oid, a.attnum), '')
+		 FROM pg_attribute a
+		 JOIN pg_class c ON c.oid = a.attrelid
+		 JOIN pg_namespace n ON n.oid = c.relnamespace
+		 LEFT JOIN pg_attrdef d ON d.adrelid = a.attrelid AND d.adnum = a.attnum
+		 WHERE n.nspname = $1 AND c.relname = $2 AND a.attnum > 0 AND NOT a.attisdropped
+		 ORDER BY a.attnum`,
+		schema, name,
+	)
+	if err != nil {
+		return table, err
+	}
+	for rows.Next() {
+		var cn, ct, dflt, comment string
+		var notNull, identity bool
+		if err := rows.Scan(&cn, &ct, &notNull, &dflt, &identity, &comment); err != nil {
+			rows.Close()
+			return table, err
+		}
+		dt := postgresqlDataType(ct)
+
+		//連番 (nextval のデフォルト・IDENTITY) は SERIAL とする
+		if identity || strings.HasPrefix(dflt, "nextval(") {
+			if serial, ok := postgresqlSerialTypes[dt.Name]; ok {
+				dt = ddlparse.DataType{Name: serial}
+				dflt = ""
+			}
+		}
+		table.Columns = append(table.Columns, ddlparse.Column{
+			Name: cn,
+			DataType: dt,
+			Constraint: ddlparse.Constraint{
+				IsNotNull: notNull,
+				Default: parseDefault(postgresqlTrimCast(dflt), isNumericType(dt.Name)),
+			},
+		})
+		if comment != "" {
+			comments[name + "." + cn] = comment
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return table, err
+	}
+
+	constraints, err := r.constraints(schema, name)
+	if err != nil {
+		return table, err
+	}
+	applyConstraints(&table, constraints)
+	return table, nil
+}
+
+// 主キー・一意制約・外部キー (カラムは制約の定義順)
+func (r *postgresqlReader) constraints(schema string, name string) ([]constraint, error) {
+	rows, err := r.db.Query(
+		`SELECT
+			con.conname
+			,con.contype
+			,array_to_string(ARRAY(
+				SELECT a.attname FROM unnest(con.conkey) WITH ORDINALITY k(attnum, ord)
+				JOIN pg_attribute a ON a.attrelid = con.conrelid AND a.attnum = k.attnum
+				ORDER BY k.ord
+			), ',')
+			,COALESCE((SELECT f.relname FROM pg_class f WHERE f.oid = con.confrelid), '')
+			,array_to_string(ARRAY(
+				SELECT a.attname FROM unnest(con.confkey) WITH ORDINALITY k(attnum, ord)
+				JOIN pg_attribute a ON a.attrelid = con.confrelid AND a.attnum = k.attnum
+				ORDER BY k.ord
+			), ',')
+		 FROM pg_constraint con
+		 JOIN pg_class c ON c.oid = con.conrelid
+		 JOIN pg_namespace n ON n.oid = c.relnamespace
+		 WHERE n.nspname = $1 AND c.relname = $2 AND con.contype IN ('p', 'u', 'f')
+		 ORDER BY con.contype, con.conname`,
+		schema, name,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ret := []constraint{}
+	for rows.Next() {
+		var con constraint
+		var columns, refColumns string
+		if err := rows.Scan(&con.name, &con.kind, &columns, &con.refTable, &refColumns); err != nil {
+			return nil, err
+		}
+		con.columns = strings.Split(columns, ",")
+		if refColumns != "" {
+			con.refColumns = strings.Split(refColumns, ",")
+		}
+		ret = append(ret, con)
+	}
+	return ret, rows.Err()
+}
+
+
+var postgresqlSerialTypes = map[string]string{
+	"SMALLINT": "SMALLSERIAL",
+	"INTEGER": "SERIAL",
+	"BIGINT": "BIGSERIAL",
+}
+
+// format_type の別名を DDL で一般的な名前にする
+var postgresqlTypeNames = map[string]string{
+	"CHARACTER VARYING": "VARCHAR",
+	"BIT VARYING": "VARBIT",
+	"TIMESTAMP WITHOUT TIME ZONE": "TIMESTAMP",
+	"TIMESTAMP WITH TIME ZONE": "TIMESTAMPTZ",
+	"TIME WITHOUT TIME ZONE": "TIME",
+	"TIME WITH TIME ZONE": "TIMETZ",
+}
+
+var rePostgresqlTimeZone = regexp.MustCompile(`(?i)^(timestamp|time)(\(\d+\))? (with(?:out)? time zone)$`)
+
+// 例: character varying(255) → VARCHAR(255)、timestamp(3) with time zone → TIMESTAMPTZ(3)
+func postgresqlDataType(s string) ddlparse.DataType {
+	if m := rePostgresqlTimeZone.FindStringSubmatch(s); m != nil {
+		s = m[1] + " " + m[3] + m[2]
+	}
+	dt, rest := parseDataType(s)
+	if name, ok := postgresqlTypeNames[dt.Name]; ok {
+		dt.Name = name
+	}
+	if rest == "[]" {
+		dt.Name += "[]"
+	}
+	return dt
+}
+
+var rePostgresqlCast = regexp.MustCompile(`^('(?:[^']|'')*'|NULL|-?[0-9.]+)::[a-z ]+(\[\])?$`)
+
+// リテラルの型変換を除く (例: 'a'::character varying → 'a')
+func postgresqlTrimCast(s string) string {
+	if m := rePostgresqlCast.FindStringSubmatch(s); m != nil {
+		return m[1]
+	}
+	return s
+}
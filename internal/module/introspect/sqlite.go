@@ -0,0 +1,191 @@
+package introspect
+
+import (
+	"strings"
+	"database/sql"
+	"github.com/kodaimura/ddlparse"
+)
+
+
+// sqlite_master と pragma_* から読み取る (SQLiteにコメントは無い)
+type sqliteReader struct {
+	db *sql.DB
+}
+
+func (r *sqliteReader) tableNames() ([]string, error) {
+	rows, err := r.db.Query(
+		`SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%' ORDER BY rowid`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ret := []string{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		ret = append(ret, name)
+	}
+	return ret, rows.Err()
+}
+
+func (r *sqliteReader) table(name string, comments map[string]string) (ddlparse.Table, error) {
+	table := ddlparse.Table{Name: name}
+
+	var ddl string
+	if err := r.db.QueryRow(`SELECT sql FROM sqlite_master WHERE type = 'table' AND name = ?`, name).Scan(&ddl); err != nil {
+		return table, err
+	}
+
+	rows, err := r.db.Query(`SELECT name, type, "notnull", dflt_value, pk FROM pragma_table_info(?) ORDER BY cid`, name)
+	if err != nil {
+		return table, err
+	}
+	defer rows.Close()
+
+	pk := map[int]string{}
+	for rows.Next() {
+		var cn, ct string
+		var notNull bool
+		var dflt sql.NullString
+		var pkSeq int
+		if err := rows.Scan(&cn, &ct, &notNull, &dflt, &pkSeq); err != nil {
+			return table, err
+		}
+		dt, _ := parseDataType(ct)
+		table.Columns = append(table.Columns, ddlparse.Column{
+			Name: cn,
+			DataType: dt,
+			Constraint: ddlparse.Constraint{
+				IsNotNull: notNull,
+				Default: parseDefault(dflt.String, isNumericType(dt.Name)),
+			},
+		})
+		if pkSeq > 0 {
+			pk[pkSeq] = cn
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return table, err
+	}
+
+	constraints := []constraint{}
+	if len(pk) > 0 {
+		pkcols := make([]string, len(pk))
+		for seq, cn := range pk {
+			pkcols[seq - 1] = cn
+		}
+		constraints = append(constraints, constraint{kind: kindPrimaryKey, columns: pkcols})
+
+		//AUTOINCREMENT は INTEGER PRIMARY KEY の1カラムのみ指定できる
+		if len(pkcols) == 1 && strings.Contains(strings.ToUpper(ddl), "AUTOINCREMENT") {
+			column(&table, pkcols[0]).Constraint.IsAutoincrement = true
+		}
+	}
+
+	uniques, err := r.uniques(name)
+	if err != nil {
+		return table, err
+	}
+	foreignKeys, err := r.foreignKeys(name)
+	if err != nil {
+		return table, err
+	}
+	applyConstraints(&table, append(append(constraints, uniques...), foreignKeys...))
+	return table, nil
+}
+
+// UNIQUE 制約 (CREATE UNIQUE INDEX の索引は含めない)
+func (r *sqliteReader) uniques(name string) ([]constraint, error) {
+	rows, err := r.db.Query(`SELECT name FROM pragma_index_list(?) WHERE "unique" = 1 AND origin = 'u' ORDER BY seq DESC`, name)
+	if err != nil {
+		return nil, err
+	}
+	indexes := []string{}
+	for rows.Next() {
+		var index string
+		if err := rows.Scan(&index); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		indexes = append(indexes, index)
+	}
+	rows.Close()
+
+	ret := []constraint{}
+	for _, index := range indexes {
+		rows, err := r.db.Query(`SELECT name FROM pragma_index_info(?) ORDER BY seqno`, index)
+		if err != nil {
+			return nil, err
+		}
+		con := constraint{kind: kindUnique}
+		for rows.Next() {
+			var cn string
+			if err := rows.Scan(&cn); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			con.columns = append(con.columns, cn)
+		}
+		rows.Close()
+		ret = append(ret, con)
+	}
+	return ret, nil
+}
+
+// 参照先のカラムが省略されている場合 (主キーを参照) は参照先の主キー
+func (r *sqliteReader) foreignKeys(name string) ([]constraint, error) {
+	rows, err := r.db.Query(`SELECT id, "table", "from", "to" FROM pragma_foreign_key_list(?) ORDER BY id, seq`, name)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := []constraint{}
+	ids := map[int]int{}
+	for rows.Next() {
+		var id int
+		var refTable, from string
+		var to sql.NullString
+		if err := rows.Scan(&id, &refTable, &from, &to); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		i, found := ids[id]
+		if !found {
+			i = len(ret)
+			ids[id] = i
+			ret = append(ret, constraint{kind: kindForeignKey, refTable: refTable})
+		}
+		ret[i].columns = append(ret[i].columns, from)
+		if to.Valid {
+			ret[i].refColumns = append(ret[i].refColumns, to.String)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range ret {
+		if len(ret[i].refColumns) > 0 {
+			continue
+		}
+		pkrows, err := r.db.Query(`SELECT name FROM pragma_table_info(?) WHERE pk > 0 ORDER BY pk`, ret[i].refTable)
+		if err != nil {
+			return nil, err
+		}
+		for pkrows.Next() {
+			var cn string
+			if err := pkrows.Scan(&cn); err != nil {
+				pkrows.Close()
+				return nil, err
+			}
+			ret[i].refColumns = append(ret[i].refColumns, cn)
+		}
+		pkrows.Close()
+	}
+	return ret, nil
+}
@@ -0,0 +1,203 @@
+package merge
+
+import (
+	"bytes"
+	"strings"
+	"unicode/utf8"
+)
+
+
+/*
+ 行単位の3方向マージ (diff3)
+ base (前回の生成) からの ours (現在のプロジェクト) と theirs (今回の生成) の変更を合わせる
+ 両方が同じ箇所を異なる内容に変更した場合は競合とし、git と同じ形式のマーカーで両方を残す
+*/
+
+// 競合のマーカーの名前
+type Labels struct {
+	Ours string
+	Base string
+	Theirs string
+}
+
+type Result struct {
+	Text []byte
+	// 競合の箇所数
+	Conflicts int
+}
+
+// テキストとしてマージできる内容 (UTF-8 で NUL を含まない)
+func IsText(b []byte) bool {
+	return utf8.Valid(b) && bytes.IndexByte(b, 0) < 0
+}
+
+func Merge(base, ours, theirs []byte, labels Labels) Result {
+	if bytes.Equal(ours, theirs) || bytes.Equal(base, theirs) {
+		return Result{Text: ours}
+	}
+	if bytes.Equal(base, ours) {
+		return Result{Text: theirs}
+	}
+
+	b, o, t := splitLines(base), splitLines(ours), splitLines(theirs)
+	mo, mt := matches(b, o), matches(b, t)
+
+	var out strings.Builder
+	conflicts := 0
+	i, io, it := 0, 0, 0
+	for i < len(b) || io < len(o) || it < len(t) {
+		//次の3つで共通の行
+		k := i
+		for k < len(b) && (mo[k] < 0 || mt[k] < 0) {
+			k++
+		}
+		if k < len(b) && k == i && mo[k] == io && mt[k] == it {
+			out.WriteString(b[i])
+			i, io, it = i + 1, io + 1, it + 1
+			continue
+		}
+		eo, et := len(o), len(t)
+		if k < len(b) {
+			eo, et = mo[k], mt[k]
+		}
+		cb, co, ct := b[i:k], o[io:eo], t[it:et]
+		switch {
+		case equalLines(cb, co):
+			writeLines(&out, ct)
+		case equalLines(cb, ct), equalLines(co, ct):
+			writeLines(&out, co)
+		default:
+			conflicts++
+			writeConflict(&out, cb, co, ct, labels)
+		}
+		i, io, it = k, eo, et
+	}
+	return Result{Text: []byte(out.String()), Conflicts: conflicts}
+}
+
+// 改行を含めて行に分ける
+func splitLines(b []byte) []string {
+	if len(b) == 0 {
+		return nil
+	}
+	lines := strings.SplitAfter(string(b), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+func equalLines(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func writeLines(out *strings.Builder, lines []string) {
+	for _, l := range lines {
+		out.WriteString(l)
+	}
+}
+
+func writeConflict(out *strings.Builder, base, ours, theirs []string, labels Labels) {
+	section := func(marker string, label string, lines []string) {
+		if label != "" {
+			marker += " " + label
+		}
+		out.WriteString(marker + "\n")
+		writeLines(out, lines)
+		//最終行に改行がない場合
+		if len(lines) > 0 && !strings.HasSuffix(lines[len(lines)-1], "\n") {
+			out.WriteString("\n")
+		}
+	}
+	section("<<<<<<<", labels.Ours, ours)
+	section("|||||||", labels.Base, base)
+	section("=======", "", nil)
+	writeLines(out, theirs)
+	if len(theirs) > 0 && !strings.HasSuffix(theirs[len(theirs)-1], "\n") {
+		out.WriteString("\n")
+	}
+	out.WriteString(">>>>>>> " + labels.Theirs + "\n")
+}
+
+/*
+ a の各行に対応する b の行の位置 (対応しない行は -1)
+ Myers の差分アルゴリズムで最長共通部分列を求める
+*/
+func matches(a, b []string) []int {
+	ret := make([]int, len(a))
+	for i := range ret {
+		ret[i] = -1
+	}
+	//前後の共通部分は除いて比較する
+	pre := 0
+	for pre < len(a) && pre < len(b) && a[pre] == b[pre] {
+		ret[pre] = pre
+		pre++
+	}
+	suf := 0
+	for suf < len(a) - pre && suf < len(b) - pre && a[len(a)-1-suf] == b[len(b)-1-suf] {
+		ret[len(a)-1-suf] = len(b) - 1 - suf
+		suf++
+	}
+	ma, mb := a[pre:len(a)-suf], b[pre:len(b)-suf]
+	n, m := len(ma), len(mb)
+	if n == 0 || m == 0 {
+		return ret
+	}
+
+	max := n + m
+	off := max + 1
+	v := make([]int, 2 * max + 3)
+	trace := [][]int{}
+	for d := 0; d <= max; d++ {
+		trace = append(trace, append([]int{}, v...))
+		for k := -d; k <= d; k += 2 {
+			x := 0
+			if k == -d || (k != d && v[k-1+off] < v[k+1+off]) {
+				x = v[k+1+off]
+			} else {
+				x = v[k-1+off] + 1
+			}
+			y := x - k
+			for x < n && y < m && ma[x] == mb[y] {
+				x, y = x + 1, y + 1
+			}
+			v[k+off] = x
+			if x >= n && y >= m {
+				backtrack(trace, d, n, m, off, func(x, y int) { ret[pre+x] = pre + y })
+				return ret
+			}
+		}
+	}
+	return ret
+}
+
+func backtrack(trace [][]int, d int, x int, y int, off int, match func(int, int)) {
+	for ; d > 0; d-- {
+		v := trace[d]
+		k := x - y
+		prevK := k - 1
+		if k == -d || (k != d && v[k-1+off] < v[k+1+off]) {
+			prevK = k + 1
+		}
+		prevX := v[prevK+off]
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			x, y = x - 1, y - 1
+			match(x, y)
+		}
+		x, y = prevX, prevY
+	}
+	for x > 0 && y > 0 {
+		x, y = x - 1, y - 1
+		match(x, y)
+	}
+}
@@ -21,6 +21,7 @@ func router() *gin.Engine {
 	//STATIC
 	r.Static("/css", "web/static/css")
 	r.Static("/js", "web/static/js")
+	r.Static("/sample", "web/static/sample")
 	r.Static("/output", "./output")
 
 	SetRouter(r)
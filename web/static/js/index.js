@@ -1,19 +1,92 @@
+const DEFAULT_PORTS = { postgresql: '5432', mysql: '3306' };
+
+/* テーブル定義の入力 (スキーマファイル / テーブル定義書 / データベース) とRDBMSに応じて入力欄を切り替え */
+const renderSourceFields = () => {
+	const fromDb = document.getElementById('source_db').checked;
+	const fromTabledef = document.getElementById('source_tabledef').checked;
+	const rdbms = document.getElementById('rdbms').value;
+	document.getElementById('source_ddl_fields').classList.toggle('d-none', fromDb || fromTabledef);
+	document.getElementById('source_tabledef_fields').classList.toggle('d-none', !fromTabledef);
+	document.getElementById('source_db_fields').classList.toggle('d-none', !fromDb);
+	document.querySelectorAll('.db-server').forEach(e => e.classList.toggle('d-none', rdbms === 'sqlite3'));
+	document.querySelectorAll('.db-postgresql').forEach(e => e.classList.toggle('d-none', rdbms !== 'postgresql'));
+	document.querySelectorAll('.db-sqlite3').forEach(e => e.classList.toggle('d-none', rdbms !== 'sqlite3'));
+	document.getElementById('db_port').placeholder = DEFAULT_PORTS[rdbms] ?? '';
+}
+
+document.getElementById('source_ddl').addEventListener('change', renderSourceFields);
+document.getElementById('source_tabledef').addEventListener('change', renderSourceFields);
+document.getElementById('source_db').addEventListener('change', renderSourceFields);
+document.getElementById('rdbms').addEventListener('change', renderSourceFields);
+
+/* データベースの接続設定 */
+const appendDbSettings = (formData, rdbms) => {
+	const dbFile = document.getElementById('db_file').files[0];
+	if (rdbms === 'sqlite3' && dbFile !== undefined) {
+		formData.append('db_file', dbFile);
+	}
+	formData.append('db_name', document.getElementById('db_name').value.trim());
+	formData.append('db_host', document.getElementById('db_host').value.trim() || 'localhost');
+	formData.append('db_port', document.getElementById('db_port').value.trim() || (DEFAULT_PORTS[rdbms] ?? ''));
+	formData.append('db_user', document.getElementById('db_user').value);
+	formData.append('db_password', document.getElementById('db_password').value);
+	formData.append('db_schema', document.getElementById('db_schema').value.trim());
+	formData.append('db_sslmode', document.getElementById('db_sslmode').value);
+	formData.append('db_tables', document.getElementById('db_tables').value);
+}
+
 document.getElementById('generate').addEventListener('click', () => {
 	document.getElementById('message').innerHTML = '';
 
+	const fromDb = document.getElementById('source_db').checked;
+	const fromTabledef = document.getElementById('source_tabledef').checked;
 	const ddl = document.getElementById('ddl').files[0];
+	const tabledef = document.getElementById('tabledef').files[0];
 	const lang = document.getElementById('lang').value;
 	const rdbms = document.getElementById('rdbms').value;
+	const authMode = document.getElementById('auth_mode').value;
+	const bodyKeyRoutes = document.getElementById('body_key_routes').checked;
+	const history = document.getElementById('history').checked;
+	const apiTokens = document.getElementById('api_tokens').checked;
+	const softDeleteColumn = document.getElementById('soft_delete_column').value.trim();
+	const previous = document.getElementById('previous').files[0];
+	const merge = document.getElementById('merge').checked;
 
-	if (ddl === undefined) {
-		renderMessage("DDLファイルが選択されていません。", false);
+	if (!fromDb && !fromTabledef && ddl === undefined) {
+		renderMessage("スキーマファイルが選択されていません。", false);
+		return;
+	}
+	if (fromTabledef && tabledef === undefined) {
+		renderMessage("テーブル定義書が選択されていません。", false);
+		return;
+	}
+	if (merge && (previous === undefined || !previous.name.toLowerCase().endsWith('.zip'))) {
+		renderMessage("マージする場合は前回の入力に前回生成したプロジェクトのzipを選択してください。", false);
 		return;
 	}
 
 	const formData = new FormData();
-	formData.append('ddl', ddl);
+	if (fromDb) {
+		formData.append('source', 'db');
+		appendDbSettings(formData, rdbms);
+	} else if (fromTabledef) {
+		formData.append('source', 'tabledef');
+		formData.append('tabledef', tabledef);
+	} else {
+		formData.append('ddl', ddl);
+		formData.append('ddl_rdbms', document.getElementById('ddl_rdbms').value);
+	}
 	formData.append('lang', lang);
 	formData.append('rdbms', rdbms);
+	formData.append('auth_mode', authMode);
+	formData.append('body_key_routes', bodyKeyRoutes);
+	formData.append('history', history);
+	formData.append('api_tokens', apiTokens);
+	formData.append('soft_delete_column', softDeleteColumn);
+	if (previous !== undefined) {
+		formData.append('previous', previous);
+	}
+	formData.append('merge', merge);
 
 	fetch('/generate', {
 		method: 'POST',
@@ -24,6 +97,8 @@ document.getElementById('generate').addEventListener('click', () => {
 		.then(data => {
 			if (response.ok) {
 				download(data.zip)
+				renderNotes(data.notes ?? [])
+				renderNotes(data.merge ?? [])
 			} else {
 				handleErrors(data.errors)
 			}
@@ -38,6 +113,8 @@ const download = (zip) => {
 	alink.href = `output/${zip}`;
 	alink.click();
 	document.getElementById('ddl').value = ''
+	document.getElementById('tabledef').value = ''
+	document.getElementById('previous').value = ''
 	renderMessage(`${zip} がダウンロードされました。`, true);
 }
 
@@ -47,6 +124,16 @@ const handleErrors = (errors) => {
 	}
 }
 
+/* 他のRDBMSのDDLから変換できなかった構文・前回生成したプロジェクトへのマージの結果 */
+const renderNotes = (notes) => {
+	for (const note of notes) {
+		let message = document.createElement('div');
+		message.textContent = note;
+		message.className = 'alert alert-warning alert-custom my-1';
+		document.getElementById('message').appendChild(message);
+	}
+}
+
 const renderMessage = (msg, isSuccess) => {
 	let message = document.createElement('div');
 	message.textContent = msg;